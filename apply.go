@@ -0,0 +1,289 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ApplyChangeSet applies the patches in cs to the files under cs.Dir. Every
+// change is checked against the current file contents first; if any of them
+// conflicts, nothing is written and the returned error aggregates a
+// *PatchConflictError per conflicting file. Files are then replaced one by one
+// and, should a write fail, those already written are restored.
+//
+// Example:
+//
+//	proposal, err := DryRun(ctx, "Fix the failing test", opts)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if approved(proposal.Changes) {
+//	    err = ApplyChangeSet(ctx, proposal.Changes)
+//	}
+func ApplyChangeSet(ctx context.Context, cs *ChangeSet) error {
+	if cs.IsEmpty() {
+		return nil
+	}
+	root, err := filepath.Abs(cs.Dir)
+	if err != nil {
+		return fmt.Errorf("invalid change set directory: %w", err)
+	}
+
+	// Plan every change before touching the filesystem
+	var plans []filePlan
+	var conflicts Errors
+	for _, change := range cs.Changes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		plan, err := planChange(root, change)
+		if err != nil {
+			conflicts = append(conflicts, err)
+			continue
+		}
+		plans = append(plans, plan)
+	}
+	if err := conflicts.ErrorOrNil(); err != nil {
+		return err
+	}
+
+	for i, plan := range plans {
+		if err := ctx.Err(); err == nil {
+			err = plan.commit()
+		}
+		if err != nil {
+			for j := i - 1; j >= 0; j-- {
+				plans[j].revert()
+			}
+			return fmt.Errorf("failed to apply change to %s: %w", plan.change.Path, err)
+		}
+	}
+	return nil
+}
+
+// filePlan is the planned new state of one file, along with its current
+// state so the change can be reverted
+type filePlan struct {
+	change  FileChange
+	path    string
+	existed bool
+	oldData []byte
+	mode    fs.FileMode
+	newData []byte // nil deletes the file
+}
+
+// planChange checks change against the file on disk and computes the result
+func planChange(root string, change FileChange) (filePlan, error) {
+	conflict := func(reason string) (filePlan, error) {
+		return filePlan{}, NewPatchConflictError(change.Path, reason)
+	}
+
+	rel := filepath.FromSlash(change.Path)
+	if filepath.IsAbs(rel) || !filepath.IsLocal(rel) {
+		return conflict("path is outside the change set directory")
+	}
+	plan := filePlan{change: change, path: filepath.Join(root, rel), mode: 0644}
+
+	info, err := os.Stat(plan.path)
+	switch {
+	case err == nil:
+		if !info.Mode().IsRegular() {
+			return conflict("not a regular file")
+		}
+		if plan.oldData, err = os.ReadFile(plan.path); err != nil {
+			return conflict(err.Error())
+		}
+		plan.existed = true
+		plan.mode = info.Mode().Perm()
+	case !os.IsNotExist(err):
+		return conflict(err.Error())
+	}
+
+	switch {
+	case change.Op == ChangeAdded && plan.existed:
+		return conflict("file already exists")
+	case change.Op != ChangeAdded && !plan.existed:
+		return conflict("file does not exist")
+	}
+
+	hunks, err := parseHunks(change.Patch)
+	if err != nil {
+		return conflict(err.Error())
+	}
+	result, err := applyHunks(splitLines(plan.oldData), hunks)
+	if err != nil {
+		return conflict(err.Error())
+	}
+
+	if change.Op == ChangeDeleted {
+		if len(result) != 0 {
+			return conflict("file has content the deletion does not account for")
+		}
+		return plan, nil
+	}
+	plan.newData = []byte(strings.Join(result, ""))
+	return plan, nil
+}
+
+// commit writes the planned state, replacing files through a rename
+func (p *filePlan) commit() error {
+	if p.newData == nil {
+		return os.Remove(p.path)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(p.path, p.newData, p.mode)
+}
+
+// revert restores the state recorded when the change was planned
+func (p *filePlan) revert() {
+	if !p.existed {
+		os.Remove(p.path)
+		return
+	}
+	writeFileAtomic(p.path, p.oldData, p.mode)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place
+func writeFileAtomic(path string, data []byte, mode fs.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// hunk is one parsed unified diff hunk
+type hunk struct {
+	oldStart int // 0-based line the hunk applies at
+	lines    []diffLine
+}
+
+// parseHunks parses the hunks of a unified diff, ignoring file headers
+func parseHunks(patch string) ([]hunk, error) {
+	if strings.HasPrefix(patch, "Binary files ") {
+		return nil, fmt.Errorf("binary patches cannot be applied")
+	}
+
+	var hunks []hunk
+	for _, line := range splitLines([]byte(patch)) {
+		switch {
+		case strings.HasPrefix(line, "@@ "):
+			start, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			hunks = append(hunks, hunk{oldStart: start})
+		case len(hunks) == 0:
+			// File headers such as "---", "+++" and "diff --git"
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" applies to the previous line
+			h := &hunks[len(hunks)-1]
+			if len(h.lines) > 0 {
+				last := &h.lines[len(h.lines)-1]
+				last.text = strings.TrimSuffix(last.text, "\n")
+			}
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			h := &hunks[len(hunks)-1]
+			h.lines = append(h.lines, diffLine{kind: line[0], text: line[1:]})
+		default:
+			return nil, fmt.Errorf("malformed patch line %q", strings.TrimSuffix(line, "\n"))
+		}
+	}
+	return hunks, nil
+}
+
+// parseHunkHeader returns the 0-based old line a hunk header refers to
+func parseHunkHeader(line string) (int, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") {
+		return 0, fmt.Errorf("malformed hunk header %q", strings.TrimSpace(line))
+	}
+	old := strings.TrimPrefix(fields[1], "-")
+	start, count := old, "1"
+	if i := strings.IndexByte(old, ','); i >= 0 {
+		start, count = old[:i], old[i+1:]
+	}
+	n, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, fmt.Errorf("malformed hunk header %q", strings.TrimSpace(line))
+	}
+	// An empty range names the line before it
+	if count == "0" {
+		return n, nil
+	}
+	return n - 1, nil
+}
+
+// applyHunks applies hunks to lines. A hunk whose context has moved is
+// searched for near its recorded position; one whose context cannot be found
+// is a conflict.
+func applyHunks(lines []string, hunks []hunk) ([]string, error) {
+	var out []string
+	pos := 0
+	for i, h := range hunks {
+		var old, updated []string
+		for _, line := range h.lines {
+			if line.kind != '+' {
+				old = append(old, line.text)
+			}
+			if line.kind != '-' {
+				updated = append(updated, line.text)
+			}
+		}
+
+		at := findHunk(lines, old, pos, h.oldStart)
+		if at < 0 {
+			return nil, fmt.Errorf("hunk %d does not match the current file", i+1)
+		}
+		out = append(out, lines[pos:at]...)
+		out = append(out, updated...)
+		pos = at + len(old)
+	}
+	return append(out, lines[pos:]...), nil
+}
+
+// findHunk returns the position at or after min where old matches lines,
+// preferring the one closest to want, or -1
+func findHunk(lines, old []string, min, want int) int {
+	matches := func(at int) bool {
+		if at < min || at+len(old) > len(lines) {
+			return false
+		}
+		for k, line := range old {
+			if lines[at+k] != line {
+				return false
+			}
+		}
+		return true
+	}
+
+	for offset := 0; want-offset >= min || want+offset <= len(lines); offset++ {
+		if matches(want - offset) {
+			return want - offset
+		}
+		if matches(want + offset) {
+			return want + offset
+		}
+	}
+	return -1
+}
@@ -0,0 +1,131 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recordChanges snapshots dir, applies edit and returns the resulting changes
+// after restoring dir to its original state
+func recordChanges(t *testing.T, dir string, edit func()) *ChangeSet {
+	t.Helper()
+	before, err := TakeSnapshot(context.Background(), dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit()
+	cs, err := before.Changes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := before.Restore(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return cs
+}
+
+func TestApplyChangeSet(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"edit.txt":   "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n",
+		"remove.txt": "bye\n",
+		"tail.txt":   "no newline",
+	})
+
+	cs := recordChanges(t, dir, func() {
+		writeTree(t, dir, map[string]string{
+			"edit.txt":    "one\n2\nthree\nfour\nfive\nsix\nseven\neight\nnine\n10\n",
+			"tail.txt":    "no newline\nnow",
+			"sub/new.txt": "fresh\n",
+		})
+		os.Remove(filepath.Join(dir, "remove.txt"))
+	})
+
+	if err := ApplyChangeSet(context.Background(), cs); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(dir, "edit.txt"), "one\n2\nthree\nfour\nfive\nsix\nseven\neight\nnine\n10\n")
+	assertFile(t, filepath.Join(dir, "tail.txt"), "no newline\nnow")
+	assertFile(t, filepath.Join(dir, "sub", "new.txt"), "fresh\n")
+	if _, err := os.Stat(filepath.Join(dir, "remove.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected remove.txt to be deleted, got %v", err)
+	}
+}
+
+func TestApplyChangeSetOffset(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"f.txt": "a\nb\nc\nd\n"})
+	cs := recordChanges(t, dir, func() {
+		writeTree(t, dir, map[string]string{"f.txt": "a\nb\nC\nd\n"})
+	})
+
+	// Unrelated lines inserted above the hunk shift it down
+	writeTree(t, dir, map[string]string{"f.txt": "x\ny\na\nb\nc\nd\n"})
+	if err := ApplyChangeSet(context.Background(), cs); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(dir, "f.txt"), "x\ny\na\nb\nC\nd\n")
+}
+
+func TestApplyChangeSetConflict(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	cs := recordChanges(t, dir, func() {
+		writeTree(t, dir, map[string]string{"a.txt": "A\n", "b.txt": "B\n", "c.txt": "c\n"})
+	})
+
+	// b.txt and c.txt diverge from what the changes were made against
+	writeTree(t, dir, map[string]string{"b.txt": "changed\n", "c.txt": "exists\n"})
+
+	err := ApplyChangeSet(context.Background(), cs)
+	if err == nil {
+		t.Fatal("expected conflict")
+	}
+	var conflicts Errors
+	if !errors.As(err, &conflicts) || len(conflicts) != 2 {
+		t.Fatalf("expected two conflicts, got %v", err)
+	}
+	var conflict *PatchConflictError
+	if !errors.As(err, &conflict) || conflict.Path != "b.txt" {
+		t.Errorf("expected conflict on b.txt, got %v", err)
+	}
+
+	// Nothing is written when any change conflicts
+	assertFile(t, filepath.Join(dir, "a.txt"), "a\n")
+	assertFile(t, filepath.Join(dir, "b.txt"), "changed\n")
+	assertFile(t, filepath.Join(dir, "c.txt"), "exists\n")
+}
+
+func TestApplyChangeSetRejectsEscapingPaths(t *testing.T) {
+	cs := &ChangeSet{Dir: t.TempDir(), Changes: []FileChange{{
+		Path:  "../evil.txt",
+		Op:    ChangeAdded,
+		Patch: "--- /dev/null\n+++ b/../evil.txt\n@@ -0,0 +1 @@\n+x\n",
+	}}}
+	var conflict *PatchConflictError
+	if err := ApplyChangeSet(context.Background(), cs); !errors.As(err, &conflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+}
+
+func TestApplyGitChanges(t *testing.T) {
+	repo := initGitRepo(t, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	writeTree(t, repo, map[string]string{"main.go": "package main\n\nfunc main() { run() }\n", "new.go": "package main\n"})
+
+	cs, err := GitChanges(context.Background(), repo, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(context.Background(), repo, "stash", "-u"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ApplyChangeSet(context.Background(), cs); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(repo, "main.go"), "package main\n\nfunc main() { run() }\n")
+	assertFile(t, filepath.Join(repo, "new.go"), "package main\n")
+}
@@ -0,0 +1,150 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+)
+
+// ApprovalAction is a reviewer's verdict on a turn
+type ApprovalAction string
+
+const (
+	// ApprovalApprove accepts the turn
+	ApprovalApprove ApprovalAction = "approve"
+	// ApprovalReject refuses the turn, reverting its file changes
+	ApprovalReject ApprovalAction = "reject"
+	// ApprovalAmend keeps the turn and sends feedback for another round
+	ApprovalAmend ApprovalAction = "amend"
+)
+
+// ApprovalDecision answers a TurnReview
+type ApprovalDecision struct {
+	Action ApprovalAction
+	// Feedback is the next prompt when amending and the reason when
+	// rejecting
+	Feedback string
+}
+
+// ApproveTurn accepts a turn
+func ApproveTurn() ApprovalDecision {
+	return ApprovalDecision{Action: ApprovalApprove}
+}
+
+// RejectTurn refuses a turn, explaining why
+func RejectTurn(reason string) ApprovalDecision {
+	return ApprovalDecision{Action: ApprovalReject, Feedback: reason}
+}
+
+// AmendTurn asks Claude to revise a turn, sending feedback as the next
+// prompt
+func AmendTurn(feedback string) ApprovalDecision {
+	return ApprovalDecision{Action: ApprovalAmend, Feedback: feedback}
+}
+
+// TurnReview is a completed turn awaiting a decision
+type TurnReview struct {
+	Round    int    // 1 for the first turn, incremented by each amendment
+	Prompt   string // Prompt that started the turn
+	Messages []Message
+	Result   ResultMessage
+	Changes  *ChangeSet // Files changed under TurnApproval.Dir; nil if Dir is empty
+}
+
+// TurnApproval configures Client.SendWithApproval
+type TurnApproval struct {
+	// Review decides on each completed turn. The session waits until it
+	// returns, so it may block on a human; ctx ends when the caller's does.
+	Review func(ctx context.Context, turn TurnReview) ApprovalDecision
+	// Dir, when set, is snapshotted before each turn so that its changes are
+	// shown to Review as a ChangeSet and reverted if the turn is rejected
+	Dir string
+	// Skip lists file or directory names under Dir that are not tracked,
+	// e.g. ".git" or "node_modules"
+	Skip []string
+}
+
+// SendWithApproval sends prompt and pauses after the turn until
+// approval.Review decides on it. Approving returns the turn's messages.
+// Amending sends the feedback as the next turn and reviews that one in turn.
+// Rejecting reverts the rejected turn's changes under approval.Dir and
+// returns a *TurnRejectedError; the session stays open either way. If Review
+// panics or ctx ends while it runs, the turn is reverted as if rejected. The
+// returned messages span every round.
+//
+// Example:
+//
+//	messages, err := client.SendWithApproval(ctx, "Refactor the parser", &TurnApproval{
+//	    Dir: repo,
+//	    Review: func(ctx context.Context, turn TurnReview) ApprovalDecision {
+//	        fmt.Print(turn.Changes.Patch())
+//	        return askUser(ctx)
+//	    },
+//	})
+func (c *Client) SendWithApproval(ctx context.Context, prompt string, approval *TurnApproval) ([]Message, error) {
+	if approval == nil || approval.Review == nil {
+		return nil, fmt.Errorf("turn approval requires a Review callback")
+	}
+
+	var all []Message
+	for round := 1; ; round++ {
+		var snapshot *Snapshot
+		if approval.Dir != "" {
+			var err error
+			if snapshot, err = TakeSnapshot(ctx, approval.Dir, approval.Skip); err != nil {
+				return all, err
+			}
+		}
+
+		if err := c.SendMessage(ctx, prompt); err != nil {
+			return all, err
+		}
+		messages, err := c.ReceiveResponse(ctx)
+		all = append(all, messages...)
+		if err != nil {
+			return all, err
+		}
+
+		turn := TurnReview{Round: round, Prompt: prompt, Messages: messages}
+		turn.Result, _ = messages[len(messages)-1].(ResultMessage)
+		if snapshot != nil {
+			if turn.Changes, err = snapshot.Changes(ctx); err != nil {
+				return all, err
+			}
+		}
+
+		var decision ApprovalDecision
+		err = guardCallback(ctx, SystemClock, "TurnApproval", 0, func(ctx context.Context) {
+			decision = approval.Review(ctx, turn)
+		})
+		if err != nil {
+			return all, rejectTurn(snapshot, err)
+		}
+
+		switch decision.Action {
+		case ApprovalApprove:
+			return all, nil
+		case ApprovalAmend:
+			if decision.Feedback == "" {
+				return all, fmt.Errorf("amend decision in round %d has no feedback", round)
+			}
+			prompt = decision.Feedback
+		case ApprovalReject:
+			return all, rejectTurn(snapshot, NewTurnRejectedError(round, decision.Feedback))
+		default:
+			return all, fmt.Errorf("invalid approval action %q in round %d", decision.Action, round)
+		}
+	}
+}
+
+// rejectTurn reverts the changes recorded by snapshot, if any, and returns
+// cause
+func rejectTurn(snapshot *Snapshot, cause error) error {
+	if snapshot == nil {
+		return cause
+	}
+	// The caller's context may be done; reverting must still happen
+	if err := snapshot.Restore(context.Background()); err != nil {
+		return Errors{cause, fmt.Errorf("rollback failed: %w", err)}
+	}
+	return cause
+}
@@ -0,0 +1,110 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// editingCLI answers each user message like streamingCLI and rewrites
+// out.txt in its working directory with the turn number
+const editingCLI = `#!/bin/sh
+n=0
+while IFS= read -r line; do
+	case "$line" in
+	*'"type":"user"'*)
+		n=$((n+1))
+		echo "v$n" > out.txt
+		echo '{"type":"assistant","message":{"content":[{"type":"text","text":"reply '"$n"'"}]}}'
+		echo '{"type":"result","subtype":"success","num_turns":'"$n"',"session_id":"s1"}'
+		;;
+	esac
+done
+`
+
+func TestClientSendWithApproval(t *testing.T) {
+	installFakeCLI(t, editingCLI)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(out, []byte("v0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(&Options{Cwd: dir})
+	if err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	t.Run("amend then approve", func(t *testing.T) {
+		var reviews []TurnReview
+		messages, err := client.SendWithApproval(ctx, "edit", &TurnApproval{
+			Dir: dir,
+			Review: func(ctx context.Context, turn TurnReview) ApprovalDecision {
+				reviews = append(reviews, turn)
+				if turn.Round == 1 {
+					return AmendTurn("again")
+				}
+				return ApproveTurn()
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(messages) != 4 || len(reviews) != 2 {
+			t.Fatalf("expected 2 rounds of 2 messages, got %d reviews and %+v", len(reviews), messages)
+		}
+		if reviews[1].Prompt != "again" || reviews[1].Result.NumTurns != 2 {
+			t.Errorf("expected the feedback to start round 2, got %+v", reviews[1])
+		}
+		if cs := reviews[0].Changes; cs == nil || len(cs.Changes) != 1 || cs.Changes[0].Path != "out.txt" {
+			t.Errorf("expected the round's change to out.txt, got %+v", cs)
+		}
+		if data, _ := os.ReadFile(out); string(data) != "v2\n" {
+			t.Errorf("expected approved changes to be kept, got %q", data)
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		_, err := client.SendWithApproval(ctx, "edit", &TurnApproval{
+			Dir: dir,
+			Review: func(ctx context.Context, turn TurnReview) ApprovalDecision {
+				return RejectTurn("too risky")
+			},
+		})
+		var rejected *TurnRejectedError
+		if !errors.As(err, &rejected) || rejected.Round != 1 || rejected.Reason != "too risky" {
+			t.Fatalf("expected TurnRejectedError, got %v", err)
+		}
+		if data, _ := os.ReadFile(out); string(data) != "v2\n" {
+			t.Errorf("expected the rejected turn to be reverted, got %q", data)
+		}
+	})
+
+	t.Run("panicking review", func(t *testing.T) {
+		_, err := client.SendWithApproval(ctx, "edit", &TurnApproval{
+			Dir: dir,
+			Review: func(ctx context.Context, turn TurnReview) ApprovalDecision {
+				panic("boom")
+			},
+		})
+		var callbackErr *CallbackError
+		if !errors.As(err, &callbackErr) {
+			t.Fatalf("expected CallbackError, got %v", err)
+		}
+		if data, _ := os.ReadFile(out); string(data) != "v2\n" {
+			t.Errorf("expected the unreviewed turn to be reverted, got %q", data)
+		}
+	})
+
+	if _, err := client.SendWithApproval(ctx, "edit", &TurnApproval{}); err == nil {
+		t.Error("expected error without a Review callback")
+	}
+}
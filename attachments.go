@@ -0,0 +1,198 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/validation"
+)
+
+// maxAttachmentSize is the largest file that can be attached to a request
+const maxAttachmentSize = 50 * 1024 * 1024 // 50MB
+
+// Attachment is a local file made available to Claude for a single request.
+// The file is copied into a temporary directory inside the working directory
+// and referenced from the prompt; Claude reads it (including images) with its
+// Read tool.
+type Attachment struct {
+	// Path is the local file to attach
+	Path string `json:"path"`
+	// Name overrides the file name used inside the working directory
+	Name string `json:"name,omitempty"`
+}
+
+// stageAttachments copies attachments into a fresh directory under workDir
+// and returns the directory and the prompt extended with references to the
+// staged files
+func stageAttachments(workDir, prompt string, attachments []Attachment) (string, string, error) {
+	dir, err := os.MkdirTemp(workDir, ".claude-attachments-")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create attachment directory: %w", err)
+	}
+
+	refs := make([]string, 0, len(attachments))
+	used := make(map[string]bool, len(attachments))
+	for _, attachment := range attachments {
+		name, err := stageAttachment(dir, attachment, used)
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", "", err
+		}
+		refs = append(refs, filepath.Join(filepath.Base(dir), name))
+	}
+
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\n\nAttached files:\n")
+	for _, ref := range refs {
+		fmt.Fprintf(&b, "- %s\n", ref)
+	}
+	return dir, b.String(), nil
+}
+
+// stageAttachment copies one attachment into dir and returns its file name
+func stageAttachment(dir string, attachment Attachment, used map[string]bool) (string, error) {
+	src, err := validation.ValidatePath(attachment.Path)
+	if err != nil {
+		return "", fmt.Errorf("invalid attachment path %q: %w", attachment.Path, err)
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("attachment %q is not a regular file", attachment.Path)
+	}
+	if info.Size() > maxAttachmentSize {
+		return "", fmt.Errorf("attachment %q exceeds maximum size of %d bytes", attachment.Path, maxAttachmentSize)
+	}
+
+	name := attachment.Name
+	if name == "" {
+		name = filepath.Base(src)
+	}
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid attachment name %q", name)
+	}
+	// Keep names unique within the request
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	used[name] = true
+
+	in, err := os.Open(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to read attachment: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to stage attachment: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return "", fmt.Errorf("failed to stage attachment: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return "", fmt.Errorf("failed to stage attachment: %w", err)
+	}
+	return name, nil
+}
+
+// withCleanup forwards a query's channels and runs cleanup once both have
+// closed. When ctx is done, forwarding stops but the channels are still
+// drained, so cleanup never runs while the CLI may be writing. cleanup is
+// told whether the query failed; an error it returns is reported like a
+// query error.
+func withCleanup(ctx context.Context, options *Options, inMsgCh <-chan Message, inErrCh <-chan error, cleanup func(failed bool) error) (<-chan Message, <-chan error) {
+	return forwardQuery(ctx, options, inMsgCh, inErrCh, nil, cleanup)
+}
+
+// forwardQuery forwards a query's channels, passing every message or error to
+// observe (if set) before delivering it, and runs done (if set) as withCleanup
+// does
+func forwardQuery(ctx context.Context, options *Options, inMsgCh <-chan Message, inErrCh <-chan error, observe func(msg Message, err error), done func(failed bool) error) (<-chan Message, <-chan error) {
+	msgCh := make(chan Message, options.GetMessageBufferSize())
+	errCh := make(chan error, options.GetErrorBufferSize())
+
+	go func() {
+		failed := false
+		defer func() {
+			var err error
+			if done != nil {
+				err = done(failed || ctx.Err() != nil)
+			}
+			if err != nil {
+				if options.InlineErrors {
+					select {
+					case msgCh <- ErrorMessage{Err: err}:
+					case <-ctx.Done():
+					}
+				} else {
+					select {
+					case errCh <- err:
+					default:
+					}
+				}
+			}
+			close(msgCh)
+			close(errCh)
+		}()
+
+		// Once ctx is done nothing more is delivered, but the input is read
+		// until it closes. ctxDone is then nil, as it is for a context that
+		// is never done.
+		ctxDone := ctx.Done()
+		stopped := false
+		for inMsgCh != nil || inErrCh != nil {
+			select {
+			case msg, ok := <-inMsgCh:
+				if !ok {
+					inMsgCh = nil
+					continue
+				}
+				switch m := msg.(type) {
+				case ErrorMessage:
+					failed = true
+				case ResultMessage:
+					failed = failed || m.IsError
+				}
+				if observe != nil {
+					observe(msg, nil)
+				}
+				if stopped {
+					continue
+				}
+				select {
+				case msgCh <- msg:
+				case <-ctxDone:
+					stopped, ctxDone = true, nil
+				}
+			case err, ok := <-inErrCh:
+				if !ok {
+					inErrCh = nil
+					continue
+				}
+				failed = true
+				if observe != nil && err != nil {
+					observe(nil, err)
+				}
+				select {
+				case errCh <- err:
+				default:
+				}
+			case <-ctxDone:
+				stopped, ctxDone = true, nil
+			}
+		}
+	}()
+
+	return msgCh, errCh
+}
@@ -0,0 +1,168 @@
+package claudecode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDoWithAttachments(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+content="$(cat .claude-attachments-*/notes.txt) $(cat .claude-attachments-*/notes-2.txt)"
+echo "{\"type\":\"result\",\"subtype\":\"success\",\"result\":\"$content\"}"
+`)
+
+	src := t.TempDir()
+	first := filepath.Join(src, "notes.txt")
+	second := filepath.Join(src, "other.txt")
+	if err := os.WriteFile(first, []byte("first"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("second"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	workDir := t.TempDir()
+	opts := NewOptions()
+	opts.Cwd = workDir
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msgCh, errCh := Do(ctx, &QueryRequest{
+		Prompt:  "Review these",
+		Options: opts,
+		Attachments: []Attachment{
+			{Path: first},
+			{Path: second, Name: "notes.txt"},
+		},
+	})
+
+	var result string
+	for msg := range msgCh {
+		if m, ok := msg.(ResultMessage); ok {
+			result = SafeStringPtr(m.Result)
+		}
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "first second" {
+		t.Errorf("Expected CLI to see both staged files, got %q", result)
+	}
+
+	entries, err := os.ReadDir(workDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected attachments to be cleaned up, found %v", entries)
+	}
+}
+
+func TestStageAttachments(t *testing.T) {
+	src := t.TempDir()
+	file := filepath.Join(src, "diagram.png")
+	if err := os.WriteFile(file, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("references staged files in prompt", func(t *testing.T) {
+		dir, prompt, err := stageAttachments(t.TempDir(), "Explain", []Attachment{{Path: file}})
+		if err != nil {
+			t.Fatalf("stageAttachments() error: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		want := "Explain\n\nAttached files:\n- " + filepath.Join(filepath.Base(dir), "diagram.png") + "\n"
+		if prompt != want {
+			t.Errorf("prompt = %q, want %q", prompt, want)
+		}
+	})
+
+	tests := []struct {
+		name        string
+		attachment  Attachment
+		expectedErr string
+	}{
+		{"missing file", Attachment{Path: filepath.Join(src, "missing")}, "failed to read attachment"},
+		{"directory", Attachment{Path: src}, "not a regular file"},
+		{"name with separator", Attachment{Path: file, Name: "../escape.png"}, "invalid attachment name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workDir := t.TempDir()
+			_, _, err := stageAttachments(workDir, "x", []Attachment{tt.attachment})
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.expectedErr, err)
+			}
+			if entries, _ := os.ReadDir(workDir); len(entries) != 0 {
+				t.Errorf("Expected staging directory to be removed on error, found %v", entries)
+			}
+		})
+	}
+}
+
+func TestWithCleanupWaitsForQuery(t *testing.T) {
+	inMsgCh := make(chan Message, 1)
+	inErrCh := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cleaned := make(chan bool, 1)
+	msgCh, errCh := withCleanup(ctx, NewOptions(), inMsgCh, inErrCh, func(failed bool) error {
+		cleaned <- failed
+		return nil
+	})
+
+	inMsgCh <- AssistantMessage{}
+	<-msgCh
+	cancel()
+
+	// The query is still running, e.g. the CLI is still writing
+	inMsgCh <- AssistantMessage{}
+	select {
+	case <-cleaned:
+		t.Fatal("expected cleanup to wait for the query's channels to close")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(inMsgCh)
+	close(inErrCh)
+	select {
+	case failed := <-cleaned:
+		if !failed {
+			t.Error("expected a canceled query to be reported as failed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected cleanup once the query's channels closed")
+	}
+	for range msgCh {
+	}
+	for range errCh {
+	}
+}
+
+func TestWithCleanupWithoutDeadline(t *testing.T) {
+	inMsgCh := make(chan Message, 1)
+	inErrCh := make(chan error)
+	inMsgCh <- ResultMessage{Subtype: "success"}
+	close(inMsgCh)
+	close(inErrCh)
+
+	msgCh, errCh := withCleanup(context.Background(), NewOptions(), inMsgCh, inErrCh, func(failed bool) error {
+		if failed {
+			t.Error("expected a successful query")
+		}
+		return nil
+	})
+	var got []Message
+	for msg := range msgCh {
+		got = append(got, msg)
+	}
+	if err := <-errCh; err != nil || len(got) != 1 {
+		t.Errorf("expected the result to be delivered, got %v, %v", got, err)
+	}
+}
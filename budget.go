@@ -0,0 +1,137 @@
+package claudecode
+
+import (
+	"context"
+	"sync"
+)
+
+// CostBudget is a spending limit shared by every query and client run under
+// a context, so a long agent run made of many queries has one guardrail.
+// Each ResultMessage's cost is charged to the budget; once the limit is
+// crossed the running query stops with BudgetExceededError, and later
+// queries under the context fail before starting the CLI.
+//
+// Example:
+//
+//	ctx, budget := WithCostBudget(ctx, 5.00)
+//	for _, task := range tasks {
+//	    if _, _, err := QueryText(ctx, task, opts); err != nil {
+//	        var over *BudgetExceededError
+//	        if errors.As(err, &over) {
+//	            break
+//	        }
+//	    }
+//	}
+//	fmt.Printf("spent $%.2f\n", budget.Spent())
+type CostBudget struct {
+	limitUSD float64
+	parent   *CostBudget
+
+	mu       sync.Mutex
+	spentUSD float64
+}
+
+type costBudgetKey struct{}
+
+// WithCostBudget returns a context carrying a budget of limitUSD. A budget
+// nested in another charges both, so the tighter one stops the run.
+func WithCostBudget(ctx context.Context, limitUSD float64) (context.Context, *CostBudget) {
+	budget := &CostBudget{limitUSD: limitUSD, parent: CostBudgetFromContext(ctx)}
+	return context.WithValue(ctx, costBudgetKey{}, budget), budget
+}
+
+// CostBudgetFromContext returns the budget carried by ctx, or nil
+func CostBudgetFromContext(ctx context.Context) *CostBudget {
+	budget, _ := ctx.Value(costBudgetKey{}).(*CostBudget)
+	return budget
+}
+
+// Limit returns the budget's limit in USD
+func (b *CostBudget) Limit() float64 {
+	return b.limitUSD
+}
+
+// Spent returns the cost charged to the budget so far
+func (b *CostBudget) Spent() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spentUSD
+}
+
+// Remaining returns how much of the budget is left, never below zero
+func (b *CostBudget) Remaining() float64 {
+	if remaining := b.limitUSD - b.Spent(); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// charge adds costUSD to the budget and its parents, returning an error for
+// the first one it takes over its limit
+func (b *CostBudget) charge(costUSD float64) error {
+	var exceeded error
+	for budget := b; budget != nil; budget = budget.parent {
+		budget.mu.Lock()
+		budget.spentUSD += costUSD
+		spent := budget.spentUSD
+		budget.mu.Unlock()
+		if exceeded == nil && spent > budget.limitUSD {
+			exceeded = NewBudgetExceededError(budget.limitUSD, spent)
+		}
+	}
+	return exceeded
+}
+
+// exhausted returns an error if the budget or one of its parents has no
+// money left for another query
+func (b *CostBudget) exhausted() error {
+	for budget := b; budget != nil; budget = budget.parent {
+		if spent := budget.Spent(); spent >= budget.limitUSD {
+			return NewBudgetExceededError(budget.limitUSD, spent)
+		}
+	}
+	return nil
+}
+
+// costGuard enforces Options.MaxCostUSD and the context's CostBudget for one
+// query or client session
+type costGuard struct {
+	maxCostUSD float64
+	budget     *CostBudget
+	reported   float64 // Session cost reported by the latest result
+}
+
+// newCostGuard returns a guard for a query or session run with options
+// under ctx, or nil if neither sets a limit
+func newCostGuard(ctx context.Context, options *Options) *costGuard {
+	budget := CostBudgetFromContext(ctx)
+	if budget == nil && options.MaxCostUSD <= 0 {
+		return nil
+	}
+	return &costGuard{maxCostUSD: options.MaxCostUSD, budget: budget}
+}
+
+// observe charges the cost reported by result. The CLI reports the
+// cumulative cost of its session, so only the increase since the previous
+// result is new spending; a lower figure starts a new count.
+func (g *costGuard) observe(result ResultMessage) error {
+	if g == nil || result.TotalCostUSD == nil {
+		return nil
+	}
+	cost := *result.TotalCostUSD
+	delta := cost - g.reported
+	if delta < 0 {
+		delta = cost
+		cost += g.reported
+	}
+	g.reported = cost
+
+	var exceeded error
+	if g.budget != nil {
+		exceeded = g.budget.charge(delta)
+	}
+	if g.maxCostUSD > 0 && g.reported > g.maxCostUSD {
+		exceeded = NewBudgetExceededError(g.maxCostUSD, g.reported)
+	}
+	return exceeded
+}
@@ -0,0 +1,107 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// costCLI is a fake CLI whose result reports a cost of $0.30
+const costCLI = `#!/bin/sh
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"done"}]}}'
+echo '{"type":"result","subtype":"success","session_id":"s1","total_cost_usd":0.3}'
+`
+
+func TestQueryMaxCostUSD(t *testing.T) {
+	installFakeCLI(t, costCLI)
+
+	opts := NewOptions()
+	opts.MaxCostUSD = 0.25
+	msgCh, errCh := Query(context.Background(), "test", opts)
+	var sawResult bool
+	for msg := range msgCh {
+		if _, ok := msg.(ResultMessage); ok {
+			sawResult = true
+		}
+	}
+	err := <-errCh
+
+	var over *BudgetExceededError
+	if !errors.As(err, &over) {
+		t.Fatalf("expected BudgetExceededError, got %v", err)
+	}
+	if over.LimitUSD != 0.25 || over.SpentUSD != 0.3 {
+		t.Errorf("unexpected budget error %+v", over)
+	}
+	if !sawResult {
+		t.Error("expected the result to be delivered before the error")
+	}
+	if ErrorCodeOf(err) != ErrorCodeBudgetExceeded {
+		t.Errorf("unexpected error code %q", ErrorCodeOf(err))
+	}
+
+	opts.MaxCostUSD = 1
+	if _, _, err := QueryText(context.Background(), "test", opts); err != nil {
+		t.Errorf("unexpected error under the limit: %v", err)
+	}
+}
+
+func TestCostBudgetAcrossQueries(t *testing.T) {
+	installFakeCLI(t, costCLI)
+
+	ctx, budget := WithCostBudget(context.Background(), 0.5)
+	if _, _, err := QueryText(ctx, "first", nil); err != nil {
+		t.Fatalf("first query: %v", err)
+	}
+	if budget.Spent() != 0.3 || budget.Remaining() != 0.2 {
+		t.Errorf("after one query spent %v, remaining %v", budget.Spent(), budget.Remaining())
+	}
+
+	var over *BudgetExceededError
+	if _, _, err := QueryText(ctx, "second", nil); !errors.As(err, &over) {
+		t.Fatalf("second query: expected BudgetExceededError, got %v", err)
+	}
+
+	// The spent budget stops further queries before they run
+	spent := budget.Spent()
+	if _, _, err := QueryText(ctx, "third", nil); !errors.As(err, &over) {
+		t.Fatalf("third query: expected BudgetExceededError, got %v", err)
+	}
+	if budget.Spent() != spent {
+		t.Errorf("third query ran: spent %v, was %v", budget.Spent(), spent)
+	}
+}
+
+func TestCostBudgetNested(t *testing.T) {
+	ctx, outer := WithCostBudget(context.Background(), 10)
+	_, inner := WithCostBudget(ctx, 1)
+
+	if err := inner.charge(0.75); err != nil {
+		t.Fatal(err)
+	}
+	err := inner.charge(0.5)
+	var over *BudgetExceededError
+	if !errors.As(err, &over) || over.LimitUSD != 1 {
+		t.Fatalf("expected the inner budget to be exceeded, got %v", err)
+	}
+	if outer.Spent() != 1.25 {
+		t.Errorf("outer budget spent %v, want 1.25", outer.Spent())
+	}
+	if err := outer.exhausted(); err != nil {
+		t.Errorf("outer budget should not be exhausted: %v", err)
+	}
+}
+
+func TestCostGuardCumulativeCost(t *testing.T) {
+	ctx, budget := WithCostBudget(context.Background(), 10)
+	guard := newCostGuard(ctx, NewOptions())
+	for _, cost := range []float64{0.5, 1.25, 2} {
+		cost := cost
+		if err := guard.observe(ResultMessage{TotalCostUSD: &cost}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if budget.Spent() != 2 {
+		t.Errorf("expected cumulative session cost of 2 to be charged once, got %v", budget.Spent())
+	}
+}
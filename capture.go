@@ -0,0 +1,215 @@
+package claudecode
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CaptureBundle gathers everything about one query — the CLI command line,
+// the query's metadata, an environment summary, the raw output stream, stderr, errors and timings —
+// so it can be saved as a single zip and attached to a bug report, whether
+// the problem lies in the SDK or the CLI.
+//
+// The command line includes the prompt and system prompt, and stdout
+// includes everything Claude produced; review a bundle before sharing it.
+// Values that may hold credentials — environment variables, --mcp-config
+// and --settings — are never recorded: only variable names are kept, and
+// flag values are replaced by their length.
+//
+// Example:
+//
+//	bundle := NewCaptureBundle()
+//	msgCh, errCh := bundle.Query(ctx, prompt, opts)
+//	err := Drain(ctx, msgCh, errCh)
+//	if err != nil {
+//	    bundle.Save("claude-bug-report.zip")
+//	}
+type CaptureBundle struct {
+	stdout lockedBuffer
+	stderr lockedBuffer
+
+	mu          sync.Mutex
+	used        bool
+	args        []string
+	dir         string
+	metadata    map[string]string
+	envNames    []string
+	errors      []string
+	started     time.Time
+	firstOutput time.Time
+	finished    time.Time
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writes and reads
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+// NewCaptureBundle creates an empty capture bundle
+func NewCaptureBundle() *CaptureBundle {
+	return &CaptureBundle{}
+}
+
+// Query behaves like the package level Query while capturing the run. A
+// bundle captures a single query; further calls fail.
+func (b *CaptureBundle) Query(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+	clk := options.GetClock()
+	b.mu.Lock()
+	used := b.used
+	b.used = true
+	b.started = clk.Now()
+	if !used {
+		b.metadata = MetadataFromContext(ctx)
+	}
+	b.mu.Unlock()
+	if used {
+		return failedQuery(fmt.Errorf("capture bundle already holds a query"), options)
+	}
+
+	var opts Options
+	if options != nil {
+		opts = *options
+	} else {
+		opts = *NewOptions()
+	}
+	opts.capture = b
+
+	msgCh, errCh := Query(ctx, prompt, &opts)
+	observe := func(msg Message, err error) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if msg != nil && b.firstOutput.IsZero() {
+			b.firstOutput = clk.Now()
+		}
+		if errMsg, ok := msg.(ErrorMessage); ok {
+			err = errMsg.Err
+		}
+		if err != nil {
+			b.errors = append(b.errors, err.Error())
+		}
+	}
+	return forwardQuery(ctx, &opts, msgCh, errCh, observe, func(bool) error {
+		b.mu.Lock()
+		b.finished = clk.Now()
+		b.mu.Unlock()
+		return nil
+	})
+}
+
+// captureStart records the command line and environment of the CLI process.
+// The transport masks credential flags in args before calling it.
+func (b *CaptureBundle) captureStart(args, env []string, dir string) {
+	names := make([]string, 0, len(env))
+	for _, kv := range env {
+		if name, _, ok := strings.Cut(kv, "="); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.args = append([]string(nil), args...)
+	b.dir = dir
+	b.envNames = names
+}
+
+// WriteZip writes the bundle as a zip archive with these entries:
+//
+//	command.json      CLI arguments, working directory and query metadata
+//	environment.json  Go runtime, platform and environment variable names
+//	stdout.jsonl      the raw output stream
+//	stderr.txt        the CLI's standard error
+//	errors.txt        errors reported by the query, one per line
+//	timings.json      start, first output and finish times
+func (b *CaptureBundle) WriteZip(w io.Writer) error {
+	b.mu.Lock()
+	command := map[string]interface{}{"args": b.args, "dir": b.dir}
+	if len(b.metadata) > 0 {
+		command["metadata"] = b.metadata
+	}
+	hostname, _ := os.Hostname()
+	environment := map[string]interface{}{
+		"go_version": runtime.Version(),
+		"os":         runtime.GOOS,
+		"arch":       runtime.GOARCH,
+		"hostname":   hostname,
+		"env_names":  b.envNames,
+	}
+	timings := map[string]interface{}{"started": b.started}
+	if !b.firstOutput.IsZero() {
+		timings["first_output"] = b.firstOutput
+		timings["time_to_first_output_ms"] = b.firstOutput.Sub(b.started).Milliseconds()
+	}
+	if !b.finished.IsZero() {
+		timings["finished"] = b.finished
+		timings["duration_ms"] = b.finished.Sub(b.started).Milliseconds()
+	}
+	var errText string
+	if len(b.errors) > 0 {
+		errText = strings.Join(b.errors, "\n") + "\n"
+	}
+	b.mu.Unlock()
+
+	zw := zip.NewWriter(w)
+	entries := []struct {
+		name string
+		data func() ([]byte, error)
+	}{
+		{"command.json", func() ([]byte, error) { return json.MarshalIndent(command, "", "  ") }},
+		{"environment.json", func() ([]byte, error) { return json.MarshalIndent(environment, "", "  ") }},
+		{"stdout.jsonl", func() ([]byte, error) { return b.stdout.Bytes(), nil }},
+		{"stderr.txt", func() ([]byte, error) { return b.stderr.Bytes(), nil }},
+		{"errors.txt", func() ([]byte, error) { return []byte(errText), nil }},
+		{"timings.json", func() ([]byte, error) { return json.MarshalIndent(timings, "", "  ") }},
+	}
+	for _, entry := range entries {
+		data, err := entry.data()
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", entry.name, err)
+		}
+		f, err := zw.Create(entry.name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// Save writes the bundle as a zip file at path
+func (b *CaptureBundle) Save(path string) error {
+	var buf bytes.Buffer
+	if err := b.WriteZip(&buf); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write capture bundle: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package claudecode
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCaptureBundle(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"partial"}]}}'
+echo "error: something broke" >&2
+exit 3
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "sk-secret-value")
+
+	workDir := t.TempDir()
+	opts := NewOptions()
+	opts.Cwd = workDir
+	opts.Model = "claude-test"
+	opts.Settings = `{"env":{"API_TOKEN":"hunter2-token"}}`
+	opts.McpServers["docs"] = McpHTTPServer("https://mcp.example.com", map[string]string{"Authorization": "Bearer mcp-secret-token"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = WithMetadata(ctx, map[string]string{"trace_id": "abc"})
+
+	bundle := NewCaptureBundle()
+	msgCh, errCh := bundle.Query(ctx, "do the thing", opts)
+	if err := Drain(ctx, msgCh, errCh); err == nil {
+		t.Fatal("expected query error")
+	}
+	if opts.capture != nil {
+		t.Error("caller's options were modified")
+	}
+
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	if err := bundle.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+
+	var command struct {
+		Args     []string          `json:"args"`
+		Dir      string            `json:"dir"`
+		Metadata map[string]string `json:"metadata"`
+	}
+	if err := json.Unmarshal([]byte(files["command.json"]), &command); err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(command.Args, " ")
+	if !strings.Contains(joined, "--model claude-test") || !strings.Contains(joined, "do the thing") || command.Dir != workDir {
+		t.Errorf("unexpected command: %+v", command)
+	}
+	if command.Metadata["trace_id"] != "abc" {
+		t.Errorf("expected query metadata, got %v", command.Metadata)
+	}
+
+	if !strings.Contains(files["stdout.jsonl"], `"text":"partial"`) {
+		t.Errorf("expected raw stdout, got %q", files["stdout.jsonl"])
+	}
+	if files["stderr.txt"] != "error: something broke\n" {
+		t.Errorf("unexpected stderr %q", files["stderr.txt"])
+	}
+	if !strings.Contains(files["errors.txt"], "something broke") {
+		t.Errorf("expected query error, got %q", files["errors.txt"])
+	}
+
+	env := files["environment.json"]
+	if !strings.Contains(env, "CLAUDE_CODE_ENTRYPOINT") {
+		t.Errorf("expected environment variable names, got %s", env)
+	}
+	for name, data := range files {
+		if strings.Contains(data, "sk-secret-value") {
+			t.Errorf("%s leaks an environment variable value", name)
+		}
+		if strings.Contains(data, "hunter2-token") || strings.Contains(data, "mcp-secret-token") {
+			t.Errorf("%s leaks a credential flag value", name)
+		}
+	}
+
+	var timings map[string]interface{}
+	if err := json.Unmarshal([]byte(files["timings.json"]), &timings); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"started", "first_output", "finished", "duration_ms"} {
+		if _, ok := timings[key]; !ok {
+			t.Errorf("timings missing %s: %v", key, timings)
+		}
+	}
+}
+
+func TestCaptureBundleSingleUse(t *testing.T) {
+	bundle := NewCaptureBundle()
+	bundle.used = true
+	msgCh, errCh := bundle.Query(context.Background(), "again", nil)
+	if err := Drain(context.Background(), msgCh, errCh); err == nil {
+		t.Fatal("expected error on reuse")
+	}
+
+	var buf bytes.Buffer
+	if err := bundle.WriteZip(&buf); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,43 @@
+package claudecode
+
+// ChangeOp is the kind of change made to a file
+type ChangeOp string
+
+const (
+	ChangeAdded    ChangeOp = "added"
+	ChangeModified ChangeOp = "modified"
+	ChangeDeleted  ChangeOp = "deleted"
+)
+
+// FileChange describes the change made to one file
+type FileChange struct {
+	// Path is relative to the workspace root, using forward slashes
+	Path string   `json:"path"`
+	Op   ChangeOp `json:"op"`
+	// Patch is the unified diff of the change
+	Patch string `json:"patch,omitempty"`
+}
+
+// ChangeSet is the set of file changes produced by a run
+type ChangeSet struct {
+	// Dir is the directory the change paths are relative to
+	Dir     string       `json:"dir,omitempty"`
+	Changes []FileChange `json:"changes"`
+}
+
+// IsEmpty reports whether the change set contains no changes
+func (cs *ChangeSet) IsEmpty() bool {
+	return cs == nil || len(cs.Changes) == 0
+}
+
+// Patch returns the concatenated patches of every change
+func (cs *ChangeSet) Patch() string {
+	if cs == nil {
+		return ""
+	}
+	var patch string
+	for _, change := range cs.Changes {
+		patch += change.Patch
+	}
+	return patch
+}
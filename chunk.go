@@ -0,0 +1,153 @@
+package claudecode
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Chunk is one prompt-sized piece of a larger text
+type Chunk struct {
+	Index int
+	// Start and End are the byte offsets of Text in the original text
+	Start int
+	End   int
+	Text  string
+}
+
+// TruncateText shortens text to at most max bytes, marker included, cutting
+// at a whitespace boundary when one is close to the limit and never inside a
+// UTF-8 sequence. marker (e.g. "...") is appended only when text is cut.
+func TruncateText(text string, max int, marker string) string {
+	if len(text) <= max {
+		return text
+	}
+	if max <= 0 {
+		return ""
+	}
+	limit := max - len(marker)
+	if limit <= 0 {
+		return marker[:runeFloor(marker, max)]
+	}
+	cut := breakPoint(text, limit-limit/4, limit)
+	return strings.TrimRight(text[:cut], " \t\r\n") + marker
+}
+
+// ChunkText splits text into chunks of at most size bytes. Consecutive chunks
+// share about overlap bytes so context that straddles a boundary appears in
+// both. Chunks end after a line break, or failing that after whitespace, when
+// one falls in the last quarter of the chunk, and never inside a UTF-8
+// sequence.
+//
+// Example:
+//
+//	for _, chunk := range ChunkText(source, 20000, 500) {
+//	    answer, err := QueryResult(ctx, "Review this code:\n"+chunk.Text, opts)
+//	    ...
+//	}
+func ChunkText(text string, size, overlap int) []Chunk {
+	if text == "" {
+		return nil
+	}
+	if size < utf8.UTFMax {
+		size = utf8.UTFMax
+	}
+	if overlap < 0 || overlap >= size/2 {
+		overlap = 0
+	}
+
+	var chunks []Chunk
+	for start := 0; ; {
+		end := len(text)
+		if end-start > size {
+			end = start + breakPoint(text[start:], size-size/4, size)
+		}
+		chunks = append(chunks, Chunk{Index: len(chunks), Start: start, End: end, Text: text[start:end]})
+		if end == len(text) {
+			return chunks
+		}
+
+		start = overlapStart(text, start, end, overlap)
+	}
+}
+
+// overlapStart returns where the chunk after text[start:end] begins: at most
+// overlap bytes before end, moved forward to the next line start or word
+// when there is one before end
+func overlapStart(text string, start, end, overlap int) int {
+	next := end - overlap
+	if overlap == 0 || next <= start {
+		return end
+	}
+	if i := strings.IndexByte(text[next:end], '\n'); i >= 0 && next+i+1 < end {
+		return next + i + 1
+	}
+	if i := strings.IndexAny(text[next:end], " \t"); i >= 0 && next+i+1 < end {
+		return next + i + 1
+	}
+	if next = runeFloor(text, next); next <= start {
+		return end
+	}
+	return next
+}
+
+// breakPoint returns where to cut text at or before limit: after the last
+// line break at or after from, else after the last whitespace at or after
+// from, else at the rune boundary nearest limit
+func breakPoint(text string, from, limit int) int {
+	if limit >= len(text) {
+		return len(text)
+	}
+	window := text[:limit]
+	if i := strings.LastIndexByte(window, '\n'); i >= 0 && i+1 >= from {
+		return i + 1
+	}
+	if i := strings.LastIndexAny(window, " \t"); i >= 0 && i+1 >= from {
+		return i + 1
+	}
+	return runeFloor(text, limit)
+}
+
+// runeFloor moves i back to the start of the UTF-8 sequence containing it
+func runeFloor(text string, i int) int {
+	for i > 0 && i < len(text) && !utf8.RuneStart(text[i]) {
+		i--
+	}
+	return i
+}
+
+// MergeChunks reassembles answers produced for consecutive chunks. Where the
+// last lines of the text so far repeat as the first lines of the next part,
+// as happens when chunks overlap, the repeated lines are kept once.
+func MergeChunks(parts []string) string {
+	var merged []string
+	for _, part := range parts {
+		lines := strings.Split(strings.TrimRight(part, "\n"), "\n")
+		merged = append(merged, lines[sharedLines(merged, lines):]...)
+	}
+	return strings.Join(merged, "\n")
+}
+
+// sharedLines returns the largest n for which the last n lines of a equal
+// the first n lines of b, ignoring trailing whitespace. Blank lines alone do
+// not count as an overlap.
+func sharedLines(a, b []string) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for ; n > 0; n-- {
+		match, blank := true, true
+		for i := 0; i < n; i++ {
+			line := strings.TrimRight(b[i], " \t\r")
+			if strings.TrimRight(a[len(a)-n+i], " \t\r") != line {
+				match = false
+				break
+			}
+			blank = blank && line == ""
+		}
+		if match && !blank {
+			return n
+		}
+	}
+	return 0
+}
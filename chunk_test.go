@@ -0,0 +1,104 @@
+package claudecode
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		text   string
+		max    int
+		marker string
+		want   string
+	}{
+		{"short", 10, "...", "short"},
+		{"the quick brown fox jumps", 16, "...", "the quick..."},
+		{"abcdefghijklmnop", 10, "...", "abcdefg..."},
+		{"héllo wörld", 3, "", "hé"},
+		{"日本語のテキスト", 7, "", "日本"},
+		{"anything", 2, "...", ".."},
+		{"anything", 0, "...", ""},
+	}
+	for _, tt := range tests {
+		got := TruncateText(tt.text, tt.max, tt.marker)
+		if got != tt.want {
+			t.Errorf("TruncateText(%q, %d, %q) = %q, want %q", tt.text, tt.max, tt.marker, got, tt.want)
+		}
+		if len(got) > tt.max || !utf8.ValidString(got) {
+			t.Errorf("TruncateText(%q, %d) produced invalid result %q", tt.text, tt.max, got)
+		}
+	}
+}
+
+func TestChunkText(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&b, "línea de texto número %d %s\n", i, strings.Repeat("x", i%7))
+	}
+	text := b.String()
+
+	chunks := ChunkText(text, 500, 80)
+	if len(chunks) < 2 {
+		t.Fatalf("expected several chunks, got %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if c.Index != i || text[c.Start:c.End] != c.Text {
+			t.Errorf("chunk %d has inconsistent offsets", i)
+		}
+		if len(c.Text) > 500 || !utf8.ValidString(c.Text) {
+			t.Errorf("chunk %d is %d bytes or invalid UTF-8", i, len(c.Text))
+		}
+		if c.End != len(text) && !strings.HasSuffix(c.Text, "\n") {
+			t.Errorf("chunk %d does not end at a line break: %q", i, c.Text)
+		}
+		if i > 0 {
+			prev := chunks[i-1]
+			if c.Start >= prev.End || prev.End-c.Start > 80 {
+				t.Errorf("chunk %d overlaps previous by %d bytes", i, prev.End-c.Start)
+			}
+			if c.Start != 0 && text[c.Start-1] != '\n' {
+				t.Errorf("chunk %d does not start at a line", i)
+			}
+		}
+	}
+	if last := chunks[len(chunks)-1]; last.End != len(text) {
+		t.Errorf("chunks do not cover the text")
+	}
+
+	if got := MergeChunks(chunkTexts(chunks)); got != strings.TrimRight(text, "\n") {
+		t.Errorf("merged chunks differ from original")
+	}
+
+	// Text without break opportunities is cut on rune boundaries
+	for _, c := range ChunkText(strings.Repeat("é", 100), 9, 0) {
+		if !utf8.ValidString(c.Text) || len(c.Text) > 9 {
+			t.Errorf("invalid chunk %q", c.Text)
+		}
+	}
+	if ChunkText("", 10, 0) != nil {
+		t.Error("expected no chunks for empty text")
+	}
+}
+
+func chunkTexts(chunks []Chunk) []string {
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.Text
+	}
+	return texts
+}
+
+func TestMergeChunks(t *testing.T) {
+	got := MergeChunks([]string{
+		"- a\n- b\n\n",
+		"\n- c\n- d\n",
+		"- d\n- e",
+	})
+	want := "- a\n- b\n\n- c\n- d\n- e"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,390 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal"
+)
+
+// Client holds a conversation with one long-lived CLI process. Unlike Query,
+// which starts the CLI for every prompt, a Client streams prompts to the same
+// process over stdin (--input-format stream-json), so later turns keep the
+// conversation's context without resuming a session, and a turn in progress
+// can be interrupted.
+//
+// Messages from every turn arrive on the channels returned by
+// ReceiveMessages; each turn ends with a ResultMessage. Options.TurnLimit,
+// QueryTimeout and StallTimeout apply to one-shot queries only;
+// StartupTimeout is measured from the first message sent.
+//
+// Example:
+//
+//	client := NewClient(opts)
+//	if err := client.Connect(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer client.Close()
+//
+//	for _, prompt := range []string{"Read main.go", "Now add tests for it"} {
+//	    if err := client.SendMessage(ctx, prompt); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    messages, err := client.ReceiveResponse(ctx)
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    fmt.Println(len(messages), "messages")
+//	}
+type Client struct {
+	options   *Options
+	transport StreamingTransport
+
+	mu      sync.Mutex
+	session *internal.Session
+	started bool
+	closed  bool
+
+	// activeTurns counts prompts whose ResultMessage has not arrived yet;
+	// cancelReason is attached to the next one
+	activeTurns  int
+	cancelReason CancelReason
+
+	// costs enforces MaxCostUSD and the Connect context's CostBudget
+	costs *costGuard
+
+	// middleware runs at Connect after the context's middleware
+	middleware []QueryMiddleware
+
+	msgCh chan Message
+	errCh chan error
+}
+
+// NewClient creates a client that starts the CLI with options (uses
+// NewOptions() if nil) when Connect is called
+func NewClient(options *Options) *Client {
+	return NewClientWithTransport(nil, options)
+}
+
+// NewClientWithTransport creates a client that holds its conversation over
+// transport instead of starting the CLI. options (uses NewOptions() if nil)
+// controls buffering and InlineErrors; a nil transport starts the CLI with
+// options as NewClient does.
+func NewClientWithTransport(transport StreamingTransport, options *Options) *Client {
+	opts := streamingOptions(options)
+	return &Client{
+		options:   opts,
+		transport: transport,
+		msgCh:     make(chan Message, opts.GetMessageBufferSize()),
+		errCh:     make(chan error, opts.GetErrorBufferSize()),
+	}
+}
+
+// streamingOptions returns a copy of options suitable for a streaming
+// session, which requires stream-json output
+func streamingOptions(options *Options) *Options {
+	if options == nil {
+		options = NewOptions()
+	}
+	opts := *options
+	opts.outputFormat = ""
+	return &opts
+}
+
+// Use adds middleware run when Connect builds the CLI command, after any
+// middleware attached to Connect's context. The request it receives has an
+// empty Prompt, as prompts are sent later with SendMessage, and only changes
+// to its Options take effect. Use must be called before Connect.
+//
+// Example:
+//
+//	client := NewClient(opts)
+//	client.Use(func(req *QueryRequest) error {
+//	    req.Options.PermissionMode = &planMode
+//	    return nil
+//	})
+func (c *Client) Use(mw ...QueryMiddleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.middleware = append(c.middleware, mw...)
+}
+
+// Connect starts the CLI. The process runs until Close is called, ctx ends
+// or the CLI exits. A client can only be connected once.
+func (c *Client) Connect(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.started || c.closed {
+		return fmt.Errorf("client already connected")
+	}
+	c.started = true
+
+	req, err := applyMiddleware(ctx, &QueryRequest{Options: c.options}, c.middleware)
+	if err != nil {
+		c.closed = true
+		close(c.msgCh)
+		close(c.errCh)
+		return err
+	}
+	c.options = streamingOptions(req.Options)
+
+	if budget := CostBudgetFromContext(ctx); budget != nil {
+		if err := budget.exhausted(); err != nil {
+			c.closed = true
+			close(c.msgCh)
+			close(c.errCh)
+			return err
+		}
+	}
+	c.costs = newCostGuard(ctx, c.options)
+
+	transport := c.transport
+	if transport == nil {
+		transport = NewStreamingSubprocessTransport(c.options, "")
+	}
+	session, err := internal.NewClient().StartSession(ctx, transport, c.options)
+	if err != nil {
+		c.closed = true
+		close(c.msgCh)
+		close(c.errCh)
+		return err
+	}
+	c.session = session
+
+	rawMsgCh, rawErrCh := session.Messages()
+	go c.pump(session, rawMsgCh, rawErrCh)
+	return nil
+}
+
+// pump converts the session's raw messages until the CLI exits
+func (c *Client) pump(session *internal.Session, rawMsgCh <-chan interface{}, rawErrCh <-chan error) {
+	defer func() {
+		close(c.msgCh)
+		close(c.errCh)
+	}()
+
+	var violation error
+	for msg := range rawMsgCh {
+		if violation != nil {
+			continue // Drain until the stopped CLI closes the channel
+		}
+		typed := convertMessage(msg)
+		if am, ok := typed.(AssistantMessage); ok && c.options.ReadOnly {
+			if tool, violated := readOnlyViolation(am); violated {
+				violation = NewReadOnlyViolationError(tool)
+				go session.Close()
+				continue
+			}
+		}
+		if result, ok := typed.(ResultMessage); ok {
+			typed = c.endTurn(result)
+		}
+		if typed != nil {
+			c.msgCh <- typed
+		}
+		if result, ok := typed.(ResultMessage); ok {
+			if err := c.costs.observe(result); err != nil {
+				violation = err
+				go session.Close()
+			}
+		}
+	}
+	err := <-rawErrCh
+	if violation != nil {
+		err = violation
+	}
+	if err != nil {
+		if c.options.InlineErrors {
+			c.msgCh <- ErrorMessage{Err: err}
+		} else {
+			c.errCh <- err
+		}
+	}
+}
+
+// endTurn records the end of a turn and attaches any pending cancellation
+// reason to its result
+func (c *Client) endTurn(result ResultMessage) ResultMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.activeTurns > 0 {
+		c.activeTurns--
+	}
+	if c.cancelReason != "" {
+		result.CancelReason = c.cancelReason
+		c.cancelReason = ""
+	}
+	return result
+}
+
+// SendMessage sends prompt to the CLI as the next user turn. It does not
+// wait for the reply; read it from ReceiveMessages or ReceiveResponse.
+func (c *Client) SendMessage(ctx context.Context, prompt string) error {
+	return c.send(ctx, func(session *internal.Session) error {
+		return session.Send(prompt)
+	})
+}
+
+// SendContent sends a user turn made of content blocks, e.g. a TextBlock
+// and the ImageBlock of a screenshot. Only TextBlock and ImageBlock are
+// accepted; images given by Path are read and encoded before sending.
+func (c *Client) SendContent(ctx context.Context, blocks ...ContentBlock) error {
+	content, err := encodeUserContent(blocks)
+	if err != nil {
+		return err
+	}
+	return c.send(ctx, func(session *internal.Session) error {
+		return session.SendContent(content)
+	})
+}
+
+// send starts a turn with fn
+func (c *Client) send(ctx context.Context, fn func(session *internal.Session) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	session, err := c.connectedSession()
+	if err != nil {
+		return err
+	}
+	if err := fn(session); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.activeTurns++
+	c.mu.Unlock()
+	return nil
+}
+
+// ReceiveMessages returns the channels that carry messages of every turn and
+// the error that ended the session, if any. The same channels are returned on
+// every call; both close when the CLI exits or the client is closed.
+func (c *Client) ReceiveMessages() (<-chan Message, <-chan error) {
+	return c.msgCh, c.errCh
+}
+
+// ReceiveResponse reads messages up to and including the ResultMessage that
+// ends the current turn
+func (c *Client) ReceiveResponse(ctx context.Context) ([]Message, error) {
+	var messages []Message
+	for {
+		select {
+		case msg, ok := <-c.msgCh:
+			if !ok {
+				if err := <-c.errCh; err != nil {
+					return messages, err
+				}
+				return messages, fmt.Errorf("session ended before the turn completed")
+			}
+			if m, ok := msg.(ErrorMessage); ok {
+				return messages, m.Err
+			}
+			messages = append(messages, msg)
+			if _, ok := msg.(ResultMessage); ok {
+				return messages, nil
+			}
+		case <-ctx.Done():
+			return messages, ctx.Err()
+		}
+	}
+}
+
+// CancelReason explains why a turn was interrupted
+type CancelReason string
+
+const (
+	// CancelReasonUser is reported when a user stopped the turn
+	CancelReasonUser CancelReason = "user_cancel"
+	// CancelReasonBudget is reported when a cost or token budget ran out
+	CancelReasonBudget CancelReason = "budget_exceeded"
+	// CancelReasonDeadline is reported when a deadline passed
+	CancelReasonDeadline CancelReason = "deadline"
+)
+
+// Interrupt stops the turn in progress and waits for the CLI to acknowledge.
+// The interrupted turn still ends with a ResultMessage, after which the
+// client accepts new messages. Acknowledgements are read while messages are
+// being received, so keep consuming ReceiveMessages while interrupting.
+func (c *Client) Interrupt(ctx context.Context) error {
+	return c.InterruptWithReason(ctx, "")
+}
+
+// InterruptWithReason interrupts like Interrupt and sends reason with the
+// control request. The reason is also recorded as the CancelReason of the
+// interrupted turn's ResultMessage, so aborted runs can be told apart
+// downstream. Any string may be used; the CancelReason constants cover
+// common cases.
+func (c *Client) InterruptWithReason(ctx context.Context, reason CancelReason) error {
+	session, err := c.connectedSession()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.activeTurns > 0 {
+		c.cancelReason = reason
+	}
+	c.mu.Unlock()
+
+	if err := session.Interrupt(ctx, string(reason)); err != nil {
+		c.mu.Lock()
+		if c.cancelReason == reason {
+			c.cancelReason = ""
+		}
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Close stops the CLI. Messages that were not yet received are discarded.
+// The CLI is interrupted and killed if it has not exited within
+// Options.DisconnectTimeout.
+func (c *Client) Close() error {
+	return c.CloseContext(context.Background())
+}
+
+// CloseContext stops the CLI like Close, killing it as soon as ctx ends if
+// it has not exited by then, e.g. to fit a server's shutdown deadline.
+//
+// Example:
+//
+//	shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+//	defer cancel()
+//	client.CloseContext(shutdownCtx)
+func (c *Client) CloseContext(ctx context.Context) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	session := c.session
+	if session == nil {
+		close(c.msgCh)
+		close(c.errCh)
+	}
+	c.mu.Unlock()
+
+	if session == nil {
+		return nil
+	}
+	// Drain so the pump can finish while the CLI shuts down
+	go func() {
+		for range c.msgCh {
+		}
+	}()
+	return session.CloseContext(ctx)
+}
+
+// connectedSession returns the session of a connected, open client
+func (c *Client) connectedSession() (*internal.Session, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.session == nil || c.closed {
+		return nil, &CLIConnectionError{SDKError: SDKError{Message: "Not connected"}}
+	}
+	return c.session, nil
+}
@@ -0,0 +1,160 @@
+package claudecode
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// streamingCLI answers each user message on stdin with a numbered reply and
+// acknowledges control requests, exiting when stdin closes
+const streamingCLI = `#!/bin/sh
+case "$*" in
+*"--input-format stream-json"*) ;;
+*) echo "error: not streaming" >&2; exit 1 ;;
+esac
+n=0
+while IFS= read -r line; do
+	case "$line" in
+	*'"control_request"'*)
+		id=$(printf '%s' "$line" | sed 's/.*"request_id":"\([^"]*\)".*/\1/')
+		echo '{"type":"control_response","response":{"subtype":"success","request_id":"'"$id"'","response":{}}}'
+		;;
+	*'"type":"user"'*)
+		n=$((n+1))
+		echo '{"type":"assistant","message":{"content":[{"type":"text","text":"reply '"$n"'"}]}}'
+		echo '{"type":"result","subtype":"success","num_turns":'"$n"',"session_id":"s1"}'
+		;;
+	esac
+done
+`
+
+func TestClient(t *testing.T) {
+	installFakeCLI(t, streamingCLI)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := NewClient(nil)
+	if err := client.SendMessage(ctx, "too early"); err == nil {
+		t.Error("expected error sending before Connect")
+	}
+	if err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Connect(ctx); err == nil {
+		t.Error("expected error connecting twice")
+	}
+
+	for turn, want := range []string{"reply 1", "reply 2"} {
+		if err := client.SendMessage(ctx, "hello"); err != nil {
+			t.Fatal(err)
+		}
+		messages, err := client.ReceiveResponse(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(messages) != 2 {
+			t.Fatalf("turn %d: expected 2 messages, got %+v", turn, messages)
+		}
+		if got := messages[0].(AssistantMessage).Content[0].(TextBlock).Text; got != want {
+			t.Errorf("turn %d: got %q, want %q", turn, got, want)
+		}
+		if result := messages[1].(ResultMessage); result.NumTurns != turn+1 {
+			t.Errorf("turn %d: expected the same process to answer, got num_turns %d", turn, result.NumTurns)
+		}
+	}
+
+	if err := client.Interrupt(ctx); err != nil {
+		t.Errorf("interrupt failed: %v", err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	msgCh, _ := client.ReceiveMessages()
+	for range msgCh {
+	}
+	if err := client.SendMessage(ctx, "after close"); err == nil {
+		t.Error("expected error sending after Close")
+	}
+}
+
+func TestClientConnectFailure(t *testing.T) {
+	installFakeCLI(t, streamingCLI)
+
+	opts := NewOptions()
+	opts.Model = "not-a-model"
+	client := NewClient(opts)
+	if err := client.Connect(context.Background()); err == nil {
+		t.Fatal("expected connect to fail with invalid options")
+	}
+	msgCh, errCh := client.ReceiveMessages()
+	if _, ok := <-msgCh; ok {
+		t.Error("expected message channel to be closed")
+	}
+	if _, ok := <-errCh; ok {
+		t.Error("expected error channel to be closed")
+	}
+	if err := client.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+// stuckTransport starts turns that only end when interrupted
+type stuckTransport struct {
+	echoTransport
+}
+
+func (s *stuckTransport) SendMessage(msg map[string]interface{}) error {
+	return nil
+}
+
+func (s *stuckTransport) ControlRequest(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+	s.echoTransport.ControlRequest(ctx, request)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.out <- map[string]interface{}{"type": "result", "subtype": "error_during_execution", "is_error": true}
+	return map[string]interface{}{}, nil
+}
+
+func TestClientInterruptWithReason(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	trans := &stuckTransport{}
+	client := NewClientWithTransport(trans, nil)
+	if err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if err := client.SendMessage(ctx, "long task"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.InterruptWithReason(ctx, CancelReasonBudget); err != nil {
+		t.Fatal(err)
+	}
+	messages, err := client.ReceiveResponse(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := messages[len(messages)-1].(ResultMessage); result.CancelReason != CancelReasonBudget {
+		t.Errorf("expected cancel reason %q, got %q", CancelReasonBudget, result.CancelReason)
+	}
+	if got := trans.controls[0]["reason"]; got != "budget_exceeded" {
+		t.Errorf("expected reason in control request, got %v", got)
+	}
+
+	// Without a turn in progress there is nothing to attribute the reason to
+	if err := client.InterruptWithReason(ctx, CancelReasonUser); err != nil {
+		t.Fatal(err)
+	}
+	messages, err = client.ReceiveResponse(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := messages[0].(ResultMessage); result.CancelReason != "" {
+		t.Errorf("expected no cancel reason, got %q", result.CancelReason)
+	}
+}
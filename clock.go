@@ -0,0 +1,33 @@
+package claudecode
+
+import (
+	"github.com/f-pisani/claude-code-sdk-go/internal/clock"
+)
+
+// Clock tells the time and creates timers. Set Options.Clock to a FakeClock
+// to drive QueryTimeout, StallTimeout, DisconnectTimeout, CallbackTimeout,
+// retry backoff and the timestamps of usage records from a test without
+// real sleeps.
+type Clock = clock.Clock
+
+// Timer is a timer created by a Clock, like time.Timer with C as a method
+type Timer = clock.Timer
+
+// SystemClock is the real clock, used when Options.Clock is nil
+var SystemClock = clock.Real
+
+// FakeClock is a Clock whose time only moves when Advance or Set is called.
+// BlockUntil waits until the code under test has armed a number of timers,
+// so a test can advance the clock exactly when it is waiting.
+//
+// Example:
+//
+//	clk := NewFakeClock(time.Now())
+//	opts := &Options{Clock: clk, Retry: &RetryPolicy{InitialBackoff: time.Minute}}
+//	msgCh, errCh := Query(ctx, prompt, opts)
+//	clk.BlockUntil(1) // the first attempt failed and is backing off
+//	clk.Advance(time.Minute)
+type FakeClock = clock.Fake
+
+// NewFakeClock creates a FakeClock set to now
+var NewFakeClock = clock.NewFake
@@ -0,0 +1,117 @@
+package claudecode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ConversationFormat identifies files written by ExportConversation
+const ConversationFormat = "claude-code-sdk-go/conversation"
+
+// conversationVersion is the version of the conversation interchange format
+const conversationVersion = 1
+
+// Conversation is the interchange format for moving a conversation between
+// storage backends and inspecting it with external tools. It holds every
+// message, a snapshot of the options the conversation ran with and its
+// total usage. Messages are stored as RecordedMessage values, the encoding
+// also used by RecordingSession.
+type Conversation struct {
+	Format    string            `json:"format"`
+	Version   int               `json:"version"`
+	SessionID string            `json:"session_id,omitempty"` // Session ID of the last result
+	CreatedAt time.Time         `json:"created_at"`
+	Options   *Options          `json:"options,omitempty"`
+	Messages  []RecordedMessage `json:"messages"`
+	Usage     UsageTotals       `json:"usage"`
+}
+
+// NewConversation builds a Conversation from messages and the options they
+// ran with (may be nil). Query does not echo prompts, so include them as
+// UserMessage values where they belong in the conversation. Callbacks and
+// in-process MCP servers are not part of the snapshot, and the values of
+// Options.Env are blanked so credentials do not leak into exports. Usage
+// totals the conversation's result messages.
+func NewConversation(messages []Message, options *Options) (*Conversation, error) {
+	conv := &Conversation{
+		Format:    ConversationFormat,
+		Version:   conversationVersion,
+		CreatedAt: time.Now().UTC(),
+		Messages:  make([]RecordedMessage, 0, len(messages)),
+	}
+	if options != nil {
+		snapshot := *options
+		if len(options.Env) > 0 {
+			snapshot.Env = make(map[string]string, len(options.Env))
+			for key := range options.Env {
+				snapshot.Env[key] = ""
+			}
+		}
+		conv.Options = &snapshot
+	}
+
+	usage := NewUsageTracker()
+	for _, msg := range messages {
+		recorded, err := recordMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+		conv.Messages = append(conv.Messages, recorded)
+		if result, ok := msg.(ResultMessage); ok {
+			usage.Record(nil, result)
+			conv.SessionID = result.SessionID
+		}
+	}
+	if report := usage.Report(); len(report) > 0 {
+		conv.Usage = report[0]
+	}
+	conv.Usage.Labels = nil
+	return conv, nil
+}
+
+// Decode returns the conversation's messages
+func (c *Conversation) Decode() ([]Message, error) {
+	messages := make([]Message, 0, len(c.Messages))
+	for i, recorded := range c.Messages {
+		msg, err := recorded.Message()
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// ExportConversation writes conv to w as indented JSON
+func ExportConversation(w io.Writer, conv *Conversation) error {
+	data, err := json.MarshalIndent(conv, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write conversation: %w", err)
+	}
+	return nil
+}
+
+// ImportConversation reads a conversation written by ExportConversation. It
+// fails on other formats, newer versions and messages that cannot be
+// decoded.
+func ImportConversation(r io.Reader) (*Conversation, error) {
+	var conv Conversation
+	if err := json.NewDecoder(r).Decode(&conv); err != nil {
+		return nil, fmt.Errorf("failed to parse conversation: %w", err)
+	}
+	if conv.Format != ConversationFormat {
+		return nil, fmt.Errorf("not a conversation export: format %q", conv.Format)
+	}
+	if conv.Version < 1 || conv.Version > conversationVersion {
+		return nil, fmt.Errorf("unsupported conversation version %d", conv.Version)
+	}
+	if _, err := conv.Decode(); err != nil {
+		return nil, fmt.Errorf("invalid conversation: %w", err)
+	}
+	return &conv, nil
+}
@@ -0,0 +1,100 @@
+package claudecode
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConversationExportImport(t *testing.T) {
+	cost := 0.25
+	answer := "4"
+	messages := []Message{
+		UserMessage{Content: "What is 2+2?"},
+		AssistantMessage{Content: []ContentBlock{
+			ToolUseBlock{ID: "t1", Name: ToolBash, Input: map[string]interface{}{"command": "echo $((2+2))"}},
+			TextBlock{Text: "4"},
+		}},
+		ResultMessage{
+			Subtype:      "success",
+			SessionID:    "s1",
+			NumTurns:     1,
+			TotalCostUSD: &cost,
+			Result:       &answer,
+			Usage:        map[string]interface{}{"input_tokens": float64(10), "output_tokens": float64(2)},
+		},
+	}
+	opts := NewOptions()
+	opts.Model = "claude-sonnet-4-5"
+	opts.Env = map[string]string{"ANTHROPIC_API_KEY": "sk-secret"}
+
+	conv, err := NewConversation(messages, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.SessionID != "s1" || conv.Usage.Queries != 1 || conv.Usage.CostUSD != 0.25 || conv.Usage.InputTokens != 10 {
+		t.Errorf("unexpected conversation summary %+v", conv)
+	}
+
+	var buf bytes.Buffer
+	if err := ExportConversation(&buf, conv); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "sk-secret") {
+		t.Error("export contains an Env value")
+	}
+
+	imported, err := ImportConversation(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Options == nil || imported.Options.Model != "claude-sonnet-4-5" {
+		t.Errorf("options snapshot not restored: %+v", imported.Options)
+	}
+	decoded, err := imported.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, messages) {
+		t.Errorf("Decode() = %#v, want %#v", decoded, messages)
+	}
+
+	// An export can be resumed from; only assistant text is replayed
+	path := filepath.Join(t.TempDir(), "conversation.json")
+	buf.Reset()
+	if err := ExportConversation(&buf, conv); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	replay, err := LoadTranscript(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{
+		transcriptMessage("user", "What is 2+2?"),
+		transcriptMessage("assistant", []interface{}{map[string]interface{}{"type": "text", "text": "4"}}),
+	}
+	if !reflect.DeepEqual(replay, want) {
+		t.Errorf("LoadTranscript() = %v, want %v", replay, want)
+	}
+}
+
+func TestImportConversationErrors(t *testing.T) {
+	for name, input := range map[string]string{
+		"not json":      "nope",
+		"wrong format":  `{"format":"other","version":1,"messages":[]}`,
+		"newer version": `{"format":"claude-code-sdk-go/conversation","version":99,"messages":[]}`,
+		"bad message":   `{"format":"claude-code-sdk-go/conversation","version":1,"messages":[{"type":"unknown","data":{}}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ImportConversation(strings.NewReader(input)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ModelPricing is a model's price in USD per million tokens
+type ModelPricing struct {
+	InputPerMTok  float64
+	OutputPerMTok float64
+}
+
+// DefaultModelPricing holds list prices by model family. A model is priced
+// by the longest key its name contains, so "claude-sonnet-4-5" and
+// "sonnet" both match "sonnet".
+var DefaultModelPricing = map[string]ModelPricing{
+	"opus":   {InputPerMTok: 15, OutputPerMTok: 75},
+	"sonnet": {InputPerMTok: 3, OutputPerMTok: 15},
+	"haiku":  {InputPerMTok: 0.8, OutputPerMTok: 4},
+}
+
+// defaultPricedModel is assumed for queries that leave Options.Model empty
+const defaultPricedModel = "sonnet"
+
+// defaultEstimatedOutputTokens is the reply length assumed per query
+const defaultEstimatedOutputTokens = 1024
+
+// EstimateTokens approximates the number of tokens in text at four bytes
+// per token
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// CostEstimate is the projected cost of a batch of queries
+type CostEstimate struct {
+	Queries      int
+	InputTokens  int
+	OutputTokens int
+	CostUSD      float64
+	// PerVariant holds the projected cost of each Experiment variant
+	PerVariant map[string]float64
+}
+
+// CostGate requires confirmation before an Experiment whose projected cost
+// exceeds ThresholdUSD launches any query
+//
+// Example:
+//
+//	exp.CostGate = &CostGate{
+//	    ThresholdUSD: 10,
+//	    Confirm: func(ctx context.Context, est CostEstimate) bool {
+//	        return askYesNo(fmt.Sprintf("Run %d queries for ~$%.2f?", est.Queries, est.CostUSD))
+//	    },
+//	}
+type CostGate struct {
+	// ThresholdUSD is the projected cost above which Confirm is asked
+	ThresholdUSD float64
+	// Confirm approves a projection over the threshold; without it such
+	// runs are refused. Panics count as a refusal.
+	Confirm func(ctx context.Context, estimate CostEstimate) bool
+	// Pricing prices models (defaults to DefaultModelPricing)
+	Pricing map[string]ModelPricing
+	// OutputTokens is the reply length assumed per query (defaults to 1024)
+	OutputTokens int
+	// OverheadTokens is added to the input of every query for what the CLI
+	// sends besides the prompt, such as its own system prompt and tools
+	OverheadTokens int
+}
+
+// pricing returns the price of model
+func (g *CostGate) pricing(model string) (ModelPricing, error) {
+	table := g.Pricing
+	if table == nil {
+		table = DefaultModelPricing
+	}
+	if model == "" {
+		model = defaultPricedModel
+	}
+	if price, ok := table[model]; ok {
+		return price, nil
+	}
+
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	// Longest match first; ties broken by name for determinism
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	lower := strings.ToLower(model)
+	for _, key := range keys {
+		if strings.Contains(lower, strings.ToLower(key)) {
+			return table[key], nil
+		}
+	}
+	return ModelPricing{}, fmt.Errorf("no pricing for model %q", model)
+}
+
+// estimate projects the cost of running every variant on every input
+func (g *CostGate) estimate(variants []Variant, inputs []string) (CostEstimate, error) {
+	outputTokens := g.OutputTokens
+	if outputTokens <= 0 {
+		outputTokens = defaultEstimatedOutputTokens
+	}
+
+	est := CostEstimate{PerVariant: make(map[string]float64, len(variants))}
+	for _, variant := range variants {
+		options := variant.Options
+		if options == nil {
+			options = NewOptions()
+		}
+		price, err := g.pricing(options.Model)
+		if err != nil {
+			return CostEstimate{}, fmt.Errorf("variant %q: %w", variant.Name, err)
+		}
+
+		fixed := g.OverheadTokens + EstimateTokens(options.SystemPrompt) + EstimateTokens(options.AppendSystemPrompt)
+		for _, input := range inputs {
+			inputTokens := fixed + EstimateTokens(variant.render(input))
+			cost := (float64(inputTokens)*price.InputPerMTok + float64(outputTokens)*price.OutputPerMTok) / 1e6
+
+			est.Queries++
+			est.InputTokens += inputTokens
+			est.OutputTokens += outputTokens
+			est.CostUSD += cost
+			est.PerVariant[variant.Name] += cost
+		}
+	}
+	return est, nil
+}
+
+// check estimates the batch and asks Confirm when it is over the threshold
+func (g *CostGate) check(ctx context.Context, variants []Variant, inputs []string) error {
+	est, err := g.estimate(variants, inputs)
+	if err != nil {
+		return err
+	}
+	if est.CostUSD <= g.ThresholdUSD {
+		return nil
+	}
+
+	confirmed := false
+	if g.Confirm != nil {
+		err := guardCallback(ctx, SystemClock, "Confirm", 0, func(ctx context.Context) {
+			confirmed = g.Confirm(ctx, est)
+		})
+		if err != nil && ctx.Err() != nil {
+			return err
+		}
+	}
+	if !confirmed {
+		return NewCostNotConfirmedError(est.CostUSD, g.ThresholdUSD)
+	}
+	return nil
+}
+
+// EstimateCost projects the cost of running the experiment with the
+// assumptions of its CostGate, or the defaults if it has none
+func (e *Experiment) EstimateCost() (CostEstimate, error) {
+	gate := e.CostGate
+	if gate == nil {
+		gate = &CostGate{}
+	}
+	return gate.estimate(e.Variants, e.Inputs)
+}
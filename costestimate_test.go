@@ -0,0 +1,115 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestExperimentEstimateCost(t *testing.T) {
+	exp := &Experiment{
+		Variants: []Variant{
+			{Name: "default", Prompt: strings.Repeat("x", 400)},
+			{Name: "opus", Options: &Options{Model: "claude-opus-4-1", SystemPrompt: strings.Repeat("y", 40)}},
+		},
+		Inputs:   []string{strings.Repeat("i", 396), ""},
+		CostGate: &CostGate{OutputTokens: 100, OverheadTokens: 50},
+	}
+
+	est, err := exp.EstimateCost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// default: prompt, blank line and input (200 or 101 tokens) plus 50
+	// overhead, on sonnet; opus: input (99 or 0 tokens), 10 of system
+	// prompt and 50 overhead
+	wantInput := (250 + 151) + (159 + 60)
+	if est.Queries != 4 || est.InputTokens != wantInput || est.OutputTokens != 400 {
+		t.Errorf("unexpected estimate %+v, want %d input tokens", est, wantInput)
+	}
+	wantDefault := (401*3 + 200*15) / 1e6
+	wantOpus := (219*15 + 200*75) / 1e6
+	if math.Abs(est.PerVariant["default"]-wantDefault) > 1e-9 || math.Abs(est.PerVariant["opus"]-wantOpus) > 1e-9 {
+		t.Errorf("per variant = %v, want %v and %v", est.PerVariant, wantDefault, wantOpus)
+	}
+	if math.Abs(est.CostUSD-(wantDefault+wantOpus)) > 1e-9 {
+		t.Errorf("total = %v", est.CostUSD)
+	}
+
+	exp.Variants[1].Options.Model = "gpt-4"
+	if _, err := exp.EstimateCost(); err == nil {
+		t.Error("expected error for a model without pricing")
+	}
+	exp.CostGate.Pricing = map[string]ModelPricing{"gpt-4": {InputPerMTok: 1}, "sonnet": {}}
+	if _, err := exp.EstimateCost(); err != nil {
+		t.Errorf("expected custom pricing to be used, got %v", err)
+	}
+}
+
+func TestExperimentCostGate(t *testing.T) {
+	var queries int32
+	responder := NewScriptedResponder(1).Default(TextResponse("ok")...)
+	query := func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+		atomic.AddInt32(&queries, 1)
+		return responder.Query(ctx, prompt, options)
+	}
+	newExperiment := func(gate *CostGate) *Experiment {
+		return &Experiment{
+			Variants: []Variant{{Name: "v"}},
+			Inputs:   []string{"a", "b"},
+			Query:    query,
+			CostGate: gate,
+		}
+	}
+
+	t.Run("under threshold", func(t *testing.T) {
+		atomic.StoreInt32(&queries, 0)
+		gate := &CostGate{ThresholdUSD: 1, Confirm: func(ctx context.Context, est CostEstimate) bool {
+			t.Error("Confirm called under the threshold")
+			return false
+		}}
+		if _, err := newExperiment(gate).Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if queries != 2 {
+			t.Errorf("expected 2 queries, got %d", queries)
+		}
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		atomic.StoreInt32(&queries, 0)
+		var asked CostEstimate
+		gate := &CostGate{ThresholdUSD: 0.01, Confirm: func(ctx context.Context, est CostEstimate) bool {
+			asked = est
+			return false
+		}}
+		_, err := newExperiment(gate).Run(context.Background())
+		var refused *CostNotConfirmedError
+		if !errors.As(err, &refused) || refused.ThresholdUSD != 0.01 || refused.EstimateUSD != asked.CostUSD {
+			t.Fatalf("expected CostNotConfirmedError, got %v", err)
+		}
+		if asked.Queries != 2 || queries != 0 {
+			t.Errorf("expected no queries after refusing %+v, got %d", asked, queries)
+		}
+	})
+
+	t.Run("confirmed", func(t *testing.T) {
+		atomic.StoreInt32(&queries, 0)
+		gate := &CostGate{ThresholdUSD: 0.01, Confirm: func(ctx context.Context, est CostEstimate) bool { return true }}
+		if _, err := newExperiment(gate).Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if queries != 2 {
+			t.Errorf("expected 2 queries, got %d", queries)
+		}
+	})
+
+	t.Run("no confirm callback", func(t *testing.T) {
+		if _, err := newExperiment(&CostGate{ThresholdUSD: 0.01}).Run(context.Background()); err == nil {
+			t.Error("expected a projection over the threshold to be refused")
+		}
+	})
+}
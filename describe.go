@@ -0,0 +1,154 @@
+package claudecode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// OptionDescription describes one Options field for config UIs and for
+// checking external configuration against what the SDK supports
+type OptionDescription struct {
+	Field       string      `json:"field"`                // Go field name
+	JSON        string      `json:"json,omitempty"`       // Key in JSON configuration; empty if the field can only be set from Go
+	Type        string      `json:"type"`                 // Go type
+	Flag        string      `json:"flag,omitempty"`       // CLI flag the field maps to; empty for SDK-side options
+	Env         []string    `json:"env,omitempty"`        // Environment variables the field sets for the CLI
+	Description string      `json:"description"`          // What the option does
+	Validation  string      `json:"validation,omitempty"` // Rules Validate enforces
+	Default     interface{} `json:"default,omitempty"`    // Value set by NewOptions, if any
+}
+
+// optionInfo is the hand-written part of an OptionDescription
+type optionInfo struct {
+	flag        string
+	env         []string
+	description string
+	validation  string
+}
+
+// optionInfos documents every exported Options field. TestDescribe fails if
+// a field is missing.
+var optionInfos = map[string]optionInfo{
+	"AllowedTools":             {"--allowedTools", nil, "Tools Claude may use without asking", "tool names without shell metacharacters; read-only tools only with ReadOnly"},
+	"MaxThinkingTokens":        {"--max-thinking-tokens", nil, "Token budget for extended thinking", "0 to 100000"},
+	"SystemPrompt":             {"--system-prompt", nil, "Replaces the default system prompt", "at most 10000 characters"},
+	"AppendSystemPrompt":       {"--append-system-prompt", nil, "Appended to the default system prompt", "at most 10000 characters"},
+	"SystemPromptFile":         {"--system-prompt", nil, "File read on every query to replace the default system prompt", "readable file; exclusive with SystemPrompt"},
+	"AppendSystemPromptFile":   {"--append-system-prompt", nil, "File read on every query and appended to the system prompt", "readable file; exclusive with AppendSystemPrompt"},
+	"McpTools":                 {"--mcp-tools", nil, "MCP tools to enable", "tool names without shell metacharacters"},
+	"McpServers":               {"--mcp-config", nil, "External MCP servers by name", "stdio needs Transport, sse/http need an http(s) URL; config JSON at most 10MB"},
+	"SdkMcpServers":            {"--mcp-config", nil, "In-process MCP servers by name", "names distinct from McpServers"},
+	"PermissionMode":           {"--permission-mode", nil, "How tool permissions are granted", "default, acceptEdits, bypassPermissions or plan"},
+	"ContinueConversation":     {"--continue", nil, "Continue the most recent conversation", ""},
+	"Resume":                   {"--resume", nil, "Session ID to resume", "no shell metacharacters"},
+	"ForkSession":              {"--fork-session", nil, "Branch the resumed or continued session into a new one", "requires Resume or ContinueConversation"},
+	"ResumeFrom":               {"", nil, "Transcript file whose messages are replayed into a new session", "existing file; exclusive with Resume and ContinueConversation"},
+	"MaxTurns":                 {"--max-turns", nil, "CLI-side cap on agent turns", "0 to 1000"},
+	"DisallowedTools":          {"--disallowedTools", nil, "Tools Claude may not use", "tool names without shell metacharacters"},
+	"Model":                    {"--model", nil, "Model to use", "known model or claude-* name"},
+	"PermissionPromptToolName": {"--permission-prompt-tool", nil, "MCP tool answering permission prompts", "no shell metacharacters; exclusive with CanUseTool"},
+	"CanUseTool":               {"--permission-prompt-tool", nil, "Go callback answering permission prompts", ""},
+	"Hooks":                    {"", nil, "Go callbacks run on CLI hook events", ""},
+	"CallbackTimeout":          {"", nil, "Seconds a callback may run before its call is failed", "0 waits indefinitely"},
+	"RequireCLIVersion":        {"", nil, "Version constraint the CLI must satisfy at Connect", "comparisons such as >=1.0.50, ^1.0 or ~1.2, joined by commas or ||"},
+	"Cwd":                      {"", nil, "Working directory of the CLI", "existing directory"},
+	"AddDirs":                  {"--add-dir", nil, "Directories outside Cwd that tools may access", "existing directories"},
+	"SettingSources":           {"--setting-sources", nil, "Settings files the CLI loads", "user, project or local"},
+	"Settings":                 {"--settings", nil, "Settings file or inline JSON loaded on top of SettingSources", "JSON object, inline or in an existing file; at most 10MB"},
+	"ContextDocuments":         {"--append-system-prompt", nil, "Documents appended to the system prompt", "readable files"},
+	"PromptInput":              {"--print", nil, "How one-shot prompts reach the CLI: argument, stdin text or stdin stream-json message", "argv, stdin or stream-json; empty switches to stdin above 64 KiB"},
+	"MessageBufferSize":        {"", nil, "Capacity of the message channel", ""},
+	"ErrorBufferSize":          {"", nil, "Capacity of the error channel", ""},
+	"InlineErrors":             {"", nil, "Deliver errors as ErrorMessage on the message channel", ""},
+	"QueryTimeout":             {"", nil, "Seconds the whole query may take", ""},
+	"ReadOnly":                 {"--permission-mode", nil, "Plan mode with read-only tools; mutating tool use stops the query", "PermissionMode unset or plan"},
+	"TurnLimit":                {"", nil, "SDK-side cap on turns, counted by assistant message ID", ""},
+	"MaxCostUSD":               {"", nil, "SDK-side spending limit in USD, checked whenever a result reports cost", "not negative"},
+	"StallTimeout":             {"", nil, "Seconds without CLI output before it is stopped", ""},
+	"StartupTimeout":           {"", nil, "Seconds the CLI may take to report its init message before it is stopped", ""},
+	"DisconnectTimeout":        {"", nil, "Seconds to wait for the CLI to exit after interrupting it before killing it", "0 waits 5 seconds"},
+	"Retry":                    {"", nil, "Retries of CLI startup failures, process failures, stalls and rate-limited results, with exponential backoff", "attempts and backoffs not negative; multiplier at least 1"},
+	"IncludePartialMessages":   {"--include-partial-messages", nil, "Deliver StreamEvent messages while replies are generated", ""},
+	"Logger":                   {"", nil, "slog logger for CLI lifecycle, redacted args, raw JSON lines (debug) and parse failures", ""},
+	"Clock":                    {"", nil, "Time source for timeouts, retry backoff and usage timestamps, e.g. a FakeClock in tests", ""},
+	"Locale":                   {"", []string{"LANG", "LC_ALL"}, "Locale of the CLI", "POSIX locale name"},
+	"Timezone":                 {"", []string{"TZ"}, "Time zone of the CLI", "IANA time zone name"},
+	"HTTPProxy":                {"", []string{"HTTP_PROXY", "http_proxy"}, "Proxy for HTTP requests", "http, https or socks5 URL"},
+	"HTTPSProxy":               {"", []string{"HTTPS_PROXY", "https_proxy"}, "Proxy for HTTPS requests", "http, https or socks5 URL"},
+	"NoProxy":                  {"", []string{"NO_PROXY", "no_proxy"}, "Hosts that bypass the proxy", "comma-separated host names"},
+	"Env":                      {"", nil, "Environment variables for the CLI, taking precedence over filtered, inherited and option-derived values", "names without = or NUL"},
+	"InheritEnv":               {"", nil, "Pass the whole parent environment instead of the filtered one", ""},
+}
+
+// Describe returns a description of every exported option in field order:
+// its JSON key, type, CLI flag or environment mapping, validation rules and
+// default
+func (o *Options) Describe() []OptionDescription {
+	defaults := reflect.ValueOf(*NewOptions())
+	typ := defaults.Type()
+
+	descs := make([]OptionDescription, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		info := optionInfos[field.Name]
+		desc := OptionDescription{
+			Field:       field.Name,
+			JSON:        jsonKey(field),
+			Type:        field.Type.String(),
+			Flag:        info.flag,
+			Env:         info.env,
+			Description: info.description,
+			Validation:  info.validation,
+		}
+		if value := defaults.Field(i); hasDefault(value) {
+			desc.Default = value.Interface()
+		}
+		descs = append(descs, desc)
+	}
+	return descs
+}
+
+// hasDefault reports whether NewOptions sets value to something other than
+// its zero or empty value
+func hasDefault(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() > 0
+	default:
+		return !value.IsZero()
+	}
+}
+
+// jsonKey returns the JSON key of field, or "" if it is not serialized
+func jsonKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// ParseOptionsJSON decodes options from JSON configuration on top of
+// NewOptions defaults. Unknown keys are rejected and the result must pass
+// Validate.
+func ParseOptionsJSON(data []byte) (*Options, error) {
+	opts := NewOptions()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(opts); err != nil {
+		return nil, fmt.Errorf("invalid options JSON: %w", err)
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
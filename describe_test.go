@@ -0,0 +1,63 @@
+package claudecode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	descs := NewOptions().Describe()
+
+	byField := make(map[string]OptionDescription, len(descs))
+	for _, desc := range descs {
+		if desc.Description == "" {
+			t.Errorf("option %s has no description", desc.Field)
+		}
+		byField[desc.Field] = desc
+	}
+
+	typ := reflect.TypeOf(Options{})
+	for i := 0; i < typ.NumField(); i++ {
+		if field := typ.Field(i); field.IsExported() {
+			if _, ok := byField[field.Name]; !ok {
+				t.Errorf("option %s is not described", field.Name)
+			}
+		}
+	}
+	for name := range optionInfos {
+		if _, ok := typ.FieldByName(name); !ok {
+			t.Errorf("description for unknown option %s", name)
+		}
+	}
+
+	if d := byField["MaxTurns"]; d.Flag != "--max-turns" || d.JSON != "max_turns" || d.Type != "*int" || d.Default != nil {
+		t.Errorf("unexpected MaxTurns description %+v", d)
+	}
+	if d := byField["MaxThinkingTokens"]; d.Default != 8000 {
+		t.Errorf("expected MaxThinkingTokens default 8000, got %v", d.Default)
+	}
+	if d := byField["CanUseTool"]; d.JSON != "" {
+		t.Errorf("expected CanUseTool to have no JSON key, got %q", d.JSON)
+	}
+	if d := byField["Timezone"]; !reflect.DeepEqual(d.Env, []string{"TZ"}) {
+		t.Errorf("unexpected Timezone env %v", d.Env)
+	}
+}
+
+func TestParseOptionsJSON(t *testing.T) {
+	opts, err := ParseOptionsJSON([]byte(`{"model":"claude-sonnet-4-20250514","max_turns":3}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Model != "claude-sonnet-4-20250514" || SafeIntPtr(opts.MaxTurns) != 3 || opts.MaxThinkingTokens != 8000 {
+		t.Errorf("unexpected options %+v", opts)
+	}
+
+	if _, err := ParseOptionsJSON([]byte(`{"max_turn":3}`)); err == nil || !strings.Contains(err.Error(), "unknown field") {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+	if _, err := ParseOptionsJSON([]byte(`{"max_turns":5000}`)); err == nil {
+		t.Error("expected validation error")
+	}
+}
@@ -0,0 +1,189 @@
+package claudecode
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines around each hunk
+	diffContext = 3
+	// maxDiffCells bounds the memory used by the line matching table; larger
+	// inputs are diffed as a full replacement
+	maxDiffCells = 1 << 22
+)
+
+// diffLine is one line of an edit script: ' ' kept, '-' removed, '+' added
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// unifiedDiff renders the change to path as a unified diff
+func unifiedDiff(path string, op ChangeOp, old, cur []byte) string {
+	from, to := "a/"+path, "b/"+path
+	switch op {
+	case ChangeAdded:
+		from = "/dev/null"
+	case ChangeDeleted:
+		to = "/dev/null"
+	}
+
+	if bytes.IndexByte(old, 0) >= 0 || bytes.IndexByte(cur, 0) >= 0 {
+		return fmt.Sprintf("Binary files %s and %s differ\n", from, to)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", from, to)
+	writeHunks(&b, diffLines(splitLines(old), splitLines(cur)))
+	return b.String()
+}
+
+// splitLines splits data into lines, keeping each line's newline
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a minimal edit script turning a into b
+func diffLines(a, b []string) []diffLine {
+	var script []diffLine
+
+	// Trim the common prefix and suffix to keep the table small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		script = append(script, diffLine{' ', a[prefix]})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	x, y := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	n, m := len(x), len(y)
+	if (n+1)*(m+1) > maxDiffCells {
+		for _, line := range x {
+			script = append(script, diffLine{'-', line})
+		}
+		for _, line := range y {
+			script = append(script, diffLine{'+', line})
+		}
+	} else {
+		// lcs[i*(m+1)+j] is the longest common subsequence of x[i:] and y[j:]
+		lcs := make([]int32, (n+1)*(m+1))
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				switch {
+				case x[i] == y[j]:
+					lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+				case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+					lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j]
+				default:
+					lcs[i*(m+1)+j] = lcs[i*(m+1)+j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < n && j < m {
+			switch {
+			case x[i] == y[j]:
+				script = append(script, diffLine{' ', x[i]})
+				i++
+				j++
+			case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+				script = append(script, diffLine{'-', x[i]})
+				i++
+			default:
+				script = append(script, diffLine{'+', y[j]})
+				j++
+			}
+		}
+		for ; i < n; i++ {
+			script = append(script, diffLine{'-', x[i]})
+		}
+		for ; j < m; j++ {
+			script = append(script, diffLine{'+', y[j]})
+		}
+	}
+
+	for k := len(a) - suffix; k < len(a); k++ {
+		script = append(script, diffLine{' ', a[k]})
+	}
+	return script
+}
+
+// writeHunks writes the changed regions of script as unified diff hunks
+func writeHunks(b *strings.Builder, script []diffLine) {
+	// oldLine[k] and newLine[k] are the 0-based line numbers before script[k]
+	oldLine := make([]int, len(script)+1)
+	newLine := make([]int, len(script)+1)
+	for k, line := range script {
+		oldLine[k+1], newLine[k+1] = oldLine[k], newLine[k]
+		if line.kind != '+' {
+			oldLine[k+1]++
+		}
+		if line.kind != '-' {
+			newLine[k+1]++
+		}
+	}
+
+	for k := 0; k < len(script); {
+		if script[k].kind == ' ' {
+			k++
+			continue
+		}
+
+		// Extend the hunk while changes are close enough to share context
+		start := k - diffContext
+		if start < 0 {
+			start = 0
+		}
+		last := k
+		for next := k + 1; next < len(script); next++ {
+			if script[next].kind == ' ' {
+				continue
+			}
+			if next-last-1 > 2*diffContext {
+				break
+			}
+			last = next
+		}
+		end := last + diffContext + 1
+		if end > len(script) {
+			end = len(script)
+		}
+
+		fmt.Fprintf(b, "@@ -%s +%s @@\n",
+			hunkRange(oldLine[start], oldLine[end]-oldLine[start]),
+			hunkRange(newLine[start], newLine[end]-newLine[start]))
+		for _, line := range script[start:end] {
+			b.WriteByte(line.kind)
+			b.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		k = end
+	}
+}
+
+// hunkRange formats a hunk's line range the way diff and git do
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
@@ -0,0 +1,45 @@
+package claudecode
+
+import (
+	"context"
+)
+
+// Drain consumes and discards whatever is left on a query's channels until
+// both are closed, so the forwarding goroutines and the CLI process can shut
+// down. It returns the error reported by the query, if any, or ctx.Err() if
+// ctx ends first.
+//
+// To stop a query early, cancel the context passed to Query and then Drain
+// its channels:
+//
+//	ctx, cancel := context.WithCancel(ctx)
+//	msgCh, errCh := Query(ctx, prompt, opts)
+//	first := <-msgCh
+//	cancel()
+//	_ = Drain(context.Background(), msgCh, errCh)
+func Drain(ctx context.Context, msgCh <-chan Message, errCh <-chan error) error {
+	var queryErr error
+	for msgCh != nil || errCh != nil {
+		select {
+		case msg, ok := <-msgCh:
+			if !ok {
+				msgCh = nil
+				continue
+			}
+			if errMsg, ok := msg.(ErrorMessage); ok {
+				queryErr = errMsg.Err
+			}
+		case err, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
+			}
+			if err != nil {
+				queryErr = err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return queryErr
+}
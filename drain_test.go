@@ -0,0 +1,64 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	t.Run("consumes remaining messages", func(t *testing.T) {
+		installFakeCLI(t, `#!/bin/sh
+for i in 1 2 3 4 5 6 7 8 9 10 11 12 13 14 15; do
+	echo '{"type":"assistant","message":{"content":[{"type":"text","text":"x"}]}}'
+done
+echo '{"type":"result","subtype":"success"}'
+`)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		msgCh, errCh := Query(ctx, "test", nil)
+		<-msgCh
+		if err := Drain(ctx, msgCh, errCh); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if _, ok := <-msgCh; ok {
+			t.Error("Expected message channel to be closed after Drain")
+		}
+	})
+
+	t.Run("returns the query error", func(t *testing.T) {
+		msgCh := make(chan Message)
+		errCh := make(chan error, 1)
+		boom := errors.New("boom")
+		errCh <- boom
+		close(msgCh)
+		close(errCh)
+
+		if err := Drain(context.Background(), msgCh, errCh); !errors.Is(err, boom) {
+			t.Errorf("Expected query error, got %v", err)
+		}
+	})
+
+	t.Run("returns inline errors", func(t *testing.T) {
+		boom := errors.New("boom")
+		msgCh := make(chan Message, 1)
+		errCh := make(chan error)
+		msgCh <- ErrorMessage{Err: boom}
+		close(msgCh)
+		close(errCh)
+
+		if err := Drain(context.Background(), msgCh, errCh); !errors.Is(err, boom) {
+			t.Errorf("Expected inline error, got %v", err)
+		}
+	})
+
+	t.Run("stops when context ends", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := Drain(ctx, make(chan Message), make(chan error)); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,289 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Proposal is the outcome of a dry run: the edits Claude attempted, rendered
+// as patches against the current files but not applied
+type Proposal struct {
+	// Changes holds one entry per file Claude tried to edit, combining all of
+	// its attempted edits to that file
+	Changes *ChangeSet
+	// Skipped lists attempted edits that could not be rendered
+	Skipped []SkippedEdit
+	// Result is the final result of the run, if one was received
+	Result *ResultMessage
+}
+
+// SkippedEdit is an attempted edit left out of a proposal
+type SkippedEdit struct {
+	ToolUseID string
+	FilePath  string
+	Reason    string
+}
+
+// DryRun runs prompt without letting Claude modify any files and returns the
+// Edit, MultiEdit and Write calls it attempted as a Proposal for review. The
+// proposal can later be applied with ApplyChangeSet.
+//
+// Edits are recorded and denied by a PreToolUse hook before they run, so
+// allow rules in the CLI's settings cannot apply them, and the other
+// mutating tools, such as Bash, are disallowed. The run also uses the default
+// permission mode, keeps only read-only tools in AllowedTools and replaces
+// PermissionPromptToolName and CanUseTool with a callback denying any tool
+// that is not read-only. Because denials are reported back to Claude, the
+// run usually ends after it has proposed its edits.
+func DryRun(ctx context.Context, prompt string, options *Options) (*Proposal, error) {
+	var opts Options
+	if options != nil {
+		opts = *options
+	} else {
+		opts = *NewOptions()
+	}
+	mode := PermissionModeDefault
+	opts.PermissionMode = &mode
+	opts.PermissionPromptToolName = ""
+	opts.InlineErrors = false
+	allowed := opts.AllowedTools
+	opts.AllowedTools = nil
+	for _, tool := range allowed {
+		if IsReadOnlyTool(tool) {
+			opts.AllowedTools = append(opts.AllowedTools, tool)
+		}
+	}
+	// Edit tools stay available so Claude can propose edits; the hook below
+	// denies them
+	opts.DisallowedTools = append([]string(nil), opts.DisallowedTools...)
+	for _, tool := range mutatingTools {
+		if !IsFileEditTool(tool) || tool == ToolNotebookEdit {
+			opts.DisallowedTools = append(opts.DisallowedTools, tool)
+		}
+	}
+
+	dir := opts.Cwd
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return nil, fmt.Errorf("failed to determine working directory: %w", err)
+		}
+	}
+	sim := newEditSimulator(dir)
+
+	hooks := make(map[HookEvent][]HookMatcher, len(opts.Hooks)+1)
+	for event, matchers := range opts.Hooks {
+		hooks[event] = matchers
+	}
+	hooks[HookPreToolUse] = append([]HookMatcher{{
+		Matcher: strings.Join([]string{ToolEdit, ToolMultiEdit, ToolWrite}, "|"),
+		Hooks: []HookCallback{func(ctx context.Context, input HookInput, toolUseID string) (HookOutput, error) {
+			sim.apply(ToolUseBlock{ID: toolUseID, Name: input.ToolName, Input: input.ToolInput})
+			return DenyToolUse(dryRunDenial), nil
+		}},
+	}}, opts.Hooks[HookPreToolUse]...)
+	opts.Hooks = hooks
+	opts.CanUseTool = func(ctx context.Context, toolName string, input map[string]interface{}) PermissionDecision {
+		if IsReadOnlyTool(toolName) {
+			return AllowTool()
+		}
+		return DenyTool(dryRunDenial)
+	}
+
+	msgCh, errCh := Query(ctx, prompt, &opts)
+	proposal := &Proposal{}
+	for msg := range msgCh {
+		switch m := msg.(type) {
+		case AssistantMessage:
+			for _, block := range m.Content {
+				if use, ok := block.(ToolUseBlock); ok {
+					sim.apply(use)
+				}
+			}
+		case ResultMessage:
+			proposal.Result = &m
+		}
+	}
+
+	proposal.Changes, proposal.Skipped = sim.changes()
+	if err := <-errCh; err != nil {
+		return proposal, err
+	}
+	return proposal, nil
+}
+
+// dryRunDenial is the reason given to Claude for tool calls a dry run denies
+const dryRunDenial = "this is a dry run: the edit was recorded for review but not applied"
+
+// editSimulator replays file edit tool calls against an in-memory overlay of
+// the files under dir. Calls are seen both by the PreToolUse hook and in the
+// message stream, so each tool use ID is replayed once.
+type editSimulator struct {
+	dir      string
+	mu       sync.Mutex
+	applied  map[string]bool
+	original map[string][]byte // nil for files that did not exist
+	current  map[string][]byte
+	skipped  []SkippedEdit
+}
+
+func newEditSimulator(dir string) *editSimulator {
+	return &editSimulator{
+		dir:      dir,
+		applied:  make(map[string]bool),
+		original: make(map[string][]byte),
+		current:  make(map[string][]byte),
+	}
+}
+
+// apply replays one tool call; calls to other tools, and calls already
+// replayed, are ignored
+func (s *editSimulator) apply(use ToolUseBlock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if use.ID != "" {
+		if s.applied[use.ID] {
+			return
+		}
+		s.applied[use.ID] = true
+	}
+
+	var filePath string
+	var edit func(content []byte, exists bool) ([]byte, error)
+
+	switch use.Name {
+	case ToolEdit, ToolMultiEdit, ToolWrite:
+	default:
+		return
+	}
+	input, err := DecodeToolInput(use)
+	if err != nil {
+		s.skip(use, "", err.Error())
+		return
+	}
+
+	switch in := input.(type) {
+	case *WriteInput:
+		filePath = in.FilePath
+		edit = func([]byte, bool) ([]byte, error) {
+			return []byte(in.Content), nil
+		}
+	case *EditInput:
+		filePath = in.FilePath
+		edit = func(content []byte, exists bool) ([]byte, error) {
+			if !exists {
+				return nil, fmt.Errorf("file does not exist")
+			}
+			return replaceEdit(content, EditOperation{
+				OldString:  in.OldString,
+				NewString:  in.NewString,
+				ReplaceAll: in.ReplaceAll,
+			})
+		}
+	case *MultiEditInput:
+		filePath = in.FilePath
+		edit = func(content []byte, exists bool) ([]byte, error) {
+			if !exists {
+				return nil, fmt.Errorf("file does not exist")
+			}
+			for _, op := range in.Edits {
+				var err error
+				if content, err = replaceEdit(content, op); err != nil {
+					return nil, err
+				}
+			}
+			return content, nil
+		}
+	default:
+		return
+	}
+
+	path := filePath
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.dir, path)
+	}
+	path = filepath.Clean(path)
+
+	content, seen := s.current[path]
+	exists := content != nil
+	if !seen {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			content, exists = data, true
+		case !os.IsNotExist(err):
+			s.skip(use, filePath, err.Error())
+			return
+		}
+	}
+
+	updated, err := edit(content, exists)
+	if err != nil {
+		s.skip(use, filePath, err.Error())
+		return
+	}
+	if !seen {
+		s.original[path] = content
+	}
+	if updated == nil {
+		updated = []byte{}
+	}
+	s.current[path] = updated
+}
+
+func (s *editSimulator) skip(use ToolUseBlock, filePath, reason string) {
+	s.skipped = append(s.skipped, SkippedEdit{ToolUseID: use.ID, FilePath: filePath, Reason: reason})
+}
+
+// changes renders the overlay as a ChangeSet relative to dir
+func (s *editSimulator) changes() (*ChangeSet, []SkippedEdit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	paths := make([]string, 0, len(s.current))
+	for path := range s.current {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	cs := &ChangeSet{Dir: s.dir}
+	for _, path := range paths {
+		old, cur := s.original[path], s.current[path]
+		op := ChangeModified
+		if old == nil {
+			op = ChangeAdded
+		} else if string(old) == string(cur) {
+			continue
+		}
+
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			rel = path
+		}
+		rel = filepath.ToSlash(rel)
+		cs.Changes = append(cs.Changes, FileChange{Path: rel, Op: op, Patch: unifiedDiff(rel, op, old, cur)})
+	}
+	return cs, s.skipped
+}
+
+// replaceEdit applies one string replacement the way the Edit tool does: the
+// old string must be present, and unique unless ReplaceAll is set
+func replaceEdit(content []byte, op EditOperation) ([]byte, error) {
+	text := string(content)
+	count := strings.Count(text, op.OldString)
+	switch {
+	case op.OldString == "":
+		return nil, fmt.Errorf("old_string is empty")
+	case count == 0:
+		return nil, fmt.Errorf("old_string not found")
+	case op.ReplaceAll != nil && *op.ReplaceAll:
+		return []byte(strings.ReplaceAll(text, op.OldString, op.NewString)), nil
+	case count > 1:
+		return nil, fmt.Errorf("old_string is not unique (%d matches)", count)
+	}
+	return []byte(strings.Replace(text, op.OldString, op.NewString, 1)), nil
+}
@@ -0,0 +1,165 @@
+package claudecode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDryRun(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+echo "$@" > args.txt
+cat <<'EOF'
+{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Edit","input":{"file_path":"main.go","old_string":"old()","new_string":"updated()"}}]}}
+{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t2","name":"Write","input":{"file_path":"docs/new.md","content":"# New\n"}}]}}
+{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t3","name":"MultiEdit","input":{"file_path":"docs/new.md","edits":[{"old_string":"New","new_string":"Newer"}]}}]}}
+{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t4","name":"Edit","input":{"file_path":"main.go","old_string":"missing","new_string":"x"}}]}}
+{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t5","name":"Read","input":{"file_path":"main.go"}}]}}
+{"type":"result","subtype":"success","result":"proposed"}
+EOF
+`)
+
+	workDir := t.TempDir()
+	writeTree(t, workDir, map[string]string{"main.go": "package main\n\nfunc main() { old() }\n"})
+
+	opts := NewOptions()
+	opts.Cwd = workDir
+	opts.AllowedTools = []string{ToolRead, ToolEdit, ToolBash}
+	mode := PermissionModeAcceptEdits
+	opts.PermissionMode = &mode
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	proposal, err := DryRun(ctx, "Rename old", opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Nothing may be written
+	assertFile(t, filepath.Join(workDir, "main.go"), "package main\n\nfunc main() { old() }\n")
+	if _, err := os.Stat(filepath.Join(workDir, "docs")); !os.IsNotExist(err) {
+		t.Errorf("expected no files created, got %v", err)
+	}
+
+	args, err := os.ReadFile(filepath.Join(workDir, "args.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "--permission-mode default") {
+		t.Errorf("expected default permission mode, got %s", args)
+	}
+	if !strings.Contains(string(args), "--allowedTools Read ") {
+		t.Errorf("expected only read-only tools allowed, got %s", args)
+	}
+	if *opts.PermissionMode != PermissionModeAcceptEdits || len(opts.AllowedTools) != 3 {
+		t.Error("caller's options were modified")
+	}
+
+	if proposal.Result == nil || proposal.Result.Result == nil || *proposal.Result.Result != "proposed" {
+		t.Errorf("unexpected result: %+v", proposal.Result)
+	}
+	if proposal.Changes.Dir != workDir {
+		t.Errorf("expected dir %s, got %s", workDir, proposal.Changes.Dir)
+	}
+
+	want := []FileChange{
+		{Path: "docs/new.md", Op: ChangeAdded, Patch: "--- /dev/null\n+++ b/docs/new.md\n@@ -0,0 +1 @@\n+# Newer\n"},
+		{Path: "main.go", Op: ChangeModified, Patch: "--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@\n package main\n \n-func main() { old() }\n+func main() { updated() }\n"},
+	}
+	if len(proposal.Changes.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), proposal.Changes.Changes)
+	}
+	for i, change := range proposal.Changes.Changes {
+		if change != want[i] {
+			t.Errorf("change %d:\nexpected %+v\ngot      %+v", i, want[i], change)
+		}
+	}
+
+	if len(proposal.Skipped) != 1 || proposal.Skipped[0].ToolUseID != "t4" {
+		t.Errorf("expected edit t4 to be skipped, got %+v", proposal.Skipped)
+	}
+}
+
+func TestDryRunDeniesAllowedEdits(t *testing.T) {
+	// The fake CLI writes the file unless the PreToolUse hook denies it, as
+	// the CLI does when a settings allow rule covers the call
+	installFakeCLI(t, `#!/bin/sh
+echo "$@" > args.txt
+read -r init
+read -r prompt
+echo '{"type":"control_request","request_id":"cli_1","request":{"subtype":"hook_callback","callback_id":"hook_0","tool_use_id":"w1","input":{"hook_event_name":"PreToolUse","tool_name":"Write","tool_input":{"file_path":"out.txt","content":"hello\\n"}}}}'
+read -r answer
+case "$answer" in
+*'"request_id":"cli_1"'*'"permissionDecision":"deny"'*) ;;
+*) printf 'hello\n' > out.txt ;;
+esac
+echo '{"type":"assistant","message":{"content":[{"type":"tool_use","id":"w1","name":"Write","input":{"file_path":"out.txt","content":"hello\\n"}}]}}'
+echo '{"type":"result","subtype":"success","result":"proposed"}'
+while read -r line; do :; done
+`)
+
+	workDir := t.TempDir()
+	opts := NewOptions()
+	opts.Cwd = workDir
+	opts.Settings = `{"permissions":{"allow":["Write","Bash"]}}`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	proposal, err := DryRun(ctx, "Write out.txt", opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "out.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected the write to be denied, got %v", err)
+	}
+	if len(opts.Hooks) != 0 || opts.CanUseTool != nil || len(opts.DisallowedTools) != 0 {
+		t.Error("caller's options were modified")
+	}
+
+	args, err := os.ReadFile(filepath.Join(workDir, "args.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "--disallowedTools Bash,KillShell,Task,NotebookEdit") {
+		t.Errorf("expected mutating tools to be disallowed, got %s", args)
+	}
+
+	want := []FileChange{{Path: "out.txt", Op: ChangeAdded, Patch: "--- /dev/null\n+++ b/out.txt\n@@ -0,0 +1 @@\n+hello\n"}}
+	if len(proposal.Changes.Changes) != 1 || proposal.Changes.Changes[0] != want[0] {
+		t.Errorf("expected %+v, got %+v", want, proposal.Changes.Changes)
+	}
+}
+
+func TestReplaceEdit(t *testing.T) {
+	replaceAll := true
+	tests := []struct {
+		name    string
+		content string
+		op      EditOperation
+		want    string
+		wantErr bool
+	}{
+		{"unique", "a b c", EditOperation{OldString: "b", NewString: "x"}, "a x c", false},
+		{"missing", "a b c", EditOperation{OldString: "z", NewString: "x"}, "", true},
+		{"ambiguous", "a a", EditOperation{OldString: "a", NewString: "x"}, "", true},
+		{"replace all", "a a", EditOperation{OldString: "a", NewString: "x", ReplaceAll: &replaceAll}, "x x", false},
+		{"empty old string", "a", EditOperation{NewString: "x"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := replaceEdit([]byte(tt.content), tt.op)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
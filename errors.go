@@ -18,7 +18,8 @@ type CLINotFoundError = errors.CLINotFoundError
 // NewCLINotFoundError creates a new CLINotFoundError
 var NewCLINotFoundError = errors.NewCLINotFoundError
 
-// ProcessError is raised when the CLI process fails
+// ProcessError is raised when the CLI process fails. RetryAfter holds any
+// retry delay stated in its stderr.
 type ProcessError = errors.ProcessError
 
 // NewProcessError creates a new ProcessError
@@ -29,3 +30,223 @@ type CLIJSONDecodeError = errors.CLIJSONDecodeError
 
 // NewCLIJSONDecodeError creates a new CLIJSONDecodeError
 var NewCLIJSONDecodeError = errors.NewCLIJSONDecodeError
+
+// StallError is raised when the CLI stops producing output for longer than
+// Options.StallTimeout
+type StallError = errors.StallError
+
+// NewStallError creates a new StallError
+var NewStallError = errors.NewStallError
+
+// StartupTimeoutError is raised when the CLI does not report its init
+// message within Options.StartupTimeout
+type StartupTimeoutError = errors.StartupTimeoutError
+
+// NewStartupTimeoutError creates a new StartupTimeoutError
+var NewStartupTimeoutError = errors.NewStartupTimeoutError
+
+// IncompatibleCLIError is raised at Connect when the installed CLI does not
+// satisfy Options.RequireCLIVersion
+type IncompatibleCLIError = errors.IncompatibleCLIError
+
+// NewIncompatibleCLIError creates a new IncompatibleCLIError
+var NewIncompatibleCLIError = errors.NewIncompatibleCLIError
+
+// LimitExceededError is raised when a query is stopped by an SDK-side limit
+// such as Options.TurnLimit or Options.QueryTimeout
+type LimitExceededError = errors.LimitExceededError
+
+// NewLimitExceededError creates a new LimitExceededError
+var NewLimitExceededError = errors.NewLimitExceededError
+
+// Limit names reported by LimitExceededError
+const (
+	// LimitTurns is reported when Options.TurnLimit is exceeded
+	LimitTurns = "turns"
+	// LimitWallClock is reported when Options.QueryTimeout elapses
+	LimitWallClock = "wall_clock"
+)
+
+// BudgetExceededError is raised when the cost of a query or session crosses
+// Options.MaxCostUSD or a context's CostBudget
+type BudgetExceededError = errors.BudgetExceededError
+
+// NewBudgetExceededError creates a new BudgetExceededError
+var NewBudgetExceededError = errors.NewBudgetExceededError
+
+// ResultError reports a ResultMessage with IsError set; see
+// ResultMessage.Err
+type ResultError = errors.ResultError
+
+// NewResultError creates a new ResultError
+var NewResultError = errors.NewResultError
+
+// MaxTurnsExceededError reports a run the CLI stopped at Options.MaxTurns
+// (result subtype "error_max_turns")
+type MaxTurnsExceededError = errors.MaxTurnsExceededError
+
+// NewMaxTurnsExceededError creates a new MaxTurnsExceededError
+var NewMaxTurnsExceededError = errors.NewMaxTurnsExceededError
+
+// RateLimitError reports a run rejected by the API rate limit or, with
+// Overloaded set, because the API is overloaded. RetryAfter holds the delay
+// the API asked for, if any.
+type RateLimitError = errors.RateLimitError
+
+// NewRateLimitError creates a new RateLimitError
+var NewRateLimitError = errors.NewRateLimitError
+
+// ReadOnlyViolationError is raised when a query with Options.ReadOnly set
+// requests a tool that could modify the workspace
+type ReadOnlyViolationError = errors.ReadOnlyViolationError
+
+// NewReadOnlyViolationError creates a new ReadOnlyViolationError
+var NewReadOnlyViolationError = errors.NewReadOnlyViolationError
+
+// CallbackError is raised when a CanUseTool or hook callback panics or runs
+// past Options.CallbackTimeout
+type CallbackError = errors.CallbackError
+
+// NewCallbackTimeoutError creates a CallbackError for a timed out callback
+var NewCallbackTimeoutError = errors.NewCallbackTimeoutError
+
+// NewCallbackPanicError creates a CallbackError for a panicking callback
+var NewCallbackPanicError = errors.NewCallbackPanicError
+
+// TurnRejectedError is raised by Client.SendWithApproval when the reviewer
+// rejects a turn
+type TurnRejectedError = errors.TurnRejectedError
+
+// NewTurnRejectedError creates a new TurnRejectedError
+var NewTurnRejectedError = errors.NewTurnRejectedError
+
+// CostNotConfirmedError is raised by Experiment.Run when the estimated cost
+// exceeds CostGate.ThresholdUSD and is not confirmed
+type CostNotConfirmedError = errors.CostNotConfirmedError
+
+// NewCostNotConfirmedError creates a new CostNotConfirmedError
+var NewCostNotConfirmedError = errors.NewCostNotConfirmedError
+
+// PatchConflictError is raised by ApplyChangeSet when a file no longer
+// matches the state a change was made against
+type PatchConflictError = errors.PatchConflictError
+
+// NewPatchConflictError creates a new PatchConflictError
+var NewPatchConflictError = errors.NewPatchConflictError
+
+// Errors aggregates several errors, such as every failure reported by
+// Options.Validate. errors.Is and errors.As inspect every element.
+type Errors = errors.Errors
+
+// ErrorCode is a stable, machine-readable identifier for a kind of error,
+// for mapping errors to API responses and alerts without matching messages
+type ErrorCode = errors.ErrorCode
+
+// Error codes reported by ErrorCodeOf
+const (
+	ErrorCodeUnknown           = errors.CodeUnknown
+	ErrorCodeSDK               = errors.CodeSDK
+	ErrorCodeCLIConnection     = errors.CodeCLIConnection
+	ErrorCodeCLINotFound       = errors.CodeCLINotFound
+	ErrorCodeIncompatibleCLI   = errors.CodeIncompatibleCLI
+	ErrorCodeProcess           = errors.CodeProcess
+	ErrorCodeJSONDecode        = errors.CodeJSONDecode
+	ErrorCodeStall             = errors.CodeStall
+	ErrorCodeStartupTimeout    = errors.CodeStartupTimeout
+	ErrorCodeTimeout           = errors.CodeTimeout
+	ErrorCodeCanceled          = errors.CodeCanceled
+	ErrorCodeTurnLimitExceeded = errors.CodeTurnLimitExceeded
+	ErrorCodeLimitExceeded     = errors.CodeLimitExceeded
+	ErrorCodeBudgetExceeded    = errors.CodeBudgetExceeded
+	ErrorCodeReadOnlyViolation = errors.CodeReadOnlyViolation
+	ErrorCodeCallbackTimeout   = errors.CodeCallbackTimeout
+	ErrorCodeCallbackPanic     = errors.CodeCallbackPanic
+	ErrorCodePatchConflict     = errors.CodePatchConflict
+	ErrorCodeResult            = errors.CodeResult
+	ErrorCodeMaxTurnsExceeded  = errors.CodeMaxTurnsExceeded
+	ErrorCodeRateLimited       = errors.CodeRateLimited
+	ErrorCodeOverloaded        = errors.CodeOverloaded
+	ErrorCodeTurnRejected      = errors.CodeTurnRejected
+	ErrorCodeCostNotConfirmed  = errors.CodeCostNotConfirmed
+	ErrorCodeMultiple          = errors.CodeMultiple
+)
+
+// ErrorCoder is implemented by every SDK error type
+type ErrorCoder = errors.Coder
+
+// ErrorCodeOf returns the code of err: that of the first SDK error in its
+// chain, ErrorCodeTimeout or ErrorCodeCanceled for context errors,
+// ErrorCodeUnknown for other errors and "" for nil
+//
+// Example:
+//
+//	if err := <-errCh; err != nil {
+//	    switch ErrorCodeOf(err) {
+//	    case ErrorCodeCLINotFound, ErrorCodeIncompatibleCLI:
+//	        http.Error(w, err.Error(), http.StatusServiceUnavailable)
+//	    case ErrorCodeTimeout:
+//	        http.Error(w, err.Error(), http.StatusGatewayTimeout)
+//	    }
+//	}
+var ErrorCodeOf = errors.CodeOf
+
+// HintKey identifies the user-facing guidance attached to an error, such as
+// install instructions. Error() only carries the developer-facing detail.
+type HintKey = errors.HintKey
+
+// Hint keys reported by SDK errors
+const (
+	// HintNodeNotInstalled explains how to install Node.js and Claude Code
+	HintNodeNotInstalled = errors.HintNodeNotInstalled
+	// HintCLINotInstalled explains how to install Claude Code or point the
+	// SDK at an existing installation
+	HintCLINotInstalled = errors.HintCLINotInstalled
+	// HintCLIPathInvalid asks to check an explicitly configured CLI path
+	HintCLIPathInvalid = errors.HintCLIPathInvalid
+	// HintCLIIncompatible explains how to install a supported CLI version
+	HintCLIIncompatible = errors.HintCLIIncompatible
+)
+
+// HintCatalog maps hint keys to text/template sources, executed with the
+// error carrying the hint (e.g. {{.CLIPath}} for CLINotFoundError,
+// {{.Version}} and {{.Constraint}} for IncompatibleCLIError)
+type HintCatalog = errors.HintCatalog
+
+// DefaultHintCatalog returns a copy of the built-in English hints, as a
+// starting point for translated or rebranded catalogs
+var DefaultHintCatalog = errors.DefaultHintCatalog
+
+// ErrorHint returns the user-facing hint for the first error in err's chain
+// that has one, rendered from catalog (may be nil). Keys missing from
+// catalog fall back to the built-in English text. Returns "" if err carries
+// no hint.
+//
+// Example:
+//
+//	catalog := HintCatalog{
+//	    HintCLINotInstalled: "Installez Claude Code : npm install -g @anthropic-ai/claude-code",
+//	}
+//	if err := <-errCh; err != nil {
+//	    log.Print(err)
+//	    fmt.Fprintln(os.Stderr, ErrorHint(err, catalog))
+//	}
+var ErrorHint = errors.RenderHint
+
+// ParseRetryAfter returns the retry delay stated in text, such as
+// "Retry-After: 30" or "try again in 2m", or 0 if it does not state one.
+// Numbers without a unit are seconds.
+var ParseRetryAfter = errors.ParseRetryAfter
+
+// ErrorRetryAfter returns the retry delay the CLI or API attached to err
+// (see ProcessError.RetryAfter and RateLimitError.RetryAfter), or 0 if none
+// was given. Options.Retry
+// waits at least this long before retrying.
+//
+// Example:
+//
+//	if err := <-errCh; err != nil {
+//	    if wait := ErrorRetryAfter(err); wait > 0 {
+//	        w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())))
+//	    }
+//	}
+var ErrorRetryAfter = errors.RetryAfterOf
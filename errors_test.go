@@ -1,9 +1,12 @@
 package claudecode
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestErrors(t *testing.T) {
@@ -66,3 +69,154 @@ func TestErrors(t *testing.T) {
 		}
 	})
 }
+
+func TestErrorsAggregate(t *testing.T) {
+	t.Run("empty aggregate is nil", func(t *testing.T) {
+		var errs Errors
+		if errs.ErrorOrNil() != nil {
+			t.Error("Expected ErrorOrNil() to return nil for no errors")
+		}
+	})
+
+	t.Run("message joins all errors", func(t *testing.T) {
+		errs := Errors{errors.New("first"), errors.New("second")}
+		if errs.Error() != "first; second" {
+			t.Errorf("Expected 'first; second', got %q", errs.Error())
+		}
+	})
+
+	t.Run("Is and As inspect every error", func(t *testing.T) {
+		sentinel := errors.New("sentinel")
+		exitCode := 2
+		err := Errors{sentinel, NewProcessError("failed", &exitCode, "")}.ErrorOrNil()
+
+		if !errors.Is(err, sentinel) {
+			t.Error("Expected errors.Is to find the sentinel")
+		}
+		var procErr *ProcessError
+		if !errors.As(err, &procErr) || *procErr.ExitCode != 2 {
+			t.Error("Expected errors.As to find the ProcessError")
+		}
+	})
+
+	t.Run("Validate returns Errors", func(t *testing.T) {
+		options := NewOptions()
+		options.Model = "gpt-4"
+		mode := PermissionMode("yolo")
+		options.PermissionMode = &mode
+		var errs Errors
+		if err := options.Validate(); !errors.As(err, &errs) || len(errs) != 2 {
+			t.Errorf("Expected two aggregated errors, got %v", err)
+		}
+	})
+}
+
+func TestErrorCodeOf(t *testing.T) {
+	exitCode := 1
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCode
+	}{
+		{"nil", nil, ""},
+		{"plain", errors.New("boom"), ErrorCodeUnknown},
+		{"sdk", SDKError{Message: "x"}, ErrorCodeSDK},
+		{"connection", &CLIConnectionError{SDKError: SDKError{Message: "x"}}, ErrorCodeCLIConnection},
+		{"not found", NewCLINotFoundError("missing", ""), ErrorCodeCLINotFound},
+		{"incompatible", NewIncompatibleCLIError("claude", "1.0.0", "^2"), ErrorCodeIncompatibleCLI},
+		{"process", NewProcessError("failed", &exitCode, ""), ErrorCodeProcess},
+		{"json", NewCLIJSONDecodeError("{", errors.New("eof")), ErrorCodeJSONDecode},
+		{"stall", NewStallError(time.Second), ErrorCodeStall},
+		{"startup timeout", NewStartupTimeoutError(time.Second), ErrorCodeStartupTimeout},
+		{"wall clock", NewLimitExceededError(LimitWallClock, 10), ErrorCodeTimeout},
+		{"turns", NewLimitExceededError(LimitTurns, 3), ErrorCodeTurnLimitExceeded},
+		{"read only", NewReadOnlyViolationError(ToolBash), ErrorCodeReadOnlyViolation},
+		{"callback timeout", NewCallbackTimeoutError("CanUseTool", time.Second), ErrorCodeCallbackTimeout},
+		{"callback panic", NewCallbackPanicError("CanUseTool", "oops"), ErrorCodeCallbackPanic},
+		{"patch conflict", NewPatchConflictError("a.go", "changed"), ErrorCodePatchConflict},
+		{"turn rejected", NewTurnRejectedError(1, "no"), ErrorCodeTurnRejected},
+		{"cost not confirmed", NewCostNotConfirmedError(12, 10), ErrorCodeCostNotConfirmed},
+		{"deadline", context.DeadlineExceeded, ErrorCodeTimeout},
+		{"canceled", fmt.Errorf("query: %w", context.Canceled), ErrorCodeCanceled},
+		{"wrapped", fmt.Errorf("connect: %w", NewCLINotFoundError("missing", "")), ErrorCodeCLINotFound},
+		{"partial", &PartialResultError{Err: NewStallError(time.Second), Partial: &PartialResult{}}, ErrorCodeStall},
+		{"inline", ErrorMessage{Err: NewLimitExceededError(LimitTurns, 3)}, ErrorCodeTurnLimitExceeded},
+		{"aggregate same", Errors{NewStallError(time.Second), NewStallError(time.Minute)}, ErrorCodeStall},
+		{"aggregate mixed", Errors{NewStallError(time.Second), errors.New("x")}, ErrorCodeMultiple},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorCodeOf(tt.err); got != tt.want {
+				t.Errorf("ErrorCodeOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorHint(t *testing.T) {
+	notInstalled := NewCLINotFoundError("Claude Code not found", "")
+	if strings.Contains(notInstalled.Error(), "npm install") {
+		t.Errorf("Error() should not contain install instructions, got %q", notInstalled.Error())
+	}
+	if hint := ErrorHint(notInstalled, nil); !strings.Contains(hint, "npm install -g @anthropic-ai/claude-code") {
+		t.Errorf("Expected default install hint, got %q", hint)
+	}
+
+	catalog := HintCatalog{
+		HintCLIPathInvalid:  "Vérifiez l'installation de Claude Code dans {{.CLIPath}}",
+		HintCLIIncompatible: "{{.Unknown}}",
+	}
+	wrapped := fmt.Errorf("connect: %w", NewCLINotFoundError("Claude Code not found", "/opt/claude"))
+	if hint := ErrorHint(wrapped, catalog); hint != "Vérifiez l'installation de Claude Code dans /opt/claude" {
+		t.Errorf("Expected localized hint, got %q", hint)
+	}
+
+	// Keys missing from the catalog and broken templates use the default
+	if hint := ErrorHint(notInstalled, catalog); hint != ErrorHint(notInstalled, nil) {
+		t.Errorf("Expected fallback to default hint, got %q", hint)
+	}
+	incompatible := NewIncompatibleCLIError("/usr/bin/claude", "1.0.0", ">=2.0")
+	if hint := ErrorHint(incompatible, catalog); !strings.Contains(hint, ">=2.0") {
+		t.Errorf("Expected default incompatible hint, got %q", hint)
+	}
+
+	if hint := ErrorHint(NewStallError(time.Second), nil); hint != "" {
+		t.Errorf("Expected no hint for StallError, got %q", hint)
+	}
+	if hint := ErrorHint(errors.New("plain"), catalog); hint != "" {
+		t.Errorf("Expected no hint for plain error, got %q", hint)
+	}
+
+	defaults := DefaultHintCatalog()
+	defaults[HintCLINotInstalled] = "changed"
+	if hint := ErrorHint(notInstalled, nil); hint == "changed" {
+		t.Error("DefaultHintCatalog should return a copy")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := map[string]time.Duration{
+		"Retry-After: 30":                              30 * time.Second,
+		"rate limited, retry after 2.5 seconds":        2500 * time.Millisecond,
+		`{"error":"overloaded","retry_after_ms":1500}`: 1500 * time.Millisecond,
+		"429 Too Many Requests. Try again in 1m":       time.Minute,
+		"retry-after=250ms":                            250 * time.Millisecond,
+		"connection reset":                             0,
+		"retry after 0":                                0,
+	}
+	for text, want := range tests {
+		if got := ParseRetryAfter(text); got != want {
+			t.Errorf("ParseRetryAfter(%q) = %v, want %v", text, got, want)
+		}
+	}
+
+	exitCode := 1
+	err := fmt.Errorf("query: %w", NewProcessError("CLI process failed", &exitCode, "Error: rate limited. Retry-After: 20"))
+	if got := ErrorRetryAfter(err); got != 20*time.Second {
+		t.Errorf("expected 20s, got %v", got)
+	}
+	if got := ErrorRetryAfter(errors.New("other")); got != 0 {
+		t.Errorf("expected 0, got %v", got)
+	}
+}
@@ -5,71 +5,51 @@ import (
 	"fmt"
 	"log"
 	"strings"
-	"sync"
 	"time"
 
 	claudecode "github.com/f-pisani/claude-code-sdk-go"
 )
 
-// Debater represents a participant in the debate
+// Debater represents a participant in the debate. Each debater keeps one
+// CLI process open for the whole debate, so it remembers earlier rounds.
 type Debater struct {
-	name      string
-	emoji     string
-	sessionID string
-	options   *claudecode.Options
-	mu        sync.Mutex
+	name    string
+	emoji   string
+	options *claudecode.Options
+	client  *claudecode.Client
+}
+
+// connect starts the debater's conversation
+func (d *Debater) connect(ctx context.Context) error {
+	d.client = claudecode.NewClient(d.options)
+	return d.client.Connect(ctx)
 }
 
 // respond generates a response to the opponent's statement
 func (d *Debater) respond(ctx context.Context, statement string) (string, error) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	// Use Resume if we have a session ID from a previous turn
-	if d.sessionID != "" {
-		d.options.Resume = d.sessionID
+	if err := d.client.SendMessage(ctx, statement); err != nil {
+		return "", fmt.Errorf("%s error: %w", d.name, err)
 	}
 
-	msgCh, errCh := claudecode.Query(ctx, statement, d.options)
+	messages, err := d.client.ReceiveResponse(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s error: %w", d.name, err)
+	}
 
 	var response string
-	var newSessionID string
-
-	for {
-		select {
-		case msg, ok := <-msgCh:
-			if !ok {
-				if response == "" {
-					return "", fmt.Errorf("%s: channel closed without response", d.name)
+	for _, msg := range messages {
+		if m, ok := msg.(claudecode.AssistantMessage); ok {
+			for _, block := range m.Content {
+				if textBlock, ok := block.(claudecode.TextBlock); ok {
+					response = strings.TrimSpace(textBlock.Text)
 				}
-				// Update session ID for next turn
-				if newSessionID != "" {
-					d.sessionID = newSessionID
-				}
-				return response, nil
 			}
-
-			switch m := msg.(type) {
-			case claudecode.AssistantMessage:
-				for _, block := range m.Content {
-					if textBlock, ok := block.(claudecode.TextBlock); ok {
-						response = strings.TrimSpace(textBlock.Text)
-					}
-				}
-			case claudecode.ResultMessage:
-				if m.SessionID != "" {
-					newSessionID = m.SessionID
-				}
-			}
-
-		case err := <-errCh:
-			if err != nil {
-				return "", fmt.Errorf("%s error: %w", d.name, err)
-			}
-		case <-ctx.Done():
-			return "", fmt.Errorf("%s: context cancelled", d.name)
 		}
 	}
+	if response == "" {
+		return "", fmt.Errorf("%s: turn ended without response", d.name)
+	}
+	return response, nil
 }
 
 func main() {
@@ -103,6 +83,13 @@ You believe AI will eventually replace most developer jobs. Present thoughtful,
 Keep responses concise (2-3 sentences) and directly address your opponent's points.`
 	pessimist.options.MaxTurns = claudecode.IntPtr(1)
 
+	for _, debater := range []*Debater{optimist, pessimist} {
+		if err := debater.connect(ctx); err != nil {
+			log.Fatalf("Failed to start %s: %v", debater.name, err)
+		}
+		defer debater.client.Close()
+	}
+
 	// Number of debate rounds
 	maxRounds := 40
 
@@ -0,0 +1,366 @@
+package claudecode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/clock"
+)
+
+// InputPlaceholder is replaced by the input in a Variant's Prompt
+const InputPlaceholder = "{{input}}"
+
+// Variant is one configuration under comparison in an Experiment
+type Variant struct {
+	// Name identifies the variant in reports
+	Name string
+	// Prompt is the prompt template. InputPlaceholder is replaced by each
+	// input; without it, the input is appended after a blank line. An empty
+	// Prompt sends the input as is.
+	Prompt string
+	// Options configures the variant's queries, e.g. its Model (uses
+	// NewOptions() if nil)
+	Options *Options
+}
+
+// render returns the prompt for input
+func (v Variant) render(input string) string {
+	switch {
+	case v.Prompt == "":
+		return input
+	case strings.Contains(v.Prompt, InputPlaceholder):
+		return strings.ReplaceAll(v.Prompt, InputPlaceholder, input)
+	default:
+		return v.Prompt + "\n\n" + input
+	}
+}
+
+// Experiment runs every Variant over a shared set of inputs for offline
+// comparison
+//
+// Example:
+//
+//	report, err := (&Experiment{
+//	    Variants: []Variant{
+//	        {Name: "terse", Prompt: "Answer briefly: {{input}}"},
+//	        {Name: "detailed", Prompt: "Explain step by step: {{input}}"},
+//	    },
+//	    Inputs:      questions,
+//	    Concurrency: 4,
+//	}).Run(ctx)
+//	report.WriteTable(os.Stdout)
+type Experiment struct {
+	Variants []Variant
+	Inputs   []string
+	// Concurrency bounds the number of queries running at once (defaults to 1)
+	Concurrency int
+	// Query runs each query (defaults to Query). A ScriptedResponder can be
+	// plugged in to exercise an experiment offline.
+	Query QueryFunc
+	// Scorers grade each run; a scorer's result is recorded in
+	// ExperimentRun.Metrics under its name and averaged per variant
+	Scorers map[string]Scorer
+	// Hook, when set, is called after each run has been scored, e.g. to
+	// record custom values in ExperimentRun.Metrics. It may be called
+	// concurrently.
+	Hook func(run *ExperimentRun)
+	// CostGate, when set, estimates the cost of the experiment before it
+	// starts and requires confirmation above a threshold
+	CostGate *CostGate
+}
+
+// ExperimentRun is the outcome of one variant on one input
+type ExperimentRun struct {
+	Variant    string             `json:"variant"`
+	InputIndex int                `json:"input_index"`
+	Input      string             `json:"input"`
+	Answer     string             `json:"answer"`
+	CostUSD    float64            `json:"cost_usd"`
+	NumTurns   int                `json:"num_turns"`
+	Latency    time.Duration      `json:"latency_ns"`
+	Err        error              `json:"-"`
+	Metrics    map[string]float64 `json:"metrics,omitempty"`
+	// ScoreErr aggregates the errors of scorers that failed on this run
+	ScoreErr error `json:"-"`
+}
+
+// MarshalJSON encodes the run with its errors as strings
+func (r ExperimentRun) MarshalJSON() ([]byte, error) {
+	type plain ExperimentRun
+	out := struct {
+		plain
+		Error      string `json:"error,omitempty"`
+		ScoreError string `json:"score_error,omitempty"`
+	}{plain: plain(r)}
+	if r.Err != nil {
+		out.Error = r.Err.Error()
+	}
+	if r.ScoreErr != nil {
+		out.ScoreError = r.ScoreErr.Error()
+	}
+	return json.Marshal(out)
+}
+
+// VariantSummary aggregates a variant's runs
+type VariantSummary struct {
+	Variant      string        `json:"variant"`
+	Runs         int           `json:"runs"`
+	Errors       int           `json:"errors"`
+	TotalCostUSD float64       `json:"total_cost_usd"`
+	MeanLatency  time.Duration `json:"mean_latency_ns"`
+	P95Latency   time.Duration `json:"p95_latency_ns"`
+	// Metrics holds the mean of each metric over the runs that recorded it
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+}
+
+// ExperimentReport holds every run of an experiment and a per-variant summary
+type ExperimentReport struct {
+	// Runs are ordered by variant, then input
+	Runs      []ExperimentRun  `json:"runs"`
+	Summaries []VariantSummary `json:"summaries"`
+}
+
+// Run executes every variant on every input. Individual query failures are
+// recorded in the report rather than returned; an error is returned only if
+// the experiment is misconfigured, its CostGate refuses it or ctx ends.
+func (e *Experiment) Run(ctx context.Context) (*ExperimentReport, error) {
+	if len(e.Variants) == 0 {
+		return nil, fmt.Errorf("experiment has no variants")
+	}
+	seen := make(map[string]bool, len(e.Variants))
+	for _, v := range e.Variants {
+		if v.Name == "" || seen[v.Name] {
+			return nil, fmt.Errorf("variant names must be unique and non-empty, got %q", v.Name)
+		}
+		seen[v.Name] = true
+	}
+	if e.CostGate != nil {
+		if err := e.CostGate.check(ctx, e.Variants, e.Inputs); err != nil {
+			return nil, err
+		}
+	}
+
+	query := e.Query
+	if query == nil {
+		query = Query
+	}
+	concurrency := e.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	runs := make([]ExperimentRun, len(e.Variants)*len(e.Inputs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+dispatch:
+	for vi, variant := range e.Variants {
+		for ii, input := range e.Inputs {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				break dispatch
+			}
+
+			run := &runs[vi*len(e.Inputs)+ii]
+			*run = ExperimentRun{Variant: variant.Name, InputIndex: ii, Input: input}
+			wg.Add(1)
+			go func(variant Variant) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				runExperiment(ctx, query, variant, run)
+				scoreRun(ctx, e.Scorers, run)
+				if e.Hook != nil {
+					e.Hook(run)
+				}
+			}(variant)
+		}
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return newExperimentReport(e.Variants, runs), nil
+}
+
+// runExperiment runs one variant on run.Input and records the outcome in run
+func runExperiment(ctx context.Context, query QueryFunc, variant Variant, run *ExperimentRun) {
+	clk := variant.Options.GetClock()
+	start := clk.Now()
+	msgCh, errCh := query(ctx, variant.render(run.Input), variant.Options)
+
+	var text []string
+	for msg := range msgCh {
+		switch m := msg.(type) {
+		case AssistantMessage:
+			for _, block := range m.Content {
+				if tb, ok := block.(TextBlock); ok {
+					text = append(text, tb.Text)
+				}
+			}
+		case ResultMessage:
+			run.CostUSD = SafeFloat64Ptr(m.TotalCostUSD)
+			run.NumTurns = m.NumTurns
+			if m.Result != nil {
+				run.Answer = *m.Result
+			}
+			if m.IsError {
+				run.Err = fmt.Errorf("query ended with %s", m.Subtype)
+			}
+		case ErrorMessage:
+			run.Err = m.Err
+		}
+	}
+	if err := <-errCh; err != nil {
+		run.Err = err
+	}
+	if run.Answer == "" {
+		run.Answer = strings.Join(text, "\n")
+	}
+	run.Latency = clock.Since(clk, start)
+}
+
+// newExperimentReport summarizes runs per variant
+func newExperimentReport(variants []Variant, runs []ExperimentRun) *ExperimentReport {
+	report := &ExperimentReport{Runs: runs}
+	for _, variant := range variants {
+		summary := VariantSummary{Variant: variant.Name}
+		var latencies []time.Duration
+		sums := make(map[string]float64)
+		counts := make(map[string]int)
+
+		for _, run := range runs {
+			if run.Variant != variant.Name {
+				continue
+			}
+			summary.Runs++
+			if run.Err != nil {
+				summary.Errors++
+			}
+			summary.TotalCostUSD += run.CostUSD
+			latencies = append(latencies, run.Latency)
+			for name, value := range run.Metrics {
+				sums[name] += value
+				counts[name]++
+			}
+		}
+
+		if len(latencies) > 0 {
+			var total time.Duration
+			for _, latency := range latencies {
+				total += latency
+			}
+			summary.MeanLatency = total / time.Duration(len(latencies))
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+			summary.P95Latency = latencies[(len(latencies)*95+99)/100-1]
+		}
+		if len(sums) > 0 {
+			summary.Metrics = make(map[string]float64, len(sums))
+			for name, sum := range sums {
+				summary.Metrics[name] = sum / float64(counts[name])
+			}
+		}
+		report.Summaries = append(report.Summaries, summary)
+	}
+	return report
+}
+
+// metricNames returns the sorted names of every metric in the report
+func (r *ExperimentReport) metricNames() []string {
+	metricSet := make(map[string]bool)
+	for _, s := range r.Summaries {
+		for name := range s.Metrics {
+			metricSet[name] = true
+		}
+	}
+	metrics := make([]string, 0, len(metricSet))
+	for name := range metricSet {
+		metrics = append(metrics, name)
+	}
+	sort.Strings(metrics)
+	return metrics
+}
+
+// WriteJSON writes the whole report as indented JSON
+func (r *ExperimentReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteTSV writes one tab-separated row per run, with a header row and one
+// column per metric. Tabs and newlines in the input and answer are escaped.
+func (r *ExperimentReport) WriteTSV(w io.Writer) error {
+	metrics := r.metricNames()
+	escape := strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+	header := []string{"variant", "input_index", "input", "answer", "cost_usd", "num_turns", "latency_ms", "error"}
+	if _, err := fmt.Fprintln(w, strings.Join(append(header, metrics...), "\t")); err != nil {
+		return err
+	}
+	for _, run := range r.Runs {
+		var errText string
+		if run.Err != nil {
+			errText = run.Err.Error()
+		}
+		row := []string{
+			escape.Replace(run.Variant),
+			strconv.Itoa(run.InputIndex),
+			escape.Replace(run.Input),
+			escape.Replace(run.Answer),
+			strconv.FormatFloat(run.CostUSD, 'f', -1, 64),
+			strconv.Itoa(run.NumTurns),
+			strconv.FormatInt(run.Latency.Milliseconds(), 10),
+			escape.Replace(errText),
+		}
+		for _, name := range metrics {
+			value, ok := run.Metrics[name]
+			if ok {
+				row = append(row, strconv.FormatFloat(value, 'f', -1, 64))
+			} else {
+				row = append(row, "")
+			}
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTable writes the per-variant summaries as an aligned text table
+func (r *ExperimentReport) WriteTable(w io.Writer) error {
+	metrics := r.metricNames()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "VARIANT\tRUNS\tERRORS\tCOST_USD\tMEAN_LATENCY\tP95_LATENCY")
+	for _, name := range metrics {
+		fmt.Fprintf(tw, "\t%s", strings.ToUpper(name))
+	}
+	fmt.Fprintln(tw)
+
+	for _, s := range r.Summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.4f\t%s\t%s", s.Variant, s.Runs, s.Errors, s.TotalCostUSD,
+			s.MeanLatency.Round(time.Millisecond), s.P95Latency.Round(time.Millisecond))
+		for _, name := range metrics {
+			if value, ok := s.Metrics[name]; ok {
+				fmt.Fprintf(tw, "\t%.3f", value)
+			} else {
+				fmt.Fprint(tw, "\t-")
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,131 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestExperimentRun(t *testing.T) {
+	responder := NewScriptedResponder(1).
+		On(`^short: `, TextResponse("ok")...).
+		OnError(`(?s)^long:.*boom`, errors.New("boom")).
+		Default(TextResponse("a much longer answer")...)
+
+	var inFlight, maxInFlight int32
+	query := func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		defer atomic.AddInt32(&inFlight, -1)
+
+		msgCh, errCh := responder.Query(ctx, prompt, options)
+		var msgs []Message
+		for msg := range msgCh {
+			msgs = append(msgs, msg)
+		}
+		outMsg := make(chan Message, len(msgs))
+		for _, msg := range msgs {
+			outMsg <- msg
+		}
+		close(outMsg)
+		outErr := make(chan error, 1)
+		if err := <-errCh; err != nil {
+			outErr <- err
+		}
+		close(outErr)
+		return outMsg, outErr
+	}
+
+	exp := &Experiment{
+		Variants: []Variant{
+			{Name: "short", Prompt: "short: {{input}}"},
+			{Name: "long", Prompt: "long:"},
+		},
+		Inputs:      []string{"hello", "boom", "world"},
+		Concurrency: 2,
+		Query:       query,
+		Hook: func(run *ExperimentRun) {
+			run.Metrics = map[string]float64{"length": float64(len(run.Answer))}
+		},
+	}
+
+	report, err := exp.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Runs) != 6 {
+		t.Fatalf("expected 6 runs, got %d", len(report.Runs))
+	}
+	if run := report.Runs[0]; run.Variant != "short" || run.Input != "hello" || run.Answer != "ok" {
+		t.Errorf("unexpected first run: %+v", run)
+	}
+	if run := report.Runs[4]; run.Variant != "long" || run.Input != "boom" || run.Err == nil {
+		t.Errorf("expected long/boom to fail, got %+v", run)
+	}
+	if maxInFlight > 2 {
+		t.Errorf("expected at most 2 concurrent queries, got %d", maxInFlight)
+	}
+
+	if len(report.Summaries) != 2 {
+		t.Fatalf("expected 2 summaries, got %d", len(report.Summaries))
+	}
+	short, long := report.Summaries[0], report.Summaries[1]
+	if short.Runs != 3 || short.Errors != 0 || short.Metrics["length"] != 2 {
+		t.Errorf("unexpected short summary: %+v", short)
+	}
+	if long.Runs != 3 || long.Errors != 1 {
+		t.Errorf("unexpected long summary: %+v", long)
+	}
+
+	var table strings.Builder
+	if err := report.WriteTable(&table); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "LENGTH") || !strings.HasPrefix(lines[1], "short") {
+		t.Errorf("unexpected table:\n%s", table.String())
+	}
+}
+
+func TestExperimentValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []Variant
+	}{
+		{"no variants", nil},
+		{"unnamed variant", []Variant{{Prompt: "x"}}},
+		{"duplicate names", []Variant{{Name: "a"}, {Name: "a"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := &Experiment{Variants: tt.variants, Inputs: []string{"x"}}
+			if _, err := exp.Run(context.Background()); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestVariantRender(t *testing.T) {
+	tests := []struct {
+		prompt string
+		want   string
+	}{
+		{"", "in"},
+		{"Q: {{input}}?", "Q: in?"},
+		{"Summarize", "Summarize\n\nin"},
+	}
+	for _, tt := range tests {
+		if got := (Variant{Prompt: tt.prompt}).render("in"); got != tt.want {
+			t.Errorf("render(%q) = %q, want %q", tt.prompt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package claudecode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CodeBlock is a fenced code block found in assistant text
+type CodeBlock struct {
+	// Language is the info string after the opening fence, lowercased,
+	// without attributes such as file names
+	Language string
+	Code     string
+}
+
+// fenceOpenPattern matches the opening line of a fenced code block. Up to
+// three spaces of indentation are allowed, as in CommonMark.
+var fenceOpenPattern = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*([^`\\s]*)")
+
+// ExtractCodeBlocks returns the fenced code blocks in text, in order. When
+// languages are given only blocks in one of them are returned; the match is
+// case-insensitive. An unterminated block at the end of text, as left by a
+// truncated reply, runs to the end of text.
+//
+// Example:
+//
+//	blocks := ExtractCodeBlocks(answer, "go")
+//	if len(blocks) > 0 {
+//	    os.WriteFile("main.go", []byte(blocks[0].Code), 0o644)
+//	}
+func ExtractCodeBlocks(text string, languages ...string) []CodeBlock {
+	var blocks []CodeBlock
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		m := fenceOpenPattern.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		fence, lang := m[1], strings.ToLower(m[2])
+
+		var code []string
+		for i++; i < len(lines); i++ {
+			if closesFence(lines[i], fence) {
+				break
+			}
+			code = append(code, lines[i])
+		}
+		if matchesLanguage(lang, languages) {
+			blocks = append(blocks, CodeBlock{Language: lang, Code: strings.Join(code, "\n")})
+		}
+	}
+	return blocks
+}
+
+// closesFence reports whether line closes a block opened with fence: the same
+// character, at least as long, and nothing else on the line
+func closesFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	if len(trimmed) < len(fence) || len(line)-len(strings.TrimLeft(line, " ")) > 3 {
+		return false
+	}
+	return strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// matchesLanguage reports whether lang is one of languages, or languages is
+// empty
+func matchesLanguage(lang string, languages []string) bool {
+	if len(languages) == 0 {
+		return true
+	}
+	for _, l := range languages {
+		if strings.EqualFold(lang, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractJSON returns the first valid JSON object in text. Fenced json blocks
+// are tried first, then every "{" in the surrounding prose, so objects
+// wrapped in explanations, trailing commentary or a second object are found.
+func ExtractJSON(text string) (json.RawMessage, error) {
+	for _, block := range ExtractCodeBlocks(text, "json", "") {
+		if obj, ok := firstJSONObject(block.Code); ok {
+			return obj, nil
+		}
+	}
+	if obj, ok := firstJSONObject(text); ok {
+		return obj, nil
+	}
+	return nil, fmt.Errorf("no JSON object found in text")
+}
+
+// ExtractJSONInto decodes the first valid JSON object in text into v
+func ExtractJSONInto(text string, v interface{}) error {
+	obj, err := ExtractJSON(text)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(obj, v)
+}
+
+// firstJSONObject decodes from each "{" in text in turn and returns the first
+// complete object
+func firstJSONObject(text string) (json.RawMessage, bool) {
+	for start := strings.IndexByte(text, '{'); start >= 0; {
+		dec := json.NewDecoder(strings.NewReader(text[start:]))
+		var obj json.RawMessage
+		if err := dec.Decode(&obj); err == nil && bytes.HasPrefix(obj, []byte("{")) {
+			return obj, true
+		}
+		next := strings.IndexByte(text[start+1:], '{')
+		if next < 0 {
+			break
+		}
+		start += next + 1
+	}
+	return nil, false
+}
+
+// listItemPattern matches a bullet or numbered list item
+var listItemPattern = regexp.MustCompile(`^\s*(?:[-*+•]|\d+[.)])\s+(.*\S)\s*$`)
+
+// ExtractList returns the items of the first bullet or numbered list in
+// text. Items may be separated by blank lines. Indented lines directly
+// following an item are continuation text and are joined onto it; nested
+// list items are returned as items of their own.
+// Markdown checkboxes are stripped.
+func ExtractList(text string) []string {
+	var items []string
+	blank := false
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if m := listItemPattern.FindStringSubmatch(line); m != nil {
+			item := m[1]
+			for _, box := range []string{"[ ] ", "[x] ", "[X] "} {
+				item = strings.TrimPrefix(item, box)
+			}
+			items = append(items, item)
+			blank = false
+			continue
+		}
+		if len(items) == 0 {
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			// A blank line ends the list unless another item follows
+			blank = true
+			continue
+		}
+		if blank || len(line) == len(strings.TrimLeft(line, " \t")) {
+			break
+		}
+		items[len(items)-1] += " " + trimmed
+	}
+	return items
+}
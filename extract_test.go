@@ -0,0 +1,102 @@
+package claudecode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractCodeBlocks(t *testing.T) {
+	text := "Here is the fix:\r\n" +
+		"```Go title=\"main.go\"\r\n" +
+		"package main\r\n" +
+		"\r\n" +
+		"func main() {}\r\n" +
+		"```\r\n" +
+		"And a nested example:\n" +
+		"````markdown\n" +
+		"```sh\n" +
+		"make\n" +
+		"```\n" +
+		"````\n" +
+		"   ~~~\n" +
+		"plain\n" +
+		"   ~~~~\n" +
+		"Truncated:\n" +
+		"```python\n" +
+		"print('hi')"
+
+	got := ExtractCodeBlocks(text)
+	want := []CodeBlock{
+		{Language: "go", Code: "package main\n\nfunc main() {}"},
+		{Language: "markdown", Code: "```sh\nmake\n```"},
+		{Language: "", Code: "plain"},
+		{Language: "python", Code: "print('hi')"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected blocks:\n got %#v\nwant %#v", got, want)
+	}
+
+	if got := ExtractCodeBlocks(text, "GO", "python"); len(got) != 2 || got[0].Language != "go" || got[1].Language != "python" {
+		t.Errorf("expected go and python blocks, got %#v", got)
+	}
+	if got := ExtractCodeBlocks("no code here", "go"); got != nil {
+		t.Errorf("expected no blocks, got %#v", got)
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"bare", `{"a":1}`, `{"a":1}`},
+		{"fenced", "Sure!\n```json\n{\"a\": [1, 2]}\n```\nLet me know.", `{"a": [1, 2]}`},
+		{"untagged fence", "```\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"prose", `The result is {"ok": true, "note": "use {braces}"} as requested.`, `{"ok": true, "note": "use {braces}"}`},
+		{"skips invalid", `Set {name} then: {"name": "x"} and {"other": 1}`, `{"name": "x"}`},
+		{"invalid fence falls back", "```json\n{broken\n```\n{\"a\": 1}", `{"a": 1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractJSON(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ExtractJSON("[1, 2] and {not json"); err == nil {
+		t.Error("expected error when no object is present")
+	}
+
+	var v struct{ Name string }
+	if err := ExtractJSONInto(`Answer: {"name": "x"}`, &v); err != nil || v.Name != "x" {
+		t.Errorf("unexpected decode %+v, %v", v, err)
+	}
+}
+
+func TestExtractList(t *testing.T) {
+	text := "Steps to take:\n" +
+		"\n" +
+		"1. Update the parser\n" +
+		"   so it handles tabs\n" +
+		"2) Add tests\n" +
+		"\n" +
+		"  - [x] nested item\n" +
+		"* [ ] Release\n" +
+		"\n" +
+		"That's all.\n" +
+		"- not part of the first list\n"
+
+	want := []string{"Update the parser so it handles tabs", "Add tests", "nested item", "Release"}
+	if got := ExtractList(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := ExtractList("no list"); got != nil {
+		t.Errorf("expected no items, got %q", got)
+	}
+}
@@ -0,0 +1,226 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/validation"
+)
+
+// FileOp is the kind of change a FileEvent reports
+type FileOp string
+
+const (
+	FileCreated  FileOp = "create"
+	FileModified FileOp = "modify"
+	FileRemoved  FileOp = "remove"
+)
+
+// defaultFileWatchInterval is how often a FileWatcher rescans by default
+const defaultFileWatchInterval = 250 * time.Millisecond
+
+// FileEvent reports a change to a regular file in a watched workspace. It is
+// delivered on the message channel of queries wrapped by FileWatcher.
+type FileEvent struct {
+	Path string `json:"path"` // Slash-separated, relative to the watched directory
+	Op   FileOp `json:"op"`
+	Size int64  `json:"size"` // Size after the change, 0 for removals
+}
+
+func (FileEvent) isMessage() {}
+
+// FileWatcher watches a workspace while queries run and reports the files
+// they create, modify and remove as FileEvent messages, so UIs can show
+// which files an agent is touching as it goes. Changes are detected by
+// rescanning file sizes and modification times; contents are not read.
+//
+// Example:
+//
+//	watcher := &FileWatcher{Skip: []string{".git", "node_modules"}}
+//	msgCh, errCh := watcher.Wrap(Query)(ctx, "Fix the failing tests", opts)
+//	for msg := range msgCh {
+//	    if ev, ok := msg.(FileEvent); ok {
+//	        fmt.Printf("%s %s\n", ev.Op, ev.Path)
+//	    }
+//	}
+type FileWatcher struct {
+	// Dir is the directory to watch (defaults to Options.Cwd, then the
+	// current directory)
+	Dir string
+	// Skip lists file or directory names to ignore, e.g. ".git"
+	Skip []string
+	// Interval is the time between scans (defaults to 250ms)
+	Interval time.Duration
+}
+
+// fileState is the recorded state of one watched file
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Wrap returns a QueryFunc that runs next and delivers FileEvent messages
+// alongside its messages. A final scan when the query ends reports its last
+// changes; with Options.InlineErrors, any ErrorMessage is still delivered
+// last.
+func (w *FileWatcher) Wrap(next QueryFunc) QueryFunc {
+	return func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+		if options == nil {
+			options = NewOptions()
+		}
+		dir := w.Dir
+		if dir == "" {
+			dir = options.Cwd
+		}
+		if dir == "" {
+			dir = "."
+		}
+		root, err := validation.ValidatePath(dir)
+		if err != nil {
+			return failedQuery(fmt.Errorf("invalid watch directory: %w", err), options)
+		}
+		state, err := scanFiles(ctx, root, w.Skip)
+		if err != nil {
+			return failedQuery(fmt.Errorf("failed to scan watch directory: %w", err), options)
+		}
+
+		inMsgCh, errCh := next(ctx, prompt, options)
+		msgCh := make(chan Message, options.GetMessageBufferSize())
+		go func() {
+			defer close(msgCh)
+
+			interval := w.Interval
+			if interval <= 0 {
+				interval = defaultFileWatchInterval
+			}
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			emit := func(msg Message) bool {
+				select {
+				case msgCh <- msg:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			}
+			poll := func() bool {
+				current, err := scanFiles(ctx, root, w.Skip)
+				if err != nil {
+					return ctx.Err() == nil // Try again on the next tick
+				}
+				for _, ev := range diffFileStates(state, current) {
+					if !emit(ev) {
+						return false
+					}
+				}
+				state = current
+				return true
+			}
+
+			var errMsg Message
+			for {
+				select {
+				case msg, ok := <-inMsgCh:
+					if !ok {
+						if poll() && errMsg != nil {
+							emit(errMsg)
+						}
+						return
+					}
+					if _, isErr := msg.(ErrorMessage); isErr {
+						errMsg = msg
+						continue
+					}
+					if !emit(msg) {
+						return
+					}
+				case <-ticker.C:
+					if !poll() {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return msgCh, errCh
+	}
+}
+
+// scanFiles records the size and modification time of every regular file
+// under root, skipping entries named in skip
+func scanFiles(ctx context.Context, root string, skip []string) (map[string]fileState, error) {
+	skipped := make(map[string]bool, len(skip))
+	for _, name := range skip {
+		skipped[name] = true
+	}
+
+	files := make(map[string]fileState)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path != root {
+				return nil // Removed while scanning
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if path != root && skipped[d.Name()] {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// diffFileStates returns the events turning before into after, ordered by
+// path
+func diffFileStates(before, after map[string]fileState) []FileEvent {
+	var events []FileEvent
+	for path, cur := range after {
+		prev, existed := before[path]
+		switch {
+		case !existed:
+			events = append(events, FileEvent{Path: path, Op: FileCreated, Size: cur.size})
+		case prev.size != cur.size || !prev.modTime.Equal(cur.modTime):
+			events = append(events, FileEvent{Path: path, Op: FileModified, Size: cur.size})
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			events = append(events, FileEvent{Path: path, Op: FileRemoved})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+	return events
+}
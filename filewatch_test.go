@@ -0,0 +1,84 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileWatcher(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"keep.txt": "same", "edit.txt": "old", "gone.txt": "x"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// agent changes the workspace, then replies and fails inline
+	agent := func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+		msgCh := make(chan Message, 2)
+		errCh := make(chan error)
+		must := func(err error) {
+			if err != nil {
+				t.Error(err)
+			}
+		}
+		must(os.WriteFile(filepath.Join(dir, "new.txt"), []byte("hello"), 0o644))
+		must(os.WriteFile(filepath.Join(dir, "edit.txt"), []byte("new content"), 0o644))
+		must(os.Remove(filepath.Join(dir, "gone.txt")))
+		must(os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref"), 0o644))
+		msgCh <- AssistantMessage{Content: []ContentBlock{TextBlock{Text: "done"}}}
+		msgCh <- ErrorMessage{Err: errors.New("boom")}
+		close(msgCh)
+		close(errCh)
+		return msgCh, errCh
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := NewOptions()
+	opts.Cwd = dir
+	opts.InlineErrors = true
+	watcher := &FileWatcher{Skip: []string{".git"}, Interval: time.Hour}
+	msgCh, _ := watcher.Wrap(agent)(ctx, "test", opts)
+
+	var events []FileEvent
+	var last Message
+	for msg := range msgCh {
+		if ev, ok := msg.(FileEvent); ok {
+			events = append(events, ev)
+		}
+		last = msg
+	}
+
+	want := []FileEvent{
+		{Path: "edit.txt", Op: FileModified, Size: 11},
+		{Path: "gone.txt", Op: FileRemoved},
+		{Path: "new.txt", Op: FileCreated, Size: 5},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+	if _, ok := last.(ErrorMessage); !ok {
+		t.Errorf("expected ErrorMessage to stay last, got %T", last)
+	}
+}
+
+func TestFileWatcherInvalidDir(t *testing.T) {
+	watcher := &FileWatcher{Dir: filepath.Join(t.TempDir(), "missing")}
+	msgCh, errCh := watcher.Wrap(Query)(context.Background(), "test", nil)
+	for range msgCh {
+		t.Error("expected no messages")
+	}
+	if err := <-errCh; err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,186 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/clock"
+)
+
+// FirstTokenEvent reports the time a query attempt took to produce its
+// first assistant content
+type FirstTokenEvent struct {
+	Attempt int           // 1-based attempt number
+	Model   string        // Options.Model of the attempt; empty for the CLI default
+	Latency time.Duration // Time from starting the attempt to its first content, or Target if it was aborted
+	Missed  bool          // Latency exceeded FirstTokenSLO.Target
+	Aborted bool          // The attempt was stopped to be retried
+}
+
+// FirstTokenSLO measures time-to-first-assistant-content and enforces a
+// latency objective on it. First content is the first AssistantMessage
+// with content or, with Options.IncludePartialMessages, the first content
+// StreamEvent. Time is measured on Options.Clock.
+//
+// When Retries is positive, an attempt that has produced no content when
+// Target elapses is aborted and the query started again, on FallbackModel
+// if set. Messages the aborted attempt delivered, such as its init
+// SystemMessage, are followed by a SystemMessage with subtype
+// SystemSubtypeRetry. The last attempt runs to completion however slow it
+// is.
+//
+// Example:
+//
+//	slo := &FirstTokenSLO{
+//	    Target:        5 * time.Second,
+//	    Retries:       1,
+//	    FallbackModel: "claude-3-5-haiku-latest",
+//	    OnFirstToken: func(ctx context.Context, ev FirstTokenEvent) {
+//	        firstTokenHistogram.Observe(ev.Latency.Seconds())
+//	    },
+//	}
+//	msgCh, errCh := slo.Wrap(Query)(ctx, prompt, opts)
+type FirstTokenSLO struct {
+	// Target is the latency objective; 0 only measures
+	Target time.Duration
+	// Retries is the number of times a query missing Target is aborted and
+	// started again; 0 never aborts
+	Retries int
+	// FallbackModel replaces Options.Model on retries; empty keeps it
+	FallbackModel string
+	// OnFirstToken is called once per attempt, when its first content
+	// arrives or when it is aborted. Panics are recovered.
+	OnFirstToken func(ctx context.Context, ev FirstTokenEvent)
+}
+
+// Wrap returns a QueryFunc that runs next under the SLO
+func (s *FirstTokenSLO) Wrap(next QueryFunc) QueryFunc {
+	return func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+		if options == nil {
+			options = NewOptions()
+		}
+		msgCh := make(chan Message, options.GetMessageBufferSize())
+		errCh := make(chan error, options.GetErrorBufferSize())
+
+		go func() {
+			defer func() {
+				close(msgCh)
+				close(errCh)
+			}()
+
+			opts := options
+			maxAttempts := s.Retries + 1
+			for attempt := 1; ; attempt++ {
+				aborted, err := s.run(ctx, next, prompt, opts, attempt, attempt < maxAttempts, msgCh)
+				if !aborted {
+					if err != nil {
+						errCh <- err
+					}
+					return
+				}
+
+				cause := fmt.Sprintf("no assistant content within first token SLO of %s", s.Target)
+				select {
+				case msgCh <- retryMessage(attempt+1, maxAttempts, 0, cause):
+				case <-ctx.Done():
+					return
+				}
+				if s.FallbackModel != "" && opts.Model != s.FallbackModel {
+					fallback := *opts
+					fallback.Model = s.FallbackModel
+					opts = &fallback
+				}
+			}
+		}()
+		return msgCh, errCh
+	}
+}
+
+// run runs one attempt, forwarding its messages to msgCh, and reports
+// whether it was aborted for missing the target along with its error
+func (s *FirstTokenSLO) run(ctx context.Context, next QueryFunc, prompt string, options *Options, attempt int, abortable bool, msgCh chan<- Message) (bool, error) {
+	clk := options.GetClock()
+	attemptCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := clk.Now()
+	inMsgCh, inErrCh := next(attemptCtx, prompt, options)
+
+	var deadline <-chan time.Time
+	if s.Target > 0 {
+		timer := clk.NewTimer(s.Target)
+		defer timer.Stop()
+		deadline = timer.C()
+	}
+
+	seen, aborted, discard := false, false, false
+	for inMsgCh != nil {
+		select {
+		case msg, ok := <-inMsgCh:
+			if !ok {
+				inMsgCh = nil
+				continue
+			}
+			if discard {
+				continue // Drain the canceled attempt
+			}
+			if !seen && isFirstContent(msg) {
+				seen = true
+				latency := clock.Since(clk, start)
+				s.report(ctx, options, FirstTokenEvent{
+					Attempt: attempt,
+					Model:   options.Model,
+					Latency: latency,
+					Missed:  s.Target > 0 && latency > s.Target,
+				})
+			}
+			select {
+			case msgCh <- msg:
+			case <-ctx.Done():
+				discard = true
+			}
+		case <-deadline:
+			deadline = nil
+			if seen || !abortable {
+				continue
+			}
+			aborted, discard = true, true
+			cancel()
+			s.report(ctx, options, FirstTokenEvent{
+				Attempt: attempt,
+				Model:   options.Model,
+				Latency: s.Target,
+				Missed:  true,
+				Aborted: true,
+			})
+		}
+	}
+
+	err := <-inErrCh
+	if aborted && ctx.Err() == nil {
+		return true, nil
+	}
+	return false, err
+}
+
+// report calls OnFirstToken, if set
+func (s *FirstTokenSLO) report(ctx context.Context, options *Options, ev FirstTokenEvent) {
+	if s.OnFirstToken == nil {
+		return
+	}
+	_ = guardCallback(ctx, options.GetClock(), "OnFirstToken", options.GetCallbackTimeout(), func(ctx context.Context) {
+		s.OnFirstToken(ctx, ev)
+	})
+}
+
+// isFirstContent reports whether msg carries assistant content
+func isFirstContent(msg Message) bool {
+	switch m := msg.(type) {
+	case AssistantMessage:
+		return len(m.Content) > 0
+	case StreamEvent:
+		return m.Event["type"] == "content_block_start" || m.Event["type"] == "content_block_delta"
+	}
+	return false
+}
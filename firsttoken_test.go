@@ -0,0 +1,105 @@
+package claudecode
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// slowFirstToken returns a QueryFunc whose attempts on slowModel report init
+// and then stall until canceled, and whose other attempts answer after
+// advancing clk by delay
+func slowFirstToken(clk *FakeClock, slowModel string, delay time.Duration, models *[]string) QueryFunc {
+	return func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+		*models = append(*models, options.Model)
+		msgCh := make(chan Message, 4)
+		errCh := make(chan error, 1)
+		go func() {
+			defer close(msgCh)
+			defer close(errCh)
+			msgCh <- SystemMessage{Subtype: "init"}
+			if options.Model == slowModel {
+				<-ctx.Done()
+				errCh <- ctx.Err()
+				return
+			}
+			clk.Advance(delay)
+			msgCh <- AssistantMessage{Content: []ContentBlock{TextBlock{Text: "hi"}}}
+			msgCh <- ResultMessage{Subtype: "success"}
+		}()
+		return msgCh, errCh
+	}
+}
+
+func TestFirstTokenSLO(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("abort and fall back", func(t *testing.T) {
+		clk := NewFakeClock(time.Now())
+		var models []string
+		var events []FirstTokenEvent
+		slo := &FirstTokenSLO{
+			Target:        5 * time.Second,
+			Retries:       1,
+			FallbackModel: "fast",
+			OnFirstToken: func(ctx context.Context, ev FirstTokenEvent) {
+				events = append(events, ev)
+			},
+		}
+		msgCh, errCh := slo.Wrap(slowFirstToken(clk, "slow", 2*time.Second, &models))(ctx, "test", &Options{Model: "slow", Clock: clk})
+
+		<-msgCh // init of the stalled attempt
+		clk.BlockUntil(1)
+		clk.Advance(5 * time.Second)
+
+		var msgs []Message
+		for msg := range msgCh {
+			msgs = append(msgs, msg)
+		}
+		if err := <-errCh; err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(models, []string{"slow", "fast"}) {
+			t.Errorf("attempted models = %v", models)
+		}
+		if retries := retryMessages(msgs); len(retries) != 1 || retries[0].Data["attempt"] != 2 {
+			t.Errorf("expected one retry message, got %+v", retries)
+		}
+		if _, ok := msgs[len(msgs)-1].(ResultMessage); !ok {
+			t.Errorf("expected the fallback's result last, got %+v", msgs)
+		}
+		want := []FirstTokenEvent{
+			{Attempt: 1, Model: "slow", Latency: 5 * time.Second, Missed: true, Aborted: true},
+			{Attempt: 2, Model: "fast", Latency: 2 * time.Second},
+		}
+		if !reflect.DeepEqual(events, want) {
+			t.Errorf("events = %+v, want %+v", events, want)
+		}
+	})
+
+	t.Run("last attempt is not aborted", func(t *testing.T) {
+		clk := NewFakeClock(time.Now())
+		var models []string
+		var events []FirstTokenEvent
+		slo := &FirstTokenSLO{
+			Target: time.Second,
+			OnFirstToken: func(ctx context.Context, ev FirstTokenEvent) {
+				events = append(events, ev)
+			},
+		}
+		msgCh, errCh := slo.Wrap(slowFirstToken(clk, "slow", 3*time.Second, &models))(ctx, "test", &Options{Clock: clk})
+		for range msgCh {
+		}
+		if err := <-errCh; err != nil {
+			t.Fatal(err)
+		}
+
+		want := []FirstTokenEvent{{Attempt: 1, Latency: 3 * time.Second, Missed: true}}
+		if len(models) != 1 || !reflect.DeepEqual(events, want) {
+			t.Errorf("expected one slow attempt, got models %v and events %+v", models, events)
+		}
+	})
+}
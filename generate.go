@@ -0,0 +1,362 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// GeneratedFile is one file in a Generator's target layout
+type GeneratedFile struct {
+	// Path is relative to Generator.Dir, using forward slashes
+	Path string `json:"path"`
+	// Instructions describe what the file should contain, beyond the spec
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// GeneratorCheck validates generated files written into dir, a copy of
+// Generator.Dir. The message of a returned error, such as compiler output,
+// is shown to Claude when the files are repaired.
+type GeneratorCheck func(ctx context.Context, dir string) error
+
+// CommandCheck returns a GeneratorCheck that runs a local command in the
+// check directory and fails with its combined output if it exits non-zero
+//
+// Example:
+//
+//	gen.Check = CommandCheck("go", "build", "./...")
+func CommandCheck(name string, args ...string) GeneratorCheck {
+	return func(ctx context.Context, dir string) error {
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s failed: %w\n%s", strings.Join(append([]string{name}, args...), " "), err, output)
+		}
+		return nil
+	}
+}
+
+// BashToolCheck returns a GeneratorCheck that has Claude run command with
+// its Bash tool in the check directory, for environments where the check
+// must run under the CLI's sandbox and permission settings rather than as a
+// local process. options (may be nil) configures the query; its Cwd and
+// tool settings are replaced.
+func BashToolCheck(command string, options *Options) GeneratorCheck {
+	return func(ctx context.Context, dir string) error {
+		opts := NewOptions()
+		if options != nil {
+			*opts = *options
+		}
+		opts.Cwd = dir
+		opts.AllowedTools = []string{ToolBash}
+		opts.DisallowedTools = AllFileEditTools()
+		opts.InlineErrors = false
+
+		prompt := fmt.Sprintf("Run this command with the Bash tool in the current directory, without modifying any files:\n\n%s\n\n"+
+			"If it exits with status 0, reply with PASS and nothing else. Otherwise reply with FAIL on the first line followed by its output.", command)
+		answer, result, err := QueryText(ctx, prompt, opts)
+		if err != nil {
+			return fmt.Errorf("failed to run check: %w", err)
+		}
+		// The verdict is the first line of the final reply; the command's
+		// output that follows a FAIL may itself contain PASS lines
+		if result.Result != nil {
+			answer = *result.Result
+		}
+		answer = strings.TrimSpace(answer)
+		if verdict, _, _ := strings.Cut(answer, "\n"); strings.TrimSpace(verdict) == "PASS" {
+			return nil
+		}
+		return fmt.Errorf("%s failed:\n%s", command, strings.TrimSpace(strings.TrimPrefix(answer, "FAIL")))
+	}
+}
+
+// Generator generates a set of files from a spec, one query per file, and
+// returns them as a ChangeSet for review instead of writing them. Files are
+// generated in order, and each prompt includes the spec, the target layout,
+// the Context files and the files generated before it, so later files build
+// on earlier ones. When a Check is set, the files are validated in a copy of
+// Dir and the files named in a failure (or all of them) are regenerated with
+// its output, up to MaxRepairs times.
+//
+// Example:
+//
+//	report, err := (&Generator{
+//	    Spec: "A REST handler for /todos backed by an in-memory store",
+//	    Files: []GeneratedFile{
+//	        {Path: "store.go", Instructions: "Thread-safe Todo store"},
+//	        {Path: "handler.go", Instructions: "net/http handler using the store"},
+//	        {Path: "handler_test.go"},
+//	    },
+//	    Dir:        "./todos",
+//	    Context:    []string{"go.mod"},
+//	    Check:      CommandCheck("go", "test", "./..."),
+//	    MaxRepairs: 2,
+//	}).Run(ctx)
+//	if err == nil {
+//	    err = ApplyChangeSet(ctx, report.Changes)
+//	}
+type Generator struct {
+	// Spec describes what is being built; it is shared by every file's prompt
+	Spec string
+	// Files is the target layout, generated in order
+	Files []GeneratedFile
+	// Dir is the project the files belong to (defaults to the current
+	// directory). It is not modified.
+	Dir string
+	// Context lists existing files, relative to Dir, included in every prompt
+	Context []string
+	// Options configures the queries (uses NewOptions() if nil). Queries run
+	// in Dir with ReadOnly set, so Claude replies with file contents instead
+	// of editing files.
+	Options *Options
+	// Query runs each query (defaults to Query)
+	Query QueryFunc
+	// Check, when set, validates the generated files
+	Check GeneratorCheck
+	// MaxRepairs is the number of times files are regenerated after a
+	// failed Check; 0 reports the first failure
+	MaxRepairs int
+	// Skip lists file or directory names not copied into the check
+	// directory, e.g. ".git" or "node_modules"
+	Skip []string
+}
+
+// GenerationReport is the outcome of a Generator run
+type GenerationReport struct {
+	// Changes holds a patch per generated file that differs from Dir
+	Changes *ChangeSet `json:"changes"`
+	// Files maps each generated path to its content
+	Files map[string]string `json:"files"`
+	// CostUSD totals the cost of every query
+	CostUSD float64 `json:"cost_usd"`
+	// Repairs is the number of repair rounds run after failed checks
+	Repairs int `json:"repairs"`
+}
+
+// Run generates every file. If a query fails or the files still fail the
+// check after MaxRepairs rounds, the report so far is returned with the
+// error.
+func (g *Generator) Run(ctx context.Context) (*GenerationReport, error) {
+	if len(g.Files) == 0 {
+		return nil, fmt.Errorf("generator has no files")
+	}
+	seen := make(map[string]bool, len(g.Files))
+	for _, file := range g.Files {
+		if !filepath.IsLocal(filepath.FromSlash(file.Path)) || seen[file.Path] {
+			return nil, fmt.Errorf("generated file paths must be unique and relative to the directory, got %q", file.Path)
+		}
+		seen[file.Path] = true
+	}
+
+	dir := g.Dir
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return nil, fmt.Errorf("failed to determine working directory: %w", err)
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid generator directory: %w", err)
+	}
+
+	contextFiles := make(map[string]string, len(g.Context))
+	for _, name := range g.Context {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read context file: %w", err)
+		}
+		contextFiles[name] = string(data)
+	}
+	original := make(map[string][]byte, len(g.Files))
+	for _, file := range g.Files {
+		if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file.Path))); err == nil {
+			original[file.Path] = data
+		}
+	}
+
+	report := &GenerationReport{Files: make(map[string]string, len(g.Files))}
+	pending := g.Files
+	feedback := ""
+	for {
+		for _, file := range pending {
+			content, err := g.generate(ctx, dir, file, contextFiles, report, feedback)
+			if err != nil {
+				report.Changes = g.changes(dir, original, report.Files)
+				return report, fmt.Errorf("failed to generate %s: %w", file.Path, err)
+			}
+			report.Files[file.Path] = content
+		}
+		report.Changes = g.changes(dir, original, report.Files)
+
+		if g.Check == nil {
+			return report, nil
+		}
+		checkErr := g.check(ctx, dir, report.Files)
+		if checkErr == nil {
+			return report, nil
+		}
+		if report.Repairs >= g.MaxRepairs || ctx.Err() != nil {
+			return report, fmt.Errorf("generated files failed check: %w", checkErr)
+		}
+		report.Repairs++
+		feedback = checkErr.Error()
+		pending = g.failedFiles(feedback)
+	}
+}
+
+// generate runs the query producing one file and returns its content
+func (g *Generator) generate(ctx context.Context, dir string, file GeneratedFile, contextFiles map[string]string, report *GenerationReport, feedback string) (string, error) {
+	opts := NewOptions()
+	if g.Options != nil {
+		*opts = *g.Options
+	}
+	opts.Cwd = dir
+	opts.ReadOnly = true
+	opts.PermissionMode = nil
+	opts.InlineErrors = false
+
+	query := g.Query
+	if query == nil {
+		query = Query
+	}
+	msgCh, errCh := query(ctx, g.prompt(file, contextFiles, report.Files, feedback), opts)
+
+	var text []string
+	var answer string
+	for msg := range msgCh {
+		switch m := msg.(type) {
+		case AssistantMessage:
+			for _, block := range m.Content {
+				if tb, ok := block.(TextBlock); ok {
+					text = append(text, tb.Text)
+				}
+			}
+		case ResultMessage:
+			report.CostUSD += SafeFloat64Ptr(m.TotalCostUSD)
+			answer = SafeStringPtr(m.Result)
+			if err := m.Err(); err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := <-errCh; err != nil {
+		return "", err
+	}
+	if answer == "" {
+		answer = strings.Join(text, "\n")
+	}
+
+	content := answer
+	if blocks := ExtractCodeBlocks(answer); len(blocks) > 0 {
+		content = blocks[0].Code
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", fmt.Errorf("reply contained no file content")
+	}
+	return content + "\n", nil
+}
+
+// prompt builds the prompt generating file
+func (g *Generator) prompt(file GeneratedFile, contextFiles, generated map[string]string, feedback string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are generating the files of a project. Reply with the complete contents of %s in a single fenced code block and nothing else. Do not edit any files.\n\n", file.Path)
+	fmt.Fprintf(&b, "Specification:\n%s\n\nFiles being generated:\n", g.Spec)
+	for _, f := range g.Files {
+		fmt.Fprintf(&b, "- %s", f.Path)
+		if f.Instructions != "" {
+			fmt.Fprintf(&b, ": %s", f.Instructions)
+		}
+		b.WriteString("\n")
+	}
+
+	writeFiles := func(title string, names []string, contents map[string]string) {
+		if len(names) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s:\n", title)
+		for _, name := range names {
+			fmt.Fprintf(&b, "\n--- %s ---\n%s\n", name, strings.TrimRight(contents[name], "\n"))
+		}
+	}
+	writeFiles("Existing files for context", g.Context, contextFiles)
+	var done []string
+	for _, f := range g.Files {
+		if _, ok := generated[f.Path]; ok && f.Path != file.Path {
+			done = append(done, f.Path)
+		}
+	}
+	writeFiles("Files generated so far", done, generated)
+
+	if previous, ok := generated[file.Path]; ok && feedback != "" {
+		fmt.Fprintf(&b, "\nYour previous version of %s:\n\n%s\n\nThe generated files failed validation:\n\n%s\n\nFix the problem.\n", file.Path, previous, feedback)
+	}
+	fmt.Fprintf(&b, "\nNow write %s.", file.Path)
+	if file.Instructions != "" {
+		fmt.Fprintf(&b, " %s", file.Instructions)
+	}
+	return b.String()
+}
+
+// check writes files into a copy of dir and runs the Check there
+func (g *Generator) check(ctx context.Context, dir string, files map[string]string) error {
+	ws, err := (&CopyWorkspaceProvider{Source: dir, Skip: g.Skip}).Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	for name, content := range files {
+		target := filepath.Join(ws.Dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return g.Check(ctx, ws.Dir)
+}
+
+// failedFiles returns the files named in a check failure, or every file if
+// it names none
+func (g *Generator) failedFiles(output string) []GeneratedFile {
+	var failed []GeneratedFile
+	for _, file := range g.Files {
+		if strings.Contains(output, file.Path) || strings.Contains(output, path.Base(file.Path)) {
+			failed = append(failed, file)
+		}
+	}
+	if len(failed) == 0 {
+		return g.Files
+	}
+	return failed
+}
+
+// changes renders the generated files as a ChangeSet relative to dir, in
+// layout order
+func (g *Generator) changes(dir string, original map[string][]byte, files map[string]string) *ChangeSet {
+	cs := &ChangeSet{Dir: dir}
+	for _, file := range g.Files {
+		content, ok := files[file.Path]
+		if !ok {
+			continue
+		}
+		old, cur := original[file.Path], []byte(content)
+		op := ChangeModified
+		if old == nil {
+			op = ChangeAdded
+		} else if string(old) == content {
+			continue
+		}
+		cs.Changes = append(cs.Changes, FileChange{Path: file.Path, Op: op, Patch: unifiedDiff(file.Path, op, old, cur)})
+	}
+	return cs
+}
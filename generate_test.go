@@ -0,0 +1,128 @@
+package claudecode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGenerator(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "spec.md"), []byte("letters only\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	responder := NewScriptedResponder(1).
+		On(`Now write a\.txt`, TextResponse("Here it is:\n```text\nalpha\n```")...).
+		On(`(?s)failed validation.*Now write b\.txt`, TextResponse("```\nfixed\n```")...).
+		On(`Now write b\.txt`, TextResponse("```\nbroken\n```")...)
+	var mu sync.Mutex
+	var prompts []string
+	query := func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+		mu.Lock()
+		prompts = append(prompts, prompt)
+		mu.Unlock()
+		if !options.ReadOnly || options.Cwd != dir {
+			t.Errorf("expected a read-only query in %s, got ReadOnly=%v Cwd=%s", dir, options.ReadOnly, options.Cwd)
+		}
+		return responder.Query(ctx, prompt, options)
+	}
+
+	gen := &Generator{
+		Spec:       "Two letter files",
+		Files:      []GeneratedFile{{Path: "a.txt", Instructions: "The first letter"}, {Path: "b.txt"}},
+		Dir:        dir,
+		Context:    []string{"spec.md"},
+		Query:      query,
+		Check:      CommandCheck("sh", "-c", "grep -q fixed b.txt || { echo 'b.txt: not fixed'; exit 1; }"),
+		MaxRepairs: 1,
+	}
+	report, err := gen.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Files["a.txt"] != "alpha\n" || report.Files["b.txt"] != "fixed\n" {
+		t.Errorf("unexpected files %q", report.Files)
+	}
+	if report.Repairs != 1 {
+		t.Errorf("expected 1 repair round, got %d", report.Repairs)
+	}
+	// a.txt, b.txt, then b.txt again as the only file named by the check
+	if len(prompts) != 3 {
+		t.Fatalf("expected 3 queries, got %d", len(prompts))
+	}
+	if !strings.Contains(prompts[1], "--- a.txt ---\nalpha") || !strings.Contains(prompts[1], "letters only") {
+		t.Errorf("expected the second prompt to include the context and earlier files, got:\n%s", prompts[1])
+	}
+	if !strings.Contains(prompts[2], "b.txt: not fixed") {
+		t.Errorf("expected the repair prompt to include the check output, got:\n%s", prompts[2])
+	}
+
+	if len(report.Changes.Changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", report.Changes.Changes)
+	}
+	if c := report.Changes.Changes[0]; c.Path != "a.txt" || c.Op != ChangeAdded {
+		t.Errorf("unexpected change %+v", c)
+	}
+	if c := report.Changes.Changes[1]; c.Path != "b.txt" || c.Op != ChangeModified || !strings.Contains(c.Patch, "+fixed") {
+		t.Errorf("unexpected change %+v", c)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "b.txt")); string(data) != "old\n" {
+		t.Errorf("generator modified its directory: %q", data)
+	}
+
+	t.Run("check still failing", func(t *testing.T) {
+		gen.MaxRepairs = 0
+		report, err := gen.Run(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "not fixed") {
+			t.Fatalf("expected check failure, got %v", err)
+		}
+		if report == nil || report.Files["b.txt"] != "broken\n" {
+			t.Errorf("expected the report so far with the error, got %+v", report)
+		}
+	})
+
+	t.Run("invalid layout", func(t *testing.T) {
+		for _, files := range [][]GeneratedFile{nil, {{Path: "../x"}}, {{Path: "a"}, {Path: "a"}}} {
+			if _, err := (&Generator{Files: files, Dir: dir, Query: query}).Run(context.Background()); err == nil {
+				t.Errorf("expected %v to be rejected", files)
+			}
+		}
+	})
+}
+
+func TestBashToolCheck(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+case "$*" in
+*"--allowedTools Bash "*) ;;
+*) echo "error: Bash not allowed: $*" >&2; exit 1 ;;
+esac
+case "$*" in
+*"go test ./..."*) reply='FAIL\\n--- FAIL: TestA\\nFAIL\\tpkg/a\\nok  \\tpkg/b\\nPASS' ;;
+*) reply='PASS' ;;
+esac
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Running the command."}]}}'
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"'"$reply"'"}]}}'
+echo '{"type":"result","subtype":"success","result":"'"$reply"'"}'
+`)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := BashToolCheck("go build ./...", nil)(ctx, t.TempDir()); err != nil {
+		t.Errorf("expected the check to pass, got %v", err)
+	}
+
+	// The failing output ends with a PASS line of another package
+	err := BashToolCheck("go test ./...", nil)(ctx, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "--- FAIL: TestA") {
+		t.Errorf("expected the check to fail with the output, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package claudecode
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/validation"
+)
+
+// GitWorktreeProvider provisions each workspace as a git worktree of Repo on
+// a new branch, supporting the "N agents each on their own branch" pattern.
+// The worktree is removed when the workspace is closed; the branch is deleted
+// too unless KeepBranch is set.
+type GitWorktreeProvider struct {
+	// Repo is the path of the git repository
+	Repo string
+	// Ref is the commit-ish new branches start from (defaults to HEAD)
+	Ref string
+	// BranchPrefix prefixes generated branch names (defaults to "claude/")
+	BranchPrefix string
+	// TempDir is where worktrees are created (defaults to os.TempDir())
+	TempDir string
+	// KeepBranch keeps the branch, and any commits on it, after Close
+	KeepBranch bool
+}
+
+// Acquire creates a worktree on a fresh branch. The returned workspace's Base
+// is the commit the branch started from, for use with GitChanges.
+func (p *GitWorktreeProvider) Acquire(ctx context.Context) (*Workspace, error) {
+	repo, err := validation.ValidatePath(p.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository path: %w", err)
+	}
+	ref := p.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	prefix := p.BranchPrefix
+	if prefix == "" {
+		prefix = "claude/"
+	}
+
+	base, err := runGit(ctx, repo, "rev-parse", "--verify", ref+"^{commit}")
+	if err != nil {
+		return nil, err
+	}
+	base = strings.TrimSpace(base)
+
+	parent, err := os.MkdirTemp(p.TempDir, "claude-worktree-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+	dir := filepath.Join(parent, "worktree")
+	branch := prefix + strings.TrimPrefix(filepath.Base(parent), "claude-worktree-")
+
+	if _, err := runGit(ctx, repo, "worktree", "add", "-b", branch, dir, base); err != nil {
+		os.RemoveAll(parent)
+		return nil, err
+	}
+
+	ws := NewWorkspace(dir, func() error {
+		// Use a fresh context: cleanup must run even after the query's ends
+		_, err := runGit(context.Background(), repo, "worktree", "remove", "--force", dir)
+		if err == nil && !p.KeepBranch {
+			_, err = runGit(context.Background(), repo, "branch", "-D", branch)
+		}
+		if rmErr := os.RemoveAll(parent); err == nil {
+			err = rmErr
+		}
+		return err
+	})
+	ws.Base = base
+	ws.Branch = branch
+	return ws, nil
+}
+
+// GitChanges returns the changes in the git working tree at dir relative to
+// base, including commits made since base and untracked files. base defaults
+// to HEAD. The index of dir is left untouched.
+func GitChanges(ctx context.Context, dir, base string) (*ChangeSet, error) {
+	if base == "" {
+		base = "HEAD"
+	}
+
+	// Stage everything into a throwaway index so untracked files show up
+	// without disturbing the real one
+	index, err := os.CreateTemp("", "claude-index-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	index.Close()
+	defer os.Remove(index.Name())
+
+	env := []string{"GIT_INDEX_FILE=" + index.Name()}
+	if _, err := runGitEnv(ctx, dir, env, "read-tree", base); err != nil {
+		return nil, err
+	}
+	if _, err := runGitEnv(ctx, dir, env, "add", "-A"); err != nil {
+		return nil, err
+	}
+	status, err := runGitEnv(ctx, dir, env, "diff", "--cached", "--no-renames", "--name-status", "-z", base)
+	if err != nil {
+		return nil, err
+	}
+
+	// Paths are reported relative to the top of the working tree
+	root, err := runGit(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	cs := &ChangeSet{Dir: strings.TrimSpace(root)}
+	fields := strings.Split(strings.TrimSuffix(status, "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		var op ChangeOp
+		switch fields[i] {
+		case "A":
+			op = ChangeAdded
+		case "D":
+			op = ChangeDeleted
+		default:
+			op = ChangeModified
+		}
+		path := fields[i+1]
+		patch, err := runGitEnv(ctx, dir, env, "diff", "--cached", "--no-renames", "--no-color", base, "--", path)
+		if err != nil {
+			return nil, err
+		}
+		cs.Changes = append(cs.Changes, FileChange{Path: path, Op: op, Patch: patch})
+	}
+	return cs, nil
+}
+
+// runGit runs git in dir and returns its standard output
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	return runGitEnv(ctx, dir, nil, args...)
+}
+
+// runGitEnv runs git in dir with extra environment variables
+func runGitEnv(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,162 @@
+package claudecode
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initGitRepo creates a git repository with one commit containing files
+func initGitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	repo := t.TempDir()
+	writeTree(t, repo, files)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"commit", "-q", "-m", "initial"},
+	} {
+		if _, err := runGit(context.Background(), repo, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func TestGitWorktreeProvider(t *testing.T) {
+	repo := initGitRepo(t, map[string]string{"main.go": "package main\n"})
+	provider := &GitWorktreeProvider{Repo: repo, TempDir: t.TempDir()}
+
+	ws1, err := provider.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws2, err := provider.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ws1.Dir == ws2.Dir || ws1.Branch == ws2.Branch {
+		t.Fatalf("expected distinct worktrees, got %+v and %+v", ws1, ws2)
+	}
+	if !strings.HasPrefix(ws1.Branch, "claude/") {
+		t.Errorf("expected default branch prefix, got %q", ws1.Branch)
+	}
+	if ws1.Base == "" {
+		t.Error("expected base commit to be set")
+	}
+	if _, err := os.Stat(filepath.Join(ws1.Dir, "main.go")); err != nil {
+		t.Errorf("expected checked out file: %v", err)
+	}
+
+	if err := ws1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(ws1.Dir); !os.IsNotExist(err) {
+		t.Errorf("expected worktree to be removed, got %v", err)
+	}
+	branches, err := runGit(context.Background(), repo, "branch", "--list", ws1.Branch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(branches) != "" {
+		t.Errorf("expected branch %s to be deleted", ws1.Branch)
+	}
+
+	ws2.Close()
+}
+
+func TestGitWorktreeProviderKeepBranch(t *testing.T) {
+	repo := initGitRepo(t, map[string]string{"main.go": "package main\n"})
+	provider := &GitWorktreeProvider{Repo: repo, TempDir: t.TempDir(), BranchPrefix: "agent-", KeepBranch: true}
+
+	ws, err := provider.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(context.Background(), repo, "rev-parse", "--verify", ws.Branch); err != nil {
+		t.Errorf("expected branch to be kept: %v", err)
+	}
+}
+
+func TestGitWorktreeProviderInvalidRepo(t *testing.T) {
+	provider := &GitWorktreeProvider{Repo: t.TempDir()}
+	if _, err := provider.Acquire(context.Background()); err == nil {
+		t.Fatal("expected error for non-repository directory")
+	}
+}
+
+func TestGitChanges(t *testing.T) {
+	repo := initGitRepo(t, map[string]string{
+		"keep.txt":   "unchanged\n",
+		"edit.txt":   "before\n",
+		"remove.txt": "gone\n",
+	})
+
+	writeTree(t, repo, map[string]string{
+		"edit.txt":    "after\n",
+		"dir/new.txt": "fresh\n",
+	})
+	if err := os.Remove(filepath.Join(repo, "remove.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	cs, err := GitChanges(context.Background(), repo, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]ChangeOp{
+		"dir/new.txt": ChangeAdded,
+		"edit.txt":    ChangeModified,
+		"remove.txt":  ChangeDeleted,
+	}
+	if len(cs.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), cs.Changes)
+	}
+	for _, change := range cs.Changes {
+		if want[change.Path] != change.Op {
+			t.Errorf("%s: expected %q, got %q", change.Path, want[change.Path], change.Op)
+		}
+		if !strings.Contains(change.Patch, change.Path) {
+			t.Errorf("%s: patch does not mention file:\n%s", change.Path, change.Patch)
+		}
+	}
+	if !strings.Contains(cs.Patch(), "+after") {
+		t.Errorf("expected combined patch to contain edit, got:\n%s", cs.Patch())
+	}
+
+	// The real index must be left alone
+	status, err := runGit(context.Background(), repo, "status", "--porcelain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(status, "?? dir/") {
+		t.Errorf("expected new file to remain untracked, got:\n%s", status)
+	}
+}
+
+func TestGitChangesEmpty(t *testing.T) {
+	repo := initGitRepo(t, map[string]string{"a.txt": "a\n"})
+	cs, err := GitChanges(context.Background(), repo, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cs.IsEmpty() {
+		t.Errorf("expected no changes, got %+v", cs.Changes)
+	}
+}
@@ -0,0 +1,216 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// HookEvent names a point in the CLI's agent loop where hooks run
+type HookEvent string
+
+const (
+	HookPreToolUse       HookEvent = "PreToolUse"
+	HookPostToolUse      HookEvent = "PostToolUse"
+	HookUserPromptSubmit HookEvent = "UserPromptSubmit"
+	HookStop             HookEvent = "Stop"
+	HookSubagentStop     HookEvent = "SubagentStop"
+	HookPreCompact       HookEvent = "PreCompact"
+)
+
+// HookInput is what the CLI reports to a hook callback. Fields that do not
+// apply to the event are empty; Raw holds the input as sent.
+type HookInput struct {
+	HookEventName  HookEvent
+	SessionID      string
+	TranscriptPath string
+	Cwd            string
+	ToolName       string                 // PreToolUse and PostToolUse
+	ToolInput      map[string]interface{} // PreToolUse and PostToolUse
+	ToolResponse   interface{}            // PostToolUse
+	Prompt         string                 // UserPromptSubmit
+	Raw            map[string]interface{}
+}
+
+// HookOutput is a hook callback's answer. The zero value lets the CLI carry
+// on unchanged.
+type HookOutput struct {
+	// Continue set to false stops the run, reporting StopReason
+	Continue   *bool
+	StopReason string
+	// SuppressOutput hides the hook's output from the transcript
+	SuppressOutput bool
+	// Decision "block" with Reason blocks the action the hook ran for, e.g.
+	// a prompt on UserPromptSubmit or stopping on Stop
+	Decision string
+	Reason   string
+	// SystemMessage is shown to the user
+	SystemMessage string
+	// HookSpecificOutput carries event-specific fields, such as
+	// permissionDecision for PreToolUse
+	HookSpecificOutput map[string]interface{}
+}
+
+// DenyToolUse returns a PreToolUse output that rejects the tool call, telling
+// Claude why
+func DenyToolUse(reason string) HookOutput {
+	return HookOutput{HookSpecificOutput: map[string]interface{}{
+		"hookEventName":            string(HookPreToolUse),
+		"permissionDecision":       "deny",
+		"permissionDecisionReason": reason,
+	}}
+}
+
+// HookCallback runs for a hook event. toolUseID is set for tool events. An
+// error is reported to the CLI as a failed hook.
+type HookCallback func(ctx context.Context, input HookInput, toolUseID string) (HookOutput, error)
+
+// HookMatcher selects the hooks run for an event. Matcher is a tool name
+// pattern for tool events, e.g. "Write|Edit"; empty matches everything.
+type HookMatcher struct {
+	Matcher string
+	Hooks   []HookCallback
+	Timeout int // Seconds the CLI waits for the hooks; 0 keeps its default
+}
+
+// hookRegistration is a hook callback with the ID announced to the CLI
+type hookRegistration struct {
+	id       string
+	callback HookCallback
+}
+
+// activeHooks returns Hooks plus, in read-only mode, the PreToolUse hook
+// denying tools that mode does not permit before they run
+func (o *Options) activeHooks() map[HookEvent][]HookMatcher {
+	if !o.ReadOnly {
+		return o.Hooks
+	}
+	hooks := make(map[HookEvent][]HookMatcher, len(o.Hooks)+1)
+	for event, matchers := range o.Hooks {
+		hooks[event] = matchers
+	}
+	hooks[HookPreToolUse] = append([]HookMatcher{{Hooks: []HookCallback{denyReadOnlyViolation}}}, o.Hooks[HookPreToolUse]...)
+	return hooks
+}
+
+// hookCallbacks assigns each hook an ID, walking events in name order so
+// that repeated calls agree
+func (o *Options) hookCallbacks() (map[HookEvent][][]hookRegistration, map[string]HookCallback) {
+	hooks := o.activeHooks()
+	events := make([]string, 0, len(hooks))
+	for event := range hooks {
+		events = append(events, string(event))
+	}
+	sort.Strings(events)
+
+	byEvent := make(map[HookEvent][][]hookRegistration, len(events))
+	byID := make(map[string]HookCallback)
+	for _, name := range events {
+		event := HookEvent(name)
+		for _, matcher := range hooks[event] {
+			regs := make([]hookRegistration, 0, len(matcher.Hooks))
+			for _, hook := range matcher.Hooks {
+				id := fmt.Sprintf("hook_%d", len(byID))
+				byID[id] = hook
+				regs = append(regs, hookRegistration{id: id, callback: hook})
+			}
+			byEvent[event] = append(byEvent[event], regs)
+		}
+	}
+	return byEvent, byID
+}
+
+// GetInitializeRequest returns the request registering Hooks with the CLI,
+// or nil when there are none
+func (o *Options) GetInitializeRequest() map[string]interface{} {
+	if o == nil || (len(o.Hooks) == 0 && !o.ReadOnly) {
+		return nil
+	}
+
+	activeHooks := o.activeHooks()
+	byEvent, _ := o.hookCallbacks()
+	hooks := make(map[string]interface{}, len(byEvent))
+	for event, matchers := range byEvent {
+		configs := make([]interface{}, 0, len(matchers))
+		for i, regs := range matchers {
+			ids := make([]string, 0, len(regs))
+			for _, reg := range regs {
+				ids = append(ids, reg.id)
+			}
+			config := map[string]interface{}{"hookCallbackIds": ids}
+			matcher := activeHooks[event][i]
+			if matcher.Matcher != "" {
+				config["matcher"] = matcher.Matcher
+			}
+			if matcher.Timeout > 0 {
+				config["timeout"] = matcher.Timeout
+			}
+			configs = append(configs, config)
+		}
+		hooks[string(event)] = configs
+	}
+	return map[string]interface{}{"subtype": "initialize", "hooks": hooks}
+}
+
+// handleHookCallback runs the hook named by a hook_callback request
+func (o *Options) handleHookCallback(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+	id := getString(request, "callback_id")
+	_, byID := o.hookCallbacks()
+	hook, ok := byID[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown hook callback: %s", id)
+	}
+
+	raw := getMap(request, "input")
+	input := HookInput{
+		HookEventName:  HookEvent(getString(raw, "hook_event_name")),
+		SessionID:      getString(raw, "session_id"),
+		TranscriptPath: getString(raw, "transcript_path"),
+		Cwd:            getString(raw, "cwd"),
+		ToolName:       getString(raw, "tool_name"),
+		ToolInput:      getMap(raw, "tool_input"),
+		ToolResponse:   raw["tool_response"],
+		Prompt:         getString(raw, "prompt"),
+		Raw:            raw,
+	}
+
+	var output HookOutput
+	var hookErr error
+	err := guardCallback(ctx, o.GetClock(), "hook", o.GetCallbackTimeout(), func(ctx context.Context) {
+		output, hookErr = hook(ctx, input, getString(request, "tool_use_id"))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if hookErr != nil {
+		return nil, hookErr
+	}
+	return output.toJSON(), nil
+}
+
+// toJSON returns the output in the CLI's hook output format
+func (h HookOutput) toJSON() map[string]interface{} {
+	out := make(map[string]interface{})
+	if h.Continue != nil {
+		out["continue"] = *h.Continue
+	}
+	if h.StopReason != "" {
+		out["stopReason"] = h.StopReason
+	}
+	if h.SuppressOutput {
+		out["suppressOutput"] = true
+	}
+	if h.Decision != "" {
+		out["decision"] = h.Decision
+	}
+	if h.Reason != "" {
+		out["reason"] = h.Reason
+	}
+	if h.SystemMessage != "" {
+		out["systemMessage"] = h.SystemMessage
+	}
+	if h.HookSpecificOutput != nil {
+		out["hookSpecificOutput"] = h.HookSpecificOutput
+	}
+	return out
+}
@@ -0,0 +1,116 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryHooks(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+read -r init
+case "$init" in
+*'"PreToolUse":[{"hookCallbackIds":["hook_0"],"matcher":"Write|Edit"}]'*'"subtype":"initialize"'*) ;;
+*) echo "error: unexpected initialize $init" >&2; exit 1 ;;
+esac
+read -r prompt
+echo '{"type":"control_request","request_id":"cli_1","request":{"subtype":"hook_callback","callback_id":"hook_0","tool_use_id":"t1","input":{"hook_event_name":"PreToolUse","session_id":"s1","tool_name":"Write","tool_input":{"file_path":"/etc/passwd"}}}}'
+read -r answer
+case "$answer" in
+*'"request_id":"cli_1"'*'"permissionDecision":"deny"'*) write=blocked ;;
+*) write=allowed ;;
+esac
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"write '"$write"'"}]}}'
+echo '{"type":"result","subtype":"success","session_id":"s1"}'
+while read -r line; do :; done
+`)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var seen HookInput
+	var seenID string
+	opts := NewOptions()
+	opts.Hooks = map[HookEvent][]HookMatcher{
+		HookPreToolUse: {{
+			Matcher: "Write|Edit",
+			Hooks: []HookCallback{func(ctx context.Context, input HookInput, toolUseID string) (HookOutput, error) {
+				seen, seenID = input, toolUseID
+				if path, _ := input.ToolInput["file_path"].(string); strings.HasPrefix(path, "/etc/") {
+					return DenyToolUse("writes to /etc are not allowed"), nil
+				}
+				return HookOutput{}, nil
+			}},
+		}},
+	}
+
+	text, _, err := QueryText(ctx, "test", opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "write blocked" {
+		t.Errorf("Unexpected reply %q", text)
+	}
+	if seen.HookEventName != HookPreToolUse || seen.ToolName != ToolWrite || seen.SessionID != "s1" || seenID != "t1" {
+		t.Errorf("Unexpected hook input %+v (tool use %q)", seen, seenID)
+	}
+}
+
+func TestHookRegistration(t *testing.T) {
+	noop := func(context.Context, HookInput, string) (HookOutput, error) { return HookOutput{}, nil }
+	failing := func(context.Context, HookInput, string) (HookOutput, error) {
+		return HookOutput{}, errors.New("hook failed")
+	}
+	opts := &Options{Hooks: map[HookEvent][]HookMatcher{
+		HookStop:       {{Hooks: []HookCallback{failing}}},
+		HookPreToolUse: {{Matcher: "Bash", Hooks: []HookCallback{noop, noop}, Timeout: 5}},
+	}}
+
+	want := map[string]interface{}{
+		"subtype": "initialize",
+		"hooks": map[string]interface{}{
+			"PreToolUse": []interface{}{map[string]interface{}{
+				"matcher":         "Bash",
+				"hookCallbackIds": []string{"hook_0", "hook_1"},
+				"timeout":         5,
+			}},
+			"Stop": []interface{}{map[string]interface{}{
+				"hookCallbackIds": []string{"hook_2"},
+			}},
+		},
+	}
+	if got := opts.GetInitializeRequest(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetInitializeRequest() = %v, want %v", got, want)
+	}
+	if (&Options{}).GetInitializeRequest() != nil {
+		t.Error("Expected no initialize request without hooks")
+	}
+
+	handler := opts.GetControlHandler()
+	got, err := handler(context.Background(), map[string]interface{}{"subtype": "hook_callback", "callback_id": "hook_0"})
+	if err != nil || len(got) != 0 {
+		t.Errorf("Expected empty output from no-op hook, got %v, %v", got, err)
+	}
+	if _, err := handler(context.Background(), map[string]interface{}{"subtype": "hook_callback", "callback_id": "hook_2"}); err == nil || err.Error() != "hook failed" {
+		t.Errorf("Expected hook error, got %v", err)
+	}
+	if _, err := handler(context.Background(), map[string]interface{}{"subtype": "hook_callback", "callback_id": "hook_9"}); err == nil {
+		t.Error("Expected error for unknown callback")
+	}
+	if _, err := handler(context.Background(), map[string]interface{}{"subtype": "can_use_tool", "tool_name": ToolBash}); err == nil {
+		t.Error("Expected error for permission request without CanUseTool")
+	}
+}
+
+func TestHookOutputJSON(t *testing.T) {
+	stop := false
+	got := HookOutput{Continue: &stop, StopReason: "done", Decision: "block", Reason: "r", SystemMessage: "m", SuppressOutput: true}.toJSON()
+	want := map[string]interface{}{
+		"continue": false, "stopReason": "done", "decision": "block", "reason": "r", "systemMessage": "m", "suppressOutput": true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toJSON() = %v, want %v", got, want)
+	}
+}
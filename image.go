@@ -0,0 +1,93 @@
+package claudecode
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Image media types accepted in prompts
+const (
+	ImageJPEG = "image/jpeg"
+	ImagePNG  = "image/png"
+	ImageGIF  = "image/gif"
+	ImageWebP = "image/webp"
+)
+
+// ImageFromBytes returns an ImageBlock holding data, detecting its media type
+func ImageFromBytes(data []byte) ImageBlock {
+	return ImageBlock{
+		MediaType: http.DetectContentType(data),
+		Data:      base64.StdEncoding.EncodeToString(data),
+	}
+}
+
+// ImageFromFile reads path into an ImageBlock
+func ImageFromFile(path string) (ImageBlock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ImageBlock{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	return ImageFromBytes(data), nil
+}
+
+// encodeUserContent converts prompt blocks to stream-json content objects
+func encodeUserContent(blocks []ContentBlock) ([]map[string]interface{}, error) {
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("message has no content")
+	}
+	content := make([]map[string]interface{}, 0, len(blocks))
+	for i, block := range blocks {
+		switch b := block.(type) {
+		case TextBlock:
+			content = append(content, map[string]interface{}{"type": "text", "text": b.Text})
+		case ImageBlock:
+			source, err := imageSource(b)
+			if err != nil {
+				return nil, fmt.Errorf("content block %d: %w", i, err)
+			}
+			content = append(content, map[string]interface{}{"type": "image", "source": source})
+		default:
+			return nil, fmt.Errorf("content block %d: %T cannot be sent in a user message", i, block)
+		}
+	}
+	return content, nil
+}
+
+// imageSource returns the base64 source object of b, reading Path if set
+func imageSource(b ImageBlock) (map[string]interface{}, error) {
+	if b.Path != "" {
+		if b.Data != "" {
+			return nil, fmt.Errorf("image has both Path and Data")
+		}
+		loaded, err := ImageFromFile(b.Path)
+		if err != nil {
+			return nil, err
+		}
+		if b.MediaType != "" {
+			loaded.MediaType = b.MediaType
+		}
+		b = loaded
+	}
+	if b.Data == "" {
+		return nil, fmt.Errorf("image has no Path or Data")
+	}
+	if b.MediaType == "" {
+		raw, err := base64.StdEncoding.DecodeString(b.Data)
+		if err != nil {
+			return nil, fmt.Errorf("image data is not valid base64: %w", err)
+		}
+		b.MediaType = http.DetectContentType(raw)
+	}
+	switch b.MediaType {
+	case ImageJPEG, ImagePNG, ImageGIF, ImageWebP:
+	default:
+		return nil, fmt.Errorf("unsupported image media type %q", b.MediaType)
+	}
+	return map[string]interface{}{
+		"type":       "base64",
+		"media_type": b.MediaType,
+		"data":       b.Data,
+	}, nil
+}
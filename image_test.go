@@ -0,0 +1,96 @@
+package claudecode
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// imageCLI answers each user message carrying an image with that image
+const imageCLI = `#!/bin/sh
+while IFS= read -r line; do
+	case "$line" in
+	*'"type":"image"'*)
+		mt=$(printf '%s' "$line" | sed 's/.*"media_type":"\([^"]*\)".*/\1/')
+		data=$(printf '%s' "$line" | sed 's/.*"data":"\([^"]*\)".*/\1/')
+		echo '{"type":"assistant","message":{"content":[{"type":"text","text":"got it"},{"type":"image","source":{"type":"base64","media_type":"'"$mt"'","data":"'"$data"'"}}]}}'
+		echo '{"type":"result","subtype":"success","num_turns":1,"session_id":"s1"}'
+		;;
+	*'"type":"user"'*)
+		echo '{"type":"result","subtype":"error_during_execution","is_error":true,"num_turns":1,"session_id":"s1"}'
+		;;
+	esac
+done
+`
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestClientSendContent(t *testing.T) {
+	installFakeCLI(t, imageCLI)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "screenshot.png")
+	if err := os.WriteFile(path, pngHeader, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(nil)
+	if err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if err := client.SendContent(ctx, TextBlock{Text: "What is wrong here?"}, ImageBlock{Path: path}); err != nil {
+		t.Fatal(err)
+	}
+	messages, err := client.ReceiveResponse(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, ok := messages[0].(AssistantMessage)
+	if !ok || len(reply.Content) != 2 {
+		t.Fatalf("expected an assistant reply with an image, got %+v", messages)
+	}
+	want := ImageBlock{MediaType: ImagePNG, Data: base64.StdEncoding.EncodeToString(pngHeader)}
+	if got := reply.Content[1]; got != want {
+		t.Errorf("image = %+v, want %+v", got, want)
+	}
+}
+
+func TestEncodeUserContent(t *testing.T) {
+	img := ImageFromBytes(pngHeader)
+	content, err := encodeUserContent([]ContentBlock{img})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := content[0]["source"].(map[string]interface{})
+	if source["media_type"] != ImagePNG || source["data"] != img.Data {
+		t.Errorf("unexpected source %+v", source)
+	}
+
+	// The media type is detected from Data when omitted
+	content, err = encodeUserContent([]ContentBlock{ImageBlock{Data: img.Data}})
+	if err != nil || content[0]["source"].(map[string]interface{})["media_type"] != ImagePNG {
+		t.Errorf("expected a detected PNG, got %+v, %v", content, err)
+	}
+
+	for name, blocks := range map[string][]ContentBlock{
+		"empty":         nil,
+		"no source":     {ImageBlock{}},
+		"both sources":  {ImageBlock{Path: "a.png", Data: img.Data}},
+		"missing file":  {ImageBlock{Path: filepath.Join(t.TempDir(), "missing.png")}},
+		"not an image":  {ImageBlock{Data: base64.StdEncoding.EncodeToString([]byte("plain text"))}},
+		"bad base64":    {ImageBlock{Data: "%%%"}},
+		"tool use":      {ToolUseBlock{Name: "Read"}},
+		"unknown media": {ImageBlock{MediaType: "image/tiff", Data: img.Data}},
+	} {
+		if _, err := encodeUserContent(blocks); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package internal
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/f-pisani/claude-code-sdk-go/internal/transport"
 )
@@ -15,8 +16,70 @@ func NewClient() *Client {
 	return &Client{}
 }
 
-// ProcessQuery processes a query through the transport
+// ProcessQuery processes a query through the subprocess transport. Options
+// that answer control requests or replay a transcript need stdin, so their
+// prompt is sent over a streaming transport instead of the command line.
 func (c *Client) ProcessQuery(ctx context.Context, prompt string, options interface{}) (<-chan interface{}, <-chan error) {
+	if needsStdin(options) {
+		trans := &oneShotTransport{
+			SubprocessCLITransport: transport.NewStreamingCLITransport(options, ""),
+			prompt:                 prompt,
+		}
+		return c.ProcessQueryWithTransport(ctx, trans, options)
+	}
+	return c.ProcessQueryWithTransport(ctx, transport.NewSubprocessCLITransport(prompt, options, ""), options)
+}
+
+// needsStdin reports whether options use stdin beyond the prompt
+func needsStdin(options interface{}) bool {
+	if provider, ok := options.(transport.ControlHandlerProvider); ok && provider.GetControlHandler() != nil {
+		return true
+	}
+	if provider, ok := options.(interface{ GetResumeFrom() string }); ok && provider.GetResumeFrom() != "" {
+		return true
+	}
+	return false
+}
+
+// oneShotTransport runs a single prompt over a streaming transport, ending
+// its input once the first result arrives so the CLI exits as it would for
+// --print
+type oneShotTransport struct {
+	*transport.SubprocessCLITransport
+	prompt string
+}
+
+// Connect starts the CLI and sends the prompt
+func (t *oneShotTransport) Connect(ctx context.Context) error {
+	if err := t.SubprocessCLITransport.Connect(ctx); err != nil {
+		return err
+	}
+	return t.SendMessage(transport.UserMessage(t.prompt))
+}
+
+// ReceiveMessages forwards the CLI's messages, ending its input after the
+// result message
+func (t *oneShotTransport) ReceiveMessages(ctx context.Context) (<-chan map[string]interface{}, <-chan error) {
+	dataCh, errCh := t.SubprocessCLITransport.ReceiveMessages(ctx)
+	out := make(chan map[string]interface{}, cap(dataCh))
+	go func() {
+		defer close(out)
+		for data := range dataCh {
+			if data["type"] == "result" {
+				t.EndInput()
+			}
+			select {
+			case out <- data:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out, errCh
+}
+
+// ProcessQueryWithTransport processes a query through trans, which is
+// connected here and disconnected once its messages are consumed
+func (c *Client) ProcessQueryWithTransport(ctx context.Context, trans transport.Transport, options interface{}) (<-chan interface{}, <-chan error) {
 	// Get buffer sizes from options if available
 	msgBufSize := 10
 	errBufSize := 1
@@ -44,9 +107,6 @@ func (c *Client) ProcessQuery(ctx context.Context, prompt string, options interf
 			close(errCh)
 		}()
 
-		// Create transport
-		trans := transport.NewSubprocessCLITransport(prompt, options, "")
-
 		// Connect
 		if err := trans.Connect(ctx); err != nil {
 			errCh <- err
@@ -56,43 +116,11 @@ func (c *Client) ProcessQuery(ctx context.Context, prompt string, options interf
 
 		// Receive messages
 		dataCh, dataErrCh := trans.ReceiveMessages(ctx)
-
-		for {
+		if queryErr := c.forward(ctx, transport.LoggerFrom(options), dataCh, dataErrCh, msgCh); queryErr != nil {
+			// errCh is buffered and only written here, so this never blocks
 			select {
-			case data, ok := <-dataCh:
-				if !ok {
-					return
-				}
-				if msg := c.parseMessage(data); msg != nil {
-					select {
-					case msgCh <- msg:
-					case <-ctx.Done():
-						return
-					}
-				}
-			case err, ok := <-dataErrCh:
-				if !ok {
-					// Error channel closed
-					return
-				}
-				if err != nil {
-					// Try to send error without blocking
-					select {
-					case errCh <- err:
-					case <-ctx.Done():
-						return
-					default:
-						// Error channel full, replace with latest error
-						select {
-						case <-errCh:
-							errCh <- err
-						default:
-						}
-					}
-					return
-				}
-			case <-ctx.Done():
-				return
+			case errCh <- queryErr:
+			default:
 			}
 		}
 	}()
@@ -100,6 +128,46 @@ func (c *Client) ProcessQuery(ctx context.Context, prompt string, options interf
 	return msgCh, errCh
 }
 
+// forward parses transport messages onto msgCh and returns the last
+// transport error. It keeps reading until both transport channels are closed
+// so that an error racing with the end of the message stream is not lost.
+// Messages that cannot be parsed are logged to log and skipped.
+func (c *Client) forward(ctx context.Context, log *slog.Logger, dataCh <-chan map[string]interface{}, dataErrCh <-chan error, msgCh chan<- interface{}) error {
+	var queryErr error
+	for dataCh != nil || dataErrCh != nil {
+		select {
+		case data, ok := <-dataCh:
+			if !ok {
+				dataCh = nil
+				continue
+			}
+			msg := c.parseMessage(data)
+			if msg == nil {
+				log.Debug("skipped unrecognized message", "type", data["type"])
+				continue
+			}
+			select {
+			case msgCh <- msg:
+			case <-ctx.Done():
+				return nil
+			}
+		case err, ok := <-dataErrCh:
+			if !ok {
+				dataErrCh = nil
+				continue
+			}
+			if err != nil {
+				log.Warn("query error", "error", err)
+				// Replace any earlier error with the latest one
+				queryErr = err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return queryErr
+}
+
 // parseMessage parses a message from CLI output and returns a map
 func (c *Client) parseMessage(data map[string]interface{}) interface{} {
 	msgType, ok := data["type"].(string)
@@ -113,6 +181,18 @@ func (c *Client) parseMessage(data map[string]interface{}) interface{} {
 			if content, ok := msgData["content"].(string); ok {
 				return map[string]interface{}{"_type": "user", "content": content}
 			}
+			// Tool results reach the CLI as user messages with block content
+			if contentData, ok := msgData["content"].([]interface{}); ok {
+				var contentBlocks []interface{}
+				for _, blockData := range contentData {
+					if blockMap, ok := blockData.(map[string]interface{}); ok {
+						if block := c.parseContentBlock(blockMap); block != nil {
+							contentBlocks = append(contentBlocks, block)
+						}
+					}
+				}
+				return map[string]interface{}{"_type": "user", "blocks": contentBlocks}
+			}
 		}
 
 	case "assistant":
@@ -126,7 +206,14 @@ func (c *Client) parseMessage(data map[string]interface{}) interface{} {
 						}
 					}
 				}
-				return map[string]interface{}{"_type": "assistant", "content": contentBlocks}
+				result := map[string]interface{}{"_type": "assistant", "content": contentBlocks}
+				if id, ok := msgData["id"].(string); ok {
+					result["id"] = id
+				}
+				if usage, ok := msgData["usage"].(map[string]interface{}); ok {
+					result["usage"] = usage
+				}
+				return result
 			}
 		}
 
@@ -134,6 +221,19 @@ func (c *Client) parseMessage(data map[string]interface{}) interface{} {
 		subtype, _ := data["subtype"].(string)
 		return map[string]interface{}{"_type": "system", "subtype": subtype, "data": data}
 
+	case "stream_event":
+		event, _ := data["event"].(map[string]interface{})
+		uuid, _ := data["uuid"].(string)
+		sessionID, _ := data["session_id"].(string)
+		parentToolUseID, _ := data["parent_tool_use_id"].(string)
+		return map[string]interface{}{
+			"_type":              "stream_event",
+			"uuid":               uuid,
+			"session_id":         sessionID,
+			"event":              event,
+			"parent_tool_use_id": parentToolUseID,
+		}
+
 	case "result":
 		subtype, _ := data["subtype"].(string)
 		durationMs, _ := data["duration_ms"].(float64)
@@ -181,6 +281,11 @@ func (c *Client) parseContentBlock(data map[string]interface{}) interface{} {
 			return map[string]interface{}{"_blockType": "text", "text": text}
 		}
 
+	case "thinking":
+		thinking, _ := data["thinking"].(string)
+		signature, _ := data["signature"].(string)
+		return map[string]interface{}{"_blockType": "thinking", "thinking": thinking, "signature": signature}
+
 	case "tool_use":
 		id, _ := data["id"].(string)
 		name, _ := data["name"].(string)
@@ -197,6 +302,13 @@ func (c *Client) parseContentBlock(data map[string]interface{}) interface{} {
 			block["is_error"] = isError
 		}
 		return block
+
+	case "image":
+		// Only base64 sources carry the image itself
+		source, _ := data["source"].(map[string]interface{})
+		mediaType, _ := source["media_type"].(string)
+		imageData, _ := source["data"].(string)
+		return map[string]interface{}{"_blockType": "image", "media_type": mediaType, "data": imageData}
 	}
 
 	return nil
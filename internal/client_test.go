@@ -32,6 +32,22 @@ func TestParseMessage(t *testing.T) {
 			},
 			wantType: "user",
 		},
+		{
+			name: "user message with tool results",
+			input: map[string]interface{}{
+				"type": "user",
+				"message": map[string]interface{}{
+					"content": []interface{}{
+						map[string]interface{}{
+							"type":        "tool_result",
+							"tool_use_id": "t1",
+							"content":     "ok",
+						},
+					},
+				},
+			},
+			wantType: "user",
+		},
 		{
 			name: "assistant message",
 			input: map[string]interface{}{
@@ -151,6 +167,15 @@ func TestParseContentBlock(t *testing.T) {
 			},
 			wantBlock: "tool_use",
 		},
+		{
+			name: "thinking block",
+			input: map[string]interface{}{
+				"type":      "thinking",
+				"thinking":  "Let me think",
+				"signature": "sig",
+			},
+			wantBlock: "thinking",
+		},
 		{
 			name: "tool result block",
 			input: map[string]interface{}{
@@ -0,0 +1,222 @@
+// Package clock abstracts time so that timeouts, idle detection, backoff and
+// metrics can be driven by a fake clock in tests
+package clock
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock tells the time and creates timers
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a stoppable, resettable timer like time.Timer. C delivers the
+// clock's time when the timer fires.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Real is the system clock
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct{ t *time.Timer }
+
+func (r realTimer) C() <-chan time.Time        { return r.t.C }
+func (r realTimer) Stop() bool                 { return r.t.Stop() }
+func (r realTimer) Reset(d time.Duration) bool { return r.t.Reset(d) }
+
+// Or returns c, or Real if c is nil
+func Or(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+	return c
+}
+
+// Since returns the time elapsed on c since t
+func Since(c Clock, t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// WithTimeout is context.WithTimeout measured on c. The returned context's
+// Err is context.DeadlineExceeded once c has advanced by timeout.
+func WithTimeout(parent context.Context, c Clock, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := c.(realClock); ok {
+		return context.WithTimeout(parent, timeout)
+	}
+
+	inner, cancel := context.WithCancel(parent)
+	ctx := &timeoutContext{Context: inner, deadline: c.Now().Add(timeout)}
+	timer := c.NewTimer(timeout)
+	go func() {
+		select {
+		case <-timer.C():
+			ctx.expired.Store(true)
+			cancel()
+		case <-inner.Done():
+			timer.Stop()
+		}
+	}()
+	return ctx, cancel
+}
+
+// timeoutContext reports a deadline measured on a Clock other than Real
+type timeoutContext struct {
+	context.Context
+	deadline time.Time
+	expired  atomic.Bool
+}
+
+func (c *timeoutContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *timeoutContext) Err() error {
+	if c.expired.Load() {
+		return context.DeadlineExceeded
+	}
+	return c.Context.Err()
+}
+
+// Fake is a Clock whose time only moves when Advance or Set is called.
+// Timers fire, in order of their deadlines, when the clock reaches them.
+type Fake struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFake creates a fake clock set to now
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTimer creates a timer firing once the clock has advanced by d
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	f.schedule(t, d)
+	return t
+}
+
+// Advance moves the clock forward by d, firing the timers due by then
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the clock to now, firing the timers due by then. The clock
+// never moves backwards.
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if now.Before(f.now) {
+		return
+	}
+	f.now = now
+	sort.SliceStable(f.timers, func(i, j int) bool {
+		return f.timers[i].deadline.Before(f.timers[j].deadline)
+	})
+	pending := f.timers[:0]
+	for _, t := range f.timers {
+		if t.deadline.After(now) {
+			pending = append(pending, t)
+			continue
+		}
+		select {
+		case t.c <- now:
+		default:
+		}
+	}
+	f.timers = pending
+	f.cond.Broadcast()
+}
+
+// BlockUntil waits until at least n timers are pending on the clock, so a
+// test can advance it once the code under test is waiting
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) < n {
+		f.cond.Wait()
+	}
+}
+
+// Pending returns the number of timers waiting to fire
+func (f *Fake) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+// schedule arms t to fire d after the current time. f.mu must be held.
+func (f *Fake) schedule(t *fakeTimer, d time.Duration) {
+	t.deadline = f.now.Add(d)
+	if d <= 0 {
+		select {
+		case t.c <- f.now:
+		default:
+		}
+		return
+	}
+	f.timers = append(f.timers, t)
+	f.cond.Broadcast()
+}
+
+// unschedule disarms t and reports whether it was pending. f.mu must be
+// held.
+func (f *Fake) unschedule(t *fakeTimer) bool {
+	for i, pending := range f.timers {
+		if pending == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	c        chan time.Time
+	deadline time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.unschedule(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.clock.unschedule(t)
+	t.clock.schedule(t, d)
+	return active
+}
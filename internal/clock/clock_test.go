@@ -0,0 +1,91 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func fired(t Timer) bool {
+	select {
+	case <-t.C():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestFake(t *testing.T) {
+	clk := NewFake(epoch)
+	short := clk.NewTimer(time.Second)
+	long := clk.NewTimer(time.Minute)
+	if clk.Pending() != 2 {
+		t.Fatalf("expected 2 pending timers, got %d", clk.Pending())
+	}
+
+	clk.Advance(time.Second)
+	if !fired(short) || fired(long) {
+		t.Error("expected only the short timer to fire")
+	}
+	if got := clk.Now(); !got.Equal(epoch.Add(time.Second)) {
+		t.Errorf("Now() = %v after advancing", got)
+	}
+
+	if !long.Stop() {
+		t.Error("expected Stop to report a pending timer")
+	}
+	clk.Advance(time.Hour)
+	if fired(long) {
+		t.Error("stopped timer fired")
+	}
+
+	if long.Reset(time.Second) {
+		t.Error("expected Reset of a stopped timer to report it inactive")
+	}
+	clk.Set(epoch) // Moving backwards is ignored
+	clk.Advance(time.Second)
+	if !fired(long) {
+		t.Error("reset timer did not fire")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		clk.BlockUntil(1)
+		close(done)
+	}()
+	timer := clk.NewTimer(time.Second)
+	<-done
+	timer.Stop()
+}
+
+func TestWithTimeout(t *testing.T) {
+	clk := NewFake(epoch)
+	ctx, cancel := WithTimeout(context.Background(), clk, time.Minute)
+	defer cancel()
+
+	if deadline, ok := ctx.Deadline(); !ok || !deadline.Equal(epoch.Add(time.Minute)) {
+		t.Errorf("Deadline() = %v, %v", deadline, ok)
+	}
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	<-ctx.Done()
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", ctx.Err())
+	}
+
+	ctx, cancel = WithTimeout(context.Background(), clk, time.Minute)
+	cancel()
+	<-ctx.Done()
+	if ctx.Err() != context.Canceled {
+		t.Errorf("expected Canceled, got %v", ctx.Err())
+	}
+
+	ctx, cancel = WithTimeout(context.Background(), Real, time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded from the real clock, got %v", ctx.Err())
+	}
+}
@@ -0,0 +1,158 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+)
+
+// ErrorCode is a stable, machine-readable identifier for a kind of error
+type ErrorCode string
+
+// Error codes reported by the SDK's error types
+const (
+	CodeUnknown           ErrorCode = "unknown"
+	CodeSDK               ErrorCode = "sdk_error"
+	CodeCLIConnection     ErrorCode = "cli_connection"
+	CodeCLINotFound       ErrorCode = "cli_not_found"
+	CodeIncompatibleCLI   ErrorCode = "incompatible_cli"
+	CodeProcess           ErrorCode = "process_failed"
+	CodeJSONDecode        ErrorCode = "json_decode"
+	CodeStall             ErrorCode = "stall"
+	CodeStartupTimeout    ErrorCode = "startup_timeout"
+	CodeTimeout           ErrorCode = "timeout"
+	CodeCanceled          ErrorCode = "canceled"
+	CodeTurnLimitExceeded ErrorCode = "turn_limit_exceeded"
+	CodeLimitExceeded     ErrorCode = "limit_exceeded"
+	CodeBudgetExceeded    ErrorCode = "budget_exceeded"
+	CodeReadOnlyViolation ErrorCode = "read_only_violation"
+	CodeCallbackTimeout   ErrorCode = "callback_timeout"
+	CodeCallbackPanic     ErrorCode = "callback_panic"
+	CodePatchConflict     ErrorCode = "patch_conflict"
+	CodeResult            ErrorCode = "result_error"
+	CodeMaxTurnsExceeded  ErrorCode = "max_turns_exceeded"
+	CodeRateLimited       ErrorCode = "rate_limited"
+	CodeOverloaded        ErrorCode = "overloaded"
+	CodeTurnRejected      ErrorCode = "turn_rejected"
+	CodeCostNotConfirmed  ErrorCode = "cost_not_confirmed"
+	CodeMultiple          ErrorCode = "multiple_errors"
+)
+
+// Coder is implemented by errors that carry an ErrorCode
+type Coder interface {
+	Code() ErrorCode
+}
+
+// CodeOf returns the code of the first error in err's chain that carries
+// one. Context deadline and cancellation errors map to CodeTimeout and
+// CodeCanceled, other errors to CodeUnknown, and nil to "".
+func CodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	var coder Coder
+	if stderrors.As(err, &coder) {
+		return coder.Code()
+	}
+	switch {
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	case stderrors.Is(err, context.Canceled):
+		return CodeCanceled
+	}
+	return CodeUnknown
+}
+
+// Code returns CodeSDK
+func (e SDKError) Code() ErrorCode { return CodeSDK }
+
+// Code returns CodeCLIConnection
+func (e CLIConnectionError) Code() ErrorCode { return CodeCLIConnection }
+
+// Code returns CodeCLINotFound
+func (e CLINotFoundError) Code() ErrorCode { return CodeCLINotFound }
+
+// Code returns CodeIncompatibleCLI
+func (e IncompatibleCLIError) Code() ErrorCode { return CodeIncompatibleCLI }
+
+// Code returns CodeProcess
+func (e ProcessError) Code() ErrorCode { return CodeProcess }
+
+// Code returns CodeJSONDecode
+func (e CLIJSONDecodeError) Code() ErrorCode { return CodeJSONDecode }
+
+// Code returns CodeStall
+func (e StallError) Code() ErrorCode { return CodeStall }
+
+// Code returns CodeStartupTimeout
+func (e StartupTimeoutError) Code() ErrorCode { return CodeStartupTimeout }
+
+// Code returns CodeTimeout for the wall-clock limit, CodeTurnLimitExceeded
+// for the turn limit and CodeLimitExceeded for any other limit
+func (e LimitExceededError) Code() ErrorCode {
+	switch e.Limit {
+	case "wall_clock":
+		return CodeTimeout
+	case "turns":
+		return CodeTurnLimitExceeded
+	}
+	return CodeLimitExceeded
+}
+
+// Code returns CodeBudgetExceeded
+func (e BudgetExceededError) Code() ErrorCode { return CodeBudgetExceeded }
+
+// Code returns CodeReadOnlyViolation
+func (e ReadOnlyViolationError) Code() ErrorCode { return CodeReadOnlyViolation }
+
+// Code returns CodeTurnRejected
+func (e TurnRejectedError) Code() ErrorCode { return CodeTurnRejected }
+
+// Code returns CodeCostNotConfirmed
+func (e CostNotConfirmedError) Code() ErrorCode { return CodeCostNotConfirmed }
+
+// Code returns CodeCallbackPanic for a panic and CodeCallbackTimeout
+// otherwise
+func (e CallbackError) Code() ErrorCode {
+	if e.Panic != nil {
+		return CodeCallbackPanic
+	}
+	return CodeCallbackTimeout
+}
+
+// Code returns CodePatchConflict
+func (e PatchConflictError) Code() ErrorCode { return CodePatchConflict }
+
+// Code returns CodeResult
+func (e ResultError) Code() ErrorCode { return CodeResult }
+
+// Code returns CodeMaxTurnsExceeded
+func (e MaxTurnsExceededError) Code() ErrorCode { return CodeMaxTurnsExceeded }
+
+// Code returns CodeOverloaded when the API is overloaded and CodeRateLimited
+// otherwise
+func (e RateLimitError) Code() ErrorCode {
+	if e.Overloaded {
+		return CodeOverloaded
+	}
+	return CodeRateLimited
+}
+
+// Code returns the code shared by every aggregated error, or CodeMultiple
+// if they differ
+func (e Errors) Code() ErrorCode {
+	var code ErrorCode
+	for _, err := range e {
+		if err == nil {
+			continue
+		}
+		c := CodeOf(err)
+		if code != "" && c != code {
+			return CodeMultiple
+		}
+		code = c
+	}
+	if code == "" {
+		return CodeUnknown
+	}
+	return code
+}
@@ -2,11 +2,14 @@ package errors
 
 import (
 	"fmt"
+	"strings"
+	"time"
 )
 
 // SDKError is the base error type for all Claude SDK errors
 type SDKError struct {
 	Message string
+	HintKey HintKey // User-facing guidance, rendered with RenderHint
 }
 
 func (e SDKError) Error() string {
@@ -24,14 +27,17 @@ type CLINotFoundError struct {
 	CLIPath string
 }
 
-// NewCLINotFoundError creates a new CLINotFoundError
+// NewCLINotFoundError creates a new CLINotFoundError. Its hint explains how
+// to install Claude Code or, when cliPath is set, to check that path.
 func NewCLINotFoundError(message string, cliPath string) *CLINotFoundError {
+	hint := HintCLINotInstalled
 	if cliPath != "" {
 		message = fmt.Sprintf("%s: %s", message, cliPath)
+		hint = HintCLIPathInvalid
 	}
 	return &CLINotFoundError{
 		CLIConnectionError: CLIConnectionError{
-			SDKError: SDKError{Message: message},
+			SDKError: SDKError{Message: message, HintKey: hint},
 		},
 		CLIPath: cliPath,
 	}
@@ -40,11 +46,13 @@ func NewCLINotFoundError(message string, cliPath string) *CLINotFoundError {
 // ProcessError is raised when the CLI process fails
 type ProcessError struct {
 	SDKError
-	ExitCode *int
-	Stderr   string
+	ExitCode   *int
+	Stderr     string
+	RetryAfter time.Duration // Delay stated in Stderr before retrying, e.g. when rate limited; 0 if none
 }
 
-// NewProcessError creates a new ProcessError
+// NewProcessError creates a new ProcessError. RetryAfter is parsed from
+// stderr.
 func NewProcessError(message string, exitCode *int, stderr string) *ProcessError {
 	if exitCode != nil {
 		message = fmt.Sprintf("%s (exit code: %d)", message, *exitCode)
@@ -53,9 +61,10 @@ func NewProcessError(message string, exitCode *int, stderr string) *ProcessError
 		message = fmt.Sprintf("%s\nError output: %s", message, stderr)
 	}
 	return &ProcessError{
-		SDKError: SDKError{Message: message},
-		ExitCode: exitCode,
-		Stderr:   stderr,
+		SDKError:   SDKError{Message: message},
+		ExitCode:   exitCode,
+		Stderr:     stderr,
+		RetryAfter: ParseRetryAfter(stderr),
 	}
 }
 
@@ -85,4 +94,293 @@ func (e CLIJSONDecodeError) Error() string {
 
 func (e CLIJSONDecodeError) Unwrap() error {
 	return e.OriginalError
-}
\ No newline at end of file
+}
+// StallError is raised when the CLI stops producing output for longer than
+// the configured stall timeout and is torn down
+type StallError struct {
+	CLIConnectionError
+	Timeout time.Duration
+}
+
+// NewStallError creates a new StallError
+func NewStallError(timeout time.Duration) *StallError {
+	return &StallError{
+		CLIConnectionError: CLIConnectionError{
+			SDKError: SDKError{Message: fmt.Sprintf("Claude Code produced no output for %s", timeout)},
+		},
+		Timeout: timeout,
+	}
+}
+
+// StartupTimeoutError is raised when the CLI does not report its init
+// message within the configured startup timeout and is killed
+type StartupTimeoutError struct {
+	CLIConnectionError
+	Timeout time.Duration
+}
+
+// NewStartupTimeoutError creates a new StartupTimeoutError
+func NewStartupTimeoutError(timeout time.Duration) *StartupTimeoutError {
+	return &StartupTimeoutError{
+		CLIConnectionError: CLIConnectionError{
+			SDKError: SDKError{Message: fmt.Sprintf("Claude Code did not start within %s", timeout)},
+		},
+		Timeout: timeout,
+	}
+}
+
+// IncompatibleCLIError is raised at Connect when the installed CLI's
+// version does not satisfy the required version constraint
+type IncompatibleCLIError struct {
+	CLIConnectionError
+	CLIPath    string
+	Version    string // Version reported by the CLI; empty if it could not be determined
+	Constraint string
+}
+
+// NewIncompatibleCLIError creates a new IncompatibleCLIError
+func NewIncompatibleCLIError(cliPath, version, constraint string) *IncompatibleCLIError {
+	message := fmt.Sprintf("Claude Code %s at %s does not satisfy required version %s", version, cliPath, constraint)
+	if version == "" {
+		message = fmt.Sprintf("Could not determine the version of Claude Code at %s (required %s)", cliPath, constraint)
+	}
+	return &IncompatibleCLIError{
+		CLIConnectionError: CLIConnectionError{
+			SDKError: SDKError{Message: message, HintKey: HintCLIIncompatible},
+		},
+		CLIPath:    cliPath,
+		Version:    version,
+		Constraint: constraint,
+	}
+}
+
+// LimitExceededError is raised when a query is stopped by an SDK-side limit.
+// Limit names the limit that fired and Value its configured value.
+type LimitExceededError struct {
+	SDKError
+	Limit string
+	Value int
+}
+
+// NewLimitExceededError creates a new LimitExceededError
+func NewLimitExceededError(limit string, value int) *LimitExceededError {
+	return &LimitExceededError{
+		SDKError: SDKError{Message: fmt.Sprintf("Query stopped: %s limit of %d exceeded", limit, value)},
+		Limit:    limit,
+		Value:    value,
+	}
+}
+
+// BudgetExceededError is raised when the cost reported for a query or
+// session crosses its spending limit
+type BudgetExceededError struct {
+	SDKError
+	LimitUSD float64
+	SpentUSD float64
+}
+
+// NewBudgetExceededError creates a new BudgetExceededError
+func NewBudgetExceededError(limitUSD, spentUSD float64) *BudgetExceededError {
+	return &BudgetExceededError{
+		SDKError: SDKError{Message: fmt.Sprintf("Query stopped: cost of $%.4f exceeds budget of $%.4f", spentUSD, limitUSD)},
+		LimitUSD: limitUSD,
+		SpentUSD: spentUSD,
+	}
+}
+
+// ResultError reports a result message with is_error set. Subtype is the
+// result's subtype, e.g. "error_during_execution", and Detail its text.
+type ResultError struct {
+	SDKError
+	Subtype string
+	Detail  string
+}
+
+// NewResultError creates a new ResultError
+func NewResultError(subtype, detail string) *ResultError {
+	message := fmt.Sprintf("Claude Code run failed (%s)", subtype)
+	if detail != "" {
+		message = fmt.Sprintf("%s: %s", message, detail)
+	}
+	return &ResultError{
+		SDKError: SDKError{Message: message},
+		Subtype:  subtype,
+		Detail:   detail,
+	}
+}
+
+// MaxTurnsExceededError reports a run the CLI stopped after reaching its
+// --max-turns limit
+type MaxTurnsExceededError struct {
+	ResultError
+	NumTurns int
+}
+
+// NewMaxTurnsExceededError creates a new MaxTurnsExceededError
+func NewMaxTurnsExceededError(subtype string, numTurns int) *MaxTurnsExceededError {
+	return &MaxTurnsExceededError{
+		ResultError: ResultError{
+			SDKError: SDKError{Message: fmt.Sprintf("Claude Code stopped after reaching the maximum of %d turns", numTurns)},
+			Subtype:  subtype,
+		},
+		NumTurns: numTurns,
+	}
+}
+
+// RateLimitError reports a run rejected because the API rate limit was hit
+// or, with Overloaded set, because the API is overloaded. RetryAfter is the
+// delay the API asked for, if it stated one.
+type RateLimitError struct {
+	ResultError
+	Overloaded bool
+	RetryAfter time.Duration
+}
+
+// NewRateLimitError creates a new RateLimitError. RetryAfter is parsed from
+// detail.
+func NewRateLimitError(subtype, detail string, overloaded bool) *RateLimitError {
+	message := "Claude Code was rate limited"
+	if overloaded {
+		message = "Claude Code API is overloaded"
+	}
+	if detail != "" {
+		message = fmt.Sprintf("%s: %s", message, detail)
+	}
+	return &RateLimitError{
+		ResultError: ResultError{
+			SDKError: SDKError{Message: message},
+			Subtype:  subtype,
+			Detail:   detail,
+		},
+		Overloaded: overloaded,
+		RetryAfter: ParseRetryAfter(detail),
+	}
+}
+
+// ReadOnlyViolationError is raised when a query running in read-only mode
+// requests a tool that could modify the workspace
+type ReadOnlyViolationError struct {
+	SDKError
+	Tool string
+}
+
+// NewReadOnlyViolationError creates a new ReadOnlyViolationError
+func NewReadOnlyViolationError(tool string) *ReadOnlyViolationError {
+	return &ReadOnlyViolationError{
+		SDKError: SDKError{Message: fmt.Sprintf("Query stopped: tool %s is not allowed in read-only mode", tool)},
+		Tool:     tool,
+	}
+}
+
+// TurnRejectedError is raised when a turn run with approval is rejected.
+// Round is the rejected round, counting amendments, and Reason the reviewer's
+// explanation, if any.
+type TurnRejectedError struct {
+	SDKError
+	Round  int
+	Reason string
+}
+
+// NewTurnRejectedError creates a new TurnRejectedError
+func NewTurnRejectedError(round int, reason string) *TurnRejectedError {
+	message := fmt.Sprintf("Turn rejected in round %d", round)
+	if reason != "" {
+		message = fmt.Sprintf("%s: %s", message, reason)
+	}
+	return &TurnRejectedError{
+		SDKError: SDKError{Message: message},
+		Round:    round,
+		Reason:   reason,
+	}
+}
+
+// CostNotConfirmedError is raised when a batch of queries whose estimated
+// cost exceeds a threshold is not confirmed before it starts
+type CostNotConfirmedError struct {
+	SDKError
+	EstimateUSD  float64
+	ThresholdUSD float64
+}
+
+// NewCostNotConfirmedError creates a new CostNotConfirmedError
+func NewCostNotConfirmedError(estimateUSD, thresholdUSD float64) *CostNotConfirmedError {
+	return &CostNotConfirmedError{
+		SDKError:     SDKError{Message: fmt.Sprintf("Estimated cost $%.2f exceeds $%.2f and was not confirmed", estimateUSD, thresholdUSD)},
+		EstimateUSD:  estimateUSD,
+		ThresholdUSD: thresholdUSD,
+	}
+}
+
+// CallbackError is raised when a user callback, such as a permission
+// callback, panics or runs past its timeout. Callback names the callback.
+type CallbackError struct {
+	SDKError
+	Callback string
+	Timeout  time.Duration // Set when the callback timed out
+	Panic    interface{}   // Set when the callback panicked
+}
+
+// NewCallbackTimeoutError creates a CallbackError for a callback that ran
+// longer than timeout
+func NewCallbackTimeoutError(callback string, timeout time.Duration) *CallbackError {
+	return &CallbackError{
+		SDKError: SDKError{Message: fmt.Sprintf("%s callback did not return within %s", callback, timeout)},
+		Callback: callback,
+		Timeout:  timeout,
+	}
+}
+
+// NewCallbackPanicError creates a CallbackError for a callback that panicked
+// with value
+func NewCallbackPanicError(callback string, value interface{}) *CallbackError {
+	return &CallbackError{
+		SDKError: SDKError{Message: fmt.Sprintf("%s callback panicked: %v", callback, value)},
+		Callback: callback,
+		Panic:    value,
+	}
+}
+
+// PatchConflictError is raised when a change cannot be applied because the
+// file no longer matches what the change was made against
+type PatchConflictError struct {
+	SDKError
+	Path   string
+	Reason string
+}
+
+// NewPatchConflictError creates a new PatchConflictError
+func NewPatchConflictError(path, reason string) *PatchConflictError {
+	return &PatchConflictError{
+		SDKError: SDKError{Message: fmt.Sprintf("Conflict applying change to %s: %s", path, reason)},
+		Path:     path,
+		Reason:   reason,
+	}
+}
+
+// Errors aggregates several errors. errors.Is and errors.As inspect every
+// element through Unwrap.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		if err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the aggregated errors
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// ErrorOrNil returns nil if no errors were collected and the aggregate
+// otherwise, avoiding a non-nil error interface holding an empty Errors
+func (e Errors) ErrorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,90 @@
+package errors
+
+import (
+	"bytes"
+	stderrors "errors"
+	"text/template"
+)
+
+// HintKey identifies user-facing guidance for an error, such as how to
+// install Claude Code, kept apart from the developer-facing error message
+type HintKey string
+
+// Hint keys set by the SDK's errors
+const (
+	HintNodeNotInstalled HintKey = "node_not_installed"
+	HintCLINotInstalled  HintKey = "cli_not_installed"
+	HintCLIPathInvalid   HintKey = "cli_path_invalid"
+	HintCLIIncompatible  HintKey = "cli_incompatible"
+)
+
+// HintCatalog maps hint keys to text/template sources. Templates are
+// executed with the error carrying the hint, so fields such as
+// {{.CLIPath}} or {{.Constraint}} can be used.
+type HintCatalog map[HintKey]string
+
+// defaultHints is the built-in English catalog
+var defaultHints = HintCatalog{
+	HintNodeNotInstalled: "Claude Code requires Node.js, which is not installed.\n\n" +
+		"Install Node.js from: https://nodejs.org/\n" +
+		"\nAfter installing Node.js, install Claude Code:\n" +
+		"  npm install -g @anthropic-ai/claude-code",
+	HintCLINotInstalled: "Install Claude Code with:\n" +
+		"  npm install -g @anthropic-ai/claude-code\n" +
+		"\nIf already installed locally, try:\n" +
+		"  export PATH=\"$HOME/node_modules/.bin:$PATH\"\n" +
+		"\nOr specify the path when creating transport:\n" +
+		"  NewSubprocessCLITransport(..., \"/path/to/claude\")",
+	HintCLIPathInvalid: "Check that Claude Code is installed at {{.CLIPath}}, " +
+		"or leave the path empty to search PATH and the usual install locations.",
+	HintCLIIncompatible: "Install a version of Claude Code matching {{.Constraint}}:\n" +
+		"  npm install -g @anthropic-ai/claude-code",
+}
+
+// DefaultHintCatalog returns a copy of the built-in English catalog
+func DefaultHintCatalog() HintCatalog {
+	catalog := make(HintCatalog, len(defaultHints))
+	for key, text := range defaultHints {
+		catalog[key] = text
+	}
+	return catalog
+}
+
+// hinter is implemented by errors embedding SDKError
+type hinter interface {
+	error
+	hintKey() HintKey
+}
+
+func (e SDKError) hintKey() HintKey { return e.HintKey }
+
+// RenderHint returns the hint for the first error in err's chain that has
+// one, rendered from catalog. Keys missing from catalog, or whose template
+// fails, use the built-in text. Returns "" if err carries no hint.
+func RenderHint(err error, catalog HintCatalog) string {
+	var h hinter
+	if !stderrors.As(err, &h) || h.hintKey() == "" {
+		return ""
+	}
+	key := h.hintKey()
+	if text, ok := catalog[key]; ok {
+		if rendered, err := renderHint(text, h); err == nil {
+			return rendered
+		}
+	}
+	rendered, _ := renderHint(defaultHints[key], h)
+	return rendered
+}
+
+// renderHint executes the hint template text with data
+func renderHint(text string, data interface{}) (string, error) {
+	tmpl, err := template.New("hint").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
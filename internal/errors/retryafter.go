@@ -0,0 +1,54 @@
+package errors
+
+import (
+	stderrors "errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retryAfterPattern matches the ways the CLI and API say when to retry, such
+// as "Retry-After: 30", "retry after 2.5 seconds", "retry_after_ms": 1500 or
+// "try again in 1m"
+var retryAfterPattern = regexp.MustCompile(`(?i)(retry[-_ ]?after(?:[-_]?(ms|s|seconds))?|try again in)["']?\s*[:=]?\s*(\d+(?:\.\d+)?)\s*(ms|milliseconds?|s|secs?|seconds?|m|mins?|minutes?)?\b`)
+
+// ParseRetryAfter returns the retry delay stated in text, or 0 if it does
+// not state one. Numbers without a unit are seconds, as in the Retry-After
+// header.
+func ParseRetryAfter(text string) time.Duration {
+	match := retryAfterPattern.FindStringSubmatch(text)
+	if match == nil {
+		return 0
+	}
+	value, err := strconv.ParseFloat(match[3], 64)
+	if err != nil || value <= 0 {
+		return 0
+	}
+	unit := strings.ToLower(match[4])
+	if unit == "" {
+		unit = strings.ToLower(match[2])
+	}
+	scale := time.Second
+	switch {
+	case strings.HasPrefix(unit, "ms"), strings.HasPrefix(unit, "milli"):
+		scale = time.Millisecond
+	case strings.HasPrefix(unit, "m"):
+		scale = time.Minute
+	}
+	return time.Duration(value * float64(scale))
+}
+
+// RetryAfterOf returns the retry delay attached to the first error in err's
+// chain that carries one, or 0
+func RetryAfterOf(err error) time.Duration {
+	var procErr *ProcessError
+	if stderrors.As(err, &procErr) {
+		return procErr.RetryAfter
+	}
+	var rateErr *RateLimitError
+	if stderrors.As(err, &rateErr) {
+		return rateErr.RetryAfter
+	}
+	return 0
+}
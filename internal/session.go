@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/transport"
+)
+
+// Session is a long-lived streaming conversation with one CLI process
+type Session struct {
+	client *Client
+	trans  transport.StreamingTransport
+	msgCh  chan interface{}
+	errCh  chan error
+	cancel context.CancelFunc
+}
+
+// StartSession connects trans, such as a streaming subprocess transport. The
+// connection lives until ctx ends or the session is closed; its parsed
+// messages are delivered on Messages across every turn.
+func (c *Client) StartSession(ctx context.Context, trans transport.StreamingTransport, options interface{}) (*Session, error) {
+	msgBufSize := 10
+	errBufSize := 1
+	if opt, ok := options.(interface {
+		GetMessageBufferSize() int
+		GetErrorBufferSize() int
+	}); ok {
+		msgBufSize = opt.GetMessageBufferSize()
+		errBufSize = opt.GetErrorBufferSize()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Session{
+		client: c,
+		trans:  trans,
+		msgCh:  make(chan interface{}, msgBufSize),
+		errCh:  make(chan error, errBufSize),
+		cancel: cancel,
+	}
+	if err := s.trans.Connect(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	dataCh, dataErrCh := s.trans.ReceiveMessages(ctx)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				s.errCh <- fmt.Errorf("panic in session: %v", r)
+			}
+			close(s.msgCh)
+			close(s.errCh)
+		}()
+
+		if err := c.forward(ctx, transport.LoggerFrom(options), dataCh, dataErrCh, s.msgCh); err != nil {
+			// errCh is buffered and only written here, so this never blocks
+			select {
+			case s.errCh <- err:
+			default:
+			}
+		}
+	}()
+
+	return s, nil
+}
+
+// Messages returns the session's message and error channels. Both close when
+// the CLI exits.
+func (s *Session) Messages() (<-chan interface{}, <-chan error) {
+	return s.msgCh, s.errCh
+}
+
+// Send writes a user message to the CLI, starting a new turn
+func (s *Session) Send(prompt string) error {
+	return s.trans.SendMessage(transport.UserMessage(prompt))
+}
+
+// SendContent writes a user message made of content block objects, such as
+// text and images, to the CLI, starting a new turn
+func (s *Session) SendContent(content []map[string]interface{}) error {
+	return s.trans.SendMessage(transport.UserMessage(content))
+}
+
+// Interrupt asks the CLI to stop the current turn and waits for it to
+// acknowledge. A non-empty reason is sent along with the request.
+func (s *Session) Interrupt(ctx context.Context, reason string) error {
+	request := map[string]interface{}{"subtype": "interrupt"}
+	if reason != "" {
+		request["reason"] = reason
+	}
+	_, err := s.trans.ControlRequest(ctx, request)
+	return err
+}
+
+// Close ends the session and stops the CLI. Messages not yet received are
+// discarded.
+func (s *Session) Close() error {
+	return s.CloseContext(context.Background())
+}
+
+// CloseContext ends the session like Close, bounding the CLI's shutdown by
+// ctx
+func (s *Session) CloseContext(ctx context.Context) error {
+	err := transport.DisconnectContext(ctx, s.trans)
+	s.cancel()
+	return err
+}
@@ -0,0 +1,22 @@
+package transport
+
+import "github.com/f-pisani/claude-code-sdk-go/internal/clock"
+
+// ClockProvider interface for options that supply the clock measuring stall
+// and disconnect timeouts. A nil clock means the system clock.
+type ClockProvider interface {
+	GetClock() clock.Clock
+}
+
+// ClockFrom returns the clock of options, or the system clock
+func ClockFrom(options interface{}) clock.Clock {
+	if provider, ok := options.(ClockProvider); ok {
+		return clock.Or(provider.GetClock())
+	}
+	return clock.Real
+}
+
+// clock returns the transport's clock
+func (t *SubprocessCLITransport) clock() clock.Clock {
+	return ClockFrom(t.options)
+}
@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// LoggerProvider interface for options that log the transport's activity:
+// process lifecycle at info level, every JSON line sent and received at
+// debug level, and output that cannot be parsed as warnings. A nil logger
+// disables logging.
+type LoggerProvider interface {
+	GetLogger() *slog.Logger
+}
+
+// LoggerFrom returns the logger of options, or one that discards everything
+func LoggerFrom(options interface{}) *slog.Logger {
+	if provider, ok := options.(LoggerProvider); ok {
+		if logger := provider.GetLogger(); logger != nil {
+			return logger
+		}
+	}
+	return discardLogger
+}
+
+// logger returns the transport's logger
+func (t *SubprocessCLITransport) logger() *slog.Logger {
+	return LoggerFrom(t.options)
+}
+
+// discardLogger drops every record
+var discardLogger = slog.New(discardHandler{})
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// redactedFlags are CLI flags whose values may carry prompts or credentials
+var redactedFlags = map[string]bool{
+	"--print":                true,
+	"--system-prompt":        true,
+	"--append-system-prompt": true,
+	"--mcp-config":           true,
+	"--settings":             true, // Inline settings may hold env values or an apiKeyHelper
+}
+
+// credentialFlags are the redactedFlags whose values may carry credentials.
+// They are masked even where prompts are kept, as in capture bundles.
+var credentialFlags = map[string]bool{
+	"--mcp-config": true, // Server headers may hold bearer tokens
+	"--settings":   true,
+}
+
+// redactArgs returns a copy of args with the values of flags replaced by
+// their length
+func redactArgs(args []string, flags map[string]bool) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i := 0; i < len(redacted)-1; i++ {
+		if flags[redacted[i]] {
+			redacted[i+1] = fmt.Sprintf("[redacted %d bytes]", len(redacted[i+1]))
+			i++
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,91 @@
+package transport
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultDisconnectTimeout is how long Disconnect waits for the CLI to exit
+// after interrupting it before killing it, unless the options set a timeout
+const defaultDisconnectTimeout = 5 * time.Second
+
+// disconnectTimeout returns how long Disconnect waits for the CLI to exit
+// after interrupting it
+func (t *SubprocessCLITransport) disconnectTimeout() time.Duration {
+	if opt, ok := t.options.(interface{ GetDisconnectTimeout() time.Duration }); ok {
+		if timeout := opt.GetDisconnectTimeout(); timeout > 0 {
+			return timeout
+		}
+	}
+	return defaultDisconnectTimeout
+}
+
+// shimScriptPattern matches the script an npm .cmd shim runs, written
+// relative to the shim's directory as "%dp0%\node_modules\...\cli.js" (or
+// "%~dp0\..." by older npm versions)
+var shimScriptPattern = regexp.MustCompile(`"%~?dp0%?\\([^"%]+\.(?:js|mjs|cjs))"`)
+
+// isBatchFile reports whether path is a Windows batch file, which can only
+// be run through cmd.exe
+func isBatchFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".cmd" || ext == ".bat"
+}
+
+// resolveNodeShim returns the node binary and script an npm .cmd shim
+// launches, so the CLI can be started directly rather than through cmd.exe.
+// Starting node directly avoids cmd.exe's argument parsing, which cannot
+// carry arbitrary prompts safely, and leaves no intermediate process that
+// outlives a kill. A node.exe next to the shim is preferred, as the shim
+// itself does.
+func resolveNodeShim(shim string, lookPath func(string) (string, error)) (node, script string, ok bool) {
+	data, err := os.ReadFile(shim)
+	if err != nil {
+		return "", "", false
+	}
+	m := shimScriptPattern.FindSubmatch(data)
+	if m == nil {
+		return "", "", false
+	}
+	dir := filepath.Dir(shim)
+	script = filepath.Join(dir, strings.ReplaceAll(string(m[1]), `\`, string(filepath.Separator)))
+	if _, err := os.Stat(script); err != nil {
+		return "", "", false
+	}
+
+	node = filepath.Join(dir, "node.exe")
+	if _, err := os.Stat(node); err != nil {
+		if node, err = lookPath("node"); err != nil {
+			return "", "", false
+		}
+	}
+	return node, script, true
+}
+
+// batchCommandLine returns the cmd.exe command line running the batch file
+// path with args. cmd.exe expands %VAR% and !VAR! even inside quotes and
+// cannot quote line breaks, so arguments containing them are rejected
+// rather than passed in a form the shim would misread.
+func batchCommandLine(path string, args []string) (string, error) {
+	parts := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{path}, args...) {
+		if strings.ContainsAny(arg, "%!\r\n\x00") {
+			return "", fmt.Errorf("cannot pass argument %q safely through cmd.exe; point the SDK at the CLI's node script or executable instead of %s", truncateArg(arg), filepath.Base(path))
+		}
+		parts = append(parts, `"`+strings.ReplaceAll(arg, `"`, `""`)+`"`)
+	}
+	// /s strips the outer quotes, keeping the inner quoting intact
+	return `/d /s /c "` + strings.Join(parts, " ") + `"`, nil
+}
+
+// truncateArg shortens arg for error messages
+func truncateArg(arg string) string {
+	if len(arg) > 40 {
+		return arg[:40] + "..."
+	}
+	return arg
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package transport
+
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
+// newCLICommand returns the command starting the CLI at path with args
+func newCLICommand(ctx context.Context, path string, args []string) (*exec.Cmd, error) {
+	return exec.CommandContext(ctx, path, args...), nil
+}
+
+// interruptProcess asks the CLI to exit gracefully
+func interruptProcess(p *os.Process) error {
+	return p.Signal(os.Interrupt)
+}
+
+// killProcess terminates the CLI immediately
+func killProcess(p *os.Process) error {
+	return p.Kill()
+}
@@ -0,0 +1,68 @@
+//go:build windows
+
+package transport
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// generateConsoleCtrlEvent delivers CTRL_BREAK to a process group, the
+// closest Windows equivalent of SIGINT
+var generateConsoleCtrlEvent = syscall.NewLazyDLL("kernel32.dll").NewProc("GenerateConsoleCtrlEvent")
+
+// newCLICommand returns the command starting the CLI at path with args. npm
+// .cmd shims are resolved to the node script they launch; other batch files
+// run through cmd.exe. The CLI gets its own process group so it can be sent
+// CTRL_BREAK without affecting this process.
+func newCLICommand(ctx context.Context, path string, args []string) (*exec.Cmd, error) {
+	var cmd *exec.Cmd
+	switch {
+	case !isBatchFile(path):
+		cmd = exec.CommandContext(ctx, path, args...)
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	default:
+		if node, script, ok := resolveNodeShim(path, exec.LookPath); ok {
+			cmd = exec.CommandContext(ctx, node, append([]string{script}, args...)...)
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+			break
+		}
+		line, err := batchCommandLine(path, args)
+		if err != nil {
+			return nil, err
+		}
+		comspec := os.Getenv("ComSpec")
+		if comspec == "" {
+			comspec = "cmd.exe"
+		}
+		cmd = exec.CommandContext(ctx, comspec)
+		cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: line}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+	cmd.Cancel = func() error {
+		return killProcess(cmd.Process)
+	}
+	return cmd, nil
+}
+
+// interruptProcess sends CTRL_BREAK to the CLI's process group. os.Interrupt
+// is not supported on Windows.
+func interruptProcess(p *os.Process) error {
+	if r, _, err := generateConsoleCtrlEvent.Call(syscall.CTRL_BREAK_EVENT, uintptr(p.Pid)); r == 0 {
+		return err
+	}
+	return nil
+}
+
+// killProcess terminates the CLI and any processes it started, so none of
+// them keeps its output pipes open. TerminateProcess is used if taskkill is
+// unavailable.
+func killProcess(p *os.Process) error {
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(p.Pid)).Run(); err == nil {
+		return nil
+	}
+	return p.Kill()
+}
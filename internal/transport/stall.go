@@ -0,0 +1,107 @@
+package transport
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/clock"
+)
+
+// stallWatchdog detects a CLI that stops writing to stdout without exiting.
+// Every read from the wrapped stdout resets the deadline. When the deadline
+// passes the process is first interrupted; if it still produces nothing for
+// another full timeout it is killed, which unblocks the pending read.
+type stallWatchdog struct {
+	process *os.Process
+	timeout time.Duration
+	clock   clock.Clock
+
+	lastRead atomic.Int64 // unix nanoseconds of the last completed read
+	stage    atomic.Int32 // 0: healthy, 1: interrupted, 2: killed
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// newStallWatchdog starts watching process with the given idle timeout,
+// measured on clk
+func newStallWatchdog(process *os.Process, timeout time.Duration, clk clock.Clock) *stallWatchdog {
+	w := &stallWatchdog{
+		process: process,
+		timeout: timeout,
+		clock:   clk,
+		done:    make(chan struct{}),
+	}
+	w.lastRead.Store(clk.Now().UnixNano())
+	go w.run()
+	return w
+}
+
+// wrap returns a reader that reports activity to the watchdog
+func (w *stallWatchdog) wrap(r io.Reader) io.Reader {
+	return &activityReader{r: r, w: w}
+}
+
+// fired reports whether the watchdog had to intervene
+func (w *stallWatchdog) fired() bool {
+	return w.stage.Load() > 0
+}
+
+// stop terminates the watchdog goroutine
+func (w *stallWatchdog) stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+	})
+}
+
+func (w *stallWatchdog) run() {
+	timer := w.clock.NewTimer(w.timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-timer.C():
+		}
+
+		idle := clock.Since(w.clock, time.Unix(0, w.lastRead.Load()))
+		if idle < w.timeout {
+			// Output arrived since the timer was armed
+			timer.Reset(w.timeout - idle)
+			continue
+		}
+
+		// Escalate: interrupt first, kill if the process stays silent
+		switch w.stage.Add(1) {
+		case 1:
+			if err := interruptProcess(w.process); err != nil {
+				w.stage.Store(2)
+				killProcess(w.process)
+				return
+			}
+			w.lastRead.Store(w.clock.Now().UnixNano())
+			timer.Reset(w.timeout)
+		default:
+			killProcess(w.process)
+			return
+		}
+	}
+}
+
+// activityReader records the time of every read on its watchdog
+type activityReader struct {
+	r io.Reader
+	w *stallWatchdog
+}
+
+func (a *activityReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	if n > 0 {
+		a.w.lastRead.Store(a.w.clock.Now().UnixNano())
+	}
+	return n, err
+}
@@ -0,0 +1,82 @@
+package transport
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/clock"
+)
+
+// StartupTimeoutProvider interface for options that bound how long the CLI
+// may take to report its init message. 0 disables the check.
+type StartupTimeoutProvider interface {
+	GetStartupTimeout() time.Duration
+}
+
+// startupWatchdog kills a CLI that has not reported its init system message
+// within the timeout of being armed. One-shot transports arm it when the
+// process is spawned; streaming sessions, whose CLI only initializes once
+// it receives a prompt, when the first user message is sent.
+type startupWatchdog struct {
+	process *os.Process
+	timeout time.Duration
+	clock   clock.Clock
+
+	armOnce  sync.Once
+	stopOnce sync.Once
+	done     chan struct{}
+	expired  atomic.Bool
+}
+
+// newStartupWatchdog creates an unarmed watchdog for process
+func newStartupWatchdog(process *os.Process, timeout time.Duration, clk clock.Clock) *startupWatchdog {
+	return &startupWatchdog{
+		process: process,
+		timeout: timeout,
+		clock:   clk,
+		done:    make(chan struct{}),
+	}
+}
+
+// arm starts the countdown; later calls have no effect
+func (w *startupWatchdog) arm() {
+	if w == nil {
+		return
+	}
+	w.armOnce.Do(func() {
+		timer := w.clock.NewTimer(w.timeout)
+		go func() {
+			defer timer.Stop()
+			select {
+			case <-w.done:
+			case <-timer.C():
+				w.expired.Store(true)
+				killProcess(w.process)
+			}
+		}()
+	})
+}
+
+// observe stops the watchdog once msg is the CLI's init message
+func (w *startupWatchdog) observe(msg map[string]interface{}) {
+	if w != nil && msg["type"] == "system" && msg["subtype"] == "init" {
+		w.stop()
+	}
+}
+
+// stop disarms the watchdog
+func (w *startupWatchdog) stop() {
+	if w == nil {
+		return
+	}
+	w.stopOnce.Do(func() {
+		close(w.done)
+	})
+}
+
+// fired reports whether the watchdog killed the process
+func (w *startupWatchdog) fired() bool {
+	return w != nil && w.expired.Load()
+}
@@ -0,0 +1,235 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/errors"
+)
+
+// NewStreamingCLITransport creates a subprocess transport for a long-lived
+// session. The CLI reads newline-delimited JSON from stdin
+// (--input-format stream-json) and keeps running across turns until stdin is
+// closed by Disconnect.
+func NewStreamingCLITransport(options interface{}, cliPath string) *SubprocessCLITransport {
+	t := NewSubprocessCLITransport("", options, cliPath)
+	t.streaming = true
+	return t
+}
+
+// SendMessage writes msg to the CLI's stdin as one line of JSON. It is only
+// available on streaming transports.
+func (t *SubprocessCLITransport) SendMessage(msg map[string]interface{}) error {
+	if !t.streaming {
+		return fmt.Errorf("transport does not accept messages after start")
+	}
+	t.mu.Lock()
+	stdin, startup := t.stdin, t.startup
+	t.mu.Unlock()
+	if stdin == nil {
+		return &errors.CLIConnectionError{
+			SDKError: errors.SDKError{Message: "Not connected"},
+		}
+	}
+	// The CLI initializes once it receives the first prompt
+	if msg["type"] == "user" {
+		startup.arm()
+	}
+	return t.writeMessage(stdin, msg)
+}
+
+// UserMessage builds the stream-json input line carrying content: a prompt
+// string or a list of content block objects
+func UserMessage(content interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "user",
+		"message": map[string]interface{}{
+			"role":    "user",
+			"content": content,
+		},
+		"parent_tool_use_id": nil,
+		"session_id":         "default",
+	}
+}
+
+// writeMessage writes msg to stdin as one line of JSON
+func (t *SubprocessCLITransport) writeMessage(stdin io.Writer, msg map[string]interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	t.logger().Debug("sent line", "line", string(data))
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := stdin.Write(append(data, '\n')); err != nil {
+		return &errors.CLIConnectionError{
+			SDKError: errors.SDKError{Message: fmt.Sprintf("Failed to write to Claude Code: %v", err)},
+		}
+	}
+	return nil
+}
+
+// ControlRequest sends a control protocol request, such as
+// {"subtype": "interrupt"}, and waits for the CLI to answer it. Responses
+// are only read while ReceiveMessages is being consumed.
+func (t *SubprocessCLITransport) ControlRequest(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+	id, replyCh := t.control.register()
+	defer t.control.forget(id)
+
+	if err := t.SendMessage(map[string]interface{}{
+		"type":       "control_request",
+		"request_id": id,
+		"request":    request,
+	}); err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply, ok := <-replyCh:
+		if !ok {
+			return nil, &errors.CLIConnectionError{
+				SDKError: errors.SDKError{Message: "Claude Code exited before answering control request"},
+			}
+		}
+		if reply["subtype"] == "error" {
+			msg, _ := reply["error"].(string)
+			return nil, fmt.Errorf("control request %v failed: %s", request["subtype"], msg)
+		}
+		body, _ := reply["response"].(map[string]interface{})
+		return body, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// EndInput closes the CLI's stdin without stopping it, so a streaming CLI
+// finishes the current turn and exits on its own
+func (t *SubprocessCLITransport) EndInput() error {
+	t.mu.Lock()
+	stdin := t.stdin
+	t.mu.Unlock()
+	if stdin == nil {
+		return nil
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	return stdin.Close()
+}
+
+// answerControlRequest answers a control_request line sent by the CLI using
+// the handler supplied by the options
+func (t *SubprocessCLITransport) answerControlRequest(ctx context.Context, data map[string]interface{}) {
+	id, _ := data["request_id"].(string)
+	request, _ := data["request"].(map[string]interface{})
+
+	var handler func(context.Context, map[string]interface{}) (map[string]interface{}, error)
+	if provider, ok := t.options.(ControlHandlerProvider); ok {
+		handler = provider.GetControlHandler()
+	}
+
+	var body map[string]interface{}
+	var err error
+	if handler == nil {
+		err = fmt.Errorf("unsupported control request: %v", request["subtype"])
+	} else {
+		body, err = handler(ctx, request)
+	}
+
+	response := map[string]interface{}{
+		"subtype":    "success",
+		"request_id": id,
+		"response":   body,
+	}
+	if err != nil {
+		response = map[string]interface{}{
+			"subtype":    "error",
+			"request_id": id,
+			"error":      err.Error(),
+		}
+	}
+	// A write failure means the CLI is gone and no longer waiting
+	_ = t.SendMessage(map[string]interface{}{
+		"type":     "control_response",
+		"response": response,
+	})
+}
+
+// controlRequests tracks control requests awaiting a response
+type controlRequests struct {
+	mu      sync.Mutex
+	counter int
+	pending map[string]chan map[string]interface{}
+	closed  bool
+}
+
+// register allocates a request ID and the channel its response arrives on
+func (c *controlRequests) register() (string, <-chan map[string]interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := c.newID()
+	ch := make(chan map[string]interface{}, 1)
+	if c.closed {
+		close(ch)
+		return id, ch
+	}
+	if c.pending == nil {
+		c.pending = make(map[string]chan map[string]interface{})
+	}
+	c.pending[id] = ch
+	return id, ch
+}
+
+// nextID allocates a request ID for a request whose response is not awaited
+func (c *controlRequests) nextID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.newID()
+}
+
+// newID allocates a request ID; c.mu must be held
+func (c *controlRequests) newID() string {
+	c.counter++
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("req_%d_%s", c.counter, hex.EncodeToString(suffix))
+}
+
+// forget drops a request that is no longer waited on
+func (c *controlRequests) forget(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, id)
+}
+
+// resolve delivers a control_response line to its waiting request
+func (c *controlRequests) resolve(data map[string]interface{}) {
+	response, _ := data["response"].(map[string]interface{})
+	id, _ := response["request_id"].(string)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ch, ok := c.pending[id]; ok {
+		ch <- response
+		delete(c.pending, id)
+	}
+}
+
+// abandon fails every pending and future request once the CLI output ends
+func (c *controlRequests) abandon() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.closed = true
+}
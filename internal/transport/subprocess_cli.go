@@ -25,12 +25,20 @@ type SubprocessCLITransport struct {
 	cliPath string
 	cwd     string
 
-	cmd    *exec.Cmd
-	stdout io.ReadCloser
-	stderr io.ReadCloser
+	cmd     *exec.Cmd
+	exit    *processExit
+	stdout  io.ReadCloser
+	stderr  io.ReadCloser
+	startup *startupWatchdog // nil unless a startup timeout applies
 
 	mu        sync.Mutex
 	connected bool
+
+	// Streaming mode keeps stdin open for user messages and control requests
+	streaming bool
+	stdin     io.WriteCloser
+	writeMu   sync.Mutex
+	control   controlRequests
 }
 
 // CwdProvider interface for options that provide a working directory
@@ -38,6 +46,69 @@ type CwdProvider interface {
 	GetCwd() string
 }
 
+// EnvProvider interface for options that set environment variables for the
+// CLI, as KEY=value pairs that replace inherited values
+type EnvProvider interface {
+	GetEnv() ([]string, error)
+}
+
+// InheritEnvProvider interface for options that pass the CLI the whole
+// parent environment instead of the filtered one
+type InheritEnvProvider interface {
+	GetInheritEnv() bool
+}
+
+// StreamCaptureProvider interface for options that want a copy of the CLI
+// command line and its raw output, e.g. for diagnostics bundles. Any of the
+// returned values may be nil.
+type StreamCaptureProvider interface {
+	GetStreamCapture() (onStart func(args, env []string, dir string), stdout, stderr io.Writer)
+}
+
+// ControlHandlerProvider interface for options that answer control requests
+// the CLI sends, such as can_use_tool permission checks. A nil handler
+// leaves them unanswered by the SDK.
+type ControlHandlerProvider interface {
+	GetControlHandler() func(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error)
+}
+
+// InitializeProvider interface for options that register SDK-side features,
+// such as hook callbacks, with a streaming CLI when it starts. A nil request
+// sends nothing.
+type InitializeProvider interface {
+	GetInitializeRequest() map[string]interface{}
+}
+
+// ReplayProvider interface for options that replay an earlier conversation
+// into a streaming CLI when it starts, as stream-json input lines. A nil
+// slice replays nothing.
+type ReplayProvider interface {
+	GetReplayMessages() ([]map[string]interface{}, error)
+}
+
+// PromptInputProvider interface for options that choose how a one-shot
+// prompt reaches the CLI: PromptInputArgv, PromptInputStdin or
+// PromptInputStreamJSON. "" passes it as an argument unless it is longer
+// than MaxArgvPrompt.
+type PromptInputProvider interface {
+	GetPromptInput() string
+}
+
+// Prompt input modes of one-shot transports
+const (
+	// PromptInputArgv passes the prompt as the argument of --print
+	PromptInputArgv = "argv"
+	// PromptInputStdin writes the prompt to the CLI's stdin as plain text
+	PromptInputStdin = "stdin"
+	// PromptInputStreamJSON writes the prompt to the CLI's stdin as one
+	// stream-json user message (--input-format stream-json)
+	PromptInputStreamJSON = "stream-json"
+)
+
+// MaxArgvPrompt is the longest prompt passed as an argument by default;
+// longer ones are written to stdin to stay clear of OS argument limits
+const MaxArgvPrompt = 64 * 1024
+
 // NewSubprocessCLITransport creates a new subprocess transport
 func NewSubprocessCLITransport(prompt string, options interface{}, cliPath string) *SubprocessCLITransport {
 	if cliPath == "" {
@@ -63,6 +134,12 @@ func NewSubprocessCLITransport(prompt string, options interface{}, cliPath strin
 	}
 }
 
+// FindCLI returns the path of the Claude CLI binary found on PATH or in the
+// usual install locations, or "" if there is none
+func FindCLI() string {
+	return findCLI()
+}
+
 // findCLI attempts to find the Claude CLI binary
 func findCLI() string {
 	// Check if claude is in PATH
@@ -149,9 +226,52 @@ func findCLI() string {
 	return ""
 }
 
+// outputFormat returns the CLI output format to request. A streaming
+// transport sends stream-json input, which the CLI only accepts with
+// stream-json output, so it ignores any override.
+func (t *SubprocessCLITransport) outputFormat() string {
+	if t.streaming {
+		return "stream-json"
+	}
+	if provider, ok := t.options.(interface{ GetOutputFormat() string }); ok {
+		if format := provider.GetOutputFormat(); format != "" {
+			return format
+		}
+	}
+	return "stream-json"
+}
+
+// promptInput returns how the prompt of a one-shot transport reaches the
+// CLI, or "" for a streaming transport
+func (t *SubprocessCLITransport) promptInput() string {
+	if t.streaming {
+		return ""
+	}
+	mode := ""
+	if provider, ok := t.options.(PromptInputProvider); ok {
+		mode = provider.GetPromptInput()
+	}
+	switch {
+	case mode == "" && len(t.prompt) > MaxArgvPrompt:
+		return PromptInputStdin
+	case mode == "":
+		return PromptInputArgv
+	case mode == PromptInputStreamJSON && t.outputFormat() != "stream-json":
+		// stream-json input requires stream-json output
+		return PromptInputStdin
+	}
+	return mode
+}
+
 // buildCommand constructs the CLI command with arguments
 func (t *SubprocessCLITransport) buildCommand() ([]string, error) {
-	cmd := []string{t.cliPath, "--output-format", "stream-json", "--verbose"}
+	outputFormat := t.outputFormat()
+	cmd := []string{t.cliPath, "--output-format", outputFormat}
+	// Verbose output is required for stream-json; with plain json it would
+	// turn the single result object into an array of every message
+	if outputFormat == "stream-json" {
+		cmd = append(cmd, "--verbose")
+	}
 
 	// Use the OptionsBuilder interface if available
 	if t.options != nil {
@@ -164,7 +284,18 @@ func (t *SubprocessCLITransport) buildCommand() ([]string, error) {
 		}
 	}
 
-	cmd = append(cmd, "--print", t.prompt)
+	switch t.promptInput() {
+	case "":
+		cmd = append(cmd, "--input-format", "stream-json")
+	case PromptInputArgv:
+		cmd = append(cmd, "--print", t.prompt)
+	case PromptInputStdin:
+		cmd = append(cmd, "--print")
+	case PromptInputStreamJSON:
+		cmd = append(cmd, "--print", "--input-format", "stream-json")
+	default:
+		return nil, fmt.Errorf("invalid prompt input mode %q", t.promptInput())
+	}
 	return cmd, nil
 }
 
@@ -180,22 +311,16 @@ func (t *SubprocessCLITransport) Connect(ctx context.Context) error {
 	if t.cliPath == "" {
 		// Check if Node.js is installed
 		if _, err := exec.LookPath("node"); err != nil {
-			errorMsg := "Claude Code requires Node.js, which is not installed.\n\n" +
-				"Install Node.js from: https://nodejs.org/\n" +
-				"\nAfter installing Node.js, install Claude Code:\n" +
-				"  npm install -g @anthropic-ai/claude-code"
-			return errors.NewCLINotFoundError(errorMsg, "")
+			notFound := errors.NewCLINotFoundError("Claude Code not found: Node.js is not installed", "")
+			notFound.HintKey = errors.HintNodeNotInstalled
+			return notFound
 		}
 
-		return errors.NewCLINotFoundError(
-			"Claude Code not found. Install with:\n"+
-				"  npm install -g @anthropic-ai/claude-code\n"+
-				"\nIf already installed locally, try:\n"+
-				"  export PATH=\"$HOME/node_modules/.bin:$PATH\"\n"+
-				"\nOr specify the path when creating transport:\n"+
-				"  NewSubprocessCLITransport(..., \"/path/to/claude\")",
-			"",
-		)
+		return errors.NewCLINotFoundError("Claude Code not found", "")
+	}
+
+	if err := t.checkCLIVersion(ctx); err != nil {
+		return err
 	}
 
 	cmdArgs, err := t.buildCommand()
@@ -203,7 +328,22 @@ func (t *SubprocessCLITransport) Connect(ctx context.Context) error {
 		return err
 	}
 
-	t.cmd = exec.CommandContext(ctx, cmdArgs[0], cmdArgs[1:]...)
+	// Load any replayed conversation before starting, so a bad transcript
+	// leaves no process behind
+	var replay []map[string]interface{}
+	if provider, ok := t.options.(ReplayProvider); ok && t.streaming {
+		if replay, err = provider.GetReplayMessages(); err != nil {
+			return err
+		}
+	}
+
+	t.cmd, err = newCLICommand(ctx, cmdArgs[0], cmdArgs[1:])
+	if err != nil {
+		return &errors.CLIConnectionError{
+			SDKError: errors.SDKError{Message: fmt.Sprintf("Failed to prepare Claude Code command: %v", err)},
+		}
+	}
+	t.exit = &processExit{done: make(chan struct{})}
 
 	// Validate and set working directory
 	if t.cwd != "" {
@@ -214,10 +354,30 @@ func (t *SubprocessCLITransport) Connect(ctx context.Context) error {
 		t.cmd.Dir = validatedCwd
 	}
 
-	// Set environment with filtering
+	// Set environment with filtering. Variables from the options take
+	// precedence over inherited ones.
 	filteredEnv := validation.FilterEnvironment(os.Environ())
+	if provider, ok := t.options.(InheritEnvProvider); ok && provider.GetInheritEnv() {
+		filteredEnv = os.Environ()
+	}
+	if provider, ok := t.options.(EnvProvider); ok {
+		extra, err := provider.GetEnv()
+		if err != nil {
+			return err
+		}
+		filteredEnv = overrideEnv(filteredEnv, extra)
+	}
 	t.cmd.Env = append(filteredEnv, "CLAUDE_CODE_ENTRYPOINT=sdk-go")
 
+	if provider, ok := t.options.(StreamCaptureProvider); ok {
+		if onStart, _, _ := provider.GetStreamCapture(); onStart != nil {
+			onStart(redactArgs(cmdArgs, credentialFlags), t.cmd.Env, t.cmd.Dir)
+		}
+	}
+
+	log := t.logger()
+	log.Info("starting Claude Code", "cli", t.cliPath, "args", redactArgs(cmdArgs[1:], redactedFlags), "dir", t.cmd.Dir, "streaming", t.streaming)
+
 	// Setup pipes
 	t.stdout, err = t.cmd.StdoutPipe()
 	if err != nil {
@@ -238,9 +398,31 @@ func (t *SubprocessCLITransport) Connect(ctx context.Context) error {
 		}
 	}
 
+	// A one-shot prompt sent over stdin is written once the CLI has started
+	promptInput := t.promptInput()
+	var promptIn io.WriteCloser
+	if t.streaming || promptInput != PromptInputArgv {
+		promptIn, err = t.cmd.StdinPipe()
+		if err != nil {
+			t.stdout.Close()
+			t.stderr.Close()
+			t.stdout, t.stderr = nil, nil
+			return &errors.CLIConnectionError{
+				SDKError: errors.SDKError{Message: "Failed to create stdin pipe"},
+			}
+		}
+		if t.streaming {
+			t.stdin = promptIn
+		}
+	}
+
 	// Start the process
 	if err := t.cmd.Start(); err != nil {
 		// Clean up pipes on start failure
+		if promptIn != nil {
+			promptIn.Close()
+			t.stdin = nil
+		}
 		if t.stdout != nil {
 			t.stdout.Close()
 			t.stdout = nil
@@ -249,6 +431,7 @@ func (t *SubprocessCLITransport) Connect(ctx context.Context) error {
 			t.stderr.Close()
 			t.stderr = nil
 		}
+		log.Error("failed to start Claude Code", "cli", t.cliPath, "error", err)
 		if strings.Contains(err.Error(), "executable file not found") {
 			return errors.NewCLINotFoundError(fmt.Sprintf("Claude Code not found at: %s", t.cliPath), t.cliPath)
 		}
@@ -257,41 +440,122 @@ func (t *SubprocessCLITransport) Connect(ctx context.Context) error {
 		}
 	}
 
+	log.Info("Claude Code started", "pid", t.cmd.Process.Pid)
+
+	// Only stream-json output reports an init message to wait for
+	t.startup = nil
+	if provider, ok := t.options.(StartupTimeoutProvider); ok && t.outputFormat() == "stream-json" {
+		if timeout := provider.GetStartupTimeout(); timeout > 0 {
+			t.startup = newStartupWatchdog(t.cmd.Process, timeout, t.clock())
+			if !t.streaming {
+				t.startup.arm()
+			}
+		}
+	}
+
+	// Write the prompt in the background: the CLI may not drain stdin before
+	// its output is read, and a large prompt would not fit the pipe buffer
+	if !t.streaming && promptIn != nil {
+		go func() {
+			defer promptIn.Close()
+			var err error
+			if promptInput == PromptInputStreamJSON {
+				err = t.writeMessage(promptIn, UserMessage(t.prompt))
+			} else {
+				_, err = io.WriteString(promptIn, t.prompt)
+			}
+			if err != nil {
+				log.Warn("failed to send prompt to Claude Code", "error", err)
+			}
+		}()
+	}
+
+	// Register SDK-side features before the first user message. The CLI's
+	// acknowledgement is not waited for, as nothing reads stdout yet.
+	if t.streaming {
+		if provider, ok := t.options.(InitializeProvider); ok {
+			if request := provider.GetInitializeRequest(); request != nil {
+				initRequest := map[string]interface{}{
+					"type":       "control_request",
+					"request_id": t.control.nextID(),
+					"request":    request,
+				}
+				if err := t.writeMessage(t.stdin, initRequest); err != nil {
+					t.stdin.Close()
+					killProcess(t.cmd.Process)
+					t.exit.wait(t.cmd)
+					return err
+				}
+			}
+		}
+		for _, msg := range replay {
+			if err := t.writeMessage(t.stdin, msg); err != nil {
+				t.stdin.Close()
+				killProcess(t.cmd.Process)
+				t.exit.wait(t.cmd)
+				return err
+			}
+		}
+	}
+
 	t.connected = true
 	return nil
 }
 
-// Disconnect terminates the subprocess
+// Disconnect terminates the subprocess, waiting up to the options'
+// disconnect timeout for it to exit after being interrupted
 func (t *SubprocessCLITransport) Disconnect() error {
+	return t.DisconnectContext(context.Background())
+}
+
+// DisconnectContext terminates the subprocess like Disconnect, killing it as
+// soon as ctx ends if it has not exited by then
+func (t *SubprocessCLITransport) DisconnectContext(ctx context.Context) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
 	if !t.connected || t.cmd == nil {
 		return nil
 	}
+	t.startup.stop()
+
+	// Closing stdin ends a streaming session
+	if t.stdin != nil {
+		t.writeMu.Lock()
+		t.stdin.Close()
+		t.writeMu.Unlock()
+	}
 
 	if t.cmd.Process != nil {
-		// Try graceful termination first
-		if err := t.cmd.Process.Signal(os.Interrupt); err == nil {
+		t.logger().Info("stopping Claude Code", "pid", t.cmd.Process.Pid)
+		// Try graceful termination first: SIGINT, or CTRL_BREAK on Windows
+		if err := interruptProcess(t.cmd.Process); err == nil {
 			// Wait a bit for graceful shutdown
 			// Make channel buffered to prevent goroutine leak
 			done := make(chan error, 1)
 			go func() {
-				done <- t.cmd.Wait()
+				done <- t.exit.wait(t.cmd)
 			}()
 
+			timer := t.clock().NewTimer(t.disconnectTimeout())
+			defer timer.Stop()
 			select {
 			case <-done:
 				// Process exited gracefully
-			case <-time.After(5 * time.Second):
+			case <-timer.C():
 				// Force kill after timeout
-				t.cmd.Process.Kill()
+				t.logger().Warn("Claude Code did not exit after interrupt, killing it", "pid", t.cmd.Process.Pid)
+				killProcess(t.cmd.Process)
+				<-done
+			case <-ctx.Done():
+				t.logger().Warn("Claude Code did not exit before the disconnect deadline, killing it", "pid", t.cmd.Process.Pid)
+				killProcess(t.cmd.Process)
 				<-done
 			}
 		} else {
 			// If we can't send interrupt, just kill it
-			t.cmd.Process.Kill()
-			t.cmd.Wait()
+			killProcess(t.cmd.Process)
+			t.exit.wait(t.cmd)
 		}
 	}
 
@@ -304,6 +568,7 @@ func (t *SubprocessCLITransport) Disconnect() error {
 
 	t.connected = false
 	t.cmd = nil
+	t.stdin = nil
 	t.stdout = nil
 	t.stderr = nil
 
@@ -334,27 +599,75 @@ func (t *SubprocessCLITransport) ReceiveMessages(ctx context.Context) (<-chan ma
 		return msgCh, errCh
 	}
 
+	// Snapshot process state so a concurrent Disconnect cannot swap it out
+	// from under the reader goroutines
+	t.mu.Lock()
+	cmd, exit, startup := t.cmd, t.exit, t.startup
+	var stdout, stderr io.Reader = t.stdout, t.stderr
+	t.mu.Unlock()
+
 	go func() {
 		// Ensure channels are always closed, even on panic
 		defer func() {
 			if r := recover(); r != nil {
 				errCh <- fmt.Errorf("panic in ReceiveMessages: %v", r)
 			}
+			t.control.abandon()
 			close(msgCh)
 			close(errCh)
 		}()
 
+		if cmd == nil || cmd.Process == nil {
+			return
+		}
+
+		// Copy raw output to any capture requested by the options
+		if provider, ok := t.options.(StreamCaptureProvider); ok {
+			_, stdoutCapture, stderrCapture := provider.GetStreamCapture()
+			if stdoutCapture != nil && stdout != nil {
+				stdout = io.TeeReader(stdout, stdoutCapture)
+			}
+			if stderrCapture != nil && stderr != nil {
+				stderr = io.TeeReader(stderr, stderrCapture)
+			}
+		}
+
 		// Collect stderr in background
-		stderrLines, stderrDone := t.collectStderr()
+		stderrCh := collectStderr(stderr)
+
+		// Watch for a CLI that stops producing output without exiting. A
+		// streaming session is legitimately silent between turns.
+		var watchdog *stallWatchdog
+		if opt, ok := t.options.(interface{ GetStallTimeout() time.Duration }); ok && !t.streaming {
+			if timeout := opt.GetStallTimeout(); timeout > 0 {
+				watchdog = newStallWatchdog(cmd.Process, timeout, t.clock())
+				stdout = watchdog.wrap(stdout)
+				defer watchdog.stop()
+			}
+		}
 
 		// Process stdout messages
-		if err := t.processStdout(ctx, msgCh, errCh); err != nil {
+		if err := t.processStdout(ctx, stdout, msgCh, errCh); err != nil {
+			return
+		}
+
+		startup.stop()
+		if startup.fired() {
+			t.logger().Warn("Claude Code did not start in time", "timeout", startup.timeout)
+			errCh <- errors.NewStartupTimeoutError(startup.timeout)
+			return
+		}
+
+		if watchdog != nil && watchdog.fired() {
+			t.logger().Warn("Claude Code stalled", "timeout", watchdog.timeout)
+			errCh <- errors.NewStallError(watchdog.timeout)
 			return
 		}
 
 		// Wait for process completion and handle any errors
-		<-stderrDone
-		t.handleProcessExit(stderrLines, errCh)
+		// Wait closes the pipes, so stderr must be fully read first
+		stderrLines := <-stderrCh
+		t.handleProcessExit(exit.wait(cmd), stderrLines, errCh)
 	}()
 
 	return msgCh, errCh
@@ -379,14 +692,60 @@ func (t *SubprocessCLITransport) handleNotConnected(msgCh chan map[string]interf
 	}()
 }
 
-// collectStderr collects stderr output in the background with resource limits
-func (t *SubprocessCLITransport) collectStderr() ([]string, <-chan struct{}) {
-	var stderrLines []string
-	stderrDone := make(chan struct{})
+// processExit lets the reader and Disconnect both wait for the process,
+// which exec.Cmd.Wait only allows once
+type processExit struct {
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+// wait waits for cmd to exit and returns the result of its single Wait call
+func (p *processExit) wait(cmd *exec.Cmd) error {
+	p.once.Do(func() {
+		go func() {
+			p.err = cmd.Wait()
+			close(p.done)
+		}()
+	})
+	<-p.done
+	return p.err
+}
+
+// overrideEnv returns env with the variables in extra added, replacing any
+// inherited value for the same key
+func overrideEnv(env, extra []string) []string {
+	if len(extra) == 0 {
+		return env
+	}
+	replaced := make(map[string]bool, len(extra))
+	for _, e := range extra {
+		replaced[strings.SplitN(e, "=", 2)[0]] = true
+	}
+	merged := make([]string, 0, len(env)+len(extra))
+	for _, e := range env {
+		if !replaced[strings.SplitN(e, "=", 2)[0]] {
+			merged = append(merged, e)
+		}
+	}
+	return append(merged, extra...)
+}
+
+// collectStderr collects stderr output in the background with resource limits.
+// The collected lines are delivered on the returned channel once stderr closes.
+func collectStderr(stderr io.Reader) <-chan []string {
+	linesCh := make(chan []string, 1)
 
 	go func() {
-		defer close(stderrDone)
-		scanner := bufio.NewScanner(t.stderr)
+		var stderrLines []string
+		defer func() {
+			linesCh <- stderrLines
+		}()
+		if stderr == nil {
+			return
+		}
+
+		scanner := bufio.NewScanner(stderr)
 		// Set max scan buffer to prevent OOM
 		scanner.Buffer(make([]byte, 0, 64*1024), validation.MaxJSONSize)
 
@@ -406,12 +765,15 @@ func (t *SubprocessCLITransport) collectStderr() ([]string, <-chan struct{}) {
 		}
 	}()
 
-	return stderrLines, stderrDone
+	return linesCh
 }
 
 // processStdout reads and processes stdout messages
-func (t *SubprocessCLITransport) processStdout(ctx context.Context, msgCh chan<- map[string]interface{}, errCh chan<- error) error {
-	scanner := bufio.NewScanner(t.stdout)
+func (t *SubprocessCLITransport) processStdout(ctx context.Context, stdout io.Reader, msgCh chan<- map[string]interface{}, errCh chan<- error) error {
+	if stdout == nil {
+		return fmt.Errorf("stdout closed")
+	}
+	scanner := bufio.NewScanner(stdout)
 	// Set max scan buffer to prevent OOM
 	scanner.Buffer(make([]byte, 0, 64*1024), validation.MaxJSONSize)
 
@@ -450,21 +812,40 @@ func (t *SubprocessCLITransport) processLine(ctx context.Context, line string, m
 		return fmt.Errorf("JSON too large")
 	}
 
+	log := t.logger()
+	log.Debug("received line", "line", line)
+
 	var data map[string]interface{}
 	if err := json.Unmarshal([]byte(line), &data); err != nil {
+		// Truncate line for error message to prevent excessive memory use
+		truncatedLine := line
+		if len(truncatedLine) > 200 {
+			truncatedLine = truncatedLine[:200] + "..."
+		}
 		// Only treat as error if it looks like JSON
 		if strings.HasPrefix(line, "{") || strings.HasPrefix(line, "[") {
-			// Truncate line for error message to prevent excessive memory use
-			truncatedLine := line
-			if len(truncatedLine) > 200 {
-				truncatedLine = truncatedLine[:200] + "..."
-			}
+			log.Warn("failed to parse CLI output", "line", truncatedLine, "error", err)
 			errCh <- errors.NewCLIJSONDecodeError(truncatedLine, err)
 			return err
 		}
+		log.Warn("skipped non-JSON CLI output", "line", truncatedLine)
 		return nil // Skip non-JSON lines
 	}
 
+	t.startup.observe(data)
+
+	// Replies to control requests go to the request that is waiting for them
+	if data["type"] == "control_response" {
+		t.control.resolve(data)
+		return nil
+	}
+
+	// Requests from the CLI are answered without blocking the reader
+	if data["type"] == "control_request" {
+		go t.answerControlRequest(ctx, data)
+		return nil
+	}
+
 	select {
 	case msgCh <- data:
 	case <-ctx.Done():
@@ -475,8 +856,12 @@ func (t *SubprocessCLITransport) processLine(ctx context.Context, line string, m
 }
 
 // handleProcessExit handles process exit and any associated errors
-func (t *SubprocessCLITransport) handleProcessExit(stderrLines []string, errCh chan<- error) {
-	if err := t.cmd.Wait(); err != nil {
+func (t *SubprocessCLITransport) handleProcessExit(waitErr error, stderrLines []string, errCh chan<- error) {
+	if waitErr == nil {
+		t.logger().Info("Claude Code exited", "exit_code", 0)
+	}
+	if err := waitErr; err != nil {
+		t.logger().Warn("Claude Code exited with error", "error", err, "stderr_lines", len(stderrLines))
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			exitCode := exitErr.ExitCode()
 			stderrOutput := strings.Join(stderrLines, "\n")
@@ -17,4 +17,33 @@ type Transport interface {
 
 	// IsConnected checks if transport is connected
 	IsConnected() bool
-}
\ No newline at end of file
+}
+
+// StreamingTransport is a Transport that stays open across turns and accepts
+// messages while connected
+type StreamingTransport interface {
+	Transport
+
+	// SendMessage writes one message to Claude
+	SendMessage(msg map[string]interface{}) error
+
+	// ControlRequest sends a control protocol request and waits for its
+	// response
+	ControlRequest(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error)
+}
+
+// ContextDisconnector is implemented by transports whose shutdown can be
+// bounded by a context, such as the subprocess transport, which kills the
+// CLI once the context ends
+type ContextDisconnector interface {
+	DisconnectContext(ctx context.Context) error
+}
+
+// DisconnectContext disconnects t, bounded by ctx if t implements
+// ContextDisconnector
+func DisconnectContext(ctx context.Context, t Transport) error {
+	if d, ok := t.(ContextDisconnector); ok {
+		return d.DisconnectContext(ctx)
+	}
+	return t.Disconnect()
+}
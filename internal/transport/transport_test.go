@@ -12,6 +12,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/f-pisani/claude-code-sdk-go/internal/clock"
 	sdkerrors "github.com/f-pisani/claude-code-sdk-go/internal/errors"
 )
 
@@ -231,6 +232,67 @@ func TestBuildCommand(t *testing.T) {
 	}
 }
 
+// promptInputOptions selects the prompt input mode and output format
+type promptInputOptions struct {
+	mode   string
+	format string
+}
+
+func (p *promptInputOptions) GetPromptInput() string {
+	return p.mode
+}
+
+func (p *promptInputOptions) GetOutputFormat() string {
+	return p.format
+}
+
+// TestBuildCommandPromptInput tests how each prompt input mode passes the
+// prompt
+func TestBuildCommandPromptInput(t *testing.T) {
+	long := strings.Repeat("x", MaxArgvPrompt+1)
+	tests := []struct {
+		name     string
+		options  *promptInputOptions
+		prompt   string
+		expected []string
+	}{
+		{"argv", &promptInputOptions{mode: PromptInputArgv}, "Hi", []string{"--verbose", "--print", "Hi"}},
+		{"stdin", &promptInputOptions{mode: PromptInputStdin}, "Hi", []string{"--verbose", "--print"}},
+		{"stream-json", &promptInputOptions{mode: PromptInputStreamJSON}, "Hi", []string{"--verbose", "--print", "--input-format", "stream-json"}},
+		{"stream-json needs stream-json output", &promptInputOptions{mode: PromptInputStreamJSON, format: "json"}, "Hi", []string{"json", "--print"}},
+		{"long prompt defaults to stdin", &promptInputOptions{}, long, []string{"--verbose", "--print"}},
+		{"long prompt in argv on request", &promptInputOptions{mode: PromptInputArgv}, long, []string{"--verbose", "--print", long}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &SubprocessCLITransport{cliPath: "/test/claude", prompt: tt.prompt, options: tt.options}
+			cmd, err := transport.buildCommand()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tail := cmd[len(cmd)-len(tt.expected):]
+			if strings.Join(tail, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("got args ending %q, want %q", tail, tt.expected)
+			}
+		})
+	}
+
+	transport := &SubprocessCLITransport{cliPath: "/test/claude", prompt: "Hi", options: &promptInputOptions{mode: "pipe"}}
+	if _, err := transport.buildCommand(); err == nil {
+		t.Error("expected error for an unknown prompt input mode")
+	}
+
+	streaming := NewStreamingCLITransport(&promptInputOptions{format: "json"}, "/test/claude")
+	cmd, err := streaming.buildCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := strings.Join(cmd, " "); !strings.Contains(args, "--output-format stream-json --verbose") {
+		t.Errorf("expected a streaming transport to ignore the output format override, got %q", args)
+	}
+}
+
 // TestSubprocessLifecycle tests the subprocess start/stop lifecycle
 func TestSubprocessLifecycle(t *testing.T) {
 	// Skip if running in CI or restricted environment
@@ -719,6 +781,101 @@ exit 0`
 	}
 }
 
+// envOptions sets environment variables for the subprocess transport
+type envOptions struct {
+	env []string
+}
+
+func (e *envOptions) GetEnv() ([]string, error) {
+	return e.env, nil
+}
+
+// TestEnvironmentOverride tests that variables from the options replace
+// inherited ones
+func TestEnvironmentOverride(t *testing.T) {
+	t.Setenv("LANG", "fr_FR.UTF-8")
+	t.Setenv("TZ", "Europe/Paris")
+
+	script := `#!/bin/sh
+echo "$LANG|$LC_ALL|$TZ"
+exit 0`
+
+	transport := &SubprocessCLITransport{
+		cliPath: createTestScript(t, script),
+		prompt:  "test",
+		cwd:     t.TempDir(),
+		options: &envOptions{env: []string{"LANG=C.UTF-8", "LC_ALL=C.UTF-8", "TZ=UTC"}},
+	}
+
+	if err := transport.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer transport.Disconnect()
+
+	line, err := bufio.NewReader(transport.stdout).ReadString('\n')
+	if err != nil && err != io.EOF {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if got := strings.TrimSpace(line); got != "C.UTF-8|C.UTF-8|UTC" {
+		t.Errorf("unexpected environment %q", got)
+	}
+	for _, e := range transport.cmd.Env {
+		if e == "TZ=Europe/Paris" || e == "LANG=fr_FR.UTF-8" {
+			t.Errorf("inherited %s was not replaced", e)
+		}
+	}
+}
+
+// inheritEnvOptions passes the whole parent environment to the subprocess
+type inheritEnvOptions struct {
+	envOptions
+}
+
+func (o *inheritEnvOptions) GetInheritEnv() bool {
+	return true
+}
+
+// TestEnvironmentInherit tests that filtered variables are only passed when
+// the options ask for the whole parent environment
+func TestEnvironmentInherit(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "sk-test")
+
+	script := `#!/bin/sh
+echo "key=$ANTHROPIC_API_KEY"
+exit 0`
+	cliPath := createTestScript(t, script)
+
+	for _, tt := range []struct {
+		name    string
+		options interface{}
+		want    string
+	}{
+		{"filtered", &envOptions{}, "key="},
+		{"inherited", &inheritEnvOptions{}, "key=sk-test"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &SubprocessCLITransport{
+				cliPath: cliPath,
+				prompt:  "test",
+				cwd:     t.TempDir(),
+				options: tt.options,
+			}
+			if err := transport.Connect(context.Background()); err != nil {
+				t.Fatalf("Connect failed: %v", err)
+			}
+			defer transport.Disconnect()
+
+			line, err := bufio.NewReader(transport.stdout).ReadString('\n')
+			if err != nil && err != io.EOF {
+				t.Fatalf("Failed to read output: %v", err)
+			}
+			if got := strings.TrimSpace(line); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 // MockTransport implements Transport interface for testing
 type MockTransport struct {
 	messages   []map[string]interface{}
@@ -783,6 +940,7 @@ func (m *MockTransport) IsConnected() bool {
 // TestTransportInterface verifies the Transport interface is properly implemented
 func TestTransportInterface(t *testing.T) {
 	var _ Transport = (*SubprocessCLITransport)(nil)
+	var _ StreamingTransport = (*SubprocessCLITransport)(nil)
 	var _ Transport = (*MockTransport)(nil)
 }
 
@@ -792,3 +950,410 @@ func TestBuildCLIArgs(t *testing.T) {
 	// due to circular dependencies. The test should be in types_test.go
 	t.Skip("BuildCLIArgs test should be in the main package")
 }
+
+// stallOptions configures a stall timeout for the subprocess transport,
+// measured on clock if set
+type stallOptions struct {
+	timeout time.Duration
+	clock   clock.Clock
+}
+
+func (s *stallOptions) GetStallTimeout() time.Duration {
+	return s.timeout
+}
+
+func (s *stallOptions) GetClock() clock.Clock {
+	return s.clock
+}
+
+// advanceWhileWaiting advances clk by step whenever a timer is pending,
+// until the returned stop function is called
+func advanceWhileWaiting(clk *clock.Fake, step time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+			}
+			if clk.Pending() > 0 {
+				clk.Advance(step)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// TestStallDetection tests that a silent CLI is interrupted and then killed
+func TestStallDetection(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{
+			name: "exits on interrupt",
+			script: `#!/bin/sh
+echo '{"type":"system","subtype":"init"}'
+exec sleep 30`,
+		},
+		{
+			name: "ignores interrupt",
+			script: `#!/bin/sh
+trap '' INT
+echo '{"type":"system","subtype":"init"}'
+while true; do sleep 0.1; done`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Now())
+			transport := &SubprocessCLITransport{
+				cliPath: createTestScript(t, tt.script),
+				prompt:  "test",
+				cwd:     t.TempDir(),
+				options: &stallOptions{timeout: time.Minute, clock: clk},
+			}
+
+			ctx := context.Background()
+			if err := transport.Connect(ctx); err != nil {
+				t.Fatalf("Connect failed: %v", err)
+			}
+			defer transport.Disconnect()
+			stop := advanceWhileWaiting(clk, time.Minute)
+			defer stop()
+
+			msgCh, errCh := transport.ReceiveMessages(ctx)
+
+			timeout := time.After(5 * time.Second)
+			var got []map[string]interface{}
+			for msgCh != nil {
+				select {
+				case msg, ok := <-msgCh:
+					if !ok {
+						msgCh = nil
+						continue
+					}
+					got = append(got, msg)
+				case <-timeout:
+					t.Fatal("stalled CLI was not torn down")
+				}
+			}
+
+			if len(got) != 1 {
+				t.Errorf("expected 1 message before the stall, got %d", len(got))
+			}
+
+			var stallErr *sdkerrors.StallError
+			if err := <-errCh; !errors.As(err, &stallErr) {
+				t.Fatalf("expected StallError, got %T: %v", err, err)
+			}
+			if stallErr.Timeout != time.Minute {
+				t.Errorf("expected timeout 1m, got %v", stallErr.Timeout)
+			}
+		})
+	}
+}
+
+// TestStallDetectionActiveOutput tests that steady output keeps the watchdog quiet
+func TestStallDetectionActiveOutput(t *testing.T) {
+	script := `#!/bin/sh
+for i in 1 2 3 4 5; do
+	echo '{"type":"system","subtype":"tick"}'
+	sleep 0.1
+done`
+
+	transport := &SubprocessCLITransport{
+		cliPath: createTestScript(t, script),
+		prompt:  "test",
+		cwd:     t.TempDir(),
+		options: &stallOptions{timeout: 300 * time.Millisecond},
+	}
+
+	ctx := context.Background()
+	if err := transport.Connect(ctx); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer transport.Disconnect()
+
+	msgCh, errCh := transport.ReceiveMessages(ctx)
+	count := 0
+	for range msgCh {
+		count++
+	}
+	if err := <-errCh; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if count != 5 {
+		t.Errorf("expected 5 messages, got %d", count)
+	}
+}
+
+type disconnectOptions struct {
+	timeout time.Duration
+	clock   clock.Clock
+}
+
+func (d *disconnectOptions) GetDisconnectTimeout() time.Duration {
+	return d.timeout
+}
+
+func (d *disconnectOptions) GetClock() clock.Clock {
+	return d.clock
+}
+
+// TestDisconnectDeadline tests that a CLI ignoring interrupts is killed once
+// the disconnect timeout or the context deadline passes
+func TestDisconnectDeadline(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	stop := advanceWhileWaiting(clk, time.Minute)
+	defer stop()
+
+	script := `#!/bin/sh
+trap '' INT
+echo '{"type":"system","subtype":"init"}'
+while true; do sleep 0.1; done`
+
+	tests := []struct {
+		name    string
+		options interface{}
+		ctx     func() (context.Context, context.CancelFunc)
+	}{
+		{
+			name:    "disconnect timeout",
+			options: &disconnectOptions{timeout: time.Minute, clock: clk},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+		},
+		{
+			name: "context deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 200*time.Millisecond)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &SubprocessCLITransport{
+				cliPath: createTestScript(t, script),
+				prompt:  "test",
+				cwd:     t.TempDir(),
+				options: tt.options,
+			}
+			if err := transport.Connect(context.Background()); err != nil {
+				t.Fatalf("Connect failed: %v", err)
+			}
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			start := time.Now()
+			if err := DisconnectContext(ctx, transport); err != nil {
+				t.Fatalf("DisconnectContext failed: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("expected the CLI to be killed promptly, took %v", elapsed)
+			}
+			if transport.IsConnected() {
+				t.Error("transport should not be connected after DisconnectContext")
+			}
+		})
+	}
+}
+
+// startupOptions configures a startup timeout measured on clock
+type startupOptions struct {
+	timeout time.Duration
+	clock   clock.Clock
+}
+
+func (s *startupOptions) GetStartupTimeout() time.Duration {
+	return s.timeout
+}
+
+func (s *startupOptions) GetClock() clock.Clock {
+	return s.clock
+}
+
+// receiveError drains the transport's messages and returns its error
+func receiveError(t *testing.T, transport *SubprocessCLITransport) error {
+	t.Helper()
+	msgCh, errCh := transport.ReceiveMessages(context.Background())
+	timeout := time.After(5 * time.Second)
+	for msgCh != nil {
+		select {
+		case _, ok := <-msgCh:
+			if !ok {
+				msgCh = nil
+			}
+		case <-timeout:
+			t.Fatal("CLI was not torn down")
+		}
+	}
+	return <-errCh
+}
+
+// TestStartupTimeout tests that a CLI which never reports its init message
+// is killed once the startup timeout passes
+func TestStartupTimeout(t *testing.T) {
+	t.Run("silent CLI", func(t *testing.T) {
+		clk := clock.NewFake(time.Now())
+		transport := &SubprocessCLITransport{
+			cliPath: createTestScript(t, "#!/bin/sh\nexec sleep 30\n"),
+			prompt:  "test",
+			cwd:     t.TempDir(),
+			options: &startupOptions{timeout: time.Minute, clock: clk},
+		}
+		if err := transport.Connect(context.Background()); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer transport.Disconnect()
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+
+		var startupErr *sdkerrors.StartupTimeoutError
+		if err := receiveError(t, transport); !errors.As(err, &startupErr) || startupErr.Timeout != time.Minute {
+			t.Fatalf("expected StartupTimeoutError, got %T: %v", err, err)
+		}
+	})
+
+	t.Run("init reported", func(t *testing.T) {
+		transport := &SubprocessCLITransport{
+			cliPath: createTestScript(t, `#!/bin/sh
+echo '{"type":"system","subtype":"init"}'
+sleep 0.2
+echo '{"type":"result","subtype":"success"}'`),
+			prompt:  "test",
+			cwd:     t.TempDir(),
+			options: &startupOptions{timeout: 5 * time.Second},
+		}
+		if err := transport.Connect(context.Background()); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer transport.Disconnect()
+		if err := receiveError(t, transport); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("streaming session armed by first prompt", func(t *testing.T) {
+		clk := clock.NewFake(time.Now())
+		transport := NewStreamingCLITransport(&startupOptions{timeout: time.Minute, clock: clk}, createTestScript(t, "#!/bin/sh\nexec sleep 30\n"))
+		if err := transport.Connect(context.Background()); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer transport.Disconnect()
+		if clk.Pending() != 0 {
+			t.Fatal("startup timeout armed before the first prompt")
+		}
+		if err := transport.SendMessage(UserMessage("hello")); err != nil {
+			t.Fatal(err)
+		}
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+
+		var startupErr *sdkerrors.StartupTimeoutError
+		if err := receiveError(t, transport); !errors.As(err, &startupErr) {
+			t.Fatalf("expected StartupTimeoutError, got %T: %v", err, err)
+		}
+	})
+}
+
+// TestStreamingBuildCommand tests that streaming transports read stdin
+// instead of taking the prompt as an argument
+func TestStreamingBuildCommand(t *testing.T) {
+	transport := NewStreamingCLITransport(nil, "/usr/bin/claude")
+	cmd, err := transport.buildCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(cmd, " ")
+	if !strings.Contains(joined, "--input-format stream-json") || strings.Contains(joined, "--print") {
+		t.Errorf("unexpected streaming command: %v", cmd)
+	}
+
+	if err := NewSubprocessCLITransport("hi", nil, "/usr/bin/claude").SendMessage(map[string]interface{}{}); err == nil {
+		t.Error("expected SendMessage to fail on a one-shot transport")
+	}
+}
+
+func TestCRLFOutput(t *testing.T) {
+	transport := &SubprocessCLITransport{}
+	stdout := strings.NewReader("{\"type\":\"system\",\"subtype\":\"init\"}\r\n\r\n{\"type\":\"result\",\"subtype\":\"success\"}\r\n")
+	msgCh := make(chan map[string]interface{}, 10)
+	errCh := make(chan error, 10)
+	if err := transport.processStdout(context.Background(), stdout, msgCh, errCh); err != nil {
+		t.Fatal(err)
+	}
+	close(msgCh)
+	close(errCh)
+	for err := range errCh {
+		t.Errorf("unexpected error %v", err)
+	}
+	var types []string
+	for msg := range msgCh {
+		types = append(types, msg["type"].(string))
+	}
+	if strings.Join(types, ",") != "system,result" {
+		t.Errorf("expected system and result messages, got %v", types)
+	}
+}
+
+func TestResolveNodeShim(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "node_modules", "@anthropic-ai", "claude-code", "cli.js")
+	if err := os.MkdirAll(filepath.Dir(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(script, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Tail of the shim npm writes for the CLI
+	shim := filepath.Join(dir, "claude.cmd")
+	content := "@ECHO off\r\nSETLOCAL\r\nCALL :find_dp0\r\n" +
+		"IF EXIST \"%dp0%\\node.exe\" (\r\n  SET \"_prog=%dp0%\\node.exe\"\r\n) ELSE (\r\n  SET \"_prog=node\"\r\n)\r\n" +
+		"endLocal & goto #_undefined_# 2>NUL || title %COMSPEC% & \"%_prog%\"  \"%dp0%\\node_modules\\@anthropic-ai\\claude-code\\cli.js\" %*\r\n"
+	if err := os.WriteFile(shim, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lookPath := func(string) (string, error) { return "/usr/bin/node", nil }
+
+	node, resolved, ok := resolveNodeShim(shim, lookPath)
+	if !ok || node != "/usr/bin/node" || resolved != script {
+		t.Errorf("resolveNodeShim = %q, %q, %v; want /usr/bin/node, %q", node, resolved, ok, script)
+	}
+
+	// A node.exe shipped next to the shim is preferred
+	if err := os.WriteFile(filepath.Join(dir, "node.exe"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if node, _, _ := resolveNodeShim(shim, lookPath); node != filepath.Join(dir, "node.exe") {
+		t.Errorf("expected the bundled node.exe, got %q", node)
+	}
+
+	if err := os.WriteFile(shim, []byte("@echo off\r\nclaude.exe %*\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := resolveNodeShim(shim, lookPath); ok {
+		t.Error("expected a shim without a node script not to resolve")
+	}
+}
+
+func TestBatchCommandLine(t *testing.T) {
+	line, err := batchCommandLine(`C:\npm\claude.cmd`, []string{"--print", `say "hi" & exit`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `/d /s /c ""C:\npm\claude.cmd" "--print" "say ""hi"" & exit""`
+	if line != want {
+		t.Errorf("batchCommandLine = %s, want %s", line, want)
+	}
+
+	for _, arg := range []string{"100%", "line\nbreak", "!PATH!"} {
+		if _, err := batchCommandLine(`C:\npm\claude.cmd`, []string{arg}); err == nil {
+			t.Errorf("expected %q to be rejected", arg)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package transport
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/errors"
+	"github.com/f-pisani/claude-code-sdk-go/internal/validation"
+)
+
+// CLIVersionProvider interface for options that require a CLI version, as a
+// constraint such as ">=1.0.50, <2". An empty constraint accepts any CLI.
+type CLIVersionProvider interface {
+	GetRequiredCLIVersion() string
+}
+
+// checkCLIVersion runs `cliPath --version` and fails with an
+// IncompatibleCLIError unless it satisfies the options' required version
+func (t *SubprocessCLITransport) checkCLIVersion(ctx context.Context) error {
+	provider, ok := t.options.(CLIVersionProvider)
+	if !ok {
+		return nil
+	}
+	constraint := provider.GetRequiredCLIVersion()
+	if constraint == "" {
+		return nil
+	}
+	if err := validation.ValidateVersionConstraint(constraint); err != nil {
+		return err
+	}
+
+	version, err := CLIVersion(ctx, t.cliPath)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return errors.NewIncompatibleCLIError(t.cliPath, "", constraint)
+	}
+	if ok, _ := validation.VersionSatisfies(version, constraint); !ok {
+		return errors.NewIncompatibleCLIError(t.cliPath, version, constraint)
+	}
+	return nil
+}
+
+// cliVersionKey identifies one build of a CLI binary
+type cliVersionKey struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// cliVersions caches the versions CLIVersion has seen, so that repeated
+// checks do not start the CLI again until the binary changes
+var cliVersions sync.Map // cliVersionKey -> string
+
+// CLIVersion returns the version the CLI at cliPath reports with --version.
+// Results are cached until the binary's size or modification time changes.
+func CLIVersion(ctx context.Context, cliPath string) (string, error) {
+	info, err := os.Stat(cliPath)
+	if err != nil {
+		return "", err
+	}
+	key := cliVersionKey{path: cliPath, size: info.Size(), modTime: info.ModTime()}
+	if version, ok := cliVersions.Load(key); ok {
+		return version.(string), nil
+	}
+
+	output, err := exec.CommandContext(ctx, cliPath, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	version, err := validation.ParseVersion(string(output))
+	if err != nil {
+		return "", err
+	}
+	cliVersions.Store(key, version)
+	return version, nil
+}
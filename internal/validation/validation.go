@@ -2,8 +2,10 @@ package validation
 
 import (
 	"fmt"
+	"net/url"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 )
 
@@ -93,20 +95,83 @@ func ValidateModel(model string) error {
 	return fmt.Errorf("invalid model: %s (must start with 'claude-')", model)
 }
 
+// localePattern matches POSIX locale names such as C, en_US.UTF-8 or de_DE@euro
+var localePattern = regexp.MustCompile(`^(C|POSIX|[a-zA-Z]{2,3}(_[a-zA-Z]{2})?)(\.[a-zA-Z0-9-]+)?(@[a-zA-Z0-9]+)?$`)
+
+// timezonePattern matches IANA zone names such as Europe/Paris and POSIX TZ
+// strings such as UTC0 or EST5EDT
+var timezonePattern = regexp.MustCompile(`^:?[A-Za-z0-9_+\-/<>,.:]{1,100}$`)
+
+// ValidateLocale checks that locale is a well-formed locale name
+func ValidateLocale(locale string) error {
+	if !localePattern.MatchString(locale) {
+		return fmt.Errorf("invalid locale: %q", locale)
+	}
+	return nil
+}
+
+// ValidateTimezone checks that tz is a well-formed TZ value
+func ValidateTimezone(tz string) error {
+	if !timezonePattern.MatchString(tz) || strings.Contains(tz, "..") {
+		return fmt.Errorf("invalid timezone: %q", tz)
+	}
+	return nil
+}
+
+// noProxyPattern matches a NO_PROXY list of host names, domain suffixes,
+// addresses, CIDR ranges and optional ports, or "*"
+var noProxyPattern = regexp.MustCompile(`^(\*|[A-Za-z0-9.\-_:\[\]/*]+(\s*,\s*[A-Za-z0-9.\-_:\[\]/*]+)*)$`)
+
+// ValidateProxyURL checks that proxy is an absolute http, https or socks5
+// URL with a host
+func ValidateProxyURL(proxy string) error {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("invalid proxy URL %q: scheme must be http, https or socks5", u.Redacted())
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid proxy URL %q: missing host", u.Redacted())
+	}
+	return nil
+}
+
+// ValidateNoProxy checks that noProxy is a comma-separated host list
+func ValidateNoProxy(noProxy string) error {
+	if !noProxyPattern.MatchString(noProxy) {
+		return fmt.Errorf("invalid no-proxy list: %q", noProxy)
+	}
+	return nil
+}
+
 // ValidatePath validates and cleans a file path
 func ValidatePath(path string) (string, error) {
+	return validatePath(path, runtime.GOOS == "windows")
+}
+
+// validatePath implements ValidatePath for the given platform flavour so the
+// Windows rules can be exercised on any host
+func validatePath(path string, windows bool) (string, error) {
 	if path == "" {
 		return "", fmt.Errorf("path cannot be empty")
 	}
-	
+
+	if windows {
+		return validateWindowsPath(path)
+	}
+
 	// Clean the path to remove any .. or . components
 	cleaned := filepath.Clean(path)
-	
+
 	// Check for path traversal attempts
-	if strings.Contains(cleaned, "..") {
+	if hasParentComponent(cleaned, "/") {
 		return "", fmt.Errorf("path traversal detected")
 	}
-	
+
 	// Ensure the path is absolute
 	if !filepath.IsAbs(cleaned) {
 		var err error
@@ -115,10 +180,135 @@ func ValidatePath(path string) (string, error) {
 			return "", fmt.Errorf("failed to resolve absolute path: %w", err)
 		}
 	}
-	
+
 	return cleaned, nil
 }
 
+// validateWindowsPath validates a path using Windows semantics: drive letters,
+// UNC shares and both separator styles
+func validateWindowsPath(path string) (string, error) {
+	volume := windowsVolume(path)
+
+	// "C:foo" is relative to the current directory of drive C, which a
+	// subprocess does not share with us
+	if len(volume) == 2 && (len(path) == 2 || !isWindowsSeparator(path[2])) {
+		return "", fmt.Errorf("drive-relative path not supported: %s", path)
+	}
+
+	cleaned := cleanWindowsPath(path)
+	if hasParentComponent(cleaned[len(windowsVolume(cleaned)):], `\`) {
+		return "", fmt.Errorf("path traversal detected")
+	}
+
+	if !isWindowsAbs(cleaned) {
+		abs, err := filepath.Abs(cleaned)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve absolute path: %w", err)
+		}
+		cleaned = abs
+	}
+
+	return cleaned, nil
+}
+
+// isWindowsAbs reports whether path is absolute under Windows rules, i.e. it
+// is a UNC path or starts with a drive letter followed by a separator
+func isWindowsAbs(path string) bool {
+	volume := windowsVolume(path)
+	if volume == "" {
+		return false
+	}
+	if len(volume) > 2 {
+		return true // UNC
+	}
+	return len(path) > 2 && isWindowsSeparator(path[2])
+}
+
+// windowsVolume returns the leading volume name of a Windows path: "C:" for
+// drive paths or `\\server\share` for UNC paths
+func windowsVolume(path string) string {
+	if len(path) >= 2 && path[1] == ':' && isASCIILetter(path[0]) {
+		return path[:2]
+	}
+
+	if len(path) < 5 || !isWindowsSeparator(path[0]) || !isWindowsSeparator(path[1]) || isWindowsSeparator(path[2]) {
+		return ""
+	}
+
+	// \\server\share: find the end of the server and share components
+	n := 3
+	for n < len(path) && !isWindowsSeparator(path[n]) {
+		n++
+	}
+	if n >= len(path)-1 {
+		return ""
+	}
+	n++
+	if isWindowsSeparator(path[n]) {
+		return ""
+	}
+	for n < len(path) && !isWindowsSeparator(path[n]) {
+		n++
+	}
+	return path[:n]
+}
+
+// cleanWindowsPath is the Windows equivalent of filepath.Clean that works on
+// any host: separators are normalized to backslashes and . and .. elements
+// are resolved lexically without climbing above the volume root
+func cleanWindowsPath(path string) string {
+	volume := windowsVolume(path)
+	rest := strings.ReplaceAll(path[len(volume):], "/", `\`)
+	rooted := strings.HasPrefix(rest, `\`) || len(volume) > 2
+
+	var parts []string
+	for _, part := range strings.Split(rest, `\`) {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(parts) > 0 && parts[len(parts)-1] != ".." {
+				parts = parts[:len(parts)-1]
+			} else if !rooted {
+				parts = append(parts, part)
+			}
+		default:
+			parts = append(parts, part)
+		}
+	}
+
+	volume = strings.ReplaceAll(volume, "/", `\`)
+	joined := strings.Join(parts, `\`)
+	switch {
+	case rooted:
+		return volume + `\` + joined
+	case joined == "":
+		return volume + "."
+	default:
+		return volume + joined
+	}
+}
+
+// hasParentComponent reports whether any element of path is ".."
+func hasParentComponent(path string, separators string) bool {
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return strings.ContainsRune(separators, r)
+	}) {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func isWindowsSeparator(c byte) bool {
+	return c == '\\' || c == '/'
+}
+
+func isASCIILetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
 // ValidateWorkingDirectory validates a working directory path
 func ValidateWorkingDirectory(dir string) (string, error) {
 	if dir == "" {
@@ -128,6 +318,11 @@ func ValidateWorkingDirectory(dir string) (string, error) {
 	return ValidatePath(dir)
 }
 
+// pathPattern matches UNC paths, drive-letter paths using either separator
+// and Unix absolute paths, in that order so a Windows path is replaced as a
+// whole instead of leaving its volume behind
+var pathPattern = regexp.MustCompile(`(\\\\[^\s\\/]+[\\/][^\s]+|\b[A-Za-z]:[\\/][^\s]*|/[^\s]+)`)
+
 // TruncateError sanitizes error messages to prevent information disclosure
 func TruncateError(err error, maxLength int) string {
 	if err == nil {
@@ -137,8 +332,6 @@ func TruncateError(err error, maxLength int) string {
 	msg := err.Error()
 	
 	// Remove any file paths that might expose system information
-	// This is a simple implementation - in production you might want more sophisticated filtering
-	pathPattern := regexp.MustCompile(`(/[^\s]+|[A-Za-z]:\\[^\s]+)`)
 	msg = pathPattern.ReplaceAllString(msg, "[path]")
 	
 	if len(msg) > maxLength {
@@ -148,6 +341,18 @@ func TruncateError(err error, maxLength int) string {
 	return msg
 }
 
+// ValidateEnvVar checks that key and value can be passed to a child process
+// as an environment variable
+func ValidateEnvVar(key, value string) error {
+	if key == "" || strings.ContainsAny(key, "=\x00") {
+		return fmt.Errorf("invalid environment variable name %q", key)
+	}
+	if strings.ContainsRune(value, 0) {
+		return fmt.Errorf("invalid value for environment variable %s: contains NUL", key)
+	}
+	return nil
+}
+
 // FilterEnvironment filters environment variables to only include safe ones
 func FilterEnvironment(env []string) []string {
 	// Define a list of safe environment variable prefixes
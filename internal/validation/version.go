@@ -0,0 +1,227 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// versionPattern matches a semantic version such as 1.0.61 or 2.0.0-beta.1
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?`)
+
+// constraintPattern matches one comparison of a version constraint, where
+// minor and patch may be omitted
+var constraintPattern = regexp.MustCompile(`^(>=|<=|==|=|>|<|\^|~)?v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?$`)
+
+// version is a parsed semantic version
+type version struct {
+	parts      [3]int
+	prerelease string
+}
+
+// compare returns -1, 0 or 1 as v is older than, equal to or newer than w.
+// A prerelease sorts before its release; prereleases compare as strings.
+func (v version) compare(w version) int {
+	for i := range v.parts {
+		if v.parts[i] != w.parts[i] {
+			if v.parts[i] < w.parts[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.prerelease == w.prerelease:
+		return 0
+	case v.prerelease == "":
+		return 1
+	case w.prerelease == "":
+		return -1
+	case v.prerelease < w.prerelease:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// ParseVersion extracts the first semantic version from s, such as the
+// output of `claude --version`
+func ParseVersion(s string) (string, error) {
+	match := versionPattern.FindString(s)
+	if match == "" {
+		return "", fmt.Errorf("no version found in %q", strings.TrimSpace(s))
+	}
+	return match, nil
+}
+
+// comparison is one term of a constraint
+type comparison struct {
+	op string
+	v  version
+}
+
+func (c comparison) matches(v version) bool {
+	cmp := v.compare(c.v)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	default:
+		return cmp == 0
+	}
+}
+
+// parseConstraint parses a constraint into alternatives of comparisons that
+// must all hold. Terms are separated by commas or spaces and alternatives by
+// "||", e.g. ">=1.0.50, <2" or "^1.0 || ^2.0". Caret and tilde ranges follow
+// npm: ^1.2.3 allows 1.x.x from 1.2.3, ~1.2.3 allows 1.2.x from 1.2.3. A bare
+// or = version with omitted parts matches any value of them.
+func parseConstraint(constraint string) ([][]comparison, error) {
+	if strings.TrimSpace(constraint) == "" {
+		return nil, fmt.Errorf("empty version constraint")
+	}
+
+	var alternatives [][]comparison
+	for _, alternative := range strings.Split(constraint, "||") {
+		terms := strings.FieldsFunc(alternative, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(terms) == 0 {
+			return nil, fmt.Errorf("invalid version constraint %q: empty alternative", constraint)
+		}
+		terms = joinOperators(terms)
+
+		var comparisons []comparison
+		for _, term := range terms {
+			parsed, err := parseTerm(term)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+			}
+			comparisons = append(comparisons, parsed...)
+		}
+		alternatives = append(alternatives, comparisons)
+	}
+	return alternatives, nil
+}
+
+// joinOperators joins operators written apart from their version, as in
+// ">= 1.0", to the following term
+func joinOperators(terms []string) []string {
+	joined := terms[:0:0]
+	pending := ""
+	for _, term := range terms {
+		if strings.Trim(term, "<>=^~") == "" {
+			pending += term
+			continue
+		}
+		joined = append(joined, pending+term)
+		pending = ""
+	}
+	if pending != "" {
+		joined = append(joined, pending)
+	}
+	return joined
+}
+
+// parseTerm expands one constraint term into plain comparisons
+func parseTerm(term string) ([]comparison, error) {
+	m := constraintPattern.FindStringSubmatch(term)
+	if m == nil {
+		return nil, fmt.Errorf("cannot parse %q", term)
+	}
+
+	op := m[1]
+	var v version
+	given := 1
+	for i := 0; i < 3; i++ {
+		if m[2+i] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[2+i])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %q", term)
+		}
+		v.parts[i] = n
+		given = i + 1
+	}
+	v.prerelease = m[5]
+
+	// upper returns the first version past v with parts from index i on
+	// incremented away
+	upper := func(i int) version {
+		u := version{}
+		copy(u.parts[:i], v.parts[:i])
+		u.parts[i-1]++
+		// Exclude prereleases of the bound itself
+		u.prerelease = "0"
+		return u
+	}
+
+	switch op {
+	case "^":
+		// The first non-zero part given may not change
+		i := 1
+		for i < given && v.parts[i-1] == 0 {
+			i++
+		}
+		return []comparison{{">=", v}, {"<", upper(i)}}, nil
+	case "~":
+		i := 2
+		if given == 1 {
+			i = 1
+		}
+		return []comparison{{">=", v}, {"<", upper(i)}}, nil
+	case "", "=", "==":
+		if given < 3 {
+			return []comparison{{">=", v}, {"<", upper(given)}}, nil
+		}
+		return []comparison{{"=", v}}, nil
+	default:
+		return []comparison{{op, v}}, nil
+	}
+}
+
+// ValidateVersionConstraint checks that constraint can be parsed
+func ValidateVersionConstraint(constraint string) error {
+	_, err := parseConstraint(constraint)
+	return err
+}
+
+// VersionSatisfies reports whether the semantic version v satisfies
+// constraint
+func VersionSatisfies(v, constraint string) (bool, error) {
+	alternatives, err := parseConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+
+	m := versionPattern.FindStringSubmatch(v)
+	if m == nil || m[0] != v {
+		return false, fmt.Errorf("invalid version %q", v)
+	}
+	var parsed version
+	for i := 0; i < 3; i++ {
+		parsed.parts[i], _ = strconv.Atoi(m[1+i])
+	}
+	parsed.prerelease = m[4]
+
+	for _, comparisons := range alternatives {
+		ok := true
+		for _, c := range comparisons {
+			if !c.matches(parsed) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
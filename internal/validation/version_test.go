@@ -0,0 +1,76 @@
+package validation
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"1.0.61 (Claude Code)\n", "1.0.61", false},
+		{"claude v2.0.0-beta.1", "2.0.0-beta.1", false},
+		{"unknown", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseVersion(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionSatisfies(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"1.0.61", ">=1.0.50", true},
+		{"1.0.49", ">=1.0.50", false},
+		{"1.0.61", ">= 1.0.50, <2", true},
+		{"2.0.0", ">=1.0.50, <2", false},
+		{"2.0.0-beta.1", "<2", true},
+		{"1.9.3", "^1.2", true},
+		{"2.0.0-beta.1", "^1.2", false},
+		{"1.1.0", "^1.2", false},
+		{"0.2.9", "^0.2.3", true},
+		{"0.3.0", "^0.2.3", false},
+		{"1.2.9", "~1.2.3", true},
+		{"1.3.0", "~1.2.3", false},
+		{"1.4.2", "1.4", true},
+		{"1.5.0", "1.4", false},
+		{"1.0.61", "=1.0.61", true},
+		{"1.0.62", "1.0.61", false},
+		{"2.1.0", "^1.0 || ^2.0", true},
+		{"3.0.0", "^1.0 || ^2.0", false},
+		{"1.0.0-rc.1", ">=1.0.0", false},
+		{"1.0.0-rc.2", ">1.0.0-rc.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version+" "+tt.constraint, func(t *testing.T) {
+			got, err := VersionSatisfies(tt.version, tt.constraint)
+			if err != nil {
+				t.Fatalf("VersionSatisfies() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("VersionSatisfies(%q, %q) = %v, want %v", tt.version, tt.constraint, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateVersionConstraint(t *testing.T) {
+	for _, constraint := range []string{"", "latest", ">=", "^1.0 ||", ">=1.0.0.0", "1.x"} {
+		if err := ValidateVersionConstraint(constraint); err == nil {
+			t.Errorf("ValidateVersionConstraint(%q) expected error", constraint)
+		}
+	}
+}
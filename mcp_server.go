@@ -0,0 +1,169 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// sdkMcpProtocolVersion is the MCP protocol version SdkMcpServer speaks
+const sdkMcpProtocolVersion = "2024-11-05"
+
+// McpToolHandler implements an in-process MCP tool. args are the arguments
+// Claude passed, shaped by the tool's input schema. An error is reported to
+// Claude as a failed tool call.
+type McpToolHandler func(ctx context.Context, args map[string]interface{}) (McpToolResult, error)
+
+// McpTool is a tool served by an SdkMcpServer
+type McpTool struct {
+	Name        string
+	Description string
+	InputSchema map[string]interface{} // JSON Schema of the arguments; nil accepts none
+	Handler     McpToolHandler
+}
+
+// McpContent is one item of an MCP tool result
+type McpContent struct {
+	Type     string `json:"type"` // "text" or "image"
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"` // Base64 image data
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// McpToolResult is what an McpToolHandler returns
+type McpToolResult struct {
+	Content []McpContent
+	IsError bool
+}
+
+// Tool defines an MCP tool backed by a Go function
+//
+// Example:
+//
+//	add := Tool("add", "Add two numbers", map[string]interface{}{
+//	    "type": "object",
+//	    "properties": map[string]interface{}{
+//	        "a": map[string]interface{}{"type": "number"},
+//	        "b": map[string]interface{}{"type": "number"},
+//	    },
+//	    "required": []string{"a", "b"},
+//	}, func(ctx context.Context, args map[string]interface{}) (McpToolResult, error) {
+//	    sum := args["a"].(float64) + args["b"].(float64)
+//	    return TextResult(fmt.Sprint(sum)), nil
+//	})
+func Tool(name, description string, schema map[string]interface{}, handler McpToolHandler) McpTool {
+	return McpTool{Name: name, Description: description, InputSchema: schema, Handler: handler}
+}
+
+// TextResult returns a successful tool result holding text
+func TextResult(text string) McpToolResult {
+	return McpToolResult{Content: []McpContent{{Type: "text", Text: text}}}
+}
+
+// SdkMcpServer is an MCP server that runs inside the Go process, so custom
+// tools need no separate server binary. Register it in
+// Options.SdkMcpServers; its tools are then available to Claude as
+// mcp__<server name>__<tool name>. The CLI reaches it over the control
+// protocol, so queries using it send their prompt on stdin.
+type SdkMcpServer struct {
+	Name    string
+	Version string
+	tools   map[string]McpTool
+	order   []string
+}
+
+// NewSdkMcpServer creates an in-process MCP server serving tools
+func NewSdkMcpServer(name, version string, tools ...McpTool) *SdkMcpServer {
+	s := &SdkMcpServer{Name: name, Version: version, tools: make(map[string]McpTool, len(tools))}
+	for _, tool := range tools {
+		if _, dup := s.tools[tool.Name]; !dup {
+			s.order = append(s.order, tool.Name)
+		}
+		s.tools[tool.Name] = tool
+	}
+	return s
+}
+
+// handle answers one JSON-RPC message from the CLI
+func (s *SdkMcpServer) handle(ctx context.Context, message map[string]interface{}, clk Clock, timeout time.Duration) map[string]interface{} {
+	response := map[string]interface{}{"jsonrpc": "2.0", "id": message["id"]}
+
+	switch method := getString(message, "method"); method {
+	case "initialize":
+		response["result"] = map[string]interface{}{
+			"protocolVersion": sdkMcpProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]interface{}{"name": s.Name, "version": s.Version},
+		}
+	case "notifications/initialized":
+		response["result"] = map[string]interface{}{}
+	case "tools/list":
+		tools := make([]interface{}, 0, len(s.order))
+		for _, name := range s.order {
+			tool := s.tools[name]
+			schema := tool.InputSchema
+			if schema == nil {
+				schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+			}
+			tools = append(tools, map[string]interface{}{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"inputSchema": schema,
+			})
+		}
+		response["result"] = map[string]interface{}{"tools": tools}
+	case "tools/call":
+		params := getMap(message, "params")
+		name := getString(params, "name")
+		tool, ok := s.tools[name]
+		if !ok || tool.Handler == nil {
+			response["error"] = map[string]interface{}{"code": -32602, "message": fmt.Sprintf("Tool '%s' not found", name)}
+			break
+		}
+		response["result"] = s.call(ctx, tool, getMap(params, "arguments"), clk, timeout)
+	default:
+		response["error"] = map[string]interface{}{"code": -32601, "message": fmt.Sprintf("Method '%s' not found", method)}
+	}
+	return response
+}
+
+// call runs tool and returns its result in MCP form. Handler errors,
+// panics and timeouts, measured on clk, become error results.
+func (s *SdkMcpServer) call(ctx context.Context, tool McpTool, args map[string]interface{}, clk Clock, timeout time.Duration) map[string]interface{} {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	var result McpToolResult
+	var toolErr error
+	err := guardCallback(ctx, clk, "MCP tool "+tool.Name, timeout, func(ctx context.Context) {
+		result, toolErr = tool.Handler(ctx, args)
+	})
+	if err == nil {
+		err = toolErr
+	}
+	if err != nil {
+		result = McpToolResult{Content: []McpContent{{Type: "text", Text: err.Error()}}, IsError: true}
+	}
+
+	content := result.Content
+	if content == nil {
+		content = []McpContent{}
+	}
+	out := map[string]interface{}{"content": content}
+	if result.IsError {
+		out["isError"] = true
+	}
+	return out
+}
+
+// handleMcpMessage routes an mcp_message control request to its server
+func (o *Options) handleMcpMessage(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+	name := getString(request, "server_name")
+	server, ok := o.SdkMcpServers[name]
+	if !ok || server == nil {
+		return nil, fmt.Errorf("unknown SDK MCP server: %s", name)
+	}
+	return map[string]interface{}{
+		"mcp_response": server.handle(ctx, getMap(request, "message"), o.GetClock(), o.GetCallbackTimeout()),
+	}, nil
+}
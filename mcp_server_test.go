@@ -0,0 +1,108 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func calcServer() *SdkMcpServer {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"a": map[string]interface{}{"type": "number"},
+			"b": map[string]interface{}{"type": "number"},
+		},
+	}
+	return NewSdkMcpServer("calc", "1.0.0",
+		Tool("add", "Add two numbers", schema, func(ctx context.Context, args map[string]interface{}) (McpToolResult, error) {
+			a, _ := args["a"].(float64)
+			b, _ := args["b"].(float64)
+			return TextResult(fmt.Sprint(a + b)), nil
+		}),
+		Tool("fail", "Always fails", nil, func(ctx context.Context, args map[string]interface{}) (McpToolResult, error) {
+			return McpToolResult{}, errors.New("out of order")
+		}),
+	)
+}
+
+func TestQuerySdkMcpServer(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+case "$*" in
+*'--mcp-config {"mcpServers":{"calc":{"name":"calc","type":"sdk"}}}'*) ;;
+*) echo "error: unexpected arguments $*" >&2; exit 1 ;;
+esac
+read -r prompt
+echo '{"type":"control_request","request_id":"cli_1","request":{"subtype":"mcp_message","server_name":"calc","message":{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"add","arguments":{"a":2,"b":3}}}}}'
+read -r answer
+case "$answer" in
+*'"mcp_response":{"id":1,"jsonrpc":"2.0","result":{"content":[{"type":"text","text":"5"}]}}'*) sum=5 ;;
+*) sum="wrong: $answer" ;;
+esac
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"sum '"$sum"'"}]}}'
+echo '{"type":"result","subtype":"success","session_id":"s1"}'
+while read -r line; do :; done
+`)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := NewOptions()
+	opts.SdkMcpServers = map[string]*SdkMcpServer{"calc": calcServer()}
+	text, _, err := QueryText(ctx, "test", opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "sum 5" {
+		t.Errorf("Unexpected reply %q", text)
+	}
+}
+
+func TestSdkMcpServerHandle(t *testing.T) {
+	server := calcServer()
+	ctx := context.Background()
+	call := func(method string, params map[string]interface{}) map[string]interface{} {
+		return server.handle(ctx, map[string]interface{}{"jsonrpc": "2.0", "id": 7, "method": method, "params": params}, SystemClock, 0)
+	}
+
+	init := call("initialize", nil)["result"].(map[string]interface{})
+	if init["serverInfo"].(map[string]interface{})["name"] != "calc" {
+		t.Errorf("unexpected initialize result %v", init)
+	}
+
+	tools := call("tools/list", nil)["result"].(map[string]interface{})["tools"].([]interface{})
+	if len(tools) != 2 || tools[0].(map[string]interface{})["name"] != "add" {
+		t.Fatalf("unexpected tools %v", tools)
+	}
+	if schema := tools[1].(map[string]interface{})["inputSchema"].(map[string]interface{}); schema["type"] != "object" {
+		t.Errorf("expected default object schema, got %v", schema)
+	}
+
+	failed := call("tools/call", map[string]interface{}{"name": "fail"})["result"]
+	want := map[string]interface{}{"content": []McpContent{{Type: "text", Text: "out of order"}}, "isError": true}
+	if !reflect.DeepEqual(failed, want) {
+		t.Errorf("failed call = %v, want %v", failed, want)
+	}
+
+	if resp := call("tools/call", map[string]interface{}{"name": "missing"}); resp["error"] == nil {
+		t.Errorf("expected error for unknown tool, got %v", resp)
+	}
+	if resp := call("resources/list", nil); resp["error"].(map[string]interface{})["code"] != -32601 {
+		t.Errorf("expected method not found, got %v", resp)
+	}
+	if resp := call("tools/list", nil); resp["id"] != 7 {
+		t.Errorf("expected id to be echoed, got %v", resp["id"])
+	}
+}
+
+func TestSdkMcpServerConfigConflict(t *testing.T) {
+	opts := &Options{
+		McpServers:    map[string]McpServerConfig{"calc": {Transport: []string{"calc-server"}}},
+		SdkMcpServers: map[string]*SdkMcpServer{"calc": calcServer()},
+	}
+	if _, err := opts.BuildCLIArgs(); err == nil {
+		t.Error("expected error for a server configured twice")
+	}
+}
@@ -0,0 +1,100 @@
+package claudecode
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+// metadataKey is the context key for query metadata
+type metadataKey struct{}
+
+// labelsKey is the context key for cost allocation labels
+type labelsKey struct{}
+
+// WithMetadata returns a context carrying metadata for every query run under
+// it. Metadata is merged with any already present, with md taking precedence,
+// and is attached to the recordings, capture bundles and logs those queries
+// produce.
+//
+// Example:
+//
+//	ctx = WithMetadata(ctx, map[string]string{"request_id": id, "user": user})
+//	msgCh, errCh := Query(ctx, prompt, opts)
+func WithMetadata(ctx context.Context, md map[string]string) context.Context {
+	return withStringMap(ctx, metadataKey{}, md)
+}
+
+// MetadataFromContext returns a copy of the metadata attached to ctx, or nil
+// if there is none
+func MetadataFromContext(ctx context.Context) map[string]string {
+	return stringMapFromContext(ctx, metadataKey{})
+}
+
+// withMetadataLogger returns options whose Logger records the metadata
+// attached to ctx as a "metadata" group. The caller's options are left
+// untouched.
+func withMetadataLogger(ctx context.Context, options *Options) *Options {
+	md := MetadataFromContext(ctx)
+	if len(md) == 0 || options.GetLogger() == nil {
+		return options
+	}
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.String(k, md[k]))
+	}
+	opts := *options
+	opts.Logger = options.Logger.With(slog.Group("metadata", attrs...))
+	return &opts
+}
+
+// WithLabels returns a context carrying cost allocation labels such as team,
+// feature or ticket for every query run under it. Labels are merged like
+// metadata and flow into recordings and UsageTracker reports, so spend can be
+// charged back to the consumers of a shared service.
+//
+// Example:
+//
+//	ctx = WithLabels(ctx, map[string]string{"team": "search", "ticket": "SRCH-42"})
+func WithLabels(ctx context.Context, labels map[string]string) context.Context {
+	return withStringMap(ctx, labelsKey{}, labels)
+}
+
+// LabelsFromContext returns a copy of the labels attached to ctx, or nil if
+// there are none
+func LabelsFromContext(ctx context.Context) map[string]string {
+	return stringMapFromContext(ctx, labelsKey{})
+}
+
+// withStringMap merges m into the map stored in ctx under key
+func withStringMap(ctx context.Context, key interface{}, m map[string]string) context.Context {
+	if len(m) == 0 {
+		return ctx
+	}
+	merged := stringMapFromContext(ctx, key)
+	if merged == nil {
+		merged = make(map[string]string, len(m))
+	}
+	for k, v := range m {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, key, merged)
+}
+
+// stringMapFromContext returns a copy of the map stored in ctx under key
+func stringMapFromContext(ctx context.Context, key interface{}) map[string]string {
+	m, _ := ctx.Value(key).(map[string]string)
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package claudecode
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithMetadata(t *testing.T) {
+	t.Run("empty context", func(t *testing.T) {
+		if md := MetadataFromContext(context.Background()); md != nil {
+			t.Errorf("Expected nil metadata, got %v", md)
+		}
+	})
+
+	t.Run("merges with precedence to the newest values", func(t *testing.T) {
+		ctx := WithMetadata(context.Background(), map[string]string{"tenant": "acme", "user": "a"})
+		ctx = WithMetadata(ctx, map[string]string{"user": "b"})
+
+		want := map[string]string{"tenant": "acme", "user": "b"}
+		if md := MetadataFromContext(ctx); !reflect.DeepEqual(md, want) {
+			t.Errorf("MetadataFromContext() = %v, want %v", md, want)
+		}
+	})
+
+	t.Run("returned map is a copy", func(t *testing.T) {
+		ctx := WithMetadata(context.Background(), map[string]string{"tenant": "acme"})
+		MetadataFromContext(ctx)["tenant"] = "changed"
+		if MetadataFromContext(ctx)["tenant"] != "acme" {
+			t.Error("Modifying the returned metadata changed the context")
+		}
+	})
+
+	t.Run("recorded with queries", func(t *testing.T) {
+		installFakeCLI(t, `#!/bin/sh
+echo '{"type":"result","subtype":"success","result":"ok"}'
+`)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ctx = WithMetadata(ctx, map[string]string{"trace_id": "abc"})
+
+		rec := NewRecordingSession()
+		msgCh, _ := rec.Query(ctx, "test", nil)
+		for range msgCh {
+		}
+
+		turns := rec.Recording().Turns
+		if len(turns) != 1 || turns[0].Metadata["trace_id"] != "abc" {
+			t.Errorf("Expected metadata in recording, got %+v", turns)
+		}
+	})
+
+	t.Run("logged with queries", func(t *testing.T) {
+		installFakeCLI(t, `#!/bin/sh
+echo '{"type":"result","subtype":"success","result":"ok"}'
+`)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ctx = WithMetadata(ctx, map[string]string{"trace_id": "abc"})
+
+		var logs bytes.Buffer
+		opts := NewOptions()
+		opts.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+		logger := opts.Logger
+		if _, _, err := QueryText(ctx, "test", opts); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(logs.String(), "metadata.trace_id=abc") {
+			t.Errorf("Expected metadata in log, got:\n%s", logs.String())
+		}
+		if opts.Logger != logger {
+			t.Error("caller's logger was replaced")
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+)
+
+// QueryMiddleware inspects or rewrites a request before the CLI command is
+// built, e.g. to append an org-wide system prompt suffix, enforce a tool
+// policy or stamp metadata. Returning an error fails the query without
+// starting the CLI.
+//
+// Middleware receives copies of the request and its Options, with Metadata
+// and Labels already merged over those attached to the context, so assigning
+// fields never affects the caller. Slices and
+// other maps in Options are shared: replace them rather than modifying them
+// in place.
+type QueryMiddleware func(req *QueryRequest) error
+
+// middlewareKey is the context key for query middleware
+type middlewareKey struct{}
+
+// WithMiddleware returns a context whose queries and clients run mw after
+// any middleware already attached, so a service can apply organization-wide
+// policy to every request it handles.
+//
+// Example:
+//
+//	ctx = WithMiddleware(ctx, func(req *QueryRequest) error {
+//	    req.Options.AppendSystemPrompt += "\nFollow the ACME coding standards."
+//	    req.Options.DisallowedTools = append([]string{ToolBash}, req.Options.DisallowedTools...)
+//	    return nil
+//	})
+func WithMiddleware(ctx context.Context, mw ...QueryMiddleware) context.Context {
+	if len(mw) == 0 {
+		return ctx
+	}
+	existing := MiddlewareFromContext(ctx)
+	chain := make([]QueryMiddleware, 0, len(existing)+len(mw))
+	chain = append(append(chain, existing...), mw...)
+	return context.WithValue(ctx, middlewareKey{}, chain)
+}
+
+// MiddlewareFromContext returns the middleware attached to ctx, or nil
+func MiddlewareFromContext(ctx context.Context) []QueryMiddleware {
+	chain, _ := ctx.Value(middlewareKey{}).([]QueryMiddleware)
+	return chain
+}
+
+// applyMiddleware runs the context's middleware followed by extra on a copy
+// of req. It returns req itself when there is no middleware.
+func applyMiddleware(ctx context.Context, req *QueryRequest, extra []QueryMiddleware) (*QueryRequest, error) {
+	chain := MiddlewareFromContext(ctx)
+	if len(chain) == 0 && len(extra) == 0 {
+		return req, nil
+	}
+
+	copied := *req
+	options := NewOptions()
+	if req.Options != nil {
+		*options = *req.Options
+	}
+	copied.Options = options
+	copied.Metadata = mergeStringMaps(MetadataFromContext(ctx), req.Metadata)
+	copied.Labels = mergeStringMaps(LabelsFromContext(ctx), req.Labels)
+
+	for _, mw := range append(chain[:len(chain):len(chain)], extra...) {
+		if err := mw(&copied); err != nil {
+			return nil, fmt.Errorf("query middleware: %w", err)
+		}
+	}
+	if copied.Options == nil {
+		copied.Options = NewOptions()
+	}
+	return &copied, nil
+}
+
+// mergeStringMaps returns a new map holding base overridden by m. It is
+// never nil, so middleware can add entries.
+func mergeStringMaps(base, m map[string]string) map[string]string {
+	out := make(map[string]string, len(base)+len(m))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
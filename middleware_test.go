@@ -0,0 +1,121 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestQueryMiddleware(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+case "$*" in
+*"ACME standards"*) text=suffixed ;;
+*) text=plain ;;
+esac
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"'$text'"}]}}'
+echo '{"type":"result","subtype":"success","session_id":"s1"}'
+`)
+
+	suffix := func(req *QueryRequest) error {
+		req.Options.AppendSystemPrompt += "Follow the ACME standards."
+		return nil
+	}
+
+	t.Run("context middleware", func(t *testing.T) {
+		opts := NewOptions()
+		ctx := WithMiddleware(context.Background(), suffix)
+		text, _, err := QueryText(ctx, "test", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != "suffixed" {
+			t.Errorf("expected middleware to change the command, got %q", text)
+		}
+		if opts.AppendSystemPrompt != "" {
+			t.Errorf("middleware modified the caller's options: %q", opts.AppendSystemPrompt)
+		}
+
+		text, _, err = QueryText(context.Background(), "test", opts)
+		if err != nil || text != "plain" {
+			t.Errorf("expected plain answer without middleware, got %q, %v", text, err)
+		}
+	})
+
+	t.Run("request middleware", func(t *testing.T) {
+		msgCh, errCh := Do(context.Background(), &QueryRequest{Prompt: "test", Middleware: []QueryMiddleware{suffix}})
+		var text string
+		for msg := range msgCh {
+			if am, ok := msg.(AssistantMessage); ok {
+				text = am.Content[0].(TextBlock).Text
+			}
+		}
+		if err := <-errCh; err != nil {
+			t.Fatal(err)
+		}
+		if text != "suffixed" {
+			t.Errorf("expected %q, got %q", "suffixed", text)
+		}
+	})
+
+	t.Run("rejection", func(t *testing.T) {
+		denied := errors.New("bash is not allowed")
+		ctx := WithMiddleware(context.Background(), func(req *QueryRequest) error {
+			return denied
+		})
+		_, _, err := QueryText(ctx, "test", nil)
+		if !errors.Is(err, denied) {
+			t.Errorf("expected middleware error, got %v", err)
+		}
+	})
+
+	t.Run("client", func(t *testing.T) {
+		denied := errors.New("clients are disabled")
+		client := NewClient(nil)
+		client.Use(func(req *QueryRequest) error {
+			return denied
+		})
+		if err := client.Connect(context.Background()); !errors.Is(err, denied) {
+			t.Errorf("expected middleware error from Connect, got %v", err)
+		}
+	})
+}
+
+func TestApplyMiddleware(t *testing.T) {
+	ctx := WithMetadata(context.Background(), map[string]string{"tenant": "acme", "user": "ctx"})
+	var order []string
+	ctx = WithMiddleware(ctx, func(req *QueryRequest) error {
+		order = append(order, "context")
+		req.Metadata["stamped"] = "yes"
+		return nil
+	})
+
+	req := &QueryRequest{Prompt: "hi", Metadata: map[string]string{"user": "req"}}
+	got, err := applyMiddleware(ctx, req, []QueryMiddleware{func(req *QueryRequest) error {
+		order = append(order, "request")
+		req.Prompt = strings.ToUpper(req.Prompt)
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(order, ",") != "context,request" {
+		t.Errorf("unexpected middleware order %v", order)
+	}
+	if got.Prompt != "HI" || req.Prompt != "hi" {
+		t.Errorf("expected a rewritten copy, got %q (original %q)", got.Prompt, req.Prompt)
+	}
+	want := map[string]string{"tenant": "acme", "user": "req", "stamped": "yes"}
+	for k, v := range want {
+		if got.Metadata[k] != v {
+			t.Errorf("metadata[%q] = %q, want %q", k, got.Metadata[k], v)
+		}
+	}
+	if _, ok := req.Metadata["stamped"]; ok {
+		t.Error("middleware modified the caller's metadata")
+	}
+
+	if same, _ := applyMiddleware(context.Background(), req, nil); same != req {
+		t.Error("expected the request itself without middleware")
+	}
+}
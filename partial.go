@@ -0,0 +1,96 @@
+package claudecode
+
+import (
+	"errors"
+	"strings"
+)
+
+// PartialResult is what a query produced before it failed or was stopped
+type PartialResult struct {
+	// Text is the assistant text received so far, one message per line
+	Text string `json:"text"`
+	// ToolCalls are the tool uses the assistant requested, in order
+	ToolCalls []ToolUseBlock `json:"tool_calls,omitempty"`
+	// SessionID is the last session ID seen, which can be passed to
+	// Options.Resume to continue the conversation
+	SessionID string `json:"session_id,omitempty"`
+	// Messages is the number of messages received
+	Messages int `json:"messages"`
+}
+
+// NewPartialResult collects the partial result of messages, e.g. the
+// messages of a turn interrupted through Client
+func NewPartialResult(messages []Message) *PartialResult {
+	p := &PartialResult{}
+	for _, msg := range messages {
+		p.add(msg)
+	}
+	return p
+}
+
+// add accumulates msg into the partial result
+func (p *PartialResult) add(msg Message) {
+	p.Messages++
+	switch m := msg.(type) {
+	case AssistantMessage:
+		var text strings.Builder
+		for _, block := range m.Content {
+			switch b := block.(type) {
+			case TextBlock:
+				text.WriteString(b.Text)
+			case ToolUseBlock:
+				p.ToolCalls = append(p.ToolCalls, b)
+			}
+		}
+		if text.Len() > 0 {
+			if p.Text != "" {
+				p.Text += "\n"
+			}
+			p.Text += text.String()
+		}
+	case SystemMessage:
+		if id, ok := m.Data["session_id"].(string); ok && id != "" {
+			p.SessionID = id
+		}
+	case ResultMessage:
+		if m.SessionID != "" {
+			p.SessionID = m.SessionID
+		}
+	}
+}
+
+// PartialResultError is a query error together with the work the query
+// completed before failing. Query returns one whenever a failed query had
+// delivered messages; its message is that of the underlying error.
+type PartialResultError struct {
+	Err     error
+	Partial *PartialResult
+}
+
+// Error returns the message of the underlying error
+func (e *PartialResultError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *PartialResultError) Unwrap() error {
+	return e.Err
+}
+
+// PartialResultOf returns the partial result attached to err, or nil if
+// there is none
+//
+// Example:
+//
+//	if err := <-errCh; err != nil {
+//	    if partial := PartialResultOf(err); partial != nil && partial.SessionID != "" {
+//	        opts.Resume = partial.SessionID // pick up where the query stopped
+//	    }
+//	}
+func PartialResultOf(err error) *PartialResult {
+	var partialErr *PartialResultError
+	if errors.As(err, &partialErr) {
+		return partialErr.Partial
+	}
+	return nil
+}
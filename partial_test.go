@@ -0,0 +1,60 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueryPartialResult(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+echo '{"type":"system","subtype":"init","session_id":"sess-1"}'
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Reading"},{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"a.go"}}]}}'
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Half done"}]}}'
+echo '{"type": broken'
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msgCh, errCh := Query(ctx, "test", nil)
+	for range msgCh {
+	}
+	err := <-errCh
+
+	var decodeErr *CLIJSONDecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected underlying decode error, got %T: %v", err, err)
+	}
+	partial := PartialResultOf(err)
+	if partial == nil {
+		t.Fatal("expected partial result")
+	}
+	if partial.Text != "Reading\nHalf done" {
+		t.Errorf("unexpected text %q", partial.Text)
+	}
+	if len(partial.ToolCalls) != 1 || partial.ToolCalls[0].Name != "Read" {
+		t.Errorf("unexpected tool calls %+v", partial.ToolCalls)
+	}
+	if partial.SessionID != "sess-1" || partial.Messages != 3 {
+		t.Errorf("unexpected session %q or message count %d", partial.SessionID, partial.Messages)
+	}
+	if err.Error() != decodeErr.Error() {
+		t.Errorf("expected error message to be unchanged, got %q", err.Error())
+	}
+}
+
+func TestPartialResultOf(t *testing.T) {
+	if PartialResultOf(errors.New("plain")) != nil || PartialResultOf(nil) != nil {
+		t.Error("expected no partial result")
+	}
+
+	partial := NewPartialResult([]Message{
+		AssistantMessage{Content: []ContentBlock{TextBlock{Text: "so far"}}},
+		ResultMessage{Subtype: "error_during_execution", SessionID: "s2", CancelReason: CancelReasonUser},
+	})
+	if partial.Text != "so far" || partial.SessionID != "s2" || partial.Messages != 2 {
+		t.Errorf("unexpected partial result %+v", partial)
+	}
+}
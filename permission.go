@@ -0,0 +1,144 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/clock"
+)
+
+// PermissionBehavior is the outcome of a permission check
+type PermissionBehavior string
+
+const (
+	PermissionAllow PermissionBehavior = "allow"
+	PermissionDeny  PermissionBehavior = "deny"
+)
+
+// PermissionDecision answers the CLI's request to use a tool
+type PermissionDecision struct {
+	Behavior PermissionBehavior
+	// UpdatedInput replaces the tool input of an allowed call (nil keeps it)
+	UpdatedInput map[string]interface{}
+	// Message tells Claude why a call was denied
+	Message string
+	// Interrupt also stops the current turn when a call is denied
+	Interrupt bool
+}
+
+// AllowTool approves a tool call as requested
+func AllowTool() PermissionDecision {
+	return PermissionDecision{Behavior: PermissionAllow}
+}
+
+// AllowToolWithInput approves a tool call with input replacing the requested
+// input
+func AllowToolWithInput(input map[string]interface{}) PermissionDecision {
+	return PermissionDecision{Behavior: PermissionAllow, UpdatedInput: input}
+}
+
+// DenyTool rejects a tool call, telling Claude why
+func DenyTool(message string) PermissionDecision {
+	return PermissionDecision{Behavior: PermissionDeny, Message: message}
+}
+
+// CanUseToolFunc decides at runtime whether the CLI may run a tool. It is
+// called before each tool use that the permission mode and tool lists do not
+// already settle, and blocks that tool until it returns.
+//
+// Example:
+//
+//	opts.CanUseTool = func(ctx context.Context, tool string, input map[string]interface{}) PermissionDecision {
+//	    if tool == ToolBash && strings.Contains(fmt.Sprint(input["command"]), "rm -rf") {
+//	        return DenyTool("destructive commands are not allowed")
+//	    }
+//	    return AllowTool()
+//	}
+type CanUseToolFunc func(ctx context.Context, toolName string, input map[string]interface{}) PermissionDecision
+
+// GetControlHandler returns the handler answering the CLI's control
+// requests, or nil when no callback, hook or SDK MCP server is configured
+// and ReadOnly, which installs a hook, is unset
+func (o *Options) GetControlHandler() func(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+	if o == nil || (o.CanUseTool == nil && len(o.Hooks) == 0 && len(o.SdkMcpServers) == 0 && !o.ReadOnly) {
+		return nil
+	}
+	return o.handleControlRequest
+}
+
+// handleControlRequest answers one control request sent by the CLI
+func (o *Options) handleControlRequest(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+	switch request["subtype"] {
+	case "hook_callback":
+		return o.handleHookCallback(ctx, request)
+	case "mcp_message":
+		return o.handleMcpMessage(ctx, request)
+	case "can_use_tool":
+		if o.CanUseTool == nil {
+			return nil, fmt.Errorf("no CanUseTool callback configured")
+		}
+		tool, _ := request["tool_name"].(string)
+		input, _ := request["input"].(map[string]interface{})
+		var decision PermissionDecision
+		err := guardCallback(ctx, o.GetClock(), "CanUseTool", o.GetCallbackTimeout(), func(ctx context.Context) {
+			decision = o.CanUseTool(ctx, tool, input)
+		})
+		if err != nil {
+			// A failed callback denies the tool rather than stalling the CLI
+			return map[string]interface{}{"behavior": "deny", "message": err.Error(), "interrupt": false}, nil
+		}
+
+		switch decision.Behavior {
+		case PermissionAllow:
+			updated := decision.UpdatedInput
+			if updated == nil {
+				updated = input
+			}
+			return map[string]interface{}{"behavior": "allow", "updatedInput": updated}, nil
+		case PermissionDeny:
+			return map[string]interface{}{
+				"behavior":  "deny",
+				"message":   decision.Message,
+				"interrupt": decision.Interrupt,
+			}, nil
+		default:
+			return nil, fmt.Errorf("invalid permission behavior %q for tool %s", decision.Behavior, tool)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported control request: %v", request["subtype"])
+	}
+}
+
+// guardCallback runs fn, isolating the caller from its panics and, when
+// timeout is positive, from a callback that does not return in time on clk.
+// fn's context is canceled on timeout; a callback that ignores it keeps
+// running in the background but its result is discarded.
+func guardCallback(ctx context.Context, clk Clock, name string, timeout time.Duration, fn func(ctx context.Context)) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = clock.WithTimeout(ctx, clk, timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- NewCallbackPanicError(name, r)
+			}
+		}()
+		fn(ctx)
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if timeout > 0 && ctx.Err() == context.DeadlineExceeded {
+			return NewCallbackTimeoutError(name, timeout)
+		}
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,143 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryCanUseTool(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+case "$*" in
+*"--permission-prompt-tool stdio"*"--input-format stream-json"*) ;;
+*) echo "error: unexpected arguments $*" >&2; exit 1 ;;
+esac
+read -r prompt
+echo '{"type":"control_request","request_id":"cli_1","request":{"subtype":"can_use_tool","tool_name":"Bash","input":{"command":"rm -rf /"}}}'
+read -r answer
+case "$answer" in
+*'"request_id":"cli_1"'*'"behavior":"deny"'*'"message":"no deletes"'*) bash=denied ;;
+*) bash=allowed ;;
+esac
+echo '{"type":"control_request","request_id":"cli_2","request":{"subtype":"can_use_tool","tool_name":"Read","input":{"file_path":"secret.txt"}}}'
+read -r answer
+case "$answer" in
+*'"request_id":"cli_2"'*'"behavior":"allow"'*'"file_path":"README.md"'*) read=rewritten ;;
+*) read=unchanged ;;
+esac
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"bash '"$bash"', read '"$read"'"}]}}'
+echo '{"type":"result","subtype":"success","session_id":"s1"}'
+# Exit once the SDK ends the input, as with --print
+while read -r line; do :; done
+`)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var asked []string
+	opts := NewOptions()
+	opts.CanUseTool = func(ctx context.Context, tool string, input map[string]interface{}) PermissionDecision {
+		asked = append(asked, tool)
+		if tool == ToolBash {
+			return DenyTool("no deletes")
+		}
+		return AllowToolWithInput(map[string]interface{}{"file_path": "README.md"})
+	}
+
+	text, _, err := QueryText(ctx, "test", opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "bash denied, read rewritten" {
+		t.Errorf("Unexpected reply %q", text)
+	}
+	if !reflect.DeepEqual(asked, []string{ToolBash, ToolRead}) {
+		t.Errorf("Callback asked about %v", asked)
+	}
+}
+
+func TestHandleControlRequest(t *testing.T) {
+	opts := &Options{CanUseTool: func(ctx context.Context, tool string, input map[string]interface{}) PermissionDecision {
+		switch tool {
+		case ToolRead:
+			return AllowTool()
+		case ToolBash:
+			return PermissionDecision{Behavior: PermissionDeny, Message: "stop", Interrupt: true}
+		}
+		return PermissionDecision{}
+	}}
+	handler := opts.GetControlHandler()
+	ctx := context.Background()
+	input := map[string]interface{}{"file_path": "a.go"}
+
+	got, err := handler(ctx, map[string]interface{}{"subtype": "can_use_tool", "tool_name": ToolRead, "input": input})
+	if err != nil || !reflect.DeepEqual(got, map[string]interface{}{"behavior": "allow", "updatedInput": input}) {
+		t.Errorf("allow: got %v, %v", got, err)
+	}
+
+	got, err = handler(ctx, map[string]interface{}{"subtype": "can_use_tool", "tool_name": ToolBash})
+	if err != nil || !reflect.DeepEqual(got, map[string]interface{}{"behavior": "deny", "message": "stop", "interrupt": true}) {
+		t.Errorf("deny: got %v, %v", got, err)
+	}
+
+	if _, err := handler(ctx, map[string]interface{}{"subtype": "can_use_tool", "tool_name": ToolWrite}); err == nil || !strings.Contains(err.Error(), "invalid permission behavior") {
+		t.Errorf("Expected invalid behavior error, got %v", err)
+	}
+	if _, err := handler(ctx, map[string]interface{}{"subtype": "hook_callback"}); err == nil {
+		t.Error("Expected error for unsupported request")
+	}
+
+	if (&Options{}).GetControlHandler() != nil {
+		t.Error("Expected no handler without a callback")
+	}
+}
+
+func TestCanUseToolGuards(t *testing.T) {
+	request := map[string]interface{}{"subtype": "can_use_tool", "tool_name": ToolBash}
+
+	t.Run("panic", func(t *testing.T) {
+		opts := &Options{CanUseTool: func(context.Context, string, map[string]interface{}) PermissionDecision {
+			panic("boom")
+		}}
+		got, err := opts.GetControlHandler()(context.Background(), request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got["behavior"] != "deny" || !strings.Contains(got["message"].(string), "CanUseTool callback panicked: boom") {
+			t.Errorf("Expected a deny for the panic, got %v", got)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		clk := NewFakeClock(time.Now())
+		opts := &Options{
+			Clock:           clk,
+			CallbackTimeout: 30,
+			CanUseTool: func(ctx context.Context, _ string, _ map[string]interface{}) PermissionDecision {
+				<-ctx.Done()
+				return AllowTool()
+			},
+		}
+		go func() {
+			clk.BlockUntil(1)
+			clk.Advance(30 * time.Second)
+		}()
+		got, err := opts.GetControlHandler()(context.Background(), request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got["behavior"] != "deny" || !strings.Contains(got["message"].(string), "did not return within 30s") {
+			t.Errorf("Expected a deny for the timeout, got %v", got)
+		}
+	})
+
+	t.Run("error type", func(t *testing.T) {
+		err := guardCallback(context.Background(), SystemClock, "hook", 0, func(context.Context) { panic(42) })
+		var cbErr *CallbackError
+		if !errors.As(err, &cbErr) || cbErr.Callback != "hook" || cbErr.Panic != 42 {
+			t.Errorf("Expected CallbackError for the panic, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package claudecode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// profileExtendsKey names the parent profile in a profile file
+const profileExtendsKey = "extends"
+
+// profileNamePattern matches valid profile names
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Profiles are named Options bundles, so the behavior of queries can be
+// managed by editing files rather than code. Each profile is a JSON file
+// named <name>.json holding Options fields by their JSON names, e.g.
+// "model" or "allowed_tools", plus an optional "extends" naming a parent
+// profile. A profile's fields replace those of its parent; fields it does
+// not set are inherited. Options that are not serializable, such as
+// callbacks, are set in code on the Options a profile returns.
+//
+// Example, with base.json and ci-review.json in ./profiles:
+//
+//	{"model": "claude-sonnet-4-5", "max_turns": 10}
+//	{"extends": "base", "read_only": true, "append_system_prompt": "Review the diff."}
+//
+//	profiles, err := LoadProfiles("./profiles")
+//	UseProfiles(profiles)
+//	msgCh, errCh := Query(ctx, prompt, Profile("ci-review"))
+type Profiles struct {
+	resolved map[string]map[string]json.RawMessage
+}
+
+// LoadProfiles reads every profile in dir and resolves their inheritance.
+// Unknown fields, missing parents and cycles are reported.
+func LoadProfiles(dir string) (*Profiles, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]map[string]json.RawMessage, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		if !profileNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid profile name %q", name)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read profile %s: %w", name, err)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+			return nil, fmt.Errorf("profile %s must be a JSON object", name)
+		}
+		raw[name] = fields
+	}
+
+	p := &Profiles{resolved: make(map[string]map[string]json.RawMessage, len(raw))}
+	for name := range raw {
+		if _, err := p.resolve(name, raw, nil); err != nil {
+			return nil, err
+		}
+	}
+	for name := range p.resolved {
+		if _, err := p.options(name); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// resolve merges the fields of name over those of its ancestors. chain holds
+// the profiles being resolved, to detect cycles.
+func (p *Profiles) resolve(name string, raw map[string]map[string]json.RawMessage, chain []string) (map[string]json.RawMessage, error) {
+	if fields, ok := p.resolved[name]; ok {
+		return fields, nil
+	}
+	for _, seen := range chain {
+		if seen == name {
+			return nil, fmt.Errorf("profile inheritance cycle: %s -> %s", strings.Join(chain, " -> "), name)
+		}
+	}
+	own, ok := raw[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %s extends unknown profile %s", chain[len(chain)-1], name)
+	}
+
+	merged := make(map[string]json.RawMessage)
+	if parentJSON, ok := own[profileExtendsKey]; ok {
+		var parent string
+		if err := json.Unmarshal(parentJSON, &parent); err != nil {
+			return nil, fmt.Errorf("profile %s: %q must be a profile name", name, profileExtendsKey)
+		}
+		inherited, err := p.resolve(parent, raw, append(chain, name))
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range inherited {
+			merged[key] = value
+		}
+	}
+	for key, value := range own {
+		if key != profileExtendsKey {
+			merged[key] = value
+		}
+	}
+	p.resolved[name] = merged
+	return merged, nil
+}
+
+// options decodes the resolved profile name over NewOptions
+func (p *Profiles) options(name string) (*Options, error) {
+	fields, ok := p.resolved[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+
+	options := NewOptions()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(options); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+	return options, nil
+}
+
+// Names returns the names of the profiles, sorted
+func (p *Profiles) Names() []string {
+	names := make([]string, 0, len(p.resolved))
+	for name := range p.resolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns new Options for the profile name, which the caller may modify
+func (p *Profiles) Get(name string) (*Options, error) {
+	return p.options(name)
+}
+
+// Profile is Get for use as a Query argument: an unknown profile is
+// reported by the query, and by Options.Validate, instead of here
+func (p *Profiles) Profile(name string) *Options {
+	options, err := p.Get(name)
+	if err != nil {
+		return &Options{profileErr: err}
+	}
+	return options
+}
+
+var (
+	defaultProfilesMu sync.RWMutex
+	defaultProfiles   *Profiles
+)
+
+// UseProfiles makes p the profiles that Profile looks names up in
+func UseProfiles(p *Profiles) {
+	defaultProfilesMu.Lock()
+	defer defaultProfilesMu.Unlock()
+	defaultProfiles = p
+}
+
+// Profile returns new Options for the profile name of the profiles set with
+// UseProfiles, e.g. Query(ctx, prompt, Profile("ci-review")). An unknown
+// profile, or calling it before UseProfiles, makes the query fail.
+func Profile(name string) *Options {
+	defaultProfilesMu.RLock()
+	p := defaultProfiles
+	defaultProfilesMu.RUnlock()
+	if p == nil {
+		return &Options{profileErr: fmt.Errorf("profile %q requested before UseProfiles", name)}
+	}
+	return p.Profile(name)
+}
@@ -0,0 +1,93 @@
+package claudecode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeProfiles(t *testing.T, profiles map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range profiles {
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadProfiles(t *testing.T) {
+	dir := writeProfiles(t, map[string]string{
+		"base":      `{"model": "claude-sonnet-4-5", "max_turns": 10, "allowed_tools": ["Read", "Grep"]}`,
+		"ci-review": `{"extends": "base", "read_only": true, "max_turns": 3, "append_system_prompt": "Review the diff."}`,
+		"nightly":   `{"extends": "ci-review", "allowed_tools": ["Read"]}`,
+	})
+	profiles, err := LoadProfiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := profiles.Names(); !reflect.DeepEqual(names, []string{"base", "ci-review", "nightly"}) {
+		t.Errorf("Names() = %v", names)
+	}
+
+	opts, err := profiles.Get("nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Model != "claude-sonnet-4-5" || *opts.MaxTurns != 3 || !opts.ReadOnly || opts.AppendSystemPrompt != "Review the diff." {
+		t.Errorf("unexpected inherited options %+v", opts)
+	}
+	if !reflect.DeepEqual(opts.AllowedTools, []string{"Read"}) || opts.MaxThinkingTokens != 8000 {
+		t.Errorf("expected overrides over NewOptions defaults, got %+v", opts)
+	}
+
+	opts.AllowedTools[0] = "Write"
+	if again, _ := profiles.Get("nightly"); again.AllowedTools[0] != "Read" {
+		t.Error("modifying returned options changed the profile")
+	}
+
+	if _, err := profiles.Get("missing"); err == nil {
+		t.Error("expected error for an unknown profile")
+	}
+}
+
+func TestLoadProfilesErrors(t *testing.T) {
+	for name, profiles := range map[string]map[string]string{
+		"unknown field":  {"a": `{"modle": "opus"}`},
+		"wrong type":     {"a": `{"max_turns": "ten"}`},
+		"not an object":  {"a": `["opus"]`},
+		"missing parent": {"a": `{"extends": "b"}`},
+		"cycle":          {"a": `{"extends": "b"}`, "b": `{"extends": "a"}`},
+		"invalid name":   {".hidden": `{}`},
+	} {
+		if _, err := LoadProfiles(writeProfiles(t, profiles)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestProfile(t *testing.T) {
+	t.Cleanup(func() { UseProfiles(nil) })
+
+	if err := Profile("ci-review").Validate(); err == nil || !strings.Contains(err.Error(), "UseProfiles") {
+		t.Errorf("expected an error before UseProfiles, got %v", err)
+	}
+
+	profiles, err := LoadProfiles(writeProfiles(t, map[string]string{"ci-review": `{"model": "claude-opus-4-1"}`}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	UseProfiles(profiles)
+	if opts := Profile("ci-review"); opts.Model != "claude-opus-4-1" || opts.Validate() != nil {
+		t.Errorf("unexpected profile options %+v", opts)
+	}
+
+	_, errCh := Query(context.Background(), "test", Profile("missing"))
+	if err := <-errCh; err == nil || !strings.Contains(err.Error(), "unknown profile") {
+		t.Errorf("expected the query to report the unknown profile, got %v", err)
+	}
+}
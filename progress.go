@@ -0,0 +1,152 @@
+package claudecode
+
+import "context"
+
+// TurnProgress reports how far a query has got through its turns, for
+// progress bars on MaxTurns-bounded runs. It is delivered on the message
+// channel of queries wrapped by WithTurnProgress.
+type TurnProgress struct {
+	Turn     int `json:"turn"`      // 1-based index of the current turn
+	MaxTurns int `json:"max_turns"` // Options.MaxTurns, 0 when unbounded
+	// Cumulative token usage of the turns so far
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	// CostUSD is the cumulative cost, which the CLI only reports with the
+	// result, so it is set on the final event alone
+	CostUSD *float64 `json:"cost_usd,omitempty"`
+	// Final marks the event sent just before the ResultMessage
+	Final bool `json:"final,omitempty"`
+}
+
+func (TurnProgress) isMessage() {}
+
+// Fraction returns the share of MaxTurns used so far, or 0 when the run is
+// unbounded
+func (p TurnProgress) Fraction() float64 {
+	if p.MaxTurns <= 0 {
+		return 0
+	}
+	if p.Turn >= p.MaxTurns {
+		return 1
+	}
+	return float64(p.Turn) / float64(p.MaxTurns)
+}
+
+// WithTurnProgress returns a QueryFunc that runs next and delivers a
+// TurnProgress message after the first message of each turn and a final one
+// before the ResultMessage. A turn is one API response; the assistant
+// messages the CLI splits it into share an ID and count once.
+//
+// Example:
+//
+//	msgCh, errCh := WithTurnProgress(Query)(ctx, prompt, opts)
+//	for msg := range msgCh {
+//	    if p, ok := msg.(TurnProgress); ok {
+//	        bar.Set(p.Fraction())
+//	    }
+//	}
+func WithTurnProgress(next QueryFunc) QueryFunc {
+	return func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+		if options == nil {
+			options = NewOptions()
+		}
+		inMsgCh, errCh := next(ctx, prompt, options)
+		msgCh := make(chan Message, options.GetMessageBufferSize())
+
+		go func() {
+			defer close(msgCh)
+
+			emit := func(msg Message) bool {
+				select {
+				case msgCh <- msg:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			}
+
+			tracker := turnTracker{maxTurns: SafeIntPtr(options.MaxTurns)}
+			for msg := range inMsgCh {
+				var progress Message
+				switch m := msg.(type) {
+				case AssistantMessage:
+					if tracker.add(m) {
+						progress = tracker.progress()
+					}
+				case ResultMessage:
+					if !emit(tracker.final(m)) {
+						return
+					}
+				}
+				if !emit(msg) {
+					return
+				}
+				if progress != nil && !emit(progress) {
+					return
+				}
+			}
+		}()
+		return msgCh, errCh
+	}
+}
+
+// turnTracker derives turn progress from a query's messages
+type turnTracker struct {
+	maxTurns int
+	turns    map[string]int           // Index in usage of each message ID seen
+	usage    []map[string]interface{} // Latest usage of each turn
+}
+
+// add records msg and reports whether it starts a new turn. A message whose
+// ID was seen before belongs to that turn, even after other turns; messages
+// without an ID are turns of their own.
+func (t *turnTracker) add(msg AssistantMessage) bool {
+	turn, seen := t.turns[msg.ID]
+	newTurn := msg.ID == "" || !seen
+	if newTurn {
+		turn = len(t.usage)
+		t.usage = append(t.usage, nil)
+		if msg.ID != "" {
+			if t.turns == nil {
+				t.turns = make(map[string]int)
+			}
+			t.turns[msg.ID] = turn
+		}
+	}
+	if msg.Usage != nil {
+		t.usage[turn] = msg.Usage
+	}
+	return newTurn
+}
+
+// progress returns the cumulative progress of the turns so far
+func (t *turnTracker) progress() TurnProgress {
+	p := TurnProgress{Turn: len(t.usage), MaxTurns: t.maxTurns}
+	for _, usage := range t.usage {
+		p.InputTokens += getInt(usage, "input_tokens")
+		p.OutputTokens += getInt(usage, "output_tokens")
+		p.CacheCreationInputTokens += getInt(usage, "cache_creation_input_tokens")
+		p.CacheReadInputTokens += getInt(usage, "cache_read_input_tokens")
+	}
+	return p
+}
+
+// final returns the progress reported with result, whose usage and cost
+// cover the whole run
+func (t *turnTracker) final(result ResultMessage) TurnProgress {
+	p := t.progress()
+	if result.NumTurns > p.Turn {
+		p.Turn = result.NumTurns
+	}
+	if result.Usage != nil {
+		p.InputTokens = getInt(result.Usage, "input_tokens")
+		p.OutputTokens = getInt(result.Usage, "output_tokens")
+		p.CacheCreationInputTokens = getInt(result.Usage, "cache_creation_input_tokens")
+		p.CacheReadInputTokens = getInt(result.Usage, "cache_read_input_tokens")
+	}
+	p.CostUSD = result.TotalCostUSD
+	p.Final = true
+	return p
+}
@@ -0,0 +1,91 @@
+package claudecode
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithTurnProgress(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+echo '{"type":"system","subtype":"init","session_id":"s1"}'
+echo '{"type":"assistant","message":{"id":"m1","content":[{"type":"text","text":"Looking"}],"usage":{"input_tokens":10,"output_tokens":2}}}'
+echo '{"type":"assistant","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Read","input":{}}],"usage":{"input_tokens":10,"output_tokens":5}}}'
+echo '{"type":"assistant","message":{"id":"m2","content":[{"type":"text","text":"Done"}],"usage":{"input_tokens":20,"output_tokens":3,"cache_read_input_tokens":8}}}'
+echo '{"type":"result","subtype":"success","num_turns":2,"total_cost_usd":0.5,"usage":{"input_tokens":30,"output_tokens":8,"cache_read_input_tokens":8}}'
+`)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := NewOptions()
+	opts.MaxTurns = IntPtr(4)
+	msgCh, errCh := WithTurnProgress(Query)(ctx, "test", opts)
+
+	var kinds []string
+	var progress []TurnProgress
+	for msg := range msgCh {
+		switch m := msg.(type) {
+		case TurnProgress:
+			kinds = append(kinds, "progress")
+			progress = append(progress, m)
+		case AssistantMessage:
+			kinds = append(kinds, "assistant")
+		case ResultMessage:
+			kinds = append(kinds, "result")
+		}
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	wantKinds := []string{"assistant", "progress", "assistant", "assistant", "progress", "progress", "result"}
+	if !reflect.DeepEqual(kinds, wantKinds) {
+		t.Errorf("message order = %v, want %v", kinds, wantKinds)
+	}
+	want := []TurnProgress{
+		{Turn: 1, MaxTurns: 4, InputTokens: 10, OutputTokens: 2},
+		{Turn: 2, MaxTurns: 4, InputTokens: 30, OutputTokens: 8, CacheReadInputTokens: 8},
+		{Turn: 2, MaxTurns: 4, InputTokens: 30, OutputTokens: 8, CacheReadInputTokens: 8, CostUSD: Float64Ptr(0.5), Final: true},
+	}
+	if !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %+v, want %+v", progress, want)
+	}
+	if f := progress[0].Fraction(); f != 0.25 {
+		t.Errorf("Fraction() = %v, want 0.25", f)
+	}
+}
+
+func TestTurnTracker(t *testing.T) {
+	var turns turnTracker
+	for _, msg := range []AssistantMessage{
+		{ID: "a", Usage: map[string]interface{}{"output_tokens": float64(1)}},
+		{ID: "b", Usage: map[string]interface{}{"output_tokens": float64(2)}},
+		{ID: "a", Usage: map[string]interface{}{"output_tokens": float64(4)}},
+		{},
+		{},
+	} {
+		turns.add(msg)
+	}
+	// a and b are one turn each however they interleave; messages without
+	// an ID count separately
+	if p := turns.progress(); p.Turn != 4 || p.OutputTokens != 6 {
+		t.Errorf("progress = %+v, want 4 turns and 6 output tokens", p)
+	}
+}
+
+func TestConvertAssistantMessageUsage(t *testing.T) {
+	msg := convertMessage(map[string]interface{}{
+		"_type":   "assistant",
+		"content": []interface{}{},
+		"id":      "msg_1",
+		"usage":   map[string]interface{}{"output_tokens": float64(7)},
+	})
+	am, ok := msg.(AssistantMessage)
+	if !ok {
+		t.Fatalf("expected AssistantMessage, got %T", msg)
+	}
+	if am.ID != "msg_1" || getInt(am.Usage, "output_tokens") != 7 {
+		t.Errorf("unexpected message %+v", am)
+	}
+}
@@ -0,0 +1,112 @@
+package claudecode
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Names of the prompts shipped with the SDK
+const (
+	PromptReviewer   = "reviewer"
+	PromptRefactorer = "refactorer"
+	PromptTestWriter = "test-writer"
+)
+
+//go:embed prompts/*.md
+var embeddedPrompts embed.FS
+
+// promptNamePattern restricts prompt names so they can be used as file names
+var promptNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// PromptLibrary resolves system prompts by name. Each prompt is stored as
+// <name>.md; override directories are searched in order before the prompts
+// embedded in the SDK, so a project can replace a built-in prompt or add its
+// own.
+//
+// Example:
+//
+//	lib := NewPromptLibrary(".claude/prompts")
+//	prompt, err := lib.Get(PromptReviewer)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	opts.SystemPrompt = prompt
+type PromptLibrary struct {
+	dirs []string
+}
+
+// NewPromptLibrary creates a library that prefers prompts found in dirs
+func NewPromptLibrary(dirs ...string) *PromptLibrary {
+	return &PromptLibrary{dirs: dirs}
+}
+
+// Get returns the prompt called name. Override directories that do not
+// exist are skipped.
+func (l *PromptLibrary) Get(name string) (string, error) {
+	if !promptNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid prompt name %q", name)
+	}
+
+	for _, dir := range l.dirs {
+		content, err := os.ReadFile(filepath.Join(dir, name+".md"))
+		if err == nil {
+			return strings.TrimSpace(string(content)), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read prompt %s: %w", name, err)
+		}
+	}
+
+	content, err := embeddedPrompts.ReadFile("prompts/" + name + ".md")
+	if err != nil {
+		return "", fmt.Errorf("unknown prompt %q", name)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// Names returns the sorted names of every prompt available in the library
+func (l *PromptLibrary) Names() ([]string, error) {
+	seen := make(map[string]bool)
+	collect := func(entries []fs.DirEntry) {
+		for _, entry := range entries {
+			name := strings.TrimSuffix(entry.Name(), ".md")
+			if !entry.IsDir() && name != entry.Name() && promptNamePattern.MatchString(name) {
+				seen[name] = true
+			}
+		}
+	}
+
+	entries, err := embeddedPrompts.ReadDir("prompts")
+	if err != nil {
+		return nil, err
+	}
+	collect(entries)
+	for _, dir := range l.dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list prompts: %w", err)
+		}
+		collect(entries)
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetPrompt returns the built-in prompt called name
+func GetPrompt(name string) (string, error) {
+	return NewPromptLibrary().Get(name)
+}
@@ -0,0 +1,51 @@
+package claudecode
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPromptLibrary(t *testing.T) {
+	for _, name := range []string{PromptReviewer, PromptRefactorer, PromptTestWriter} {
+		prompt, err := GetPrompt(name)
+		if err != nil || prompt == "" || strings.HasSuffix(prompt, "\n") {
+			t.Errorf("built-in prompt %s: %q, %v", name, prompt, err)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "reviewer.md"), []byte("Be strict.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "security.md"), []byte("Audit it."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lib := NewPromptLibrary(filepath.Join(dir, "missing"), dir)
+
+	if got, err := lib.Get(PromptReviewer); err != nil || got != "Be strict." {
+		t.Errorf("expected override, got %q, %v", got, err)
+	}
+	if got, err := lib.Get("security"); err != nil || got != "Audit it." {
+		t.Errorf("expected custom prompt, got %q, %v", got, err)
+	}
+	if _, err := lib.Get(PromptRefactorer); err != nil {
+		t.Errorf("expected built-in fallback, got %v", err)
+	}
+	for _, name := range []string{"nope", "../reviewer", "Reviewer", ""} {
+		if _, err := lib.Get(name); err == nil {
+			t.Errorf("expected error for %q", name)
+		}
+	}
+
+	names, err := lib.Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"refactorer", "reviewer", "security", "test-writer"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got names %v, want %v", names, want)
+	}
+}
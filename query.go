@@ -3,8 +3,10 @@ package claudecode
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/f-pisani/claude-code-sdk-go/internal"
+	"github.com/f-pisani/claude-code-sdk-go/internal/clock"
 )
 
 // Query sends a prompt to Claude Code and returns channels for messages and errors.
@@ -25,6 +27,10 @@ import (
 //   - msgCh: Channel that yields messages from the conversation
 //   - errCh: Channel for errors (buffered, receives at most one error)
 //
+// With options.InlineErrors set, errors are instead delivered as a final
+// ErrorMessage on msgCh and errCh only closes, so a single range loop over
+// msgCh observes everything in order.
+//
 // Example:
 //
 //	// Simple usage
@@ -48,22 +54,76 @@ import (
 //	options.SystemPrompt = "You are helpful"
 //	options.Cwd = "/home/user"
 //	msgCh, errCh := Query(context.Background(), "Hello", options)
+//
+// Middleware attached to ctx with WithMiddleware runs before the CLI
+// command is built.
 func Query(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+	if len(MiddlewareFromContext(ctx)) > 0 {
+		return Do(ctx, &QueryRequest{Prompt: prompt, Options: options})
+	}
+	return query(ctx, prompt, options)
+}
+
+// query runs a prompt once any middleware has been applied
+func query(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+	if options == nil {
+		options = NewOptions()
+	}
+	options = withMetadataLogger(ctx, options)
+	return runQuery(ctx, options, func(queryCtx context.Context) (<-chan interface{}, <-chan error) {
+		return internal.NewClient().ProcessQuery(queryCtx, prompt, options)
+	})
+}
+
+// QueryWithTransport runs a query over a caller-supplied Transport instead of
+// starting the CLI, e.g. a mock in unit tests or a CLI reached over SSH. The
+// transport carries its own prompt; it is connected here and disconnected
+// once the query ends. options (uses NewOptions() if nil) controls buffering,
+// SDK-side limits and InlineErrors as for Query. Options.Retry is ignored,
+// as a used transport cannot be connected again.
+//
+// Example:
+//
+//	trans := NewSubprocessTransport("Hello", opts, "/opt/claude/bin/claude")
+//	msgCh, errCh := QueryWithTransport(ctx, trans, opts)
+func QueryWithTransport(ctx context.Context, transport Transport, options *Options) (<-chan Message, <-chan error) {
 	if options == nil {
 		options = NewOptions()
 	}
+	if options.Retry != nil {
+		opts := *options
+		opts.Retry = nil
+		options = &opts
+	}
+	options = withMetadataLogger(ctx, options)
+	return runQuery(ctx, options, func(queryCtx context.Context) (<-chan interface{}, <-chan error) {
+		return internal.NewClient().ProcessQueryWithTransport(queryCtx, transport, options)
+	})
+}
 
-	// Apply query timeout if specified
-	queryCtx := ctx
+// runQuery converts the raw stream started by process into typed messages,
+// enforcing the SDK-side limits in options
+func runQuery(ctx context.Context, options *Options, process func(queryCtx context.Context) (<-chan interface{}, <-chan error)) (<-chan Message, <-chan error) {
+	// A spent budget fails the query before the CLI starts
+	if budget := CostBudgetFromContext(ctx); budget != nil {
+		if err := budget.exhausted(); err != nil {
+			return failedQuery(err, options)
+		}
+	}
+	costs := newCostGuard(ctx, options)
+
+	// Apply query timeout if specified. The query context is always
+	// cancelable so SDK-side limits can stop the CLI.
+	var queryCtx context.Context
 	var cancel context.CancelFunc
 	if timeout := options.GetQueryTimeout(); timeout > 0 {
-		queryCtx, cancel = context.WithTimeout(ctx, timeout)
+		queryCtx, cancel = clock.WithTimeout(ctx, options.GetClock(), timeout)
+	} else {
+		queryCtx, cancel = context.WithCancel(ctx)
 	}
 
-	client := internal.NewClient()
-
 	// Get raw channels from internal client
-	rawMsgCh, rawErrCh := client.ProcessQuery(queryCtx, prompt, options)
+	rawMsgCh, rawErrCh := process(queryCtx)
 
 	// Create typed channels with configurable buffer sizes
 	msgCh := make(chan Message, options.GetMessageBufferSize())
@@ -71,66 +131,189 @@ func Query(ctx context.Context, prompt string, options *Options) (<-chan Message
 
 	// Convert raw messages to typed messages
 	go func() {
+		var queryErr error
+		partial := &PartialResult{}
+
 		// Add panic recovery to ensure channels are always closed
 		defer func() {
 			if r := recover(); r != nil {
-				// Try to send panic error, but don't block
-				select {
-				case errCh <- fmt.Errorf("panic in message conversion: %v", r):
-				default:
+				queryErr = fmt.Errorf("panic in message conversion: %v", r)
+			}
+			if queryErr == nil {
+				queryErr = limitError(ctx, queryCtx, options)
+			}
+			if queryErr != nil && partial.Messages > 0 {
+				queryErr = &PartialResultError{Err: queryErr, Partial: partial}
+			}
+			// Stop the CLI and wait for it to exit, so that nothing it does
+			// outlives the query
+			cancel()
+			if rawMsgCh != nil {
+				for range rawMsgCh {
+				}
+			}
+			if rawErrCh != nil {
+				for range rawErrCh {
+				}
+			}
+			if queryErr != nil {
+				if options.InlineErrors {
+					select {
+					case msgCh <- ErrorMessage{Err: queryErr}:
+					case <-ctx.Done():
+					}
+				} else {
+					// errCh is buffered and only written here, so this never blocks
+					select {
+					case errCh <- queryErr:
+					default:
+					}
 				}
 			}
 			close(msgCh)
 			close(errCh)
-			// Cancel timeout if it was set
-			if cancel != nil {
-				cancel()
-			}
 		}()
 
-		for {
-			select {
-			case rawMsg, ok := <-rawMsgCh:
-				if !ok {
-					return
-				}
-				if msg := convertMessage(rawMsg); msg != nil {
+		var turns turnTracker
+		maxAttempts := options.Retry.attempts()
+		for attempt := 1; ; attempt++ {
+			// Messages other than system messages commit the attempt, and a
+			// failed result the policy retries, such as a RateLimitError, is
+			// held back while it can still be retried
+			delivered := false
+			var held *ResultMessage
+
+			// Keep reading until both raw channels are closed so that messages
+			// buffered ahead of an error are delivered before it
+			for rawMsgCh != nil || rawErrCh != nil {
+				select {
+				case rawMsg, ok := <-rawMsgCh:
+					if !ok {
+						rawMsgCh = nil
+						continue
+					}
+					msg := convertMessage(rawMsg)
+					if msg == nil {
+						continue
+					}
+					if am, ok := msg.(AssistantMessage); ok {
+						if tool, violated := readOnlyViolation(am); violated && options.ReadOnly {
+							queryErr = NewReadOnlyViolationError(tool)
+							return
+						}
+						if turns.add(am) && options.TurnLimit > 0 && len(turns.usage) > options.TurnLimit {
+							queryErr = NewLimitExceededError(LimitTurns, options.TurnLimit)
+							return
+						}
+					}
+					if result, ok := msg.(ResultMessage); ok && !delivered && attempt < maxAttempts {
+						if err := result.Err(); err != nil && options.Retry.retryable(err) {
+							held = &result
+							continue
+						}
+					}
 					select {
 					case msgCh <- msg:
+						partial.add(msg)
 					case <-queryCtx.Done():
 						return
 					}
+					if _, ok := msg.(SystemMessage); !ok {
+						delivered = true
+					}
+					if result, ok := msg.(ResultMessage); ok {
+						if err := costs.observe(result); err != nil {
+							queryErr = err
+							return
+						}
+					}
+				case err, ok := <-rawErrCh:
+					if !ok {
+						rawErrCh = nil
+						continue
+					}
+					// Errors caused by the query being canceled are not reported;
+					// limitError accounts for SDK-side cancellation
+					if err != nil && queryCtx.Err() == nil {
+						// Prioritize the most recent error
+						queryErr = err
+					}
+				case <-queryCtx.Done():
+					return
 				}
-			case err, ok := <-rawErrCh:
-				if !ok {
-					// Error channel closed, we're done
+			}
+
+			var cause string
+			var retryAfter time.Duration
+			switch {
+			case delivered || attempt >= maxAttempts:
+			case queryErr != nil && options.Retry.retryable(queryErr):
+				cause = queryErr.Error()
+				retryAfter = ErrorRetryAfter(queryErr)
+			case queryErr == nil && held != nil:
+				heldErr := held.Err()
+				cause = heldErr.Error()
+				retryAfter = ErrorRetryAfter(heldErr)
+			}
+			if retryAfter > options.Retry.maxBackoff() {
+				// A stated delay beyond MaxBackoff is not waited out; the
+				// failure is reported instead
+				cause = ""
+			}
+			if held != nil {
+				if err := costs.observe(*held); err != nil {
+					queryErr = err
 					return
 				}
-				if err != nil {
-					// Try to send error without blocking
+			}
+			if cause == "" {
+				// Not retried: a held result is delivered as it is
+				if held != nil {
 					select {
-					case errCh <- err:
+					case msgCh <- *held:
+						partial.add(*held)
 					case <-queryCtx.Done():
-						return
-					default:
-						// Error channel full, prioritize most recent error
-						select {
-						case <-errCh:
-							errCh <- err
-						default:
-						}
 					}
-					return
 				}
+				return
+			}
+
+			// A delay stated by the CLI or API takes precedence over a
+			// shorter backoff
+			delay := options.Retry.backoff(attempt)
+			if retryAfter > delay {
+				delay = retryAfter
+			}
+			select {
+			case msgCh <- retryMessage(attempt+1, maxAttempts, delay, cause):
+			case <-queryCtx.Done():
+				return
+			}
+			timer := options.GetClock().NewTimer(delay)
+			select {
+			case <-timer.C():
 			case <-queryCtx.Done():
+				timer.Stop()
 				return
 			}
+			queryErr = nil
+			rawMsgCh, rawErrCh = process(queryCtx)
 		}
 	}()
 
 	return msgCh, errCh
 }
 
+// limitError reports the SDK-side limit that ended a query, if any. A query
+// stopped by its own QueryTimeout while the caller's context is still live
+// hit the wall-clock limit.
+func limitError(ctx, queryCtx context.Context, options *Options) error {
+	if ctx.Err() == nil && queryCtx.Err() == context.DeadlineExceeded {
+		return NewLimitExceededError(LimitWallClock, options.QueryTimeout)
+	}
+	return nil
+}
+
 // convertMessage converts raw message map to typed Message
 func convertMessage(raw interface{}) Message {
 	data, ok := raw.(map[string]interface{})
@@ -148,6 +331,15 @@ func convertMessage(raw interface{}) Message {
 		if content, ok := data["content"].(string); ok {
 			return UserMessage{Content: content}
 		}
+		if blocksData, ok := data["blocks"].([]interface{}); ok {
+			var blocks []ContentBlock
+			for _, blockData := range blocksData {
+				if block := convertContentBlock(blockData); block != nil {
+					blocks = append(blocks, block)
+				}
+			}
+			return UserMessage{Blocks: blocks}
+		}
 
 	case "assistant":
 		if contentData, ok := data["content"].([]interface{}); ok {
@@ -157,7 +349,11 @@ func convertMessage(raw interface{}) Message {
 					contentBlocks = append(contentBlocks, block)
 				}
 			}
-			return AssistantMessage{Content: contentBlocks}
+			return AssistantMessage{
+				Content: contentBlocks,
+				ID:      getString(data, "id"),
+				Usage:   getMap(data, "usage"),
+			}
 		}
 
 	case "system":
@@ -168,6 +364,14 @@ func convertMessage(raw interface{}) Message {
 			Data:    systemData,
 		}
 
+	case "stream_event":
+		return StreamEvent{
+			UUID:            getString(data, "uuid"),
+			SessionID:       getString(data, "session_id"),
+			Event:           getMap(data, "event"),
+			ParentToolUseID: getString(data, "parent_tool_use_id"),
+		}
+
 	case "result":
 		msg := ResultMessage{
 			Subtype:       getString(data, "subtype"),
@@ -212,6 +416,12 @@ func convertContentBlock(raw interface{}) ContentBlock {
 			return TextBlock{Text: text}
 		}
 
+	case "thinking":
+		return ThinkingBlock{
+			Thinking:  getString(data, "thinking"),
+			Signature: getString(data, "signature"),
+		}
+
 	case "tool_use":
 		return ToolUseBlock{
 			ID:    getString(data, "id"),
@@ -230,6 +440,12 @@ func convertContentBlock(raw interface{}) ContentBlock {
 			block.IsError = &isError
 		}
 		return block
+
+	case "image":
+		return ImageBlock{
+			MediaType: getString(data, "media_type"),
+			Data:      getString(data, "data"),
+		}
 	}
 
 	return nil
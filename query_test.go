@@ -1,7 +1,13 @@
 package claudecode
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -239,6 +245,15 @@ func TestConvertContentBlock(t *testing.T) {
 			},
 			wantType: "ToolUseBlock",
 		},
+		{
+			name: "thinking block",
+			input: map[string]interface{}{
+				"_blockType": "thinking",
+				"thinking":   "Let me think",
+				"signature":  "sig",
+			},
+			wantType: "ThinkingBlock",
+		},
 		{
 			name: "tool result block",
 			input: map[string]interface{}{
@@ -288,6 +303,10 @@ func TestConvertContentBlock(t *testing.T) {
 				if tt.wantType != "ToolUseBlock" {
 					t.Errorf("got ToolUseBlock, want %s", tt.wantType)
 				}
+			case ThinkingBlock:
+				if tt.wantType != "ThinkingBlock" || block.Thinking != "Let me think" || block.Signature != "sig" {
+					t.Errorf("got %+v, want %s", block, tt.wantType)
+				}
 			case ToolResultBlock:
 				if tt.wantType != "ToolResultBlock" {
 					t.Errorf("got ToolResultBlock, want %s", tt.wantType)
@@ -449,6 +468,223 @@ func TestQueryOptions(t *testing.T) {
 	}
 }
 
+func TestQueryInlineErrors(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"partial"}]}}'
+echo '{"type": broken'
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := NewOptions()
+	opts.InlineErrors = true
+	msgCh, errCh := Query(ctx, "test", opts)
+
+	var msgs []Message
+	for msg := range msgCh {
+		msgs = append(msgs, msg)
+	}
+	if err, ok := <-errCh; ok {
+		t.Fatalf("Expected error channel to close without errors, got %v", err)
+	}
+
+	if len(msgs) != 2 {
+		t.Fatalf("Expected 2 messages, got %d: %+v", len(msgs), msgs)
+	}
+	if _, ok := msgs[0].(AssistantMessage); !ok {
+		t.Errorf("Expected first message to be AssistantMessage, got %T", msgs[0])
+	}
+	errMsg, ok := msgs[1].(ErrorMessage)
+	if !ok {
+		t.Fatalf("Expected last message to be ErrorMessage, got %T", msgs[1])
+	}
+	var decodeErr *CLIJSONDecodeError
+	if !errors.As(errMsg, &decodeErr) {
+		t.Errorf("Expected ErrorMessage to wrap CLIJSONDecodeError, got %v", errMsg.Err)
+	}
+}
+
+func TestQueryPartialMessages(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+case "$*" in
+*--include-partial-messages*) ;;
+*) exit 1 ;;
+esac
+echo '{"type":"stream_event","uuid":"u1","session_id":"s1","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}}'
+echo '{"type":"stream_event","uuid":"u2","session_id":"s1","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}}'
+echo '{"type":"stream_event","uuid":"u3","session_id":"s1","event":{"type":"message_stop"}}'
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Hello"}]}}'
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := NewOptions()
+	opts.IncludePartialMessages = true
+	msgCh, errCh := Query(ctx, "test", opts)
+
+	var deltas strings.Builder
+	var events int
+	var final string
+	for msg := range msgCh {
+		switch m := msg.(type) {
+		case StreamEvent:
+			events++
+			deltas.WriteString(m.TextDelta())
+			if m.SessionID != "s1" || m.UUID == "" {
+				t.Errorf("unexpected event metadata %+v", m)
+			}
+		case AssistantMessage:
+			final = m.Content[0].(TextBlock).Text
+		}
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if events != 3 || deltas.String() != "Hello" || final != "Hello" {
+		t.Errorf("got %d events, deltas %q, final %q", events, deltas.String(), final)
+	}
+}
+
+func TestQueryRequireCLIVersion(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+if [ "$1" = "--version" ]; then
+	echo "1.0.61 (Claude Code)"
+	exit 0
+fi
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Hello"}]}}'
+echo '{"type":"result","subtype":"success","session_id":"s1"}'
+`)
+
+	t.Run("satisfied", func(t *testing.T) {
+		opts := NewOptions()
+		opts.RequireCLIVersion = ">=1.0.50, <2"
+		text, _, err := QueryText(context.Background(), "test", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != "Hello" {
+			t.Errorf("expected %q, got %q", "Hello", text)
+		}
+	})
+
+	t.Run("not satisfied", func(t *testing.T) {
+		opts := NewOptions()
+		opts.RequireCLIVersion = "^2.0"
+		msgCh, errCh := Query(context.Background(), "test", opts)
+		var msgs []Message
+		for msg := range msgCh {
+			msgs = append(msgs, msg)
+		}
+		err := <-errCh
+		var incompatible *IncompatibleCLIError
+		if !errors.As(err, &incompatible) {
+			t.Fatalf("expected IncompatibleCLIError, got %v", err)
+		}
+		if incompatible.Version != "1.0.61" || incompatible.Constraint != "^2.0" {
+			t.Errorf("unexpected error fields %+v", incompatible)
+		}
+		if len(msgs) != 0 {
+			t.Errorf("expected no messages, got %d", len(msgs))
+		}
+	})
+
+	t.Run("invalid constraint", func(t *testing.T) {
+		opts := NewOptions()
+		opts.RequireCLIVersion = "latest"
+		if err := opts.Validate(); err == nil {
+			t.Error("expected Validate to reject the constraint")
+		}
+	})
+}
+
+func TestQueryLogger(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+echo 'warming up'
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Hello"}]}}'
+echo '{"type":"result","subtype":"success","session_id":"s1"}'
+`)
+
+	var logs bytes.Buffer
+	opts := NewOptions()
+	opts.SystemPrompt = "top secret instructions"
+	opts.Settings = `{"env":{"API_TOKEN":"hunter2-token"}}`
+	opts.Logger = slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	if _, _, err := QueryText(context.Background(), "test", opts); err != nil {
+		t.Fatal(err)
+	}
+
+	out := logs.String()
+	for _, want := range []string{
+		`msg="starting Claude Code"`,
+		"[redacted 23 bytes]",
+		`msg="Claude Code started"`,
+		`msg="skipped non-JSON CLI output" line="warming up"`,
+		`msg="received line"`,
+		`msg="Claude Code exited"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "top secret") {
+		t.Errorf("log contains the system prompt:\n%s", out)
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("log contains the inline settings:\n%s", out)
+	}
+}
+
+func TestQueryPromptInput(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+case "$*" in
+*"secret prompt"*) echo "error: prompt in argv" >&2; exit 1 ;;
+esac
+input=$(cat)
+case "$input" in
+*'"content":"secret prompt"'*) kind=json ;;
+"secret prompt") kind=text ;;
+*) kind=unknown ;;
+esac
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"'"$kind"'"}]}}'
+echo '{"type":"result","subtype":"success","session_id":"s1"}'
+`)
+
+	for mode, want := range map[PromptInput]string{PromptInputStdin: "text", PromptInputStreamJSON: "json"} {
+		t.Run(string(mode), func(t *testing.T) {
+			opts := NewOptions()
+			opts.PromptInput = mode
+			msgCh, errCh := Query(context.Background(), "secret prompt", opts)
+			partial := &PartialResult{}
+			for msg := range msgCh {
+				partial.add(msg)
+			}
+			if err := <-errCh; err != nil {
+				t.Fatal(err)
+			}
+			if partial.Text != want {
+				t.Errorf("expected the CLI to read the prompt as %s from stdin, got %q", want, partial.Text)
+			}
+		})
+	}
+
+	if err := (&Options{PromptInput: "pipe"}).Validate(); err == nil {
+		t.Error("expected Validate to reject an unknown prompt input mode")
+	}
+}
+
+// installFakeCLI puts an executable named claude running script first on PATH
+func installFakeCLI(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "claude"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
 // Helper function for creating int pointers
 func intPtr(i int) *int {
 	return &i
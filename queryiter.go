@@ -0,0 +1,42 @@
+//go:build go1.23
+
+package claudecode
+
+import (
+	"context"
+	"iter"
+)
+
+// QueryIter runs Query and returns its messages as an iterator, so the
+// conversation can be consumed with a single range loop. A failed query ends
+// with a final (nil, err) pair. Breaking out of the loop stops the CLI.
+//
+// Example:
+//
+//	for msg, err := range QueryIter(ctx, "Hello", nil) {
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    fmt.Printf("%+v\n", msg)
+//	}
+func QueryIter(ctx context.Context, prompt string, options *Options) iter.Seq2[Message, error] {
+	return func(yield func(Message, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		msgCh, errCh := Query(ctx, prompt, options)
+		for msg := range msgCh {
+			if !yield(msg, nil) {
+				cancel()
+				// Drain so the query can shut the CLI down
+				for range msgCh {
+				}
+				<-errCh
+				return
+			}
+		}
+		if err := <-errCh; err != nil {
+			yield(nil, err)
+		}
+	}
+}
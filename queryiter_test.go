@@ -0,0 +1,61 @@
+//go:build go1.23
+
+package claudecode
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueryIter(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"one"}]}}'
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"two"}]}}'
+echo '{"type":"result","subtype":"success","session_id":"s1"}'
+`)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("all messages", func(t *testing.T) {
+		var count int
+		for msg, err := range QueryIter(ctx, "test", nil) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg == nil {
+				t.Fatal("unexpected nil message")
+			}
+			count++
+		}
+		if count != 3 {
+			t.Errorf("Expected 3 messages, got %d", count)
+		}
+	})
+
+	t.Run("break", func(t *testing.T) {
+		var count int
+		for range QueryIter(ctx, "test", nil) {
+			count++
+			break
+		}
+		if count != 1 {
+			t.Errorf("Expected loop to stop after 1 message, got %d", count)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		opts := NewOptions()
+		opts.Model = "not-a-model"
+		var got error
+		for msg, err := range QueryIter(ctx, "test", opts) {
+			if msg != nil {
+				t.Errorf("unexpected message %v", msg)
+			}
+			got = err
+		}
+		if got == nil {
+			t.Error("Expected an error for invalid options")
+		}
+	})
+}
@@ -0,0 +1,394 @@
+package claudecode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// recordingVersion is the version of the recording file format
+const recordingVersion = 1
+
+// DefaultVolatileFields lists recording fields that change between otherwise
+// identical runs and are ignored by CompareRecordings by default
+var DefaultVolatileFields = []string{
+	"session_id",
+	"total_cost_usd",
+	"duration_ms",
+	"duration_api_ms",
+	"usage",
+}
+
+// RecordedTurn is one prompt sent during a recorded run and what came back
+type RecordedTurn struct {
+	Prompt   string            `json:"prompt"`
+	Answer   string            `json:"answer"`
+	Result   *ResultMessage    `json:"result,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	// Messages is the full message stream, kept when
+	// RecordingSession.RecordMessages is set
+	Messages []RecordedMessage `json:"messages,omitempty"`
+}
+
+// RecordedMessage is a Message in a form that survives a JSON round trip
+type RecordedMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// recordMessage encodes msg as a RecordedMessage
+func recordMessage(msg Message) (RecordedMessage, error) {
+	var typ string
+	var v interface{} = msg
+	switch m := msg.(type) {
+	case UserMessage:
+		typ = "user"
+	case AssistantMessage:
+		typ = "assistant"
+	case SystemMessage:
+		typ = "system"
+	case ResultMessage:
+		typ = "result"
+	case StreamEvent:
+		typ = "stream_event"
+	case FileEvent:
+		typ = "file_event"
+	case TurnProgress:
+		typ = "turn_progress"
+	case CLIUpdateAdvisory:
+		typ = "cli_update_advisory"
+	case ErrorMessage:
+		typ = "error"
+		v = m.Error()
+	default:
+		return RecordedMessage{}, fmt.Errorf("cannot record message of type %T", msg)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return RecordedMessage{}, err
+	}
+	return RecordedMessage{Type: typ, Data: data}, nil
+}
+
+// Message decodes the recorded message
+func (m RecordedMessage) Message() (Message, error) {
+	var msg Message
+	var err error
+	switch m.Type {
+	case "user":
+		var um UserMessage
+		err = json.Unmarshal(m.Data, &um)
+		msg = um
+	case "assistant":
+		var am AssistantMessage
+		err = json.Unmarshal(m.Data, &am)
+		msg = am
+	case "system":
+		var sm SystemMessage
+		err = json.Unmarshal(m.Data, &sm)
+		msg = sm
+	case "result":
+		var rm ResultMessage
+		err = json.Unmarshal(m.Data, &rm)
+		msg = rm
+	case "stream_event":
+		var se StreamEvent
+		err = json.Unmarshal(m.Data, &se)
+		msg = se
+	case "file_event":
+		var fe FileEvent
+		err = json.Unmarshal(m.Data, &fe)
+		msg = fe
+	case "turn_progress":
+		var tp TurnProgress
+		err = json.Unmarshal(m.Data, &tp)
+		msg = tp
+	case "cli_update_advisory":
+		var adv CLIUpdateAdvisory
+		err = json.Unmarshal(m.Data, &adv)
+		msg = adv
+	case "error":
+		var text string
+		err = json.Unmarshal(m.Data, &text)
+		msg = ErrorMessage{Err: errors.New(text)}
+	default:
+		return nil, fmt.Errorf("unknown recorded message type %q", m.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode recorded %s message: %w", m.Type, err)
+	}
+	return msg, nil
+}
+
+// Recording is the file format produced by RecordingSession
+type Recording struct {
+	Version int            `json:"version"`
+	Turns   []RecordedTurn `json:"turns"`
+}
+
+// RecordingSession captures the prompts and final answers of every query made
+// through it, so a multi-turn agent run can be saved as a golden file and
+// compared against future runs.
+//
+// Example:
+//
+//	rec := NewRecordingSession()
+//	runAgent(ctx, rec.Query) // agent code takes a Query-compatible func
+//	rec.AssertMatches(t, "testdata/agent.golden.json")
+type RecordingSession struct {
+	// RecordMessages keeps every message of each turn, including tool calls
+	// and results, so the run can be replayed with a Replayer
+	RecordMessages bool
+
+	mu    sync.Mutex
+	turns []RecordedTurn
+}
+
+// NewRecordingSession creates an empty recording session
+func NewRecordingSession() *RecordingSession {
+	return &RecordingSession{}
+}
+
+// Query behaves like the package level Query and records the exchange once
+// the stream completes
+func (s *RecordingSession) Query(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+	if options == nil {
+		options = NewOptions()
+	}
+	innerMsgCh, innerErrCh := Query(ctx, prompt, options)
+
+	msgCh := make(chan Message, options.GetMessageBufferSize())
+	errCh := make(chan error, options.GetErrorBufferSize())
+
+	go func() {
+		defer close(msgCh)
+		defer close(errCh)
+
+		turn := RecordedTurn{
+			Prompt:   prompt,
+			Metadata: MetadataFromContext(ctx),
+			Labels:   LabelsFromContext(ctx),
+		}
+		var text strings.Builder
+		defer func() {
+			if turn.Answer == "" {
+				turn.Answer = text.String()
+			}
+			s.mu.Lock()
+			s.turns = append(s.turns, turn)
+			s.mu.Unlock()
+		}()
+
+		for innerMsgCh != nil || innerErrCh != nil {
+			select {
+			case msg, ok := <-innerMsgCh:
+				if !ok {
+					innerMsgCh = nil
+					continue
+				}
+				if s.RecordMessages {
+					if recorded, err := recordMessage(msg); err == nil {
+						turn.Messages = append(turn.Messages, recorded)
+					}
+				}
+				switch m := msg.(type) {
+				case AssistantMessage:
+					for _, block := range m.Content {
+						if tb, ok := block.(TextBlock); ok {
+							text.WriteString(tb.Text)
+						}
+					}
+				case ResultMessage:
+					result := m
+					turn.Result = &result
+					turn.Answer = SafeStringPtr(m.Result)
+				case ErrorMessage:
+					turn.Error = m.Error()
+				}
+				select {
+				case msgCh <- msg:
+				case <-ctx.Done():
+					return
+				}
+			case err, ok := <-innerErrCh:
+				if !ok {
+					innerErrCh = nil
+					continue
+				}
+				if err != nil {
+					turn.Error = err.Error()
+					errCh <- err
+				}
+			}
+		}
+	}()
+
+	return msgCh, errCh
+}
+
+// Recording returns a snapshot of everything recorded so far
+func (s *RecordingSession) Recording() *Recording {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	turns := make([]RecordedTurn, len(s.turns))
+	copy(turns, s.turns)
+	return &Recording{Version: recordingVersion, Turns: turns}
+}
+
+// Save writes the recording to path as indented JSON
+func (s *RecordingSession) Save(path string) error {
+	data, err := json.MarshalIndent(s.Recording(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal recording: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return nil
+}
+
+// LoadRecording reads a recording previously written by Save
+func LoadRecording(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to parse recording: %w", err)
+	}
+	if rec.Version != recordingVersion {
+		return nil, fmt.Errorf("unsupported recording version %d", rec.Version)
+	}
+	return &rec, nil
+}
+
+// TestingT is the subset of testing.TB used by the assertion helpers
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertMatches compares the session against the golden recording at path,
+// ignoring DefaultVolatileFields plus any extra volatile field names, and
+// reports every difference on t
+func (s *RecordingSession) AssertMatches(t TestingT, path string, volatile ...string) {
+	t.Helper()
+
+	golden, err := LoadRecording(path)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	ignored := make([]string, 0, len(DefaultVolatileFields)+len(volatile))
+	ignored = append(ignored, DefaultVolatileFields...)
+	ignored = append(ignored, volatile...)
+	for _, diff := range CompareRecordings(golden, s.Recording(), ignored...) {
+		t.Errorf("recording mismatch: %s", diff)
+	}
+}
+
+// CompareRecordings returns a description of every difference between two
+// recordings. Fields whose JSON name is listed in volatile are ignored at any
+// depth.
+func CompareRecordings(want, got *Recording, volatile ...string) []string {
+	ignore := make(map[string]bool, len(volatile))
+	for _, field := range volatile {
+		ignore[field] = true
+	}
+
+	var diffs []string
+	if len(want.Turns) != len(got.Turns) {
+		diffs = append(diffs, fmt.Sprintf("turn count: want %d, got %d", len(want.Turns), len(got.Turns)))
+	}
+	for i := 0; i < len(want.Turns) && i < len(got.Turns); i++ {
+		wantTurn, err := normalizeRecorded(want.Turns[i], ignore)
+		if err != nil {
+			return append(diffs, err.Error())
+		}
+		gotTurn, err := normalizeRecorded(got.Turns[i], ignore)
+		if err != nil {
+			return append(diffs, err.Error())
+		}
+		diffs = append(diffs, diffValues(fmt.Sprintf("turns[%d]", i), wantTurn, gotTurn)...)
+	}
+	return diffs
+}
+
+// normalizeRecorded converts v to its generic JSON form with ignored keys removed
+func normalizeRecorded(v interface{}, ignore map[string]bool) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal recording: %w", err)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("failed to normalize recording: %w", err)
+	}
+	return stripKeys(generic, ignore), nil
+}
+
+func stripKeys(v interface{}, ignore map[string]bool) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, child := range val {
+			if ignore[key] {
+				delete(val, key)
+				continue
+			}
+			val[key] = stripKeys(child, ignore)
+		}
+	case []interface{}:
+		for i, child := range val {
+			val[i] = stripKeys(child, ignore)
+		}
+	}
+	return v
+}
+
+// diffValues describes the differences between two generic JSON values
+func diffValues(path string, want, got interface{}) []string {
+	wantMap, wantIsMap := want.(map[string]interface{})
+	gotMap, gotIsMap := got.(map[string]interface{})
+	if wantIsMap && gotIsMap {
+		keys := make([]string, 0, len(wantMap)+len(gotMap))
+		for key := range wantMap {
+			keys = append(keys, key)
+		}
+		for key := range gotMap {
+			if _, ok := wantMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		var diffs []string
+		for _, key := range keys {
+			w, inWant := wantMap[key]
+			g, inGot := gotMap[key]
+			switch {
+			case !inGot:
+				diffs = append(diffs, fmt.Sprintf("%s.%s: missing", path, key))
+			case !inWant:
+				diffs = append(diffs, fmt.Sprintf("%s.%s: unexpected", path, key))
+			default:
+				diffs = append(diffs, diffValues(path+"."+key, w, g)...)
+			}
+		}
+		return diffs
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		return []string{fmt.Sprintf("%s: want %v, got %v", path, want, got)}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAgentCLI answers every prompt with a fixed text and a result whose cost
+// and session ID vary between runs
+func fakeAgentCLI(t *testing.T, answer string, run int) {
+	installFakeCLI(t, fmt.Sprintf(`#!/bin/sh
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"%[1]s"}]}}'
+echo '{"type":"result","subtype":"success","duration_ms":%[2]d,"num_turns":1,"session_id":"session-%[2]d","total_cost_usd":0.0%[2]d,"result":"%[1]s"}'
+`, answer, run))
+}
+
+// runRecordedAgent drives a two-turn agent through the recording session
+func runRecordedAgent(t *testing.T, rec *RecordingSession) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, prompt := range []string{"plan the change", "apply the change"} {
+		msgCh, errCh := rec.Query(ctx, prompt, nil)
+		for range msgCh {
+		}
+		if err := <-errCh; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+// recordingT captures assertion failures
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecordingSession(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "agent.golden.json")
+
+	fakeAgentCLI(t, "done", 1)
+	rec := NewRecordingSession()
+	runRecordedAgent(t, rec)
+
+	recording := rec.Recording()
+	if len(recording.Turns) != 2 {
+		t.Fatalf("Expected 2 recorded turns, got %d", len(recording.Turns))
+	}
+	if recording.Turns[1].Prompt != "apply the change" || recording.Turns[1].Answer != "done" {
+		t.Errorf("Unexpected turn: %+v", recording.Turns[1])
+	}
+	if err := rec.Save(golden); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	t.Run("matches modulo volatile fields", func(t *testing.T) {
+		fakeAgentCLI(t, "done", 2)
+		rerun := NewRecordingSession()
+		runRecordedAgent(t, rerun)
+
+		rt := &recordingT{}
+		rerun.AssertMatches(rt, golden)
+		if len(rt.errors) != 0 {
+			t.Errorf("Expected no differences, got %v", rt.errors)
+		}
+	})
+
+	t.Run("reports changed answers", func(t *testing.T) {
+		fakeAgentCLI(t, "different", 1)
+		rerun := NewRecordingSession()
+		runRecordedAgent(t, rerun)
+
+		rt := &recordingT{}
+		rerun.AssertMatches(rt, golden)
+		if len(rt.errors) == 0 {
+			t.Fatal("Expected differences to be reported")
+		}
+		if !strings.Contains(rt.errors[0], "turns[0].answer") {
+			t.Errorf("Expected answer difference first, got %v", rt.errors)
+		}
+	})
+}
+
+func TestCompareRecordings(t *testing.T) {
+	want := &Recording{Version: 1, Turns: []RecordedTurn{{Prompt: "a", Answer: "x"}}}
+
+	t.Run("turn count", func(t *testing.T) {
+		got := &Recording{Version: 1}
+		diffs := CompareRecordings(want, got)
+		if len(diffs) != 1 || !strings.Contains(diffs[0], "turn count") {
+			t.Errorf("Expected turn count difference, got %v", diffs)
+		}
+	})
+
+	t.Run("custom volatile field", func(t *testing.T) {
+		got := &Recording{Version: 1, Turns: []RecordedTurn{{Prompt: "a", Answer: "y"}}}
+		if diffs := CompareRecordings(want, got, "answer"); len(diffs) != 0 {
+			t.Errorf("Expected answer to be ignored, got %v", diffs)
+		}
+	})
+}
+
+func TestLoadRecordingErrors(t *testing.T) {
+	if _, err := LoadRecording(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected error for missing recording")
+	}
+}
@@ -0,0 +1,196 @@
+package claudecode
+
+import (
+	"context"
+	"regexp"
+)
+
+// DefaultRedactionPatterns match common secrets: AWS access key IDs and
+// secret keys, JSON Web Tokens, PEM private keys, Anthropic API keys and
+// GitHub tokens
+var DefaultRedactionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`(?i)aws_secret_access_key["']?\s*[=:]\s*["']?[A-Za-z0-9/+=]{40}["']?`),
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+}
+
+// Redactor replaces secrets in assistant text, thinking, tool results and
+// final results before messages are delivered or archived. Tool results are
+// redacted in the user messages the CLI reports them in; tool inputs and
+// the text of user messages are left untouched. The text of StreamEvent deltas is
+// blanked rather than matched, as a secret may be split across deltas; the
+// redacted text follows in the AssistantMessage.
+//
+// Example:
+//
+//	redactor := NewRedactor(regexp.MustCompile(`internal-[0-9a-f]{32}`))
+//	rec := NewRecordingSession()
+//	runAgent(ctx, redactor.Wrap(rec.Query)) // recordings hold redacted text
+type Redactor struct {
+	// Patterns are the expressions whose matches are replaced
+	Patterns []*regexp.Regexp
+	// Replacement is substituted for each match (defaults to "[REDACTED]")
+	Replacement string
+}
+
+// NewRedactor creates a Redactor for DefaultRedactionPatterns plus extra
+func NewRedactor(extra ...*regexp.Regexp) *Redactor {
+	patterns := make([]*regexp.Regexp, 0, len(DefaultRedactionPatterns)+len(extra))
+	patterns = append(patterns, DefaultRedactionPatterns...)
+	return &Redactor{Patterns: append(patterns, extra...)}
+}
+
+// RedactText returns text with every match replaced
+func (r *Redactor) RedactText(text string) string {
+	replacement := r.Replacement
+	if replacement == "" {
+		replacement = "[REDACTED]"
+	}
+	for _, pattern := range r.Patterns {
+		text = pattern.ReplaceAllLiteralString(text, replacement)
+	}
+	return text
+}
+
+// RedactMessage returns a redacted copy of msg. The original message and
+// its content blocks are not modified.
+func (r *Redactor) RedactMessage(msg Message) Message {
+	switch m := msg.(type) {
+	case AssistantMessage:
+		content := make([]ContentBlock, len(m.Content))
+		for i, block := range m.Content {
+			content[i] = r.redactBlock(block)
+		}
+		m.Content = content
+		return m
+	case UserMessage:
+		if len(m.Blocks) > 0 {
+			blocks := make([]ContentBlock, len(m.Blocks))
+			for i, block := range m.Blocks {
+				blocks[i] = r.redactBlock(block)
+			}
+			m.Blocks = blocks
+		}
+		return m
+	case ResultMessage:
+		if m.Result != nil {
+			m.Result = StringPtr(r.RedactText(*m.Result))
+		}
+		return m
+	case StreamEvent:
+		return redactStreamEvent(m)
+	}
+	return msg
+}
+
+// redactStreamEvent returns a copy of e with the text of a text or thinking
+// delta blanked
+func redactStreamEvent(e StreamEvent) StreamEvent {
+	if e.Event["type"] != "content_block_delta" {
+		return e
+	}
+	delta, _ := e.Event["delta"].(map[string]interface{})
+	var field string
+	switch delta["type"] {
+	case "text_delta":
+		field = "text"
+	case "thinking_delta":
+		field = "thinking"
+	default:
+		return e
+	}
+
+	blanked := make(map[string]interface{}, len(delta))
+	for k, v := range delta {
+		blanked[k] = v
+	}
+	blanked[field] = ""
+	event := make(map[string]interface{}, len(e.Event))
+	for k, v := range e.Event {
+		event[k] = v
+	}
+	event["delta"] = blanked
+	e.Event = event
+	return e
+}
+
+// redactBlock returns a redacted copy of block
+func (r *Redactor) redactBlock(block ContentBlock) ContentBlock {
+	switch b := block.(type) {
+	case TextBlock:
+		b.Text = r.RedactText(b.Text)
+		return b
+	case ThinkingBlock:
+		b.Thinking = r.RedactText(b.Thinking)
+		return b
+	case ToolResultBlock:
+		b.Content = r.redactToolContent(b.Content)
+		return b
+	}
+	return block
+}
+
+// redactToolContent redacts a tool result, which is a string or a list of
+// content items with text fields
+func (r *Redactor) redactToolContent(content interface{}) interface{} {
+	switch c := content.(type) {
+	case string:
+		return r.RedactText(c)
+	case []interface{}:
+		items := make([]interface{}, len(c))
+		for i, item := range c {
+			items[i] = r.redactToolContent(item)
+		}
+		return items
+	case []map[string]interface{}:
+		items := make([]map[string]interface{}, len(c))
+		for i, item := range c {
+			items[i] = r.redactToolContent(item).(map[string]interface{})
+		}
+		return items
+	case map[string]interface{}:
+		item := make(map[string]interface{}, len(c))
+		for k, v := range c {
+			if text, ok := v.(string); ok && k == "text" {
+				v = r.RedactText(text)
+			}
+			item[k] = v
+		}
+		return item
+	}
+	return content
+}
+
+// Wrap returns a QueryFunc that runs next and redacts every message it
+// delivers
+func (r *Redactor) Wrap(next QueryFunc) QueryFunc {
+	return func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+		msgCh, errCh := next(ctx, prompt, options)
+		return r.Stream(ctx, msgCh), errCh
+	}
+}
+
+// Stream redacts the messages of msgCh. The returned channel closes when
+// msgCh does; once ctx is done, messages are no longer delivered but msgCh
+// is still drained.
+func (r *Redactor) Stream(ctx context.Context, msgCh <-chan Message) <-chan Message {
+	out := make(chan Message, cap(msgCh))
+	go func() {
+		defer close(out)
+		stopped := false
+		for msg := range msgCh {
+			if stopped {
+				continue
+			}
+			select {
+			case out <- r.RedactMessage(msg):
+			case <-ctx.Done():
+				stopped = true
+			}
+		}
+	}()
+	return out
+}
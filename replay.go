@@ -0,0 +1,158 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Replayer serves a Recording back turn by turn, so a recorded multi-turn
+// agent run can be replayed as a regression test. Each query must send the
+// prompt recorded for its turn; a different prompt means the orchestration
+// code made a different decision given the same model output, and is
+// reported as a divergence.
+//
+// Turns recorded with RecordingSession.RecordMessages replay their full
+// message stream, including tool calls and results. Other turns replay their
+// answer and result.
+//
+// Example:
+//
+//	replayer, err := LoadReplayer("testdata/agent.golden.json")
+//	if err != nil {
+//	    t.Fatal(err)
+//	}
+//	runAgent(ctx, replayer.Query)
+//	replayer.AssertComplete(t)
+type Replayer struct {
+	mu          sync.Mutex
+	turns       []RecordedTurn
+	next        int
+	divergences []string
+}
+
+// NewReplayer creates a Replayer for rec
+func NewReplayer(rec *Recording) *Replayer {
+	return &Replayer{turns: rec.Turns}
+}
+
+// LoadReplayer creates a Replayer for the recording saved at path
+func LoadReplayer(path string) (*Replayer, error) {
+	rec, err := LoadRecording(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(rec), nil
+}
+
+// Query has the signature of the package level Query and replays the next
+// recorded turn
+func (r *Replayer) Query(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+	if options == nil {
+		options = NewOptions()
+	}
+
+	r.mu.Lock()
+	var turn RecordedTurn
+	var err error
+	switch {
+	case r.next >= len(r.turns):
+		err = fmt.Errorf("replay diverged: unexpected query %d with prompt %q", r.next+1, prompt)
+	case r.turns[r.next].Prompt != prompt:
+		err = fmt.Errorf("replay diverged at turn %d: expected prompt %q, got %q", r.next+1, r.turns[r.next].Prompt, prompt)
+	default:
+		turn = r.turns[r.next]
+	}
+	if err != nil {
+		r.divergences = append(r.divergences, err.Error())
+	}
+	r.next++
+	r.mu.Unlock()
+
+	if err != nil {
+		return failedQuery(err, options)
+	}
+
+	msgs, err := replayMessages(turn)
+	if err != nil {
+		return failedQuery(err, options)
+	}
+
+	msgCh := make(chan Message, options.GetMessageBufferSize())
+	errCh := make(chan error, options.GetErrorBufferSize())
+	go func() {
+		defer close(msgCh)
+		defer close(errCh)
+
+		for _, msg := range msgs {
+			// Recorded errors are replayed the way the options ask for them
+			if errMsg, ok := msg.(ErrorMessage); ok && !options.InlineErrors {
+				errCh <- errMsg.Err
+				continue
+			}
+			select {
+			case msgCh <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if len(turn.Messages) == 0 && turn.Error != "" {
+			err := errors.New(turn.Error)
+			if options.InlineErrors {
+				select {
+				case msgCh <- ErrorMessage{Err: err}:
+				case <-ctx.Done():
+				}
+			} else {
+				errCh <- err
+			}
+		}
+	}()
+	return msgCh, errCh
+}
+
+// replayMessages returns the messages to replay for turn
+func replayMessages(turn RecordedTurn) ([]Message, error) {
+	if len(turn.Messages) == 0 {
+		var msgs []Message
+		if turn.Answer != "" {
+			msgs = append(msgs, AssistantMessage{Content: []ContentBlock{TextBlock{Text: turn.Answer}}})
+		}
+		if turn.Result != nil {
+			msgs = append(msgs, *turn.Result)
+		}
+		return msgs, nil
+	}
+
+	msgs := make([]Message, 0, len(turn.Messages))
+	for _, recorded := range turn.Messages {
+		msg, err := recorded.Message()
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// Divergences describes every query that did not match the recording, plus
+// any recorded turns that were never replayed
+func (r *Replayer) Divergences() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	divergences := append([]string(nil), r.divergences...)
+	for i := r.next; i < len(r.turns); i++ {
+		divergences = append(divergences, fmt.Sprintf("turn %d with prompt %q was not replayed", i+1, r.turns[i].Prompt))
+	}
+	return divergences
+}
+
+// AssertComplete reports every divergence on t
+func (r *Replayer) AssertComplete(t TestingT) {
+	t.Helper()
+	for _, d := range r.Divergences() {
+		t.Errorf("%s", d)
+	}
+}
@@ -0,0 +1,173 @@
+package claudecode
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// toolAgent asks for a plan and follows up depending on the tool Claude used.
+// It returns every message it saw.
+func toolAgent(t *testing.T, query QueryFunc, followUp func(tool string) string) []Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var seen []Message
+	prompt := "inspect the repo"
+	for prompt != "" {
+		msgCh, errCh := query(ctx, prompt, nil)
+		next := ""
+		for msg := range msgCh {
+			seen = append(seen, msg)
+			if am, ok := msg.(AssistantMessage); ok {
+				for _, block := range am.Content {
+					if use, ok := block.(ToolUseBlock); ok {
+						next = followUp(use.Name)
+					}
+				}
+			}
+		}
+		if err := <-errCh; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		prompt = next
+	}
+	return seen
+}
+
+func TestReplayRecordedSession(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+case "$*" in
+*"inspect the repo"*)
+	echo '{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Grep","input":{"pattern":"TODO"}}]}}'
+	echo '{"type":"assistant","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"main.go:3: TODO"}]}}'
+	echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Found one TODO"}]}}'
+	;;
+*)
+	echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Fixed"}]}}'
+	;;
+esac
+echo '{"type":"result","subtype":"success","num_turns":1,"session_id":"s","total_cost_usd":0.01}'
+`)
+
+	followUp := func(tool string) string {
+		if tool == ToolGrep {
+			return "fix the TODOs"
+		}
+		return ""
+	}
+
+	rec := NewRecordingSession()
+	rec.RecordMessages = true
+	recorded := toolAgent(t, rec.Query, followUp)
+	golden := filepath.Join(t.TempDir(), "agent.golden.json")
+	if err := rec.Save(golden); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay without the CLI
+	t.Setenv("PATH", t.TempDir())
+	replayer, err := LoadReplayer(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := toolAgent(t, replayer.Query, followUp)
+	replayer.AssertComplete(t)
+
+	if !reflect.DeepEqual(recorded, replayed) {
+		t.Errorf("replayed messages differ:\nrecorded %#v\nreplayed %#v", recorded, replayed)
+	}
+
+	// Orchestration that reacts differently to the same output diverges
+	replayer, err = LoadReplayer(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	msgCh, errCh := replayer.Query(ctx, "inspect the repo", nil)
+	for range msgCh {
+	}
+	<-errCh
+	msgCh, errCh = replayer.Query(ctx, "write a report", nil)
+	for range msgCh {
+	}
+	if err := <-errCh; err == nil || !strings.Contains(err.Error(), "diverged at turn 2") {
+		t.Errorf("expected divergence error, got %v", err)
+	}
+
+	divergences := replayer.Divergences()
+	if len(divergences) != 1 {
+		t.Errorf("expected 1 divergence, got %v", divergences)
+	}
+}
+
+func TestReplayIncompleteAndErrors(t *testing.T) {
+	result := ResultMessage{Subtype: "success", Result: StringPtr("hi")}
+	replayer := NewReplayer(&Recording{Version: recordingVersion, Turns: []RecordedTurn{
+		{Prompt: "hello", Answer: "hi", Result: &result},
+		{Prompt: "fail", Error: "boom"},
+		{Prompt: "never sent"},
+	}})
+	ctx := context.Background()
+
+	msgCh, errCh := replayer.Query(ctx, "hello", nil)
+	var msgs []Message
+	for msg := range msgCh {
+		msgs = append(msgs, msg)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || !reflect.DeepEqual(msgs[1], result) {
+		t.Errorf("unexpected replay of answer-only turn: %#v", msgs)
+	}
+
+	opts := NewOptions()
+	opts.InlineErrors = true
+	msgCh, _ = replayer.Query(ctx, "fail", opts)
+	var last Message
+	for msg := range msgCh {
+		last = msg
+	}
+	if errMsg, ok := last.(ErrorMessage); !ok || errMsg.Error() != "boom" {
+		t.Errorf("expected inline recorded error, got %#v", last)
+	}
+
+	mock := &recordingT{}
+	replayer.AssertComplete(mock)
+	if len(mock.errors) != 1 || !strings.Contains(mock.errors[0], "never sent") {
+		t.Errorf("expected unreplayed turn to be reported, got %v", mock.errors)
+	}
+}
+
+func TestRecordedMessageRoundTrip(t *testing.T) {
+	isError := true
+	msgs := []Message{
+		UserMessage{Content: "hi"},
+		UserMessage{Blocks: []ContentBlock{ToolResultBlock{ToolUseID: "1", Content: "out"}}},
+		AssistantMessage{Content: []ContentBlock{
+			TextBlock{Text: "x"},
+			ToolUseBlock{ID: "1", Name: "Read", Input: map[string]interface{}{"file_path": "a"}},
+			ToolResultBlock{ToolUseID: "1", Content: "data", IsError: &isError},
+		}},
+		SystemMessage{Subtype: "init", Data: map[string]interface{}{"k": "v"}},
+		ResultMessage{Subtype: "success", NumTurns: 2, TotalCostUSD: Float64Ptr(0.5)},
+	}
+	for _, msg := range msgs {
+		recorded, err := recordMessage(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := recorded.Message()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, msg) {
+			t.Errorf("round trip mismatch:\nwant %#v\ngot  %#v", msg, got)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// QueryRequest describes a single query. It is the extension point for
+// per-request data that does not belong in Options.
+type QueryRequest struct {
+	// Prompt is the prompt to send to Claude
+	Prompt string `json:"prompt"`
+	// Options configures the query (uses NewOptions() if nil)
+	Options *Options `json:"options,omitempty"`
+	// Metadata carries caller-defined key/value pairs such as tenant or
+	// trace identifiers alongside the request. It is merged over metadata
+	// attached to the context with WithMetadata.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Labels are cost allocation labels such as team, feature or ticket. They
+	// are merged over labels attached to the context with WithLabels and
+	// flow into recordings and usage reports.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Attachments are local files staged into the working directory for the
+	// duration of the query and referenced from the prompt
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Workspace, when set, provisions an isolated working directory for the
+	// query. It overrides Options.Cwd and is disposed of when the query ends.
+	Workspace WorkspaceProvider `json:"-"`
+	// Rollback, when set, reverts the query's file changes in its working
+	// directory if it fails or the policy's Review rejects them
+	Rollback *RollbackPolicy `json:"-"`
+	// Usage, when set, records the query's cost and token usage under its
+	// labels
+	Usage *UsageTracker `json:"-"`
+	// Middleware runs on the request after any middleware attached to the
+	// context with WithMiddleware, before the CLI command is built
+	Middleware []QueryMiddleware `json:"-"`
+}
+
+// Do runs the query described by req. It behaves like Query. Middleware
+// attached to the context and req.Middleware run first, on a copy of req.
+//
+// Example:
+//
+//	msgCh, errCh := Do(ctx, &QueryRequest{
+//	    Prompt:   "Summarize the README",
+//	    Metadata: map[string]string{"tenant": "acme"},
+//	})
+func Do(ctx context.Context, req *QueryRequest) (<-chan Message, <-chan error) {
+	if req == nil {
+		return failedQuery(fmt.Errorf("query request cannot be nil"), nil)
+	}
+	prepared, err := applyMiddleware(ctx, req, req.Middleware)
+	if err != nil {
+		return failedQuery(err, req.Options)
+	}
+	req = prepared
+	ctx = WithMetadata(ctx, req.Metadata)
+	ctx = WithLabels(ctx, req.Labels)
+
+	msgCh, errCh := do(ctx, req)
+	if req.Usage != nil {
+		return req.Usage.track(ctx, req.Options, msgCh, errCh)
+	}
+	return msgCh, errCh
+}
+
+// do runs req once the context carries its metadata and labels
+func do(ctx context.Context, req *QueryRequest) (<-chan Message, <-chan error) {
+	if len(req.Attachments) == 0 && req.Workspace == nil && req.Rollback == nil {
+		return query(ctx, req.Prompt, req.Options)
+	}
+
+	options := req.Options
+	if options == nil {
+		options = NewOptions()
+	}
+
+	var cleanups []func(failed bool) error
+	cleanup := func(failed bool) error {
+		var errs Errors
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			if err := cleanups[i](failed); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errs.ErrorOrNil()
+	}
+
+	if req.Workspace != nil {
+		ws, err := req.Workspace.Acquire(ctx)
+		if err != nil {
+			return failedQuery(fmt.Errorf("failed to acquire workspace: %w", err), options)
+		}
+		cleanups = append(cleanups, func(bool) error {
+			return ws.Close()
+		})
+
+		// Run in the workspace without touching the caller's options
+		wsOptions := *options
+		wsOptions.Cwd = ws.Dir
+		options = &wsOptions
+	}
+
+	workDir := options.GetCwd()
+	if workDir == "" {
+		var err error
+		if workDir, err = os.Getwd(); err != nil {
+			cleanup(true)
+			return failedQuery(fmt.Errorf("failed to determine working directory: %w", err), options)
+		}
+	}
+
+	if req.Rollback != nil {
+		settle, err := req.Rollback.begin(ctx, workDir)
+		if err != nil {
+			cleanup(true)
+			return failedQuery(err, options)
+		}
+		cleanups = append(cleanups, settle)
+	}
+
+	prompt := req.Prompt
+	if len(req.Attachments) > 0 {
+
+		dir, staged, err := stageAttachments(workDir, prompt, req.Attachments)
+		if err != nil {
+			cleanup(true)
+			return failedQuery(err, options)
+		}
+		prompt = staged
+		cleanups = append(cleanups, func(bool) error {
+			os.RemoveAll(dir)
+			return nil
+		})
+	}
+
+	msgCh, errCh := query(ctx, prompt, options)
+	return withCleanup(ctx, options, msgCh, errCh, cleanup)
+}
+
+// failedQuery returns closed channels reporting err the way Query would for
+// the given options
+func failedQuery(err error, options *Options) (<-chan Message, <-chan error) {
+	if options == nil {
+		options = NewOptions()
+	}
+
+	msgCh := make(chan Message, 1)
+	errCh := make(chan error, 1)
+	if options.InlineErrors {
+		msgCh <- ErrorMessage{Err: err}
+	} else {
+		errCh <- err
+	}
+	close(msgCh)
+	close(errCh)
+	return msgCh, errCh
+}
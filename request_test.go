@@ -0,0 +1,75 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// releaseFailingProvider hands out workspaces whose release fails
+type releaseFailingProvider struct {
+	dir string
+}
+
+func (p releaseFailingProvider) Acquire(ctx context.Context) (*Workspace, error) {
+	return NewWorkspace(p.dir, func() error {
+		return errors.New("release failed")
+	}), nil
+}
+
+func TestDo(t *testing.T) {
+	t.Run("runs the request prompt", func(t *testing.T) {
+		installFakeCLI(t, `#!/bin/sh
+for arg in "$@"; do last="$arg"; done
+echo "{\"type\":\"result\",\"subtype\":\"success\",\"result\":\"$last\"}"
+`)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		msgCh, errCh := Do(ctx, &QueryRequest{
+			Prompt:   "echo me",
+			Metadata: map[string]string{"tenant": "acme"},
+		})
+		var result *ResultMessage
+		for msg := range msgCh {
+			if m, ok := msg.(ResultMessage); ok {
+				result = &m
+			}
+		}
+		if err := <-errCh; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result == nil || SafeStringPtr(result.Result) != "echo me" {
+			t.Errorf("Expected result to echo the prompt, got %+v", result)
+		}
+	})
+
+	t.Run("nil request", func(t *testing.T) {
+		msgCh, errCh := Do(context.Background(), nil)
+		for range msgCh {
+		}
+		if err := <-errCh; err == nil || !strings.Contains(err.Error(), "cannot be nil") {
+			t.Errorf("Expected nil request error, got %v", err)
+		}
+	})
+
+	t.Run("reports workspace release errors", func(t *testing.T) {
+		installFakeCLI(t, `#!/bin/sh
+echo '{"type":"result","subtype":"success"}'
+`)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		msgCh, errCh := Do(ctx, &QueryRequest{
+			Prompt:    "test",
+			Workspace: releaseFailingProvider{dir: t.TempDir()},
+		})
+		for range msgCh {
+		}
+		if err := <-errCh; err == nil || !strings.Contains(err.Error(), "release failed") {
+			t.Errorf("Expected the release error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// QueryResult runs prompt and returns only the final ResultMessage. The CLI is
+// asked for its non-streaming JSON output, so no intermediate messages are
+// produced or delivered. Options that need stdin, such as CanUseTool or
+// Hooks, run over stream-json instead; the intermediate messages are then
+// discarded. A result with IsError set is returned without an error; its Err
+// method reports why the run failed.
+//
+// Example:
+//
+//	result, err := QueryResult(ctx, "Run the linter and fix warnings", opts)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Printf("cost: $%.4f\n", SafeFloat64Ptr(result.TotalCostUSD))
+func QueryResult(ctx context.Context, prompt string, options *Options) (*ResultMessage, error) {
+	var opts Options
+	if options != nil {
+		opts = *options
+	} else {
+		opts = *NewOptions()
+	}
+	opts.outputFormat = "json"
+	opts.InlineErrors = false
+
+	msgCh, errCh := Query(ctx, prompt, &opts)
+
+	var result *ResultMessage
+	for msg := range msgCh {
+		if m, ok := msg.(ResultMessage); ok {
+			result = &m
+		}
+	}
+	if err := <-errCh; err != nil {
+		return result, err
+	}
+	if result == nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("no result message received")
+	}
+	return result, nil
+}
+
+// QueryText runs prompt and returns the assistant's text together with the
+// final ResultMessage. The text blocks of each assistant message are joined,
+// one message per line. On failure the text received so far is returned
+// with the error.
+//
+// Example:
+//
+//	answer, result, err := QueryText(ctx, "What does main.go do?", nil)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(answer)
+func QueryText(ctx context.Context, prompt string, options *Options) (string, *ResultMessage, error) {
+	var opts Options
+	if options != nil {
+		opts = *options
+	} else {
+		opts = *NewOptions()
+	}
+	opts.InlineErrors = false
+
+	msgCh, errCh := Query(ctx, prompt, &opts)
+
+	collected := &PartialResult{}
+	var result *ResultMessage
+	for msg := range msgCh {
+		collected.add(msg)
+		if m, ok := msg.(ResultMessage); ok {
+			result = &m
+		}
+	}
+	if err := <-errCh; err != nil {
+		return collected.Text, result, err
+	}
+	if result == nil {
+		if err := ctx.Err(); err != nil {
+			return collected.Text, nil, err
+		}
+		return collected.Text, nil, fmt.Errorf("no result message received")
+	}
+	return collected.Text, result, nil
+}
+
+// rateLimitMarkers and overloadMarkers identify failed results caused by the
+// API rate limit or by overload, in their subtype or text
+var (
+	rateLimitMarkers = []string{"rate limit", "rate_limit", "too many requests", "error: 429"}
+	overloadMarkers  = []string{"overloaded", "error: 529"}
+)
+
+// Err returns the error a failed result reports, or nil if the run
+// succeeded: a MaxTurnsExceededError for the error_max_turns subtype, a
+// RateLimitError when the API rate limit was hit or the API is overloaded,
+// and a ResultError otherwise. A result fails if IsError is set or its
+// subtype starts with "error".
+//
+// Example:
+//
+//	result, err := QueryResult(ctx, prompt, opts)
+//	if err == nil {
+//	    err = result.Err()
+//	}
+//	var rateErr *RateLimitError
+//	if errors.As(err, &rateErr) {
+//	    time.Sleep(rateErr.RetryAfter)
+//	}
+func (m ResultMessage) Err() error {
+	if !m.IsError && !strings.HasPrefix(m.Subtype, "error") {
+		return nil
+	}
+	detail := SafeStringPtr(m.Result)
+	if m.Subtype == "error_max_turns" {
+		return NewMaxTurnsExceededError(m.Subtype, m.NumTurns)
+	}
+
+	text := strings.ToLower(m.Subtype + " " + detail)
+	for _, marker := range overloadMarkers {
+		if strings.Contains(text, marker) {
+			return NewRateLimitError(m.Subtype, detail, true)
+		}
+	}
+	for _, marker := range rateLimitMarkers {
+		if strings.Contains(text, marker) {
+			return NewRateLimitError(m.Subtype, detail, false)
+		}
+	}
+	return NewResultError(m.Subtype, detail)
+}
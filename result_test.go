@@ -0,0 +1,157 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryResult(t *testing.T) {
+	t.Run("requests non-streaming output", func(t *testing.T) {
+		installFakeCLI(t, `#!/bin/sh
+case "$*" in
+	*"--output-format json "*--verbose*) echo '{"type":"result","subtype":"error_during_execution","is_error":true}' ;;
+	*"--output-format json "*) echo '{"type":"result","subtype":"success","num_turns":2,"total_cost_usd":0.25,"result":"ok"}' ;;
+	*) echo '{"type":"result","subtype":"error_during_execution","is_error":true}' ;;
+esac
+`)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		result, err := QueryResult(ctx, "test", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Subtype != "success" || SafeStringPtr(result.Result) != "ok" || SafeFloat64Ptr(result.TotalCostUSD) != 0.25 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("streams when options need stdin", func(t *testing.T) {
+		installFakeCLI(t, `#!/bin/sh
+case "$*" in
+	*"--output-format stream-json --verbose"*"--input-format stream-json"*)
+		echo '{"type":"assistant","message":{"content":[{"type":"text","text":"working"}]}}'
+		echo '{"type":"result","subtype":"success","result":"ok"}' ;;
+	*) echo "error: unexpected arguments $*" >&2; exit 1 ;;
+esac
+while read -r line; do :; done
+`)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		opts := NewOptions()
+		opts.CanUseTool = func(context.Context, string, map[string]interface{}) PermissionDecision {
+			return AllowTool()
+		}
+		result, err := QueryResult(ctx, "test", opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Subtype != "success" || SafeStringPtr(result.Result) != "ok" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("does not modify caller options", func(t *testing.T) {
+		installFakeCLI(t, `#!/bin/sh
+echo '{"type":"result","subtype":"success"}'
+`)
+		opts := NewOptions()
+		opts.InlineErrors = true
+		if _, err := QueryResult(context.Background(), "test", opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if opts.GetOutputFormat() != "" || !opts.InlineErrors {
+			t.Error("QueryResult modified the caller's options")
+		}
+	})
+
+	t.Run("missing result", func(t *testing.T) {
+		installFakeCLI(t, `#!/bin/sh
+exit 0
+`)
+		_, err := QueryResult(context.Background(), "test", nil)
+		if err == nil || !strings.Contains(err.Error(), "no result message") {
+			t.Errorf("Expected missing result error, got %v", err)
+		}
+	})
+}
+
+func TestQueryText(t *testing.T) {
+	t.Run("joins assistant text", func(t *testing.T) {
+		installFakeCLI(t, `#!/bin/sh
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Let me "},{"type":"tool_use","id":"t1","name":"Read","input":{}},{"type":"text","text":"check."}]}}'
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"It prints hello."}]}}'
+echo '{"type":"result","subtype":"success","num_turns":2,"result":"It prints hello."}'
+`)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		text, result, err := QueryText(ctx, "test", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if text != "Let me check.\nIt prints hello." {
+			t.Errorf("Unexpected text %q", text)
+		}
+		if result == nil || result.NumTurns != 2 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("returns text received before a failure", func(t *testing.T) {
+		installFakeCLI(t, `#!/bin/sh
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"partial"}]}}'
+exit 3
+`)
+		opts := NewOptions()
+		opts.InlineErrors = true
+		text, result, err := QueryText(context.Background(), "test", opts)
+		if err == nil {
+			t.Fatal("Expected an error")
+		}
+		if text != "partial" || result != nil {
+			t.Errorf("Unexpected text %q and result %+v", text, result)
+		}
+	})
+}
+
+func TestResultMessageErr(t *testing.T) {
+	text := func(s string) *string { return &s }
+
+	if err := (ResultMessage{Subtype: "success"}).Err(); err != nil {
+		t.Errorf("expected nil for a successful result, got %v", err)
+	}
+
+	var maxTurns *MaxTurnsExceededError
+	err := ResultMessage{Subtype: "error_max_turns", NumTurns: 5}.Err()
+	if !errors.As(err, &maxTurns) || maxTurns.NumTurns != 5 || ErrorCodeOf(err) != ErrorCodeMaxTurnsExceeded {
+		t.Errorf("expected MaxTurnsExceededError after 5 turns, got %#v", err)
+	}
+
+	var rateErr *RateLimitError
+	err = ResultMessage{Subtype: "error_during_execution", IsError: true, Result: text("API Error: 429 rate_limit_error. Retry-After: 12")}.Err()
+	if !errors.As(err, &rateErr) || rateErr.Overloaded || rateErr.RetryAfter != 12*time.Second {
+		t.Errorf("expected RateLimitError with a 12s retry delay, got %#v", err)
+	}
+	if ErrorCodeOf(err) != ErrorCodeRateLimited || ErrorRetryAfter(err) != 12*time.Second {
+		t.Errorf("unexpected code %q or retry delay %v", ErrorCodeOf(err), ErrorRetryAfter(err))
+	}
+
+	err = ResultMessage{Subtype: "error_during_execution", IsError: true, Result: text("API Error: 529 overloaded_error")}.Err()
+	if !errors.As(err, &rateErr) || !rateErr.Overloaded || ErrorCodeOf(err) != ErrorCodeOverloaded {
+		t.Errorf("expected overloaded RateLimitError, got %#v", err)
+	}
+
+	var resultErr *ResultError
+	err = ResultMessage{Subtype: "error_during_execution", IsError: true, Result: text("tool crashed")}.Err()
+	if !errors.As(err, &resultErr) || resultErr.Detail != "tool crashed" || ErrorCodeOf(err) != ErrorCodeResult {
+		t.Errorf("expected ResultError, got %#v", err)
+	}
+	if errors.As(err, &rateErr) || IsRetryableError(err) {
+		t.Error("a generic failed result should not be a retryable RateLimitError")
+	}
+}
@@ -0,0 +1,136 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// SystemSubtypeRetry is the subtype of the SystemMessage delivered before a
+// query is retried under Options.Retry. Its Data holds "attempt" (the
+// attempt about to start), "max_attempts", "delay_ms" and "error".
+const SystemSubtypeRetry = "retry"
+
+// Retry defaults used for zero RetryPolicy fields
+const (
+	defaultRetryAttempts       = 3
+	defaultRetryInitialBackoff = time.Second
+	defaultRetryMaxBackoff     = 30 * time.Second
+	defaultRetryMultiplier     = 2
+)
+
+// RetryPolicy configures automatic retries of queries that fail with
+// transient errors: CLI startup failures, process failures, stalls and
+// results failing with a RateLimitError. An attempt is only retried
+// while it has delivered nothing but system messages, so retries never
+// duplicate conversation output. A retry delay stated by the CLI or API
+// (see ErrorRetryAfter) is honored when longer than the backoff; a failure
+// stating a delay longer than MaxBackoff is reported without retrying.
+//
+// Example:
+//
+//	opts := NewOptions()
+//	opts.Retry = &RetryPolicy{MaxAttempts: 5, InitialBackoff: 2 * time.Second}
+//	msgCh, errCh := Query(ctx, "Hello", opts)
+type RetryPolicy struct {
+	MaxAttempts    int           `json:"max_attempts,omitempty"`    // Attempts including the first; 0 means 3
+	InitialBackoff time.Duration `json:"initial_backoff,omitempty"` // Delay before the first retry; 0 means 1s
+	MaxBackoff     time.Duration `json:"max_backoff,omitempty"`     // Cap on the delay, including stated ones; 0 means 30s
+	Multiplier     float64       `json:"multiplier,omitempty"`      // Growth of the delay per retry; 0 means 2
+	Jitter         bool          `json:"jitter,omitempty"`          // Randomize each delay between half and all of it
+	// Retryable classifies errors; nil uses IsRetryableError
+	Retryable func(err error) bool `json:"-"`
+}
+
+// IsRetryableError reports whether err is a transient failure worth
+// retrying: a CLI connection failure other than a missing or incompatible
+// CLI, a process failure, a stall, a startup timeout or a RateLimitError.
+// Context errors and SDK-side limits are not retryable.
+func IsRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var connErr *CLIConnectionError
+	var procErr *ProcessError
+	var stallErr *StallError
+	var startupErr *StartupTimeoutError
+	var rateErr *RateLimitError
+	return errors.As(err, &connErr) || errors.As(err, &procErr) || errors.As(err, &stallErr) ||
+		errors.As(err, &startupErr) || errors.As(err, &rateErr)
+}
+
+// attempts returns the total number of attempts the policy allows
+func (p *RetryPolicy) attempts() int {
+	if p == nil {
+		return 1
+	}
+	if p.MaxAttempts <= 0 {
+		return defaultRetryAttempts
+	}
+	return p.MaxAttempts
+}
+
+// retryable reports whether err should be retried under the policy
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryableError(err)
+}
+
+// maxBackoff returns the longest delay the policy waits before a retry
+func (p *RetryPolicy) maxBackoff() time.Duration {
+	if p == nil || p.MaxBackoff <= 0 {
+		return defaultRetryMaxBackoff
+	}
+	return p.MaxBackoff
+}
+
+// backoff returns the delay before the given retry, counted from 1
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	delay, limit, multiplier := p.InitialBackoff, p.maxBackoff(), p.Multiplier
+	if delay <= 0 {
+		delay = defaultRetryInitialBackoff
+	}
+	if multiplier <= 0 {
+		multiplier = defaultRetryMultiplier
+	}
+	for i := 1; i < retry && delay < limit; i++ {
+		delay = time.Duration(float64(delay) * multiplier)
+	}
+	if delay > limit {
+		delay = limit
+	}
+	if p.Jitter && delay > 1 {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+	return delay
+}
+
+// validate checks the policy's fields
+func (p *RetryPolicy) validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxAttempts < 0 || p.InitialBackoff < 0 || p.MaxBackoff < 0 {
+		return errors.New("retry attempts and backoffs must not be negative")
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return errors.New("retry multiplier must be at least 1")
+	}
+	return nil
+}
+
+// retryMessage builds the SystemMessage announcing a retry
+func retryMessage(attempt, maxAttempts int, delay time.Duration, cause string) SystemMessage {
+	return SystemMessage{
+		Subtype: SystemSubtypeRetry,
+		Data: map[string]interface{}{
+			"attempt":      attempt,
+			"max_attempts": maxAttempts,
+			"delay_ms":     delay.Milliseconds(),
+			"error":        cause,
+		},
+	}
+}
@@ -0,0 +1,218 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// installFlakyCLI installs a fake CLI that runs failure on its first
+// failures invocations and then answers normally
+func installFlakyCLI(t *testing.T, failures int, failure string) {
+	t.Helper()
+	counter := filepath.Join(t.TempDir(), "attempts")
+	installFakeCLI(t, fmt.Sprintf(`#!/bin/sh
+echo x >> %[1]q
+if [ "$(wc -l < %[1]q)" -le %[2]d ]; then
+%[3]s
+fi
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Hello"}]}}'
+echo '{"type":"result","subtype":"success","session_id":"s1"}'
+`, counter, failures, failure))
+}
+
+func retryMessages(msgs []Message) []SystemMessage {
+	var retries []SystemMessage
+	for _, msg := range msgs {
+		if sm, ok := msg.(SystemMessage); ok && sm.Subtype == SystemSubtypeRetry {
+			retries = append(retries, sm)
+		}
+	}
+	return retries
+}
+
+func collectQuery(t *testing.T, opts *Options) ([]Message, error) {
+	t.Helper()
+	msgCh, errCh := Query(context.Background(), "test", opts)
+	var msgs []Message
+	for msg := range msgCh {
+		msgs = append(msgs, msg)
+	}
+	return msgs, <-errCh
+}
+
+func TestQueryRetry(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+	t.Run("process failure", func(t *testing.T) {
+		installFlakyCLI(t, 2, "echo 'error: connection reset' >&2; exit 1")
+		opts := NewOptions()
+		opts.Retry = policy
+		msgs, err := collectQuery(t, opts)
+		if err != nil {
+			t.Fatalf("expected success after retries, got %v", err)
+		}
+		retries := retryMessages(msgs)
+		if len(retries) != 2 {
+			t.Fatalf("expected 2 retry messages, got %d", len(retries))
+		}
+		if retries[1].Data["attempt"] != 3 || retries[1].Data["max_attempts"] != 3 {
+			t.Errorf("unexpected retry data %v", retries[1].Data)
+		}
+		partial := &PartialResult{}
+		for _, msg := range msgs {
+			partial.add(msg)
+		}
+		if partial.Text != "Hello" {
+			t.Errorf("expected %q, got %q", "Hello", partial.Text)
+		}
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		installFlakyCLI(t, 3, "echo 'error: overloaded' >&2; exit 1")
+		opts := NewOptions()
+		opts.Retry = policy
+		msgs, err := collectQuery(t, opts)
+		var procErr *ProcessError
+		if !errors.As(err, &procErr) {
+			t.Fatalf("expected ProcessError, got %v", err)
+		}
+		if n := len(retryMessages(msgs)); n != 2 {
+			t.Errorf("expected 2 retry messages, got %d", n)
+		}
+	})
+
+	t.Run("rate limited result", func(t *testing.T) {
+		installFlakyCLI(t, 1, `echo '{"type":"result","subtype":"error_during_execution","is_error":true,"result":"API Error: 429 rate limit exceeded"}'; exit 0`)
+		opts := NewOptions()
+		opts.Retry = policy
+		msgs, err := collectQuery(t, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, msg := range msgs {
+			if result, ok := msg.(ResultMessage); ok && result.IsError {
+				t.Error("rate-limited result should have been retried, not delivered")
+			}
+		}
+		if n := len(retryMessages(msgs)); n != 1 {
+			t.Errorf("expected 1 retry message, got %d", n)
+		}
+	})
+
+	t.Run("retry after", func(t *testing.T) {
+		installFlakyCLI(t, 1, `echo '{"type":"result","subtype":"error_during_execution","is_error":true,"result":"Overloaded, retry after 30m"}'; exit 0`)
+		clk := NewFakeClock(time.Now())
+		opts := NewOptions()
+		opts.Clock = clk
+		opts.Retry = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Hour}
+		advanced := make(chan struct{})
+		go func() {
+			defer close(advanced)
+			clk.BlockUntil(1)
+			clk.Advance(30*time.Minute - time.Second)
+			if clk.Pending() != 1 {
+				t.Error("retried before the stated delay")
+			}
+			clk.Advance(time.Second)
+		}()
+		msgs, err := collectQuery(t, opts)
+		<-advanced
+		if err != nil {
+			t.Fatal(err)
+		}
+		retries := retryMessages(msgs)
+		if len(retries) != 1 || retries[0].Data["delay_ms"] != int64(30*time.Minute/time.Millisecond) {
+			t.Fatalf("expected one retry after 30m, got %v", retries)
+		}
+	})
+
+	t.Run("retry after beyond max backoff", func(t *testing.T) {
+		installFlakyCLI(t, 1, `echo '{"type":"result","subtype":"error_during_execution","is_error":true,"result":"Overloaded, retry after 30m"}'; exit 0`)
+		opts := NewOptions()
+		opts.Retry = policy
+		msgs, err := collectQuery(t, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(retryMessages(msgs)); n != 0 {
+			t.Fatalf("expected no retry, got %d", n)
+		}
+		result, ok := msgs[len(msgs)-1].(ResultMessage)
+		if !ok || ErrorRetryAfter(result.Err()) != 30*time.Minute {
+			t.Errorf("expected the failed result with its stated delay, got %+v", msgs)
+		}
+	})
+
+	t.Run("classifier", func(t *testing.T) {
+		installFlakyCLI(t, 1, "echo 'error: overloaded' >&2; exit 1")
+		opts := NewOptions()
+		opts.Retry = &RetryPolicy{InitialBackoff: time.Millisecond, Retryable: func(error) bool { return false }}
+		msgs, err := collectQuery(t, opts)
+		if err == nil || len(retryMessages(msgs)) != 0 {
+			t.Errorf("expected no retry, got err=%v messages=%d", err, len(msgs))
+		}
+	})
+
+	t.Run("output already delivered", func(t *testing.T) {
+		installFlakyCLI(t, 1, `echo '{"type":"assistant","message":{"content":[{"type":"text","text":"partial"}]}}'; echo 'error: reset' >&2; exit 1`)
+		opts := NewOptions()
+		opts.Retry = policy
+		msgs, err := collectQuery(t, opts)
+		if err == nil {
+			t.Fatal("expected the failure to be reported")
+		}
+		if n := len(retryMessages(msgs)); n != 0 {
+			t.Errorf("expected no retry after output, got %d", n)
+		}
+	})
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{&CLIConnectionError{SDKError: SDKError{Message: "failed to start"}}, true},
+		{NewProcessError("failed", nil, ""), true},
+		{fmt.Errorf("wrapped: %w", NewStallError(time.Second)), true},
+		{NewStartupTimeoutError(time.Second), true},
+		{NewCLINotFoundError("Claude Code not found", ""), false},
+		{NewIncompatibleCLIError("/bin/claude", "1.0.0", ">=2"), false},
+		{NewLimitExceededError(LimitTurns, 1), false},
+		{context.DeadlineExceeded, false},
+		{errors.New("other"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryableError(tt.err); got != tt.want {
+			t.Errorf("IsRetryableError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for retry, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second, 10: time.Second} {
+		if got := p.backoff(retry); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", retry, got, want)
+		}
+	}
+
+	p.Jitter = true
+	for i := 0; i < 20; i++ {
+		if got := p.backoff(2); got < 100*time.Millisecond || got > 200*time.Millisecond {
+			t.Fatalf("jittered backoff %v outside [100ms, 200ms]", got)
+		}
+	}
+
+	if (&RetryPolicy{Multiplier: 0.5}).validate() == nil {
+		t.Error("expected multiplier below 1 to be rejected")
+	}
+	if (&RetryPolicy{MaxAttempts: -1}).validate() == nil {
+		t.Error("expected negative attempts to be rejected")
+	}
+}
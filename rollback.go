@@ -0,0 +1,42 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+)
+
+// RollbackPolicy gives a query transactional semantics: the file changes it
+// makes to its working directory are reverted if it fails or if Review
+// rejects them
+type RollbackPolicy struct {
+	// Skip lists file or directory names excluded from tracking, e.g. ".git"
+	// or "node_modules"; changes to them are never reverted
+	Skip []string
+	// Review is called with the changes of a successful query; returning
+	// false reverts them. When nil, successful changes are kept.
+	Review func(cs *ChangeSet) bool
+}
+
+// begin snapshots dir and returns the cleanup that settles the transaction
+func (p *RollbackPolicy) begin(ctx context.Context, dir string) (func(failed bool) error, error) {
+	snapshot, err := TakeSnapshot(ctx, dir, p.Skip)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(failed bool) error {
+		// The query's context may be done; settling must still happen
+		ctx := context.Background()
+		if !failed && p.Review != nil {
+			cs, err := snapshot.Changes(ctx)
+			failed = err != nil || !p.Review(cs)
+		}
+		if !failed {
+			return nil
+		}
+		if err := snapshot.Restore(ctx); err != nil {
+			return fmt.Errorf("rollback failed: %w", err)
+		}
+		return nil
+	}, nil
+}
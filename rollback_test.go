@@ -0,0 +1,153 @@
+package claudecode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const rollbackScript = `#!/bin/sh
+echo changed > edit.txt
+mkdir -p sub
+echo new > sub/new.txt
+rm remove.txt
+echo ignored > node_modules/x.js
+`
+
+func runRollback(t *testing.T, script string, policy *RollbackPolicy) (string, error) {
+	t.Helper()
+	installFakeCLI(t, script)
+
+	workDir := t.TempDir()
+	writeTree(t, workDir, map[string]string{
+		"edit.txt":          "original\n",
+		"remove.txt":        "keep me\n",
+		"node_modules/x.js": "dep\n",
+	})
+	opts := NewOptions()
+	opts.Cwd = workDir
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msgCh, errCh := Do(ctx, &QueryRequest{
+		Prompt:   "Edit things",
+		Options:  opts,
+		Rollback: policy,
+	})
+	return workDir, Drain(ctx, msgCh, errCh)
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("reading %s: %v", path, err)
+		return
+	}
+	if string(data) != want {
+		t.Errorf("%s: expected %q, got %q", path, want, data)
+	}
+}
+
+func assertRolledBack(t *testing.T, workDir string) {
+	t.Helper()
+	assertFile(t, filepath.Join(workDir, "edit.txt"), "original\n")
+	assertFile(t, filepath.Join(workDir, "remove.txt"), "keep me\n")
+	if _, err := os.Stat(filepath.Join(workDir, "sub")); !os.IsNotExist(err) {
+		t.Errorf("expected added directory to be removed, got %v", err)
+	}
+	// Skipped paths are never reverted
+	assertFile(t, filepath.Join(workDir, "node_modules", "x.js"), "ignored\n")
+}
+
+func TestRollbackOnFailure(t *testing.T) {
+	workDir, err := runRollback(t, rollbackScript+"echo \"error: boom\" >&2\nexit 1\n", &RollbackPolicy{Skip: []string{"node_modules"}})
+	if err == nil {
+		t.Fatal("expected query error")
+	}
+	assertRolledBack(t, workDir)
+}
+
+func TestRollbackOnErrorResult(t *testing.T) {
+	script := rollbackScript + `echo '{"type":"result","subtype":"error_during_execution","is_error":true}'` + "\n"
+	workDir, err := runRollback(t, script, &RollbackPolicy{Skip: []string{"node_modules"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRolledBack(t, workDir)
+}
+
+func TestRollbackOnRejectedReview(t *testing.T) {
+	script := rollbackScript + `echo '{"type":"result","subtype":"success"}'` + "\n"
+	var reviewed *ChangeSet
+	workDir, err := runRollback(t, script, &RollbackPolicy{
+		Skip: []string{"node_modules"},
+		Review: func(cs *ChangeSet) bool {
+			reviewed = cs
+			return false
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reviewed == nil || len(reviewed.Changes) != 3 {
+		t.Fatalf("expected review of 3 changes, got %+v", reviewed)
+	}
+	assertRolledBack(t, workDir)
+}
+
+func TestRollbackKeepsAcceptedChanges(t *testing.T) {
+	script := rollbackScript + `echo '{"type":"result","subtype":"success"}'` + "\n"
+	workDir, err := runRollback(t, script, &RollbackPolicy{
+		Skip:   []string{"node_modules"},
+		Review: func(*ChangeSet) bool { return true },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertFile(t, filepath.Join(workDir, "edit.txt"), "changed\n")
+	assertFile(t, filepath.Join(workDir, "sub", "new.txt"), "new\n")
+	if _, err := os.Stat(filepath.Join(workDir, "remove.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected deleted file to stay deleted, got %v", err)
+	}
+}
+
+func TestRollbackAfterCancelWaitsForCLI(t *testing.T) {
+	// The CLI keeps editing files until it is killed
+	installFakeCLI(t, `#!/bin/sh
+echo changed > edit.txt
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"editing"}]}}'
+i=0
+while :; do
+	i=$((i + 1))
+	echo "$i" > edit.txt
+	echo "$i" > "new$((i % 8)).txt"
+	rm -f "new$(((i + 4) % 8)).txt"
+done
+`)
+	for i := 0; i < 20; i++ {
+		workDir := t.TempDir()
+		writeTree(t, workDir, map[string]string{"edit.txt": "original\n"})
+		opts := NewOptions()
+		opts.Cwd = workDir
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		msgCh, errCh := Do(ctx, &QueryRequest{Prompt: "Edit things", Options: opts, Rollback: &RollbackPolicy{}})
+		for msg := range msgCh {
+			if _, ok := msg.(AssistantMessage); ok {
+				cancel()
+			}
+		}
+		for range errCh {
+		}
+		cancel()
+
+		assertFile(t, filepath.Join(workDir, "edit.txt"), "original\n")
+		if entries, _ := os.ReadDir(workDir); len(entries) != 1 {
+			t.Fatalf("expected only edit.txt after the rollback, found %v", entries)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Scorer grades the answer of a successful experiment run. Higher scores are
+// better; the scale is up to the scorer.
+type Scorer interface {
+	Score(ctx context.Context, run *ExperimentRun) (float64, error)
+}
+
+// ScorerFunc adapts a function to the Scorer interface
+type ScorerFunc func(ctx context.Context, run *ExperimentRun) (float64, error)
+
+// Score calls f
+func (f ScorerFunc) Score(ctx context.Context, run *ExperimentRun) (float64, error) {
+	return f(ctx, run)
+}
+
+// scoreRun records each scorer's result in run.Metrics. Failed runs are not
+// scored.
+func scoreRun(ctx context.Context, scorers map[string]Scorer, run *ExperimentRun) {
+	if run.Err != nil || len(scorers) == 0 {
+		return
+	}
+
+	var errs Errors
+	for name, scorer := range scorers {
+		score, err := scorer.Score(ctx, run)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("scorer %s: %w", name, err))
+			continue
+		}
+		if run.Metrics == nil {
+			run.Metrics = make(map[string]float64, len(scorers))
+		}
+		run.Metrics[name] = score
+	}
+	run.ScoreErr = errs.ErrorOrNil()
+}
+
+// judgeScorePattern finds the verdict line in a judge's reply
+var judgeScorePattern = regexp.MustCompile(`(?i)SCORE:\s*(-?[0-9]+(?:\.[0-9]+)?)`)
+
+// JudgeScorer grades answers by asking Claude to judge them against a rubric.
+// The score is the judge's 0 to Scale verdict normalized to 0..1.
+//
+// Example:
+//
+//	exp.Scorers = map[string]Scorer{
+//	    "accuracy": &JudgeScorer{Rubric: "Is the answer factually correct?"},
+//	}
+type JudgeScorer struct {
+	// Rubric describes what a good answer looks like
+	Rubric string
+	// Scale is the top of the judge's scale (defaults to 10)
+	Scale float64
+	// Options configures the judge's queries, e.g. a cheaper Model
+	Options *Options
+	// Query runs the judge's queries (defaults to Query)
+	Query QueryFunc
+}
+
+// Score asks the judge to grade run.Answer for run.Input
+func (j *JudgeScorer) Score(ctx context.Context, run *ExperimentRun) (float64, error) {
+	scale := j.Scale
+	if scale <= 0 {
+		scale = 10
+	}
+	query := j.Query
+	if query == nil {
+		query = Query
+	}
+
+	prompt := fmt.Sprintf(`You are grading an answer against a rubric.
+
+<rubric>
+%s
+</rubric>
+
+<input>
+%s
+</input>
+
+<answer>
+%s
+</answer>
+
+Explain your reasoning briefly, then end with a line of the form "SCORE: n" where n is a number from 0 to %s.`,
+		j.Rubric, run.Input, run.Answer, strconv.FormatFloat(scale, 'f', -1, 64))
+
+	msgCh, errCh := query(ctx, prompt, j.Options)
+	var reply []string
+	for msg := range msgCh {
+		switch m := msg.(type) {
+		case AssistantMessage:
+			for _, block := range m.Content {
+				if tb, ok := block.(TextBlock); ok {
+					reply = append(reply, tb.Text)
+				}
+			}
+		case ErrorMessage:
+			return 0, m.Err
+		}
+	}
+	if err := <-errCh; err != nil {
+		return 0, err
+	}
+
+	matches := judgeScorePattern.FindAllStringSubmatch(strings.Join(reply, "\n"), -1)
+	if len(matches) == 0 {
+		return 0, fmt.Errorf("judge reply contains no score")
+	}
+	score, err := strconv.ParseFloat(matches[len(matches)-1][1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid judge score: %w", err)
+	}
+	if score < 0 || score > scale {
+		return 0, fmt.Errorf("judge score %v outside 0..%v", score, scale)
+	}
+	return score / scale, nil
+}
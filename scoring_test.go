@@ -0,0 +1,144 @@
+package claudecode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExperimentScorers(t *testing.T) {
+	responder := NewScriptedResponder(1).
+		OnError(`fail`, errors.New("boom")).
+		Default(TextResponse("four")...)
+
+	exp := &Experiment{
+		Variants: []Variant{{Name: "v"}},
+		Inputs:   []string{"2+2", "fail"},
+		Query:    responder.Query,
+		Scorers: map[string]Scorer{
+			"exact": ScorerFunc(func(ctx context.Context, run *ExperimentRun) (float64, error) {
+				if run.Answer == "four" {
+					return 1, nil
+				}
+				return 0, nil
+			}),
+			"broken": ScorerFunc(func(ctx context.Context, run *ExperimentRun) (float64, error) {
+				return 0, errors.New("scorer failed")
+			}),
+		},
+	}
+
+	report, err := exp.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, failed := report.Runs[0], report.Runs[1]
+	if ok.Metrics["exact"] != 1 {
+		t.Errorf("expected exact score 1, got %v", ok.Metrics)
+	}
+	if _, scored := ok.Metrics["broken"]; scored || ok.ScoreErr == nil {
+		t.Errorf("expected broken scorer to record an error, got %v, %v", ok.Metrics, ok.ScoreErr)
+	}
+	if failed.Metrics != nil {
+		t.Errorf("expected failed run not to be scored, got %v", failed.Metrics)
+	}
+	if got := report.Summaries[0].Metrics["exact"]; got != 1 {
+		t.Errorf("expected mean exact score 1, got %v", got)
+	}
+
+	var tsv bytes.Buffer
+	if err := report.WriteTSV(&tsv); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(tsv.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got:\n%s", tsv.String())
+	}
+	if !strings.HasSuffix(lines[0], "\terror\texact") {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+	if cols := strings.Split(lines[2], "\t"); len(cols) != 9 || cols[7] != "boom" || cols[8] != "" {
+		t.Errorf("unexpected failed row %q", lines[2])
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Runs []struct {
+			Error      string             `json:"error"`
+			ScoreError string             `json:"score_error"`
+			Metrics    map[string]float64 `json:"metrics"`
+		} `json:"runs"`
+		Summaries []VariantSummary `json:"summaries"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Runs[1].Error != "boom" || !strings.Contains(decoded.Runs[0].ScoreError, "scorer failed") {
+		t.Errorf("expected errors in JSON report, got %+v", decoded.Runs)
+	}
+	if decoded.Summaries[0].Metrics["exact"] != 1 {
+		t.Errorf("expected summary metrics in JSON report, got %+v", decoded.Summaries)
+	}
+}
+
+func TestWriteTSVEscaping(t *testing.T) {
+	report := &ExperimentReport{Runs: []ExperimentRun{{Variant: "v", Input: "a\tb", Answer: "line1\nline2"}}}
+	var buf bytes.Buffer
+	if err := report.WriteTSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `a\tb	line1\nline2`) {
+		t.Errorf("unexpected TSV:\n%s", buf.String())
+	}
+}
+
+func TestJudgeScorer(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		scale   float64
+		want    float64
+		wantErr bool
+	}{
+		{"default scale", "Mostly right.\nSCORE: 8", 0, 0.8, false},
+		{"custom scale", "score: 3", 5, 0.6, false},
+		{"last verdict wins", "SCORE: 1 is too harsh.\nSCORE: 9.5", 0, 0.95, false},
+		{"no score", "Looks fine.", 0, 0, true},
+		{"out of range", "SCORE: 11", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prompt string
+			judge := &JudgeScorer{
+				Rubric: "Be correct",
+				Scale:  tt.scale,
+				Query: func(ctx context.Context, p string, options *Options) (<-chan Message, <-chan error) {
+					prompt = p
+					return NewScriptedResponder(1).Default(TextResponse(tt.reply)...).Query(ctx, p, options)
+				},
+			}
+
+			got, err := judge.Score(context.Background(), &ExperimentRun{Input: "2+2", Answer: "four"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			for _, part := range []string{"Be correct", "2+2", "four"} {
+				if !strings.Contains(prompt, part) {
+					t.Errorf("judge prompt missing %q:\n%s", part, prompt)
+				}
+			}
+		})
+	}
+}
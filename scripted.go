@@ -0,0 +1,184 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/clock"
+)
+
+// QueryFunc has the signature of Query. Code that accepts a QueryFunc can be
+// driven by Query in production and by a ScriptedResponder offline.
+type QueryFunc func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error)
+
+// scriptedRule maps prompts matching pattern to a canned reply
+type scriptedRule struct {
+	pattern  *regexp.Regexp
+	messages []Message
+	err      error
+}
+
+// ScriptedResponder answers prompts with canned message sequences selected by
+// regular expression, without starting the CLI. Messages are streamed with a
+// configurable delay and seeded jitter so runs are realistic yet repeatable.
+//
+// Example:
+//
+//	responder := NewScriptedResponder(42).
+//	    On(`(?i)hello`, TextResponse("Hi there!")...).
+//	    OnError(`fail`, errors.New("boom"))
+//	var query QueryFunc = responder.Query
+type ScriptedResponder struct {
+	// Delay is the base pause before each message
+	Delay time.Duration
+	// Jitter is the maximum random extra pause added to Delay
+	Jitter time.Duration
+
+	mu       sync.Mutex
+	rules    []scriptedRule
+	fallback *scriptedRule
+	rng      *rand.Rand
+}
+
+// NewScriptedResponder creates a responder whose jitter is derived from seed
+func NewScriptedResponder(seed int64) *ScriptedResponder {
+	return &ScriptedResponder{
+		rng: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// On replies with messages to prompts matching pattern. Rules are tried in
+// the order they were added. It panics if pattern is not a valid regular
+// expression.
+func (r *ScriptedResponder) On(pattern string, messages ...Message) *ScriptedResponder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = append(r.rules, scriptedRule{pattern: regexp.MustCompile(pattern), messages: messages})
+	return r
+}
+
+// OnError fails prompts matching pattern with err. It panics if pattern is
+// not a valid regular expression.
+func (r *ScriptedResponder) OnError(pattern string, err error) *ScriptedResponder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = append(r.rules, scriptedRule{pattern: regexp.MustCompile(pattern), err: err})
+	return r
+}
+
+// Default replies with messages to prompts no rule matches
+func (r *ScriptedResponder) Default(messages ...Message) *ScriptedResponder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = &scriptedRule{messages: messages}
+	return r
+}
+
+// match returns the rule for prompt
+func (r *ScriptedResponder) match(prompt string) (scriptedRule, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rule := range r.rules {
+		if rule.pattern.MatchString(prompt) {
+			return rule, true
+		}
+	}
+	if r.fallback != nil {
+		return *r.fallback, true
+	}
+	return scriptedRule{}, false
+}
+
+// pause returns the delay before the next message
+func (r *ScriptedResponder) pause() time.Duration {
+	if r.Jitter <= 0 {
+		return r.Delay
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Delay + time.Duration(r.rng.Int63n(int64(r.Jitter)+1))
+}
+
+// Query streams the scripted reply for prompt. It has the same contract as
+// Query, including buffer sizes, QueryTimeout and InlineErrors.
+func (r *ScriptedResponder) Query(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+	if options == nil {
+		options = NewOptions()
+	}
+
+	queryCtx := ctx
+	var cancel context.CancelFunc
+	if timeout := options.GetQueryTimeout(); timeout > 0 {
+		queryCtx, cancel = clock.WithTimeout(ctx, options.GetClock(), timeout)
+	}
+
+	msgCh := make(chan Message, options.GetMessageBufferSize())
+	errCh := make(chan error, options.GetErrorBufferSize())
+
+	go func() {
+		defer func() {
+			close(msgCh)
+			close(errCh)
+			if cancel != nil {
+				cancel()
+			}
+		}()
+
+		fail := func(err error) {
+			if options.InlineErrors {
+				select {
+				case msgCh <- ErrorMessage{Err: err}:
+				case <-queryCtx.Done():
+				}
+				return
+			}
+			errCh <- err
+		}
+
+		rule, ok := r.match(prompt)
+		if !ok {
+			fail(fmt.Errorf("no scripted response for prompt %q", prompt))
+			return
+		}
+
+		for _, msg := range rule.messages {
+			if delay := r.pause(); delay > 0 {
+				timer := options.GetClock().NewTimer(delay)
+				select {
+				case <-timer.C():
+				case <-queryCtx.Done():
+					timer.Stop()
+					return
+				}
+			}
+			select {
+			case msgCh <- msg:
+			case <-queryCtx.Done():
+				return
+			}
+		}
+
+		if rule.err != nil {
+			fail(rule.err)
+		}
+	}()
+
+	return msgCh, errCh
+}
+
+// TextResponse builds the messages of a successful single-turn reply
+// consisting of text
+func TextResponse(text string) []Message {
+	return []Message{
+		AssistantMessage{Content: []ContentBlock{TextBlock{Text: text}}},
+		ResultMessage{
+			Subtype:  "success",
+			NumTurns: 1,
+			Result:   StringPtr(text),
+		},
+	}
+}
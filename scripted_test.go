@@ -0,0 +1,112 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func collectScripted(t *testing.T, query QueryFunc, prompt string, opts *Options) ([]Message, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msgCh, errCh := query(ctx, prompt, opts)
+	var msgs []Message
+	for msg := range msgCh {
+		msgs = append(msgs, msg)
+	}
+	return msgs, <-errCh
+}
+
+func TestScriptedResponder(t *testing.T) {
+	boom := errors.New("boom")
+	responder := NewScriptedResponder(1).
+		On(`(?i)^hello`, TextResponse("Hi there!")...).
+		OnError(`fail`, boom).
+		Default(TextResponse("fallback")...)
+
+	t.Run("matches pattern", func(t *testing.T) {
+		msgs, err := collectScripted(t, responder.Query, "Hello Claude", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(msgs) != 2 {
+			t.Fatalf("Expected 2 messages, got %d", len(msgs))
+		}
+		result, ok := msgs[1].(ResultMessage)
+		if !ok || SafeStringPtr(result.Result) != "Hi there!" {
+			t.Errorf("Unexpected result message: %+v", msgs[1])
+		}
+	})
+
+	t.Run("scripted error", func(t *testing.T) {
+		_, err := collectScripted(t, responder.Query, "please fail", nil)
+		if !errors.Is(err, boom) {
+			t.Errorf("Expected scripted error, got %v", err)
+		}
+	})
+
+	t.Run("scripted error inline", func(t *testing.T) {
+		opts := NewOptions()
+		opts.InlineErrors = true
+		msgs, err := collectScripted(t, responder.Query, "please fail", opts)
+		if err != nil {
+			t.Fatalf("Expected no error on error channel, got %v", err)
+		}
+		if len(msgs) != 1 || !errors.Is(msgs[0].(ErrorMessage), boom) {
+			t.Errorf("Expected a single ErrorMessage, got %+v", msgs)
+		}
+	})
+
+	t.Run("default response", func(t *testing.T) {
+		msgs, err := collectScripted(t, responder.Query, "something else", nil)
+		if err != nil || len(msgs) != 2 {
+			t.Errorf("Expected fallback response, got %+v, %v", msgs, err)
+		}
+	})
+
+	t.Run("no match without default", func(t *testing.T) {
+		_, err := collectScripted(t, NewScriptedResponder(1).Query, "anything", nil)
+		if err == nil || !strings.Contains(err.Error(), "no scripted response") {
+			t.Errorf("Expected no match error, got %v", err)
+		}
+	})
+}
+
+func TestScriptedResponderTiming(t *testing.T) {
+	t.Run("same seed gives same pauses", func(t *testing.T) {
+		a := NewScriptedResponder(7)
+		b := NewScriptedResponder(7)
+		a.Delay, a.Jitter = time.Millisecond, 50*time.Millisecond
+		b.Delay, b.Jitter = time.Millisecond, 50*time.Millisecond
+		for i := 0; i < 10; i++ {
+			if pa, pb := a.pause(), b.pause(); pa != pb {
+				t.Fatalf("pause %d differs: %v != %v", i, pa, pb)
+			}
+		}
+	})
+
+	t.Run("cancellation stops the stream", func(t *testing.T) {
+		responder := NewScriptedResponder(1).On(".", TextResponse("slow")...)
+		responder.Delay = time.Hour
+
+		ctx, cancel := context.WithCancel(context.Background())
+		msgCh, errCh := responder.Query(ctx, "x", nil)
+		cancel()
+
+		select {
+		case _, ok := <-msgCh:
+			if ok {
+				t.Error("Expected no messages after cancellation")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timeout waiting for channel to close")
+		}
+		if err := <-errCh; err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,215 @@
+package claudecode
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/validation"
+)
+
+// maxSessionSummary caps the length of SessionInfo.Summary
+const maxSessionSummary = 200
+
+// sessionIDPattern matches IDs that are safe to use as a file name
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// SessionInfo describes a session stored by the CLI
+type SessionInfo struct {
+	ID       string
+	Cwd      string    // Working directory the session ran in
+	Path     string    // Transcript file
+	Summary  string    // The CLI's summary of the session, or its first prompt
+	Messages int       // User and assistant messages in the transcript
+	Created  time.Time // Timestamp of the first message
+	Updated  time.Time // Timestamp of the last message
+}
+
+// Sessions manages the sessions the CLI keeps for Options.Resume, e.g. to
+// find one to fork with Options.ForkSession or to clean up stale ones. The
+// CLI stores each session as a JSONL transcript under the projects
+// directory of its configuration directory; Sessions reads and deletes those
+// files, so it must run as the user the CLI runs as.
+//
+// Example:
+//
+//	sessions := &Sessions{Cwd: repoDir}
+//	list, err := sessions.List(ctx)
+//	for _, s := range list {
+//	    if time.Since(s.Updated) > 30*24*time.Hour {
+//	        sessions.Delete(ctx, s.ID)
+//	    }
+//	}
+type Sessions struct {
+	// ConfigDir is the CLI's configuration directory (defaults to
+	// $CLAUDE_CONFIG_DIR, or ~/.claude)
+	ConfigDir string
+	// Cwd, when set, restricts List to sessions run in that directory
+	Cwd string
+}
+
+// projectsDir returns the directory holding the CLI's session transcripts
+func (s *Sessions) projectsDir() (string, error) {
+	dir := s.ConfigDir
+	if dir == "" {
+		dir = os.Getenv("CLAUDE_CONFIG_DIR")
+	}
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to locate CLI configuration directory: %w", err)
+		}
+		dir = filepath.Join(home, ".claude")
+	}
+	return filepath.Join(dir, "projects"), nil
+}
+
+// List returns the stored sessions, most recently updated first
+func (s *Sessions) List(ctx context.Context) ([]SessionInfo, error) {
+	projects, err := s.projectsDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(projects, "*", "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	cwd := ""
+	if s.Cwd != "" {
+		if cwd, err = validation.ValidateWorkingDirectory(s.Cwd); err != nil {
+			return nil, fmt.Errorf("invalid working directory: %w", err)
+		}
+	}
+
+	sessions := make([]SessionInfo, 0, len(paths))
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		info, err := readSessionInfo(path)
+		if err != nil {
+			return nil, err
+		}
+		if cwd != "" && filepath.Clean(info.Cwd) != cwd {
+			continue
+		}
+		sessions = append(sessions, *info)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].Updated.After(sessions[j].Updated)
+	})
+	return sessions, nil
+}
+
+// Get returns the session with id. The error wraps os.ErrNotExist if there
+// is none.
+func (s *Sessions) Get(ctx context.Context, id string) (*SessionInfo, error) {
+	path, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return readSessionInfo(path)
+}
+
+// Delete removes the session with id and the files the CLI keeps beside
+// its transcript. The error wraps os.ErrNotExist if there is no such
+// session.
+func (s *Sessions) Delete(ctx context.Context, id string) error {
+	path, err := s.find(id)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(strings.TrimSuffix(path, ".jsonl")); err != nil {
+		return fmt.Errorf("failed to delete session %s: %w", id, err)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to delete session %s: %w", id, err)
+	}
+	return nil
+}
+
+// find returns the transcript path of the session with id
+func (s *Sessions) find(id string) (string, error) {
+	if !sessionIDPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid session ID %q", id)
+	}
+	projects, err := s.projectsDir()
+	if err != nil {
+		return "", err
+	}
+	paths, err := filepath.Glob(filepath.Join(projects, "*", id+".jsonl"))
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("session %s: %w", id, os.ErrNotExist)
+	}
+	return paths[0], nil
+}
+
+// readSessionInfo summarizes the transcript at path. Lines that are not
+// valid JSON are skipped, as the CLI may be appending to the file.
+func readSessionInfo(path string) (*SessionInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+	defer file.Close()
+
+	info := &SessionInfo{ID: strings.TrimSuffix(filepath.Base(path), ".jsonl"), Path: path}
+	firstPrompt := ""
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), validation.MaxJSONSize)
+	for scanner.Scan() {
+		var entry struct {
+			Type      string `json:"type"`
+			Summary   string `json:"summary"`
+			Cwd       string `json:"cwd"`
+			Timestamp string `json:"timestamp"`
+			Message   struct {
+				Content interface{} `json:"content"`
+			} `json:"message"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+
+		if entry.Cwd != "" && info.Cwd == "" {
+			info.Cwd = entry.Cwd
+		}
+		if ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp); err == nil {
+			if info.Created.IsZero() {
+				info.Created = ts
+			}
+			info.Updated = ts
+		}
+		switch entry.Type {
+		case "summary":
+			info.Summary = entry.Summary
+		case "user":
+			info.Messages++
+			if text, ok := entry.Message.Content.(string); ok && firstPrompt == "" {
+				firstPrompt = text
+			}
+		case "assistant":
+			info.Messages++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	if info.Summary == "" {
+		info.Summary = firstPrompt
+	}
+	info.Summary = TruncateText(strings.TrimSpace(info.Summary), maxSessionSummary, "...")
+	return info, nil
+}
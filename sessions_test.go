@@ -0,0 +1,94 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSession(t *testing.T, configDir, project, id string, lines ...string) string {
+	t.Helper()
+	dir := filepath.Join(configDir, "projects", project)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, id+".jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	configDir := t.TempDir()
+	writeSession(t, configDir, "-repo-api", "s1",
+		`{"type":"user","cwd":"/repo/api","timestamp":"2024-05-01T10:00:00Z","message":{"role":"user","content":"Fix the login bug"}}`,
+		`{"type":"assistant","cwd":"/repo/api","timestamp":"2024-05-01T10:01:00Z","message":{"content":[{"type":"text","text":"Done"}]}}`,
+		`{"type":"user","cwd":"/repo/api","timestamp":"2024-05-01T10:02:00.5Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}`,
+		`{"type":"assistant","cwd":"/repo/api","timestamp":"2024-05-01T10:0`, // Partially written
+	)
+	writeSession(t, configDir, "-repo-web", "s2",
+		`{"type":"summary","summary":"Dark mode","leafUuid":"u1"}`,
+		`{"type":"user","cwd":"/repo/web","timestamp":"2024-06-01T09:00:00Z","message":{"role":"user","content":"Add dark mode"}}`,
+	)
+	if err := os.MkdirAll(filepath.Join(configDir, "projects", "-repo-web", "s2"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := &Sessions{ConfigDir: configDir}
+	list, err := sessions.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != "s2" || list[1].ID != "s1" {
+		t.Fatalf("expected s2 then s1, got %+v", list)
+	}
+	s1 := list[1]
+	if s1.Cwd != "/repo/api" || s1.Summary != "Fix the login bug" || s1.Messages != 3 {
+		t.Errorf("unexpected session %+v", s1)
+	}
+	if s1.Created.Format("15:04:05") != "10:00:00" || s1.Updated.Format("15:04:05.0") != "10:02:00.5" {
+		t.Errorf("unexpected timestamps %v and %v", s1.Created, s1.Updated)
+	}
+	if list[0].Summary != "Dark mode" {
+		t.Errorf("expected the CLI's summary, got %q", list[0].Summary)
+	}
+
+	filtered, err := (&Sessions{ConfigDir: configDir, Cwd: "/repo/api/"}).List(ctx)
+	if err != nil || len(filtered) != 1 || filtered[0].ID != "s1" {
+		t.Errorf("expected only s1 for /repo/api, got %+v, %v", filtered, err)
+	}
+
+	got, err := sessions.Get(ctx, "s2")
+	if err != nil || got.Cwd != "/repo/web" {
+		t.Errorf("Get(s2) = %+v, %v", got, err)
+	}
+	if _, err := sessions.Get(ctx, "missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+	if _, err := sessions.Get(ctx, "../s1"); err == nil || errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected an invalid ID error, got %v", err)
+	}
+
+	if err := sessions.Delete(ctx, "s2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "projects", "-repo-web", "s2")); !os.IsNotExist(err) {
+		t.Error("expected the session's directory to be deleted")
+	}
+	if list, _ := sessions.List(ctx); len(list) != 1 {
+		t.Errorf("expected one session left, got %+v", list)
+	}
+	if err := sessions.Delete(ctx, "s2"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist deleting twice, got %v", err)
+	}
+
+	t.Setenv("CLAUDE_CONFIG_DIR", configDir)
+	if list, err := (&Sessions{}).List(ctx); err != nil || len(list) != 1 {
+		t.Errorf("expected CLAUDE_CONFIG_DIR to be used, got %+v, %v", list, err)
+	}
+}
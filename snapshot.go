@@ -0,0 +1,195 @@
+package claudecode
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/validation"
+)
+
+// Snapshot records the contents of a directory tree so that the changes a
+// query makes to it can be extracted afterwards without involving git.
+// Only regular files are recorded and contents are held in memory.
+type Snapshot struct {
+	// Dir is the snapshotted directory
+	Dir string
+
+	skip  []string
+	files map[string]snapshotFile
+	dirs  map[string]bool
+}
+
+// snapshotFile is the recorded state of one regular file
+type snapshotFile struct {
+	data []byte
+	mode fs.FileMode
+}
+
+// TakeSnapshot records every regular file under dir. Files or directories
+// whose name is listed in skip, e.g. ".git" or "node_modules", are ignored.
+func TakeSnapshot(ctx context.Context, dir string, skip []string) (*Snapshot, error) {
+	root, err := validation.ValidatePath(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot directory: %w", err)
+	}
+
+	skipped := make(map[string]bool, len(skip))
+	for _, name := range skip {
+		skipped[name] = true
+	}
+
+	s := &Snapshot{
+		Dir:   root,
+		skip:  skip,
+		files: make(map[string]snapshotFile),
+		dirs:  make(map[string]bool),
+	}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if path != root && skipped[d.Name()] {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			s.dirs[filepath.ToSlash(rel)] = true
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		s.files[filepath.ToSlash(rel)] = snapshotFile{data: data, mode: info.Mode().Perm()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot %s: %w", root, err)
+	}
+	return s, nil
+}
+
+// Changes snapshots the directory again and returns what changed since s
+func (s *Snapshot) Changes(ctx context.Context) (*ChangeSet, error) {
+	after, err := TakeSnapshot(ctx, s.Dir, s.skip)
+	if err != nil {
+		return nil, err
+	}
+	return DiffSnapshots(s, after), nil
+}
+
+// DiffSnapshots returns the changes between two snapshots, ordered by path
+func DiffSnapshots(before, after *Snapshot) *ChangeSet {
+	paths := make(map[string]bool, len(before.files)+len(after.files))
+	for path := range before.files {
+		paths[path] = true
+	}
+	for path := range after.files {
+		paths[path] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	cs := &ChangeSet{Dir: after.Dir}
+	for _, path := range sorted {
+		old, hadOld := before.files[path]
+		cur, hasNew := after.files[path]
+
+		var op ChangeOp
+		switch {
+		case !hadOld:
+			op = ChangeAdded
+		case !hasNew:
+			op = ChangeDeleted
+		case bytes.Equal(old.data, cur.data):
+			continue
+		default:
+			op = ChangeModified
+		}
+		cs.Changes = append(cs.Changes, FileChange{
+			Path:  path,
+			Op:    op,
+			Patch: unifiedDiff(path, op, old.data, cur.data),
+		})
+	}
+	return cs
+}
+
+// Restore reverts the directory to the snapshot: files added since are
+// removed, and modified or deleted files are rewritten with their recorded
+// contents and permissions. Skipped paths are left alone.
+func (s *Snapshot) Restore(ctx context.Context) error {
+	current, err := TakeSnapshot(ctx, s.Dir, s.skip)
+	if err != nil {
+		return err
+	}
+
+	var errs Errors
+	for path := range current.files {
+		if _, ok := s.files[path]; !ok {
+			if err := os.Remove(filepath.Join(s.Dir, filepath.FromSlash(path))); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for path, file := range s.files {
+		if cur, ok := current.files[path]; ok && cur.mode == file.mode && bytes.Equal(cur.data, file.data) {
+			continue
+		}
+		target := filepath.Join(s.Dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := os.WriteFile(target, file.data, file.mode); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// WriteFile keeps the mode of an existing file
+		if err := os.Chmod(target, file.mode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Remove directories created since, deepest first; non-empty ones
+	// (holding skipped files) are kept
+	var added []string
+	for dir := range current.dirs {
+		if !s.dirs[dir] {
+			added = append(added, dir)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(added)))
+	for _, dir := range added {
+		os.Remove(filepath.Join(s.Dir, filepath.FromSlash(dir)))
+	}
+
+	if err := errs.ErrorOrNil(); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", s.Dir, err)
+	}
+	return nil
+}
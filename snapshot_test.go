@@ -0,0 +1,164 @@
+package claudecode
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"keep.txt":          "same\n",
+		"edit.txt":          "one\ntwo\nthree\n",
+		"remove.txt":        "bye\n",
+		"node_modules/x.js": "ignored\n",
+	})
+
+	before, err := TakeSnapshot(context.Background(), dir, []string{"node_modules"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTree(t, dir, map[string]string{
+		"edit.txt":          "one\n2\nthree\n",
+		"sub/new.txt":       "hello",
+		"node_modules/x.js": "changed\n",
+	})
+	if err := os.Remove(filepath.Join(dir, "remove.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	cs, err := before.Changes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []FileChange{
+		{Path: "edit.txt", Op: ChangeModified, Patch: "--- a/edit.txt\n+++ b/edit.txt\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n"},
+		{Path: "remove.txt", Op: ChangeDeleted, Patch: "--- a/remove.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n"},
+		{Path: "sub/new.txt", Op: ChangeAdded, Patch: "--- /dev/null\n+++ b/sub/new.txt\n@@ -0,0 +1 @@\n+hello\n\\ No newline at end of file\n"},
+	}
+	if len(cs.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), cs.Changes)
+	}
+	for i, change := range cs.Changes {
+		if change != want[i] {
+			t.Errorf("change %d:\nexpected %+v\ngot      %+v", i, want[i], change)
+		}
+	}
+}
+
+func TestSnapshotNoChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "a\n"})
+
+	before, err := TakeSnapshot(context.Background(), dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := before.Changes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cs.IsEmpty() {
+		t.Errorf("expected no changes, got %+v", cs.Changes)
+	}
+}
+
+func TestSnapshotBinary(t *testing.T) {
+	dir := t.TempDir()
+	before, err := TakeSnapshot(context.Background(), dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, dir, map[string]string{"blob.bin": "\x00\x01"})
+
+	cs, err := before.Changes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs.Changes) != 1 || cs.Changes[0].Patch != "Binary files /dev/null and b/blob.bin differ\n" {
+		t.Errorf("unexpected changes: %+v", cs.Changes)
+	}
+}
+
+func TestUnifiedDiffApplies(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	var old, cur []string
+	for i := 0; i < 40; i++ {
+		old = append(old, "line "+string(rune('a'+i%26)))
+	}
+	cur = append(cur, "header")
+	cur = append(cur, old[:10]...)
+	cur = append(cur, "inserted")
+	cur = append(cur, old[12:30]...)
+	cur = append(cur, old[31:]...)
+	oldText := strings.Join(old, "\n") + "\n"
+	curText := strings.Join(cur, "\n")
+
+	patch := unifiedDiff("f.txt", ChangeModified, []byte(oldText), []byte(curText))
+	if strings.Count(patch, "@@ -") != 4 {
+		t.Errorf("expected four hunks, got:\n%s", patch)
+	}
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"f.txt": oldText, "change.patch": patch})
+	cmd := exec.Command("git", "apply", "change.patch")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git apply failed: %v: %s\n%s", err, out, patch)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "f.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != curText {
+		t.Errorf("patched file mismatch:\n%q\n%q", got, curText)
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"run.sh": "#!/bin/sh\n", "a/b.txt": "b\n"})
+	if err := os.Chmod(filepath.Join(dir, "run.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := TakeSnapshot(context.Background(), dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chmod(filepath.Join(dir, "run.sh"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, "a")); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, dir, map[string]string{"x/y/z.txt": "z\n"})
+
+	if err := before.Restore(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("expected mode 0755, got %v", info.Mode().Perm())
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "a", "b.txt")); err != nil || string(data) != "b\n" {
+		t.Errorf("expected a/b.txt restored, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "x")); !os.IsNotExist(err) {
+		t.Errorf("expected added directories removed, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SummaryModel is the model Summarize uses when no Options are given. A
+// small model is enough to condense a transcript and keeps the cost low.
+const SummaryModel = "claude-3-5-haiku-20241022"
+
+// maxSummarizedToolResult caps how much of each tool result is included in a
+// transcript sent for summarization
+const maxSummarizedToolResult = 500
+
+// defaultSummaryWords is the default target length of a summary
+const defaultSummaryWords = 150
+
+// SummarizeOptions configures Summarize
+type SummarizeOptions struct {
+	// MaxWords is the target length of the summary (defaults to 150)
+	MaxWords int
+	// Instructions replaces the default request to summarize, e.g. to ask
+	// for a short conversation title
+	Instructions string
+	// Options configures the summarizing query (defaults to a single turn
+	// on SummaryModel)
+	Options *Options
+	// Query runs the summarizing query (defaults to Query)
+	Query QueryFunc
+}
+
+// Summarize asks Claude for a summary of a conversation. The history is
+// rendered as a plain transcript, with long tool results cut short, and sent
+// in one query.
+//
+// Example:
+//
+//	title, err := Summarize(ctx, history, &SummarizeOptions{
+//	    Instructions: "Give this conversation a title of at most six words.",
+//	})
+func Summarize(ctx context.Context, history []Message, opts *SummarizeOptions) (string, error) {
+	if opts == nil {
+		opts = &SummarizeOptions{}
+	}
+	transcript := renderTranscript(history)
+	if transcript == "" {
+		return "", fmt.Errorf("conversation has nothing to summarize")
+	}
+
+	maxWords := opts.MaxWords
+	if maxWords <= 0 {
+		maxWords = defaultSummaryWords
+	}
+	instructions := opts.Instructions
+	if instructions == "" {
+		instructions = fmt.Sprintf("Summarize the conversation above in at most %d words. Keep decisions, open questions and the names of files and tools involved.", maxWords)
+	}
+	options := opts.Options
+	if options == nil {
+		options = NewOptions()
+		options.Model = SummaryModel
+		options.MaxTurns = IntPtr(1)
+	}
+	query := opts.Query
+	if query == nil {
+		query = Query
+	}
+
+	prompt := fmt.Sprintf("<conversation>\n%s\n</conversation>\n\n%s Reply with the summary only.", transcript, instructions)
+
+	msgCh, errCh := query(ctx, prompt, options)
+	var text []string
+	var result *ResultMessage
+	for msg := range msgCh {
+		switch m := msg.(type) {
+		case AssistantMessage:
+			for _, block := range m.Content {
+				if tb, ok := block.(TextBlock); ok {
+					text = append(text, tb.Text)
+				}
+			}
+		case ResultMessage:
+			result = &m
+		case ErrorMessage:
+			return "", m.Err
+		}
+	}
+	if err := <-errCh; err != nil {
+		return "", err
+	}
+	if result != nil && result.IsError {
+		return "", fmt.Errorf("summary query ended with %s", result.Subtype)
+	}
+
+	summary := strings.Join(text, "\n")
+	if result != nil && result.Result != nil {
+		summary = *result.Result
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("summary query returned no text")
+	}
+	return summary, nil
+}
+
+// renderTranscript writes the user and assistant turns of history as plain
+// text, one speaker-prefixed paragraph per message
+func renderTranscript(history []Message) string {
+	var parts []string
+	for _, msg := range history {
+		switch m := msg.(type) {
+		case UserMessage:
+			if text := strings.TrimSpace(m.Content + renderBlocks(m.Blocks)); text != "" {
+				parts = append(parts, "User: "+text)
+			}
+		case AssistantMessage:
+			if text := strings.TrimSpace(renderBlocks(m.Content)); text != "" {
+				parts = append(parts, "Assistant: "+text)
+			}
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// renderBlocks writes content blocks as plain text, noting tool calls,
+// tool results and images in brackets
+func renderBlocks(blocks []ContentBlock) string {
+	var b strings.Builder
+	for _, block := range blocks {
+		switch blk := block.(type) {
+		case TextBlock:
+			b.WriteString(blk.Text)
+		case ToolUseBlock:
+			fmt.Fprintf(&b, "\n[used tool %s]\n", blk.Name)
+		case ToolResultBlock:
+			content, ok := blk.Content.(string)
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(&b, "\n[tool result: %s]\n", TruncateText(content, maxSummarizedToolResult, "..."))
+		case ImageBlock:
+			b.WriteString("\n[image]\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,64 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	var gotPrompt string
+	var gotOptions *Options
+	responder := NewScriptedResponder(1).Default(TextResponse("  Fixed the parser.  ")...)
+	query := func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+		gotPrompt, gotOptions = prompt, options
+		return responder.Query(ctx, prompt, options)
+	}
+
+	history := []Message{
+		UserMessage{Content: "Fix the parser"},
+		AssistantMessage{Content: []ContentBlock{
+			TextBlock{Text: "Looking at it."},
+			ToolUseBlock{ID: "1", Name: "Read"},
+			ToolResultBlock{ToolUseID: "1", Content: strings.Repeat("x", 2000)},
+		}},
+		ResultMessage{Subtype: "success", Result: StringPtr("ignored")},
+	}
+
+	summary, err := Summarize(context.Background(), history, &SummarizeOptions{Query: query})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != "Fixed the parser." {
+		t.Errorf("unexpected summary %q", summary)
+	}
+	for _, want := range []string{"User: Fix the parser", "Assistant: Looking at it.", "[used tool Read]", "at most 150 words"} {
+		if !strings.Contains(gotPrompt, want) {
+			t.Errorf("expected prompt to contain %q, got:\n%s", want, gotPrompt)
+		}
+	}
+	if strings.Contains(gotPrompt, strings.Repeat("x", maxSummarizedToolResult+1)) || strings.Contains(gotPrompt, "ignored") {
+		t.Errorf("expected long tool result truncated and result skipped, got:\n%s", gotPrompt)
+	}
+	if gotOptions.Model != SummaryModel || SafeIntPtr(gotOptions.MaxTurns) != 1 {
+		t.Errorf("expected single turn on %s, got %s/%v", SummaryModel, gotOptions.Model, gotOptions.MaxTurns)
+	}
+}
+
+func TestSummarizeErrors(t *testing.T) {
+	if _, err := Summarize(context.Background(), nil, nil); err == nil {
+		t.Error("expected error for empty history")
+	}
+
+	history := []Message{UserMessage{Content: "hi"}}
+	responder := NewScriptedResponder(1).OnError(`.`, errors.New("boom"))
+	if _, err := Summarize(context.Background(), history, &SummarizeOptions{Query: responder.Query}); err == nil || err.Error() != "boom" {
+		t.Errorf("expected query error, got %v", err)
+	}
+
+	responder = NewScriptedResponder(1).Default(ResultMessage{Subtype: "error_max_turns", IsError: true})
+	if _, err := Summarize(context.Background(), history, &SummarizeOptions{Query: responder.Query}); err == nil {
+		t.Error("expected error for failed result")
+	}
+}
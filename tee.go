@@ -0,0 +1,104 @@
+package claudecode
+
+// BackpressurePolicy controls what Tee does when a consumer falls behind
+type BackpressurePolicy int
+
+const (
+	// BackpressureBlock waits for the consumer, slowing every other consumer
+	// down to its pace
+	BackpressureBlock BackpressurePolicy = iota
+	// BackpressureDropNewest discards messages the consumer has no room for
+	BackpressureDropNewest
+	// BackpressureDropOldest discards the oldest buffered message to make
+	// room for the newest one
+	BackpressureDropOldest
+)
+
+// defaultTeeBuffer is the buffer size of outputs created by Tee
+const defaultTeeBuffer = 10
+
+// TeeOutput configures one consumer of TeeWithPolicies
+type TeeOutput struct {
+	// Buffer is the channel buffer size (defaults to 10 when <= 0)
+	Buffer int
+	// Policy decides what happens when the buffer is full
+	Policy BackpressurePolicy
+}
+
+// Tee fans the messages of one query out to n consumers. Every consumer
+// receives every message; a slow consumer blocks the others. All outputs close
+// once msgCh closes. The error channel is not affected and should still be
+// read by one consumer. With n <= 0 there are no outputs and msgCh is
+// drained.
+//
+// Example:
+//
+//	msgCh, errCh := Query(ctx, prompt, opts)
+//	outs := Tee(msgCh, 2)
+//	go render(outs[0])
+//	go archive(outs[1])
+func Tee(msgCh <-chan Message, n int) []<-chan Message {
+	if n < 0 {
+		n = 0
+	}
+	outputs := make([]TeeOutput, n)
+	return TeeWithPolicies(msgCh, outputs...)
+}
+
+// TeeWithPolicies fans the messages of one query out to one consumer per
+// output, each with its own buffer and backpressure policy. Only consumers
+// using BackpressureBlock can slow the others down.
+func TeeWithPolicies(msgCh <-chan Message, outputs ...TeeOutput) []<-chan Message {
+	chans := make([]chan Message, len(outputs))
+	result := make([]<-chan Message, len(outputs))
+	for i, out := range outputs {
+		size := out.Buffer
+		if size <= 0 {
+			size = defaultTeeBuffer
+		}
+		chans[i] = make(chan Message, size)
+		result[i] = chans[i]
+	}
+
+	go func() {
+		defer func() {
+			for _, ch := range chans {
+				close(ch)
+			}
+		}()
+
+		for msg := range msgCh {
+			for i, ch := range chans {
+				teeSend(ch, msg, outputs[i].Policy)
+			}
+		}
+	}()
+
+	return result
+}
+
+// teeSend delivers msg to ch according to policy
+func teeSend(ch chan Message, msg Message, policy BackpressurePolicy) {
+	switch policy {
+	case BackpressureDropNewest:
+		select {
+		case ch <- msg:
+		default:
+		}
+	case BackpressureDropOldest:
+		for {
+			select {
+			case ch <- msg:
+				return
+			default:
+			}
+			// Make room; the consumer may have emptied the slot meanwhile
+			select {
+			case <-ch:
+			default:
+			}
+		}
+	default:
+		ch <- msg
+	}
+}
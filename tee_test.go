@@ -0,0 +1,114 @@
+package claudecode
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func feedMessages(n int) <-chan Message {
+	ch := make(chan Message, n)
+	for i := 0; i < n; i++ {
+		ch <- UserMessage{Content: string(rune('a' + i))}
+	}
+	close(ch)
+	return ch
+}
+
+func TestTee(t *testing.T) {
+	outs := Tee(feedMessages(5), 3)
+	if len(outs) != 3 {
+		t.Fatalf("Expected 3 outputs, got %d", len(outs))
+	}
+
+	var wg sync.WaitGroup
+	counts := make([]int, len(outs))
+	for i, out := range outs {
+		wg.Add(1)
+		go func(i int, out <-chan Message) {
+			defer wg.Done()
+			for range out {
+				counts[i]++
+			}
+		}(i, out)
+	}
+	wg.Wait()
+
+	for i, count := range counts {
+		if count != 5 {
+			t.Errorf("Output %d received %d messages, want 5", i, count)
+		}
+	}
+}
+
+func TestTeeNoOutputs(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		msgCh := make(chan Message)
+		if outs := Tee(msgCh, n); len(outs) != 0 {
+			t.Fatalf("Tee(%d) returned %d outputs", n, len(outs))
+		}
+		select {
+		case msgCh <- UserMessage{Content: "a"}:
+		case <-time.After(time.Second):
+			t.Fatalf("Tee(%d) did not drain its input", n)
+		}
+		close(msgCh)
+	}
+}
+
+func TestTeeWithPolicies(t *testing.T) {
+	t.Run("drop newest keeps the first messages", func(t *testing.T) {
+		outs := TeeWithPolicies(feedMessages(5),
+			TeeOutput{Policy: BackpressureBlock},
+			TeeOutput{Buffer: 2, Policy: BackpressureDropNewest},
+		)
+		for range outs[0] {
+		}
+		var got []string
+		for msg := range outs[1] {
+			got = append(got, msg.(UserMessage).Content)
+		}
+		if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+			t.Errorf("Expected [a b], got %v", got)
+		}
+	})
+
+	t.Run("drop oldest keeps the last messages", func(t *testing.T) {
+		outs := TeeWithPolicies(feedMessages(5),
+			TeeOutput{Policy: BackpressureBlock},
+			TeeOutput{Buffer: 2, Policy: BackpressureDropOldest},
+		)
+		for range outs[0] {
+		}
+		var got []string
+		for msg := range outs[1] {
+			got = append(got, msg.(UserMessage).Content)
+		}
+		if len(got) != 2 || got[0] != "d" || got[1] != "e" {
+			t.Errorf("Expected [d e], got %v", got)
+		}
+	})
+
+	t.Run("stalled dropping consumer does not block others", func(t *testing.T) {
+		outs := TeeWithPolicies(feedMessages(5),
+			TeeOutput{Buffer: 1},
+			TeeOutput{Buffer: 1, Policy: BackpressureDropNewest},
+		)
+		done := make(chan int)
+		go func() {
+			n := 0
+			for range outs[0] {
+				n++
+			}
+			done <- n
+		}()
+		select {
+		case n := <-done:
+			if n != 5 {
+				t.Errorf("Expected 5 messages, got %d", n)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Blocking consumer was held up by a dropping consumer")
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package claudecode
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Common volatile spans for TextTolerance.Volatile
+var (
+	// VolatileUUID matches UUIDs such as session or request IDs
+	VolatileUUID = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	// VolatileTimestamp matches ISO 8601 dates and times
+	VolatileTimestamp = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?)?\b`)
+	// VolatileNumber matches integers and decimals
+	VolatileNumber = regexp.MustCompile(`-?\b\d+(?:\.\d+)?\b`)
+)
+
+// volatilePlaceholder replaces volatile spans before comparison
+const volatilePlaceholder = "<volatile>"
+
+// TextTolerance relaxes the comparison of streamed text with an expected
+// transcript so that harmless variation does not fail a test
+type TextTolerance struct {
+	// IgnoreWhitespace collapses runs of spaces and tabs, trims every line
+	// and drops blank lines
+	IgnoreWhitespace bool
+	// IgnoreCase compares case-insensitively
+	IgnoreCase bool
+	// Volatile spans are replaced by a placeholder in both texts, e.g.
+	// VolatileUUID or VolatileTimestamp
+	Volatile []*regexp.Regexp
+}
+
+// Normalize applies the tolerance rules to s
+func (tol TextTolerance) Normalize(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	for _, re := range tol.Volatile {
+		s = re.ReplaceAllString(s, volatilePlaceholder)
+	}
+	if tol.IgnoreCase {
+		s = strings.ToLower(s)
+	}
+	if tol.IgnoreWhitespace {
+		lines := strings.Split(s, "\n")
+		kept := lines[:0]
+		for _, line := range lines {
+			if line = strings.Join(strings.Fields(line), " "); line != "" {
+				kept = append(kept, line)
+			}
+		}
+		s = strings.Join(kept, "\n")
+	}
+	return s
+}
+
+// DiffText compares actual with expected under tol. It returns "" if they
+// match and a unified diff of the normalized texts otherwise.
+func DiffText(expected, actual string, tol TextTolerance) string {
+	want, got := tol.Normalize(expected), tol.Normalize(actual)
+	if want == got {
+		return ""
+	}
+	if !strings.HasSuffix(want, "\n") {
+		want += "\n"
+	}
+	if !strings.HasSuffix(got, "\n") {
+		got += "\n"
+	}
+
+	var b strings.Builder
+	b.WriteString("--- expected\n+++ actual\n")
+	writeHunks(&b, diffLines(splitLines([]byte(want)), splitLines([]byte(got))))
+	return b.String()
+}
+
+// CollectText reads msgCh until it closes and returns the streamed assistant
+// text. The text of successive assistant messages is separated by newlines.
+func CollectText(msgCh <-chan Message) string {
+	var parts []string
+	for msg := range msgCh {
+		am, ok := msg.(AssistantMessage)
+		if !ok {
+			continue
+		}
+		var text strings.Builder
+		for _, block := range am.Content {
+			if tb, ok := block.(TextBlock); ok {
+				text.WriteString(tb.Text)
+			}
+		}
+		if text.Len() > 0 {
+			parts = append(parts, text.String())
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// AssertStreamedText consumes a query's channels and reports on t if the
+// streamed assistant text differs from expected under tol, or if the query
+// fails.
+//
+// Example:
+//
+//	msgCh, errCh := Query(ctx, "List the supported formats", opts)
+//	AssertStreamedText(t, msgCh, errCh, string(golden), TextTolerance{
+//	    IgnoreWhitespace: true,
+//	    Volatile:         []*regexp.Regexp{VolatileTimestamp},
+//	})
+func AssertStreamedText(t TestingT, msgCh <-chan Message, errCh <-chan error, expected string, tol TextTolerance) {
+	t.Helper()
+	actual := CollectText(msgCh)
+	if err := <-errCh; err != nil {
+		t.Errorf("query failed: %v", err)
+		return
+	}
+	if diff := DiffText(expected, actual, tol); diff != "" {
+		t.Errorf("streamed text does not match expected transcript:\n%s", diff)
+	}
+}
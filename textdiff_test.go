@@ -0,0 +1,81 @@
+package claudecode
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDiffText(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		actual   string
+		tol      TextTolerance
+		match    bool
+	}{
+		{"identical", "a\nb", "a\nb", TextTolerance{}, true},
+		{"different", "a\nb", "a\nc", TextTolerance{}, false},
+		{"whitespace strict", "a  b\n", "a b", TextTolerance{}, false},
+		{"whitespace tolerant", "  a  b\n\n\tc\r\n", "a b\nc", TextTolerance{IgnoreWhitespace: true}, true},
+		{"case tolerant", "Hello", "hello", TextTolerance{IgnoreCase: true}, true},
+		{
+			"volatile spans",
+			"Session 123e4567-e89b-12d3-a456-426614174000 at 2024-01-02T03:04:05Z took 12 steps",
+			"Session 9f1c2d3e-0000-4000-8000-000000000001 at 2026-10-15T10:00:00+02:00 took 7 steps",
+			TextTolerance{Volatile: []*regexp.Regexp{VolatileUUID, VolatileTimestamp, VolatileNumber}},
+			true,
+		},
+		{
+			"volatile does not hide real changes",
+			"took 12 steps",
+			"took 7 attempts",
+			TextTolerance{Volatile: []*regexp.Regexp{VolatileNumber}},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffText(tt.expected, tt.actual, tt.tol)
+			if (diff == "") != tt.match {
+				t.Errorf("expected match=%v, got diff:\n%s", tt.match, diff)
+			}
+		})
+	}
+}
+
+func TestDiffTextOutput(t *testing.T) {
+	diff := DiffText("one\ntwo\nthree", "one\n2\nthree", TextTolerance{})
+	want := "--- expected\n+++ actual\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n"
+	if diff != want {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+}
+
+func TestAssertStreamedText(t *testing.T) {
+	responder := NewScriptedResponder(1).
+		On(`multi`,
+			AssistantMessage{Content: []ContentBlock{TextBlock{Text: "First  part"}}},
+			AssistantMessage{Content: []ContentBlock{ToolUseBlock{ID: "1", Name: ToolRead}}},
+			AssistantMessage{Content: []ContentBlock{TextBlock{Text: "Second part"}}},
+			ResultMessage{Subtype: "success"},
+		).
+		Default(TextResponse("other")...)
+	ctx := context.Background()
+
+	mock := &recordingT{}
+	msgCh, errCh := responder.Query(ctx, "multi", nil)
+	AssertStreamedText(mock, msgCh, errCh, "First part\nSecond part\n", TextTolerance{IgnoreWhitespace: true})
+	if len(mock.errors) != 0 {
+		t.Errorf("expected match, got %v", mock.errors)
+	}
+
+	mock = &recordingT{}
+	msgCh, errCh = responder.Query(ctx, "multi", nil)
+	AssertStreamedText(mock, msgCh, errCh, "First part\nThird part", TextTolerance{IgnoreWhitespace: true})
+	if len(mock.errors) != 1 || !strings.Contains(mock.errors[0], "+Second part") {
+		t.Errorf("expected a diff, got %v", mock.errors)
+	}
+}
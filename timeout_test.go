@@ -2,6 +2,9 @@ package claudecode
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -153,3 +156,208 @@ done:
 		t.Errorf("Query took %v, expected to timeout after ~%d seconds", elapsed, options.QueryTimeout)
 	}
 }
+
+func TestQuerySDKLimits(t *testing.T) {
+	t.Run("turn limit", func(t *testing.T) {
+		installFakeCLI(t, `#!/bin/sh
+for i in 1 2 3; do
+	echo '{"type":"assistant","message":{"content":[{"type":"text","text":"turn"}]}}'
+done
+echo '{"type":"result","subtype":"success"}'
+`)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		opts := NewOptions()
+		opts.TurnLimit = 2
+		msgCh, errCh := Query(ctx, "test", opts)
+
+		turns := 0
+		for msg := range msgCh {
+			if _, ok := msg.(AssistantMessage); ok {
+				turns++
+			}
+		}
+		if turns != 2 {
+			t.Errorf("Expected 2 assistant messages before the limit, got %d", turns)
+		}
+
+		var limitErr *LimitExceededError
+		if err := <-errCh; !errors.As(err, &limitErr) {
+			t.Fatalf("Expected LimitExceededError, got %v", err)
+		}
+		if limitErr.Limit != LimitTurns || limitErr.Value != 2 {
+			t.Errorf("Unexpected limit error: %+v", limitErr)
+		}
+	})
+
+	t.Run("turn limit counts message IDs", func(t *testing.T) {
+		// One API response split into several messages is a single turn
+		installFakeCLI(t, `#!/bin/sh
+echo '{"type":"assistant","message":{"id":"msg_1","content":[{"type":"thinking","thinking":"plan","signature":"s"}]}}'
+echo '{"type":"assistant","message":{"id":"msg_1","content":[{"type":"text","text":"reading"}]}}'
+echo '{"type":"assistant","message":{"id":"msg_1","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"a.go"}}]}}'
+echo '{"type":"assistant","message":{"id":"msg_2","content":[{"type":"text","text":"done"}]}}'
+echo '{"type":"result","subtype":"success"}'
+`)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		opts := NewOptions()
+		opts.TurnLimit = 2
+		msgCh, errCh := Query(ctx, "test", opts)
+
+		messages := 0
+		for msg := range msgCh {
+			if _, ok := msg.(AssistantMessage); ok {
+				messages++
+			}
+		}
+		if err := <-errCh; err != nil {
+			t.Fatalf("Expected two turns to fit the limit, got %v", err)
+		}
+		if messages != 4 {
+			t.Errorf("Expected 4 assistant messages, got %d", messages)
+		}
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		// The stream check stops the query even if the PreToolUse hook is
+		// bypassed
+		installFakeCLI(t, `#!/bin/sh
+read -r init
+read -r prompt
+echo '{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"a.go"}}]}}'
+echo '{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t2","name":"Write","input":{"file_path":"a.go","content":""}}]}}'
+echo '{"type":"result","subtype":"success"}'
+while read -r line; do :; done
+`)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		opts := NewOptions()
+		opts.ReadOnly = true
+		msgCh, errCh := Query(ctx, "test", opts)
+
+		var tools []string
+		for msg := range msgCh {
+			if am, ok := msg.(AssistantMessage); ok {
+				for _, block := range am.Content {
+					if use, ok := block.(ToolUseBlock); ok {
+						tools = append(tools, use.Name)
+					}
+				}
+			}
+		}
+		if len(tools) != 1 || tools[0] != ToolRead {
+			t.Errorf("Expected only the Read tool use to be delivered, got %v", tools)
+		}
+
+		var violation *ReadOnlyViolationError
+		if err := <-errCh; !errors.As(err, &violation) {
+			t.Fatalf("Expected ReadOnlyViolationError, got %v", err)
+		}
+		if violation.Tool != ToolWrite {
+			t.Errorf("Expected violation for Write, got %q", violation.Tool)
+		}
+	})
+
+	t.Run("read only denies tools before they run", func(t *testing.T) {
+		// The fake CLI writes the file unless the PreToolUse hook denies it,
+		// as the CLI does when a settings allow rule covers the call
+		installFakeCLI(t, `#!/bin/sh
+read -r init
+case "$init" in
+*'"PreToolUse":[{"hookCallbackIds":["hook_0"]}]'*) ;;
+*) echo "error: unexpected initialize $init" >&2; exit 1 ;;
+esac
+read -r prompt
+echo '{"type":"control_request","request_id":"cli_1","request":{"subtype":"hook_callback","callback_id":"hook_0","tool_use_id":"t1","input":{"hook_event_name":"PreToolUse","tool_name":"Write","tool_input":{"file_path":"out.txt","content":""}}}}'
+read -r answer
+case "$answer" in
+*'"request_id":"cli_1"'*'"permissionDecision":"deny"'*) ;;
+*) : > out.txt ;;
+esac
+echo '{"type":"result","subtype":"success"}'
+while read -r line; do :; done
+`)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		opts := NewOptions()
+		opts.Cwd = t.TempDir()
+		opts.ReadOnly = true
+		msgCh, errCh := Query(ctx, "test", opts)
+		for range msgCh {
+		}
+		if err := <-errCh; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(opts.Cwd, "out.txt")); !os.IsNotExist(err) {
+			t.Errorf("expected the write to be denied, got %v", err)
+		}
+		if len(opts.Hooks) != 0 {
+			t.Error("read-only mode modified the caller's hooks")
+		}
+	})
+
+	t.Run("wall clock limit", func(t *testing.T) {
+		installFakeCLI(t, `#!/bin/sh
+exec sleep 10
+`)
+		clk := NewFakeClock(time.Now())
+		opts := NewOptions()
+		opts.Clock = clk
+		opts.QueryTimeout = 60
+		msgCh, errCh := Query(context.Background(), "test", opts)
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+		for range msgCh {
+		}
+
+		var limitErr *LimitExceededError
+		if err := <-errCh; !errors.As(err, &limitErr) || limitErr.Limit != LimitWallClock || limitErr.Value != 60 {
+			t.Fatalf("Expected wall clock LimitExceededError, got %v", err)
+		}
+	})
+
+	t.Run("caller cancellation is not a limit", func(t *testing.T) {
+		installFakeCLI(t, `#!/bin/sh
+exec sleep 10
+`)
+		ctx, cancel := context.WithCancel(context.Background())
+		opts := NewOptions()
+		opts.QueryTimeout = 5
+		msgCh, errCh := Query(ctx, "test", opts)
+		cancel()
+		for range msgCh {
+		}
+		if err := <-errCh; err != nil {
+			t.Errorf("Expected no error on caller cancellation, got %v", err)
+		}
+	})
+
+	t.Run("errors after cancellation are not reported", func(t *testing.T) {
+		// A query canceled before the CLI starts fails to start it; that
+		// failure is pending as soon as the query reads its channels, so run
+		// the query repeatedly
+		failedStart := func(queryCtx context.Context) (<-chan interface{}, <-chan error) {
+			rawMsgCh := make(chan interface{})
+			rawErrCh := make(chan error, 1)
+			rawErrCh <- queryCtx.Err()
+			close(rawMsgCh)
+			close(rawErrCh)
+			return rawMsgCh, rawErrCh
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		for i := 0; i < 50; i++ {
+			msgCh, errCh := runQuery(ctx, NewOptions(), failedStart)
+			for range msgCh {
+			}
+			if err := <-errCh; err != nil {
+				t.Fatalf("run %d: expected no error on caller cancellation, got %v", i, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package claudecode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// ReadInput is the input of the Read tool
+type ReadInput struct {
+	FilePath string `json:"file_path"`
+	Offset   *int   `json:"offset,omitempty"`
+	Limit    *int   `json:"limit,omitempty"`
+}
+
+// WriteInput is the input of the Write tool
+type WriteInput struct {
+	FilePath string `json:"file_path"`
+	Content  string `json:"content"`
+}
+
+// EditInput is the input of the Edit tool
+type EditInput struct {
+	FilePath   string `json:"file_path"`
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll *bool  `json:"replace_all,omitempty"`
+}
+
+// EditOperation is a single replacement within MultiEditInput
+type EditOperation struct {
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll *bool  `json:"replace_all,omitempty"`
+}
+
+// MultiEditInput is the input of the MultiEdit tool
+type MultiEditInput struct {
+	FilePath string          `json:"file_path"`
+	Edits    []EditOperation `json:"edits"`
+}
+
+// BashInput is the input of the Bash tool
+type BashInput struct {
+	Command         string `json:"command"`
+	Timeout         *int   `json:"timeout,omitempty"` // Milliseconds
+	Description     string `json:"description,omitempty"`
+	RunInBackground *bool  `json:"run_in_background,omitempty"`
+}
+
+// GlobInput is the input of the Glob tool
+type GlobInput struct {
+	Pattern string `json:"pattern"`
+	Path    string `json:"path,omitempty"`
+}
+
+// GrepInput is the input of the Grep tool
+type GrepInput struct {
+	Pattern         string `json:"pattern"`
+	Path            string `json:"path,omitempty"`
+	Glob            string `json:"glob,omitempty"`
+	Type            string `json:"type,omitempty"`
+	OutputMode      string `json:"output_mode,omitempty"`
+	CaseInsensitive *bool  `json:"-i,omitempty"`
+	Multiline       *bool  `json:"multiline,omitempty"`
+	HeadLimit       *int   `json:"head_limit,omitempty"`
+}
+
+// LSInput is the input of the LS tool
+type LSInput struct {
+	Path   string   `json:"path"`
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+// NotebookEditInput is the input of the NotebookEdit tool
+type NotebookEditInput struct {
+	NotebookPath string `json:"notebook_path"`
+	CellID       string `json:"cell_id,omitempty"`
+	NewSource    string `json:"new_source"`
+	CellType     string `json:"cell_type,omitempty"`
+	EditMode     string `json:"edit_mode,omitempty"`
+}
+
+// WebFetchInput is the input of the WebFetch tool
+type WebFetchInput struct {
+	URL    string `json:"url"`
+	Prompt string `json:"prompt"`
+}
+
+// WebSearchInput is the input of the WebSearch tool
+type WebSearchInput struct {
+	Query          string   `json:"query"`
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+	BlockedDomains []string `json:"blocked_domains,omitempty"`
+}
+
+// TaskInput is the input of the Task tool
+type TaskInput struct {
+	Description  string `json:"description"`
+	Prompt       string `json:"prompt"`
+	SubagentType string `json:"subagent_type,omitempty"`
+}
+
+// TodoItem is a single entry of TodoWriteInput
+type TodoItem struct {
+	Content    string `json:"content"`
+	Status     string `json:"status"`
+	ActiveForm string `json:"activeForm,omitempty"`
+}
+
+// TodoWriteInput is the input of the TodoWrite tool
+type TodoWriteInput struct {
+	Todos []TodoItem `json:"todos"`
+}
+
+// toolInputRegistry maps tool names to constructors of their input types
+var toolInputRegistry = struct {
+	sync.RWMutex
+	factories map[string]func() interface{}
+}{
+	factories: map[string]func() interface{}{
+		ToolRead:         func() interface{} { return &ReadInput{} },
+		ToolWrite:        func() interface{} { return &WriteInput{} },
+		ToolEdit:         func() interface{} { return &EditInput{} },
+		ToolMultiEdit:    func() interface{} { return &MultiEditInput{} },
+		ToolBash:         func() interface{} { return &BashInput{} },
+		ToolGlob:         func() interface{} { return &GlobInput{} },
+		ToolGrep:         func() interface{} { return &GrepInput{} },
+		ToolLS:           func() interface{} { return &LSInput{} },
+		ToolNotebookEdit: func() interface{} { return &NotebookEditInput{} },
+		ToolWebFetch:     func() interface{} { return &WebFetchInput{} },
+		ToolWebSearch:    func() interface{} { return &WebSearchInput{} },
+		ToolTask:         func() interface{} { return &TaskInput{} },
+		ToolTodoWrite:    func() interface{} { return &TodoWriteInput{} },
+	},
+}
+
+// RegisterToolInput registers the input type of a tool, typically an MCP
+// tool, for DecodeToolInput. newInput must return a pointer to a fresh value
+// that input JSON can be unmarshaled into. Registering an existing name
+// replaces it.
+func RegisterToolInput(name string, newInput func() interface{}) {
+	toolInputRegistry.Lock()
+	defer toolInputRegistry.Unlock()
+	toolInputRegistry.factories[name] = newInput
+}
+
+// DecodeToolInput decodes the input of a tool use into the registered input
+// type, returned as a pointer (e.g. *BashInput for Bash)
+//
+// Example:
+//
+//	input, err := DecodeToolInput(block)
+//	if bash, ok := input.(*BashInput); ok {
+//	    log.Printf("running %q", bash.Command)
+//	}
+func DecodeToolInput(block ToolUseBlock) (interface{}, error) {
+	toolInputRegistry.RLock()
+	newInput, ok := toolInputRegistry.factories[block.Name]
+	toolInputRegistry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no input type registered for tool %q", block.Name)
+	}
+
+	data, err := json.Marshal(block.Input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s input: %w", block.Name, err)
+	}
+	input := newInput()
+	if err := json.Unmarshal(data, input); err != nil {
+		return nil, fmt.Errorf("failed to decode %s input: %w", block.Name, err)
+	}
+	return input, nil
+}
@@ -0,0 +1,93 @@
+package claudecode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeToolInput(t *testing.T) {
+	t.Run("Bash", func(t *testing.T) {
+		input, err := DecodeToolInput(ToolUseBlock{
+			Name:  ToolBash,
+			Input: map[string]interface{}{"command": "go test ./...", "timeout": float64(60000)},
+		})
+		if err != nil {
+			t.Fatalf("DecodeToolInput() error: %v", err)
+		}
+		bash, ok := input.(*BashInput)
+		if !ok {
+			t.Fatalf("Expected *BashInput, got %T", input)
+		}
+		if bash.Command != "go test ./..." || SafeIntPtr(bash.Timeout) != 60000 {
+			t.Errorf("Unexpected input: %+v", bash)
+		}
+	})
+
+	t.Run("Read", func(t *testing.T) {
+		input, err := DecodeToolInput(ToolUseBlock{
+			Name:  ToolRead,
+			Input: map[string]interface{}{"file_path": "/tmp/a.go", "offset": float64(10), "limit": float64(20)},
+		})
+		if err != nil {
+			t.Fatalf("DecodeToolInput() error: %v", err)
+		}
+		read := input.(*ReadInput)
+		if read.FilePath != "/tmp/a.go" || SafeIntPtr(read.Offset) != 10 || SafeIntPtr(read.Limit) != 20 {
+			t.Errorf("Unexpected input: %+v", read)
+		}
+	})
+
+	t.Run("MultiEdit", func(t *testing.T) {
+		input, err := DecodeToolInput(ToolUseBlock{
+			Name: ToolMultiEdit,
+			Input: map[string]interface{}{
+				"file_path": "/tmp/a.go",
+				"edits": []interface{}{
+					map[string]interface{}{"old_string": "a", "new_string": "b", "replace_all": true},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("DecodeToolInput() error: %v", err)
+		}
+		edit := input.(*MultiEditInput)
+		if len(edit.Edits) != 1 || !SafeBoolPtr(edit.Edits[0].ReplaceAll) {
+			t.Errorf("Unexpected input: %+v", edit)
+		}
+	})
+
+	t.Run("unknown tool", func(t *testing.T) {
+		_, err := DecodeToolInput(ToolUseBlock{Name: "mcp__unknown__tool"})
+		if err == nil || !strings.Contains(err.Error(), "no input type registered") {
+			t.Errorf("Expected unregistered tool error, got %v", err)
+		}
+	})
+
+	t.Run("mismatched input", func(t *testing.T) {
+		_, err := DecodeToolInput(ToolUseBlock{
+			Name:  ToolBash,
+			Input: map[string]interface{}{"command": 42},
+		})
+		if err == nil || !strings.Contains(err.Error(), "failed to decode Bash input") {
+			t.Errorf("Expected decode error, got %v", err)
+		}
+	})
+}
+
+func TestRegisterToolInput(t *testing.T) {
+	type deployInput struct {
+		Environment string `json:"environment"`
+	}
+	RegisterToolInput("mcp__ops__deploy", func() interface{} { return &deployInput{} })
+
+	input, err := DecodeToolInput(ToolUseBlock{
+		Name:  "mcp__ops__deploy",
+		Input: map[string]interface{}{"environment": "staging"},
+	})
+	if err != nil {
+		t.Fatalf("DecodeToolInput() error: %v", err)
+	}
+	if deploy, ok := input.(*deployInput); !ok || deploy.Environment != "staging" {
+		t.Errorf("Unexpected input: %#v", input)
+	}
+}
@@ -0,0 +1,137 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ToolLimiter caps how many calls of a tool may run at once across every
+// agent sharing it, e.g. to run Bash serially for agents working in the same
+// workspace. Calls over the limit wait in arrival order.
+//
+// Apply installs hooks on an agent's Options that take a slot before each
+// limited tool runs and free it once the tool has run. Acquire serves code
+// that runs tools itself.
+//
+// Example:
+//
+//	limiter := NewToolLimiter(map[string]int{ToolBash: 1, ToolWrite: 1})
+//	for _, opts := range agentOptions {
+//	    limiter.Apply(opts)
+//	}
+type ToolLimiter struct {
+	slots map[string]chan struct{}
+}
+
+// NewToolLimiter creates a ToolLimiter allowing limits[tool] concurrent
+// calls of each tool. A limit of 1 serializes the tool; tools without a
+// positive limit are not restricted.
+func NewToolLimiter(limits map[string]int) *ToolLimiter {
+	l := &ToolLimiter{slots: make(map[string]chan struct{}, len(limits))}
+	for tool, limit := range limits {
+		if limit > 0 {
+			l.slots[tool] = make(chan struct{}, limit)
+		}
+	}
+	return l
+}
+
+// Limit returns the concurrency limit of tool, or 0 if it is unrestricted
+func (l *ToolLimiter) Limit(tool string) int {
+	return cap(l.slots[tool])
+}
+
+// Acquire waits for a free slot of tool and returns the function that frees
+// it. The release function may be called more than once. Acquire returns
+// ctx's error if ctx ends first.
+func (l *ToolLimiter) Acquire(ctx context.Context, tool string) (func(), error) {
+	slots, ok := l.slots[tool]
+	if !ok {
+		return func() {}, nil
+	}
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-slots })
+	}, nil
+}
+
+// Apply makes the agent run with options share the limiter. A PreToolUse
+// hook waits for a free slot before each limited tool runs, and a
+// PostToolUse hook frees it, matched by tool use ID; slots still held when
+// the agent stops, e.g. for calls denied after the PreToolUse hook, are
+// freed by a Stop hook. A call still queued when the CLI's hook timeout
+// expires is denied. Hooks already set on options are kept.
+func (l *ToolLimiter) Apply(options *Options) {
+	if len(l.slots) == 0 {
+		return
+	}
+	tools := make([]string, 0, len(l.slots))
+	for tool := range l.slots {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+
+	var mu sync.Mutex
+	held := make(map[string]func())
+	release := func(toolUseID string) {
+		mu.Lock()
+		releaseSlot, ok := held[toolUseID]
+		delete(held, toolUseID)
+		mu.Unlock()
+		if ok {
+			releaseSlot()
+		}
+	}
+
+	acquire := func(ctx context.Context, input HookInput, toolUseID string) (HookOutput, error) {
+		releaseSlot, err := l.Acquire(ctx, input.ToolName)
+		if err != nil {
+			return DenyToolUse(fmt.Sprintf("no free %s slot: %v", input.ToolName, err)), nil
+		}
+		mu.Lock()
+		previous := held[toolUseID]
+		held[toolUseID] = releaseSlot
+		mu.Unlock()
+		if previous != nil {
+			previous()
+		}
+		return HookOutput{}, nil
+	}
+	free := func(ctx context.Context, input HookInput, toolUseID string) (HookOutput, error) {
+		release(toolUseID)
+		return HookOutput{}, nil
+	}
+	freeAll := func(ctx context.Context, input HookInput, toolUseID string) (HookOutput, error) {
+		mu.Lock()
+		ids := make([]string, 0, len(held))
+		for id := range held {
+			ids = append(ids, id)
+		}
+		mu.Unlock()
+		for _, id := range ids {
+			release(id)
+		}
+		return HookOutput{}, nil
+	}
+
+	matcher := strings.Join(tools, "|")
+	hooks := make(map[HookEvent][]HookMatcher, len(options.Hooks)+3)
+	for event, matchers := range options.Hooks {
+		hooks[event] = matchers
+	}
+	hooks[HookPreToolUse] = append(append([]HookMatcher(nil), hooks[HookPreToolUse]...),
+		HookMatcher{Matcher: matcher, Hooks: []HookCallback{acquire}})
+	hooks[HookPostToolUse] = append(append([]HookMatcher(nil), hooks[HookPostToolUse]...),
+		HookMatcher{Matcher: matcher, Hooks: []HookCallback{free}})
+	hooks[HookStop] = append(append([]HookMatcher(nil), hooks[HookStop]...),
+		HookMatcher{Hooks: []HookCallback{freeAll}})
+	options.Hooks = hooks
+}
@@ -0,0 +1,158 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestToolLimiter(t *testing.T) {
+	limiter := NewToolLimiter(map[string]int{ToolBash: 1, ToolWrite: 2, ToolRead: 0})
+
+	t.Run("limits", func(t *testing.T) {
+		if limiter.Limit(ToolBash) != 1 || limiter.Limit(ToolWrite) != 2 || limiter.Limit(ToolRead) != 0 {
+			t.Errorf("unexpected limits: Bash=%d Write=%d Read=%d",
+				limiter.Limit(ToolBash), limiter.Limit(ToolWrite), limiter.Limit(ToolRead))
+		}
+	})
+
+	t.Run("serializes", func(t *testing.T) {
+		var running, peak int32
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				release, err := limiter.Acquire(context.Background(), ToolBash)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer release()
+				n := atomic.AddInt32(&running, 1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+			}()
+		}
+		wg.Wait()
+		if peak != 1 {
+			t.Errorf("expected at most 1 concurrent Bash call, got %d", peak)
+		}
+	})
+
+	t.Run("unrestricted", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			if _, err := limiter.Acquire(context.Background(), ToolRead); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("context ends while queued", func(t *testing.T) {
+		release, err := limiter.Acquire(context.Background(), ToolBash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := limiter.Acquire(ctx, ToolBash); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+
+		release()
+		release() // releasing twice frees a single slot
+		if _, err := limiter.Acquire(context.Background(), ToolBash); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := limiter.Acquire(ctx, ToolBash); err == nil {
+			t.Error("expected the slot to be held")
+		}
+	})
+}
+
+func TestToolLimiterApply(t *testing.T) {
+	limiter := NewToolLimiter(map[string]int{ToolBash: 1})
+	var userHookRan bool
+	opts := NewOptions()
+	opts.Hooks = map[HookEvent][]HookMatcher{
+		HookPreToolUse: {{Hooks: []HookCallback{func(context.Context, HookInput, string) (HookOutput, error) {
+			userHookRan = true
+			return HookOutput{}, nil
+		}}}},
+	}
+	userHooks := opts.Hooks
+	limiter.Apply(opts)
+	if len(userHooks[HookPostToolUse]) != 0 {
+		t.Error("Apply modified the caller's hooks map")
+	}
+
+	run := func(event HookEvent, toolUseID string) HookOutput {
+		t.Helper()
+		var out HookOutput
+		for _, matcher := range opts.Hooks[event] {
+			for _, hook := range matcher.Hooks {
+				got, err := hook(context.Background(), HookInput{HookEventName: event, ToolName: ToolBash}, toolUseID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.HookSpecificOutput != nil {
+					out = got
+				}
+			}
+		}
+		return out
+	}
+
+	run(HookPreToolUse, "t1")
+	if !userHookRan {
+		t.Error("expected existing hooks to be kept")
+	}
+	if matcher := opts.Hooks[HookPreToolUse][1].Matcher; matcher != ToolBash {
+		t.Errorf("expected matcher %q, got %q", ToolBash, matcher)
+	}
+
+	second := make(chan struct{})
+	go func() {
+		run(HookPreToolUse, "t2")
+		close(second)
+	}()
+	select {
+	case <-second:
+		t.Fatal("expected the second Bash call to wait for the first")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	run(HookPostToolUse, "t1")
+	select {
+	case <-second:
+	case <-time.After(time.Second):
+		t.Fatal("expected the second Bash call to run once the first finished")
+	}
+
+	// t2 is never reported done, e.g. because it was denied; Stop frees it
+	run(HookStop, "")
+	release, err := limiter.Acquire(context.Background(), ToolBash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A call still queued when the hook's context ends is denied
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	out, err := opts.Hooks[HookPreToolUse][1].Hooks[0](ctx, HookInput{ToolName: ToolBash}, "t3")
+	if err != nil || out.HookSpecificOutput["permissionDecision"] != "deny" {
+		t.Errorf("expected a deny, got %+v, %v", out, err)
+	}
+	release()
+}
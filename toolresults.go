@@ -0,0 +1,210 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// AnyTool registers a ToolResultPipeline processor for the results of every
+// tool
+const AnyTool = "*"
+
+// ToolResultProcessor transforms the text of a result of tool. Returning an
+// error leaves the text as it was before the processor ran.
+type ToolResultProcessor func(ctx context.Context, tool, text string) (string, error)
+
+// ToolResultPipeline post-processes tool results before they are delivered,
+// e.g. to truncate long Bash output, strip terminal escape codes or
+// summarize large files, so UIs, recordings and archives downstream see the
+// processed text. Results are matched to their tool by the ToolUseBlock
+// that requested them; text items of list results are processed one by one.
+//
+// Example:
+//
+//	pipeline := NewToolResultPipeline().
+//	    Register(ToolBash, StripANSI(), TruncateToolResult(4000)).
+//	    Register(ToolRead, SummarizeToolResult(20000, nil))
+//	rec := NewRecordingSession()
+//	runAgent(ctx, pipeline.Wrap(rec.Query)) // recordings hold processed results
+type ToolResultPipeline struct {
+	processors map[string][]ToolResultProcessor
+	// OnError, when set, is called with the errors of failed processors
+	OnError func(tool string, err error)
+}
+
+// NewToolResultPipeline creates an empty pipeline
+func NewToolResultPipeline() *ToolResultPipeline {
+	return &ToolResultPipeline{processors: make(map[string][]ToolResultProcessor)}
+}
+
+// Register adds processors for the results of tool, or of every tool with
+// AnyTool. A tool's own processors run first, in registration order, then
+// those registered for AnyTool.
+func (p *ToolResultPipeline) Register(tool string, processors ...ToolResultProcessor) *ToolResultPipeline {
+	p.processors[tool] = append(p.processors[tool], processors...)
+	return p
+}
+
+// Process runs the processors registered for tool on text
+func (p *ToolResultPipeline) Process(ctx context.Context, tool, text string) string {
+	keys := []string{tool}
+	if tool != AnyTool {
+		keys = append(keys, AnyTool)
+	}
+	for _, key := range keys {
+		for _, process := range p.processors[key] {
+			processed, err := process(ctx, tool, text)
+			if err != nil {
+				if p.OnError != nil {
+					p.OnError(tool, err)
+				}
+				continue
+			}
+			text = processed
+		}
+	}
+	return text
+}
+
+// Wrap returns a QueryFunc that runs next and processes the tool results of
+// every message it delivers
+func (p *ToolResultPipeline) Wrap(next QueryFunc) QueryFunc {
+	return func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+		msgCh, errCh := next(ctx, prompt, options)
+		return p.Stream(ctx, msgCh), errCh
+	}
+}
+
+// Stream processes the tool results of msgCh. The returned channel closes
+// when msgCh does; once ctx is done, messages are no longer delivered but
+// msgCh is still drained.
+func (p *ToolResultPipeline) Stream(ctx context.Context, msgCh <-chan Message) <-chan Message {
+	out := make(chan Message, cap(msgCh))
+	go func() {
+		defer close(out)
+		tools := make(map[string]string) // Tool names by tool use ID
+		stopped := false
+		for msg := range msgCh {
+			if stopped {
+				continue
+			}
+			select {
+			case out <- p.processMessage(ctx, msg, tools):
+			case <-ctx.Done():
+				stopped = true
+			}
+		}
+	}()
+	return out
+}
+
+// processMessage returns a copy of msg with its tool results processed,
+// recording the tools it requests in tools. The CLI reports tool results in
+// user messages.
+func (p *ToolResultPipeline) processMessage(ctx context.Context, msg Message, tools map[string]string) Message {
+	switch m := msg.(type) {
+	case AssistantMessage:
+		m.Content = p.processBlocks(ctx, m.Content, tools)
+		return m
+	case UserMessage:
+		if len(m.Blocks) > 0 {
+			m.Blocks = p.processBlocks(ctx, m.Blocks, tools)
+		}
+		return m
+	}
+	return msg
+}
+
+// processBlocks returns a copy of blocks with their tool results processed
+func (p *ToolResultPipeline) processBlocks(ctx context.Context, blocks []ContentBlock, tools map[string]string) []ContentBlock {
+	processed := make([]ContentBlock, len(blocks))
+	for i, block := range blocks {
+		switch b := block.(type) {
+		case ToolUseBlock:
+			tools[b.ID] = b.Name
+		case ToolResultBlock:
+			tool := tools[b.ToolUseID]
+			b.Content = p.processContent(ctx, tool, b.Content)
+			block = b
+		}
+		processed[i] = block
+	}
+	return processed
+}
+
+// processContent processes a tool result, which is a string or a list of
+// content items with text fields
+func (p *ToolResultPipeline) processContent(ctx context.Context, tool string, content interface{}) interface{} {
+	switch c := content.(type) {
+	case string:
+		return p.Process(ctx, tool, c)
+	case []interface{}:
+		items := make([]interface{}, len(c))
+		for i, item := range c {
+			items[i] = p.processContent(ctx, tool, item)
+		}
+		return items
+	case []map[string]interface{}:
+		items := make([]map[string]interface{}, len(c))
+		for i, item := range c {
+			items[i] = p.processContent(ctx, tool, item).(map[string]interface{})
+		}
+		return items
+	case map[string]interface{}:
+		item := make(map[string]interface{}, len(c))
+		for k, v := range c {
+			if text, ok := v.(string); ok && k == "text" {
+				v = p.Process(ctx, tool, text)
+			}
+			item[k] = v
+		}
+		return item
+	}
+	return content
+}
+
+// ansiPattern matches CSI sequences (colors, cursor movement) and OSC
+// sequences (titles, hyperlinks)
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// StripANSI returns a processor removing terminal escape codes
+func StripANSI() ToolResultProcessor {
+	return func(ctx context.Context, tool, text string) (string, error) {
+		return ansiPattern.ReplaceAllString(text, ""), nil
+	}
+}
+
+// TruncateToolResult returns a processor cutting results to at most max
+// bytes with TruncateText, marking where text was removed
+func TruncateToolResult(max int) ToolResultProcessor {
+	return func(ctx context.Context, tool, text string) (string, error) {
+		return TruncateText(text, max, "\n[truncated]"), nil
+	}
+}
+
+// SummarizeToolResult returns a processor replacing results longer than
+// threshold bytes with a summary from Summarize, by default on SummaryModel
+func SummarizeToolResult(threshold int, opts *SummarizeOptions) ToolResultProcessor {
+	return func(ctx context.Context, tool, text string) (string, error) {
+		if len(text) <= threshold {
+			return text, nil
+		}
+		summaryOpts := SummarizeOptions{}
+		if opts != nil {
+			summaryOpts = *opts
+		}
+		if summaryOpts.Instructions == "" {
+			words := summaryOpts.MaxWords
+			if words <= 0 {
+				words = defaultSummaryWords
+			}
+			summaryOpts.Instructions = fmt.Sprintf("The message above is the output of the %s tool. Summarize it in at most %d words, keeping file names, errors and figures.", tool, words)
+		}
+		summary, err := Summarize(ctx, []Message{UserMessage{Content: text}}, &summaryOpts)
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize %s result: %w", tool, err)
+		}
+		return fmt.Sprintf("[summary of %d bytes of output]\n%s", len(text), summary), nil
+	}
+}
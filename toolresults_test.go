@@ -0,0 +1,135 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToolResultPipeline(t *testing.T) {
+	bashOutput := "\x1b[32mok\x1b[0m  \x1b]8;;http://x\x07pkg\x1b]8;;\x07 " + strings.Repeat("line\n", 100)
+	readItems := []interface{}{
+		map[string]interface{}{"type": "text", "text": strings.Repeat("package main\n", 10)},
+	}
+	messages := []Message{
+		AssistantMessage{Content: []ContentBlock{
+			ToolUseBlock{ID: "t1", Name: ToolBash},
+			ToolUseBlock{ID: "t2", Name: ToolRead},
+		}},
+		UserMessage{Blocks: []ContentBlock{
+			ToolResultBlock{ToolUseID: "t1", Content: bashOutput},
+			ToolResultBlock{ToolUseID: "t2", Content: readItems},
+			ToolResultBlock{ToolUseID: "unknown", Content: "other"},
+		}},
+		ResultMessage{Subtype: "success"},
+	}
+	query := func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+		msgCh := make(chan Message, len(messages))
+		for _, msg := range messages {
+			msgCh <- msg
+		}
+		close(msgCh)
+		errCh := make(chan error)
+		close(errCh)
+		return msgCh, errCh
+	}
+
+	summarizer := NewScriptedResponder(1).Default(TextResponse("a main package")...)
+	var failures []string
+	pipeline := NewToolResultPipeline().
+		Register(ToolBash, StripANSI(), TruncateToolResult(20)).
+		Register(ToolRead, SummarizeToolResult(50, &SummarizeOptions{Query: summarizer.Query})).
+		Register(AnyTool, func(ctx context.Context, tool, text string) (string, error) {
+			if tool == "" {
+				return "", errors.New("unknown tool")
+			}
+			return "[" + tool + "] " + text, nil
+		})
+	pipeline.OnError = func(tool string, err error) {
+		failures = append(failures, err.Error())
+	}
+
+	msgCh, errCh := pipeline.Wrap(query)(context.Background(), "test", nil)
+	var got []Message
+	for msg := range msgCh {
+		got = append(got, msg)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 messages, got %+v", got)
+	}
+
+	results := got[1].(UserMessage).Blocks
+	if bash := results[0].(ToolResultBlock).Content; bash != "[Bash] ok  pkg\n[truncated]" {
+		t.Errorf("bash result = %q", bash)
+	}
+	items := results[1].(ToolResultBlock).Content.([]interface{})
+	if text := items[0].(map[string]interface{})["text"]; text != "[Read] [summary of 130 bytes of output]\na main package" {
+		t.Errorf("read result = %q", text)
+	}
+	if other := results[2].(ToolResultBlock).Content; other != "other" {
+		t.Errorf("expected the failed processor to leave the result, got %q", other)
+	}
+	if len(failures) != 1 || failures[0] != "unknown tool" {
+		t.Errorf("unexpected failures %v", failures)
+	}
+
+	if messages[1].(UserMessage).Blocks[0].(ToolResultBlock).Content != bashOutput {
+		t.Error("original message was modified")
+	}
+}
+
+func TestToolResultPipelineQuery(t *testing.T) {
+	// The CLI reports tool results in user messages
+	installFakeCLI(t, `#!/bin/sh
+cat <<'EOF'
+{"type":"assistant","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go test"}}]}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"\u001b[31mFAIL\u001b[0m pkg"}]}}
+{"type":"result","subtype":"success"}
+EOF
+`)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pipeline := NewToolResultPipeline().Register(ToolBash, StripANSI())
+	msgCh, errCh := pipeline.Wrap(Query)(ctx, "test", nil)
+	var results []ToolResultBlock
+	for msg := range msgCh {
+		if m, ok := msg.(UserMessage); ok {
+			for _, block := range m.Blocks {
+				if result, ok := block.(ToolResultBlock); ok {
+					results = append(results, result)
+				}
+			}
+		}
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ToolUseID != "t1" || results[0].Content != "FAIL pkg" {
+		t.Errorf("expected the processed Bash result, got %+v", results)
+	}
+}
+
+func TestToolResultPipelineStreamStopsWithContext(t *testing.T) {
+	msgCh := make(chan Message)
+	ctx, cancel := context.WithCancel(context.Background())
+	out := NewToolResultPipeline().Stream(ctx, msgCh)
+
+	// Nobody reads out once ctx is done; msgCh must still be drained
+	cancel()
+	for i := 0; i < 3; i++ {
+		select {
+		case msgCh <- AssistantMessage{}:
+		case <-time.After(time.Second):
+			t.Fatal("expected Stream to keep draining its input")
+		}
+	}
+	close(msgCh)
+	for range out {
+	}
+}
@@ -0,0 +1,132 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+)
+
+// Built-in Claude Code tool names, for use in AllowedTools and
+// DisallowedTools and when inspecting ToolUseBlock.Name
+const (
+	ToolTask         = "Task"
+	ToolBash         = "Bash"
+	ToolBashOutput   = "BashOutput"
+	ToolKillShell    = "KillShell"
+	ToolGlob         = "Glob"
+	ToolGrep         = "Grep"
+	ToolLS           = "LS"
+	ToolExitPlanMode = "ExitPlanMode"
+	ToolRead         = "Read"
+	ToolEdit         = "Edit"
+	ToolMultiEdit    = "MultiEdit"
+	ToolWrite        = "Write"
+	ToolNotebookRead = "NotebookRead"
+	ToolNotebookEdit = "NotebookEdit"
+	ToolWebFetch     = "WebFetch"
+	ToolWebSearch    = "WebSearch"
+	ToolTodoRead     = "TodoRead"
+	ToolTodoWrite    = "TodoWrite"
+)
+
+// readOnlyTools are the built-in tools that cannot modify the workspace or
+// run arbitrary commands
+var readOnlyTools = []string{
+	ToolRead,
+	ToolGlob,
+	ToolGrep,
+	ToolLS,
+	ToolNotebookRead,
+	ToolWebFetch,
+	ToolWebSearch,
+	ToolTodoRead,
+}
+
+// readOnlyModeTools are the tools Options.ReadOnly permits: the read-only
+// tools plus planning tools that only touch the CLI's own state
+var readOnlyModeTools = append([]string{ToolExitPlanMode, ToolTodoWrite}, readOnlyTools...)
+
+// fileEditTools are the built-in tools that write to files
+var fileEditTools = []string{
+	ToolEdit,
+	ToolMultiEdit,
+	ToolWrite,
+	ToolNotebookEdit,
+}
+
+// AllTools returns the names of all built-in tools
+func AllTools() []string {
+	return []string{
+		ToolTask,
+		ToolBash,
+		ToolBashOutput,
+		ToolKillShell,
+		ToolGlob,
+		ToolGrep,
+		ToolLS,
+		ToolExitPlanMode,
+		ToolRead,
+		ToolEdit,
+		ToolMultiEdit,
+		ToolWrite,
+		ToolNotebookRead,
+		ToolNotebookEdit,
+		ToolWebFetch,
+		ToolWebSearch,
+		ToolTodoRead,
+		ToolTodoWrite,
+	}
+}
+
+// AllReadOnlyTools returns the names of the built-in tools that only read
+func AllReadOnlyTools() []string {
+	return append([]string(nil), readOnlyTools...)
+}
+
+// AllFileEditTools returns the names of the built-in tools that modify files
+func AllFileEditTools() []string {
+	return append([]string(nil), fileEditTools...)
+}
+
+// IsReadOnlyTool reports whether name is a built-in read-only tool
+func IsReadOnlyTool(name string) bool {
+	return containsTool(readOnlyTools, name)
+}
+
+// mutatingTools are the built-in tools Options.ReadOnly disallows
+var mutatingTools = append([]string{ToolBash, ToolKillShell, ToolTask}, fileEditTools...)
+
+// denyReadOnlyViolation is the PreToolUse hook Options.ReadOnly installs: it
+// denies tools read-only mode does not permit before they run, even where
+// the CLI's settings allow them. The message stream is still checked in case
+// the hook is bypassed.
+func denyReadOnlyViolation(ctx context.Context, input HookInput, toolUseID string) (HookOutput, error) {
+	if containsTool(readOnlyModeTools, input.ToolName) {
+		return HookOutput{}, nil
+	}
+	return DenyToolUse(fmt.Sprintf("%s is not permitted in read-only mode", input.ToolName)), nil
+}
+
+// readOnlyViolation returns the first tool requested in msg that read-only
+// mode does not permit, if any
+func readOnlyViolation(msg AssistantMessage) (string, bool) {
+	for _, block := range msg.Content {
+		if use, ok := block.(ToolUseBlock); ok && !containsTool(readOnlyModeTools, use.Name) {
+			return use.Name, true
+		}
+	}
+	return "", false
+}
+
+// IsFileEditTool reports whether name is a built-in tool that modifies files
+func IsFileEditTool(name string) bool {
+	return containsTool(fileEditTools, name)
+}
+
+func containsTool(tools []string, name string) bool {
+	for _, tool := range tools {
+		if tool == name {
+			return true
+		}
+	}
+	return false
+}
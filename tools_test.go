@@ -0,0 +1,55 @@
+package claudecode
+
+import (
+	"testing"
+)
+
+func TestToolSets(t *testing.T) {
+	all := make(map[string]bool)
+	for _, tool := range AllTools() {
+		if all[tool] {
+			t.Errorf("Duplicate tool %q in AllTools()", tool)
+		}
+		all[tool] = true
+	}
+
+	for _, tool := range AllReadOnlyTools() {
+		if !all[tool] {
+			t.Errorf("Read-only tool %q missing from AllTools()", tool)
+		}
+		if IsFileEditTool(tool) {
+			t.Errorf("Tool %q is both read-only and file editing", tool)
+		}
+	}
+	for _, tool := range AllFileEditTools() {
+		if !all[tool] {
+			t.Errorf("File edit tool %q missing from AllTools()", tool)
+		}
+	}
+
+	if IsReadOnlyTool(ToolBash) || !IsReadOnlyTool(ToolGrep) {
+		t.Error("IsReadOnlyTool() misclassified built-in tools")
+	}
+	if !IsFileEditTool(ToolWrite) || IsFileEditTool("mcp__fs__write") {
+		t.Error("IsFileEditTool() misclassified tools")
+	}
+}
+
+func TestToolSetsAreCopies(t *testing.T) {
+	tools := AllReadOnlyTools()
+	tools[0] = "Bash"
+	if IsReadOnlyTool("Bash") {
+		t.Error("Modifying AllReadOnlyTools() result changed the read-only set")
+	}
+}
+
+func TestToolConstantsBuildCLIArgs(t *testing.T) {
+	options := &Options{
+		AllowedTools:      AllReadOnlyTools(),
+		DisallowedTools:   []string{ToolBash},
+		MaxThinkingTokens: 8000,
+	}
+	if _, err := options.BuildCLIArgs(); err != nil {
+		t.Errorf("Tool constants failed validation: %v", err)
+	}
+}
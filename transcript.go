@@ -0,0 +1,135 @@
+package claudecode
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// maxTranscriptLineSize bounds one line of a JSONL transcript
+const maxTranscriptLineSize = 10 * 1024 * 1024
+
+// LoadTranscript reads the user and assistant messages of a conversation
+// exported to path, in the form they are replayed for Options.ResumeFrom.
+// Three formats are accepted: JSONL transcripts with one
+// {"type": "user"|"assistant", "message": {...}} object per line, as stored
+// by the CLI in its session directory or printed with stream-json output,
+// conversations written by ExportConversation and recordings saved by
+// RecordingSession. Other lines, such as system and result messages, are
+// skipped.
+func LoadTranscript(path string) ([]map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	if conv, err := ImportConversation(bytes.NewReader(data)); err == nil {
+		return conversationTranscript(conv), nil
+	}
+
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err == nil && rec.Version != 0 {
+		if rec.Version != recordingVersion {
+			return nil, fmt.Errorf("unsupported recording version %d", rec.Version)
+		}
+		return recordingTranscript(&rec), nil
+	}
+
+	var messages []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxTranscriptLineSize)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry struct {
+			Type    string                 `json:"type"`
+			Message map[string]interface{} `json:"message"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("invalid transcript line %d: %w", lineNum, err)
+		}
+		if (entry.Type != "user" && entry.Type != "assistant") || entry.Message["content"] == nil {
+			continue
+		}
+		messages = append(messages, transcriptMessage(entry.Type, entry.Message["content"]))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	return messages, nil
+}
+
+// conversationTranscript converts the user and assistant messages of conv.
+// Only text is kept: tool calls are dropped, and with them the user messages
+// carrying their results.
+func conversationTranscript(conv *Conversation) []map[string]interface{} {
+	messages, _ := conv.Decode() // Checked by ImportConversation
+	var replay []map[string]interface{}
+	for _, msg := range messages {
+		switch m := msg.(type) {
+		case UserMessage:
+			if m.Content != "" {
+				replay = append(replay, transcriptMessage("user", m.Content))
+			}
+		case AssistantMessage:
+			var content []interface{}
+			for _, block := range m.Content {
+				if text, ok := block.(TextBlock); ok {
+					content = append(content, map[string]interface{}{"type": "text", "text": text.Text})
+				}
+			}
+			if len(content) > 0 {
+				replay = append(replay, transcriptMessage("assistant", content))
+			}
+		}
+	}
+	return replay
+}
+
+// recordingTranscript converts the prompt and answer of each recorded turn
+func recordingTranscript(rec *Recording) []map[string]interface{} {
+	var messages []map[string]interface{}
+	for _, turn := range rec.Turns {
+		messages = append(messages, transcriptMessage("user", turn.Prompt))
+		if turn.Answer != "" {
+			messages = append(messages, transcriptMessage("assistant", []interface{}{
+				map[string]interface{}{"type": "text", "text": turn.Answer},
+			}))
+		}
+	}
+	return messages
+}
+
+// transcriptMessage builds the stream-json input line replaying one message
+func transcriptMessage(role string, content interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type": role,
+		"message": map[string]interface{}{
+			"role":    role,
+			"content": content,
+		},
+		"parent_tool_use_id": nil,
+		"session_id":         "default",
+	}
+}
+
+// GetResumeFrom returns the transcript a new session replays, if any
+func (o *Options) GetResumeFrom() string {
+	if o == nil {
+		return ""
+	}
+	return o.ResumeFrom
+}
+
+// GetReplayMessages returns the stream-json lines replaying ResumeFrom.
+// Returns nil if no transcript is set.
+func (o *Options) GetReplayMessages() ([]map[string]interface{}, error) {
+	if o == nil || o.ResumeFrom == "" {
+		return nil, nil
+	}
+	return LoadTranscript(o.ResumeFrom)
+}
@@ -0,0 +1,104 @@
+package claudecode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTranscript writes content to a transcript file and returns its path
+func writeTranscript(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadTranscript(t *testing.T) {
+	t.Run("jsonl", func(t *testing.T) {
+		path := writeTranscript(t, "session.jsonl", strings.Join([]string{
+			`{"type":"summary","summary":"Greeting"}`,
+			`{"type":"user","uuid":"u1","message":{"role":"user","content":"Hi"}}`,
+			`{"type":"assistant","uuid":"a1","message":{"id":"msg_1","role":"assistant","content":[{"type":"text","text":"Hello"}]}}`,
+			``,
+			`{"type":"result","subtype":"success","session_id":"s1"}`,
+		}, "\n"))
+
+		messages, err := LoadTranscript(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []map[string]interface{}{
+			transcriptMessage("user", "Hi"),
+			transcriptMessage("assistant", []interface{}{map[string]interface{}{"type": "text", "text": "Hello"}}),
+		}
+		if !reflect.DeepEqual(messages, want) {
+			t.Errorf("LoadTranscript() = %v, want %v", messages, want)
+		}
+	})
+
+	t.Run("recording", func(t *testing.T) {
+		path := writeTranscript(t, "run.json", `{"version":1,"turns":[{"prompt":"Hi","answer":"Hello"},{"prompt":"Bye","answer":""}]}`)
+
+		messages, err := LoadTranscript(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var roles []string
+		for _, msg := range messages {
+			roles = append(roles, msg["type"].(string))
+		}
+		if !reflect.DeepEqual(roles, []string{"user", "assistant", "user"}) {
+			t.Errorf("unexpected replayed roles %v", roles)
+		}
+	})
+
+	t.Run("invalid line", func(t *testing.T) {
+		path := writeTranscript(t, "bad.jsonl", "{\"type\":\"user\",\"message\":{\"content\":\"Hi\"}}\nnot json\n")
+		if _, err := LoadTranscript(path); err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("expected error for line 2, got %v", err)
+		}
+	})
+}
+
+func TestQueryResumeFrom(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+read -r first
+read -r second
+read -r prompt
+case "$first|$second|$prompt" in
+*'"content":"What is 2+2?"'*'|'*'"text":"4"'*'|'*'"content":"And doubled?"'*) reply=resumed ;;
+*) reply=fresh ;;
+esac
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"'"$reply"'"}]}}'
+echo '{"type":"result","subtype":"success","session_id":"s1"}'
+while read -r line; do :; done
+`)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := NewOptions()
+	opts.ResumeFrom = writeTranscript(t, "session.jsonl", strings.Join([]string{
+		`{"type":"user","message":{"role":"user","content":"What is 2+2?"}}`,
+		`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"4"}]}}`,
+	}, "\n"))
+
+	text, _, err := QueryText(ctx, "And doubled?", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "resumed" {
+		t.Errorf("expected the transcript to be replayed before the prompt, got %q", text)
+	}
+
+	opts.Resume = "session-id"
+	if err := opts.Validate(); err == nil {
+		t.Error("expected ResumeFrom and Resume to conflict")
+	}
+}
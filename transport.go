@@ -0,0 +1,38 @@
+package claudecode
+
+import (
+	"github.com/f-pisani/claude-code-sdk-go/internal/transport"
+)
+
+// Transport carries one conversation with Claude Code. ReceiveMessages
+// yields the CLI's stream-json messages as decoded JSON objects, e.g.
+// {"type": "assistant", "message": {...}}, and closes its channels when the
+// conversation ends. Implement it to run the CLI elsewhere (over SSH, in a
+// container) or to feed canned messages in unit tests, and pass it to
+// QueryWithTransport.
+type Transport = transport.Transport
+
+// StreamingTransport is a Transport that stays open across turns, as used by
+// Client. SendMessage writes one stream-json input message; ControlRequest
+// sends a control protocol request such as {"subtype": "interrupt"} and
+// returns the body of the CLI's response.
+type StreamingTransport = transport.StreamingTransport
+
+// ContextDisconnector is implemented by transports whose shutdown can be
+// bounded by a context. The subprocess transports implement it, killing the
+// CLI once the context ends; Client.CloseContext uses it when available.
+type ContextDisconnector = transport.ContextDisconnector
+
+// NewSubprocessTransport creates the transport Query uses: the Claude Code
+// CLI run with prompt and options. An empty cliPath searches PATH and the
+// usual install locations.
+func NewSubprocessTransport(prompt string, options *Options, cliPath string) Transport {
+	return transport.NewSubprocessCLITransport(prompt, options, cliPath)
+}
+
+// NewStreamingSubprocessTransport creates the transport Client uses: the
+// Claude Code CLI run with options, reading prompts from stdin. An empty
+// cliPath searches PATH and the usual install locations.
+func NewStreamingSubprocessTransport(options *Options, cliPath string) StreamingTransport {
+	return transport.NewStreamingCLITransport(streamingOptions(options), cliPath)
+}
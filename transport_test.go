@@ -0,0 +1,161 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTransport replays canned CLI messages
+type fakeTransport struct {
+	messages   []map[string]interface{}
+	err        error
+	connected  bool
+	disconnect int
+}
+
+func (f *fakeTransport) Connect(ctx context.Context) error {
+	f.connected = true
+	return nil
+}
+
+func (f *fakeTransport) Disconnect() error {
+	f.connected = false
+	f.disconnect++
+	return nil
+}
+
+func (f *fakeTransport) IsConnected() bool {
+	return f.connected
+}
+
+func (f *fakeTransport) ReceiveMessages(ctx context.Context) (<-chan map[string]interface{}, <-chan error) {
+	msgCh := make(chan map[string]interface{}, len(f.messages))
+	errCh := make(chan error, 1)
+	for _, msg := range f.messages {
+		msgCh <- msg
+	}
+	if f.err != nil {
+		errCh <- f.err
+	}
+	close(msgCh)
+	close(errCh)
+	return msgCh, errCh
+}
+
+func TestQueryWithTransport(t *testing.T) {
+	trans := &fakeTransport{
+		messages: []map[string]interface{}{
+			{"type": "assistant", "message": map[string]interface{}{
+				"content": []interface{}{map[string]interface{}{"type": "text", "text": "hi"}},
+			}},
+			{"type": "result", "subtype": "success", "num_turns": float64(1)},
+		},
+		err: errors.New("transport failed"),
+	}
+
+	msgCh, errCh := QueryWithTransport(context.Background(), trans, nil)
+	var msgs []Message
+	for msg := range msgCh {
+		msgs = append(msgs, msg)
+	}
+	if err := <-errCh; err == nil || err.Error() != "transport failed" {
+		t.Errorf("expected transport error, got %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %+v", msgs)
+	}
+	if text := msgs[0].(AssistantMessage).Content[0].(TextBlock).Text; text != "hi" {
+		t.Errorf("unexpected text %q", text)
+	}
+	if trans.disconnect != 1 {
+		t.Errorf("expected transport to be disconnected once, got %d", trans.disconnect)
+	}
+}
+
+// echoTransport answers each user message with its own content
+type echoTransport struct {
+	mu       sync.Mutex
+	out      chan map[string]interface{}
+	controls []map[string]interface{}
+}
+
+func (e *echoTransport) Connect(ctx context.Context) error {
+	e.out = make(chan map[string]interface{}, 10)
+	return nil
+}
+
+func (e *echoTransport) Disconnect() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.out != nil {
+		close(e.out)
+		e.out = nil
+	}
+	return nil
+}
+
+func (e *echoTransport) IsConnected() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.out != nil
+}
+
+func (e *echoTransport) ReceiveMessages(ctx context.Context) (<-chan map[string]interface{}, <-chan error) {
+	errCh := make(chan error)
+	close(errCh)
+	return e.out, errCh
+}
+
+func (e *echoTransport) SendMessage(msg map[string]interface{}) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	content := msg["message"].(map[string]interface{})["content"]
+	e.out <- map[string]interface{}{"type": "assistant", "message": map[string]interface{}{
+		"content": []interface{}{map[string]interface{}{"type": "text", "text": content}},
+	}}
+	e.out <- map[string]interface{}{"type": "result", "subtype": "success"}
+	return nil
+}
+
+func (e *echoTransport) ControlRequest(ctx context.Context, request map[string]interface{}) (map[string]interface{}, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.controls = append(e.controls, request)
+	return map[string]interface{}{}, nil
+}
+
+func TestClientWithTransport(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	trans := &echoTransport{}
+	client := NewClientWithTransport(trans, nil)
+	if err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SendMessage(ctx, "ping"); err != nil {
+		t.Fatal(err)
+	}
+	messages, err := client.ReceiveResponse(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := messages[0].(AssistantMessage).Content[0].(TextBlock).Text; text != "ping" {
+		t.Errorf("unexpected reply %q", text)
+	}
+	if err := client.Interrupt(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(trans.controls) != 1 || trans.controls[0]["subtype"] != "interrupt" {
+		t.Errorf("expected one interrupt request, got %v", trans.controls)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if trans.IsConnected() {
+		t.Error("expected transport to be disconnected")
+	}
+}
@@ -3,9 +3,16 @@ package claudecode
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/f-pisani/claude-code-sdk-go/internal/clock"
+	"github.com/f-pisani/claude-code-sdk-go/internal/transport"
 	"github.com/f-pisani/claude-code-sdk-go/internal/validation"
 )
 
@@ -16,12 +23,107 @@ const (
 	PermissionModeDefault           PermissionMode = "default"
 	PermissionModeAcceptEdits       PermissionMode = "acceptEdits"
 	PermissionModeBypassPermissions PermissionMode = "bypassPermissions"
+	PermissionModePlan              PermissionMode = "plan"
 )
 
-// McpServerConfig represents MCP server configuration
+// SettingSource identifies a location the CLI loads settings and CLAUDE.md
+// project context from
+type SettingSource string
+
+const (
+	SettingSourceUser    SettingSource = "user"
+	SettingSourceProject SettingSource = "project"
+	SettingSourceLocal   SettingSource = "local"
+)
+
+// PromptInput selects how the prompt of a one-shot query reaches the CLI
+type PromptInput string
+
+const (
+	// PromptInputArgv passes the prompt as a command-line argument
+	PromptInputArgv PromptInput = transport.PromptInputArgv
+	// PromptInputStdin writes the prompt to the CLI's stdin, keeping it out
+	// of the argument list and of ps output
+	PromptInputStdin PromptInput = transport.PromptInputStdin
+	// PromptInputStreamJSON writes the prompt to the CLI's stdin as a
+	// stream-json user message
+	PromptInputStreamJSON PromptInput = transport.PromptInputStreamJSON
+)
+
+// ContextDocument is an additional document injected into the system prompt
+// of a single query
+type ContextDocument struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// MCP server types
+const (
+	McpServerTypeStdio = "stdio"
+	McpServerTypeSSE   = "sse"
+	McpServerTypeHTTP  = "http"
+)
+
+// McpServerConfig represents MCP server configuration. Type selects the
+// shape: stdio servers (the default) are started from Transport and Env,
+// while sse and http servers are reached at URL with Headers.
 type McpServerConfig struct {
+	Type      string                 `json:"type,omitempty"`
 	Transport []string               `json:"transport"`
 	Env       map[string]interface{} `json:"env,omitempty"`
+	URL       string                 `json:"url,omitempty"`
+	Headers   map[string]string      `json:"headers,omitempty"`
+}
+
+// McpSSEServer returns the configuration of a remote MCP server using
+// server-sent events
+func McpSSEServer(url string, headers map[string]string) McpServerConfig {
+	return McpServerConfig{Type: McpServerTypeSSE, URL: url, Headers: headers}
+}
+
+// McpHTTPServer returns the configuration of a remote MCP server using
+// streamable HTTP
+func McpHTTPServer(url string, headers map[string]string) McpServerConfig {
+	return McpServerConfig{Type: McpServerTypeHTTP, URL: url, Headers: headers}
+}
+
+// MarshalJSON encodes only the fields of the configuration's type
+func (c McpServerConfig) MarshalJSON() ([]byte, error) {
+	switch c.Type {
+	case McpServerTypeSSE, McpServerTypeHTTP:
+		return json.Marshal(struct {
+			Type    string            `json:"type"`
+			URL     string            `json:"url"`
+			Headers map[string]string `json:"headers,omitempty"`
+		}{c.Type, c.URL, c.Headers})
+	default:
+		return json.Marshal(struct {
+			Type      string                 `json:"type,omitempty"`
+			Transport []string               `json:"transport"`
+			Env       map[string]interface{} `json:"env,omitempty"`
+		}{c.Type, c.Transport, c.Env})
+	}
+}
+
+// validate checks that the configuration is complete for its type
+func (c McpServerConfig) validate(name string) error {
+	switch c.Type {
+	case "", McpServerTypeStdio:
+		if len(c.Transport) == 0 {
+			return fmt.Errorf("MCP server %q: stdio servers need a transport command", name)
+		}
+	case McpServerTypeSSE, McpServerTypeHTTP:
+		if len(c.Transport) > 0 || len(c.Env) > 0 {
+			return fmt.Errorf("MCP server %q: %s servers take a URL, not a transport command", name, c.Type)
+		}
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("MCP server %q: invalid URL %q", name, c.URL)
+		}
+	default:
+		return fmt.Errorf("MCP server %q: unknown type %q", name, c.Type)
+	}
+	return nil
 }
 
 // ContentBlock represents different types of content blocks
@@ -36,6 +138,15 @@ type TextBlock struct {
 
 func (TextBlock) isContentBlock() {}
 
+// ThinkingBlock represents the model's reasoning, emitted when extended
+// thinking is enabled
+type ThinkingBlock struct {
+	Thinking  string `json:"thinking"`
+	Signature string `json:"signature,omitempty"`
+}
+
+func (ThinkingBlock) isContentBlock() {}
+
 // ToolUseBlock represents tool usage
 type ToolUseBlock struct {
 	ID    string                 `json:"id"`
@@ -54,14 +165,28 @@ type ToolResultBlock struct {
 
 func (ToolResultBlock) isContentBlock() {}
 
+// ImageBlock represents an image. In responses Data holds the base64
+// encoded image; in prompts sent with Client.SendContent the image may be
+// given by Path instead, and is read and encoded when sent.
+type ImageBlock struct {
+	MediaType string `json:"media_type,omitempty"` // e.g. "image/png"; detected from the image if empty
+	Data      string `json:"data,omitempty"`       // Base64 encoded image
+	Path      string `json:"path,omitempty"`       // Image file to send instead of Data
+}
+
+func (ImageBlock) isContentBlock() {}
+
 // Message represents different types of messages
 type Message interface {
 	isMessage()
 }
 
-// UserMessage represents a message from the user
+// UserMessage represents a message from the user. The CLI also reports the
+// results of tool calls as user messages, whose Blocks hold the
+// ToolResultBlock values and whose Content is empty.
 type UserMessage struct {
-	Content string `json:"content"`
+	Content string         `json:"content"`
+	Blocks  []ContentBlock `json:"blocks,omitempty"`
 }
 
 func (UserMessage) isMessage() {}
@@ -69,6 +194,10 @@ func (UserMessage) isMessage() {}
 // AssistantMessage represents a message from the assistant
 type AssistantMessage struct {
 	Content []ContentBlock `json:"content"`
+	// ID is the API message ID. The CLI may split one API response into
+	// several messages sharing an ID.
+	ID    string                 `json:"id,omitempty"`
+	Usage map[string]interface{} `json:"usage,omitempty"` // Token usage of the API response so far
 }
 
 func (AssistantMessage) isMessage() {}
@@ -92,29 +221,116 @@ type ResultMessage struct {
 	TotalCostUSD  *float64               `json:"total_cost_usd,omitempty"`
 	Usage         map[string]interface{} `json:"usage,omitempty"`
 	Result        *string                `json:"result,omitempty"`
+	// CancelReason is why the turn was interrupted, when it was stopped by
+	// Client.InterruptWithReason. It is set by the SDK, not the CLI.
+	CancelReason CancelReason `json:"cancel_reason,omitempty"`
 }
 
 func (ResultMessage) isMessage() {}
 
+// StreamEvent is a raw streaming event of a message being generated,
+// delivered when Options.IncludePartialMessages is set. Event holds the API
+// event, e.g. {"type": "content_block_delta", "delta": {...}}; the complete
+// AssistantMessage still follows.
+type StreamEvent struct {
+	UUID            string                 `json:"uuid"`
+	SessionID       string                 `json:"session_id"`
+	Event           map[string]interface{} `json:"event"`
+	ParentToolUseID string                 `json:"parent_tool_use_id,omitempty"`
+}
+
+func (StreamEvent) isMessage() {}
+
+// TextDelta returns the text added by a text delta event, or "" for any other
+// event
+func (e StreamEvent) TextDelta() string {
+	if e.Event["type"] != "content_block_delta" {
+		return ""
+	}
+	delta, _ := e.Event["delta"].(map[string]interface{})
+	if delta["type"] != "text_delta" {
+		return ""
+	}
+	text, _ := delta["text"].(string)
+	return text
+}
+
+// ErrorMessage carries a query error on the message channel when
+// Options.InlineErrors is set. It is always the last message of the stream.
+type ErrorMessage struct {
+	Err error `json:"-"`
+}
+
+func (ErrorMessage) isMessage() {}
+
+// Error returns the message of the wrapped error
+func (m ErrorMessage) Error() string {
+	if m.Err == nil {
+		return ""
+	}
+	return m.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (m ErrorMessage) Unwrap() error {
+	return m.Err
+}
+
 // Options represents configuration options for Claude Code
 type Options struct {
-	AllowedTools             []string                   `json:"allowed_tools,omitempty"`
-	MaxThinkingTokens        int                        `json:"max_thinking_tokens"`
-	SystemPrompt             string                     `json:"system_prompt,omitempty"`
-	AppendSystemPrompt       string                     `json:"append_system_prompt,omitempty"`
-	McpTools                 []string                   `json:"mcp_tools,omitempty"`
-	McpServers               map[string]McpServerConfig `json:"mcp_servers,omitempty"`
-	PermissionMode           *PermissionMode            `json:"permission_mode,omitempty"`
-	ContinueConversation     bool                       `json:"continue_conversation,omitempty"`
-	Resume                   string                     `json:"resume,omitempty"`
-	MaxTurns                 *int                       `json:"max_turns,omitempty"`
-	DisallowedTools          []string                   `json:"disallowed_tools,omitempty"`
-	Model                    string                     `json:"model,omitempty"`
-	PermissionPromptToolName string                     `json:"permission_prompt_tool_name,omitempty"`
-	Cwd                      string                     `json:"cwd,omitempty"`
-	MessageBufferSize        int                        `json:"message_buffer_size,omitempty"`
-	ErrorBufferSize          int                        `json:"error_buffer_size,omitempty"`
-	QueryTimeout             int                        `json:"query_timeout,omitempty"` // Timeout in seconds for the entire query
+	AllowedTools             []string                    `json:"allowed_tools,omitempty"`
+	MaxThinkingTokens        int                         `json:"max_thinking_tokens"`
+	SystemPrompt             string                      `json:"system_prompt,omitempty"`
+	AppendSystemPrompt       string                      `json:"append_system_prompt,omitempty"`
+	SystemPromptFile         string                      `json:"system_prompt_file,omitempty"`        // Read on every query, so edits apply to the next one
+	AppendSystemPromptFile   string                      `json:"append_system_prompt_file,omitempty"` // Read on every query, so edits apply to the next one
+	McpTools                 []string                    `json:"mcp_tools,omitempty"`
+	McpServers               map[string]McpServerConfig  `json:"mcp_servers,omitempty"`
+	SdkMcpServers            map[string]*SdkMcpServer    `json:"-"` // In-process MCP servers, keyed by the name tools are exposed under
+	PermissionMode           *PermissionMode             `json:"permission_mode,omitempty"`
+	ContinueConversation     bool                        `json:"continue_conversation,omitempty"`
+	Resume                   string                      `json:"resume,omitempty"`
+	ResumeFrom               string                      `json:"resume_from,omitempty"`  // Transcript file replayed into a new session, see LoadTranscript
+	ForkSession              bool                        `json:"fork_session,omitempty"` // Continue Resume or ContinueConversation in a new session, leaving the original unchanged
+	MaxTurns                 *int                        `json:"max_turns,omitempty"`
+	DisallowedTools          []string                    `json:"disallowed_tools,omitempty"`
+	Model                    string                      `json:"model,omitempty"`
+	PermissionPromptToolName string                      `json:"permission_prompt_tool_name,omitempty"`
+	CanUseTool               CanUseToolFunc              `json:"-"`                             // Runtime permission callback; answers the CLI's permission prompts
+	Hooks                    map[HookEvent][]HookMatcher `json:"-"`                             // Go callbacks run by the CLI's hook system
+	CallbackTimeout          int                         `json:"callback_timeout,omitempty"`    // Seconds a callback may run before its call is failed; 0 waits indefinitely
+	RequireCLIVersion        string                      `json:"require_cli_version,omitempty"` // Version constraint checked at Connect, e.g. ">=1.0.50, <2"
+	Cwd                      string                      `json:"cwd,omitempty"`
+	AddDirs                  []string                    `json:"add_dirs,omitempty"`        // Directories outside Cwd that tools may read and write
+	SettingSources           []SettingSource             `json:"setting_sources,omitempty"` // nil keeps the CLI default, empty loads none
+	Settings                 string                      `json:"settings,omitempty"`        // Settings file path or inline JSON object loaded on top of SettingSources
+	ContextDocuments         []ContextDocument           `json:"context_documents,omitempty"`
+	PromptInput              PromptInput                 `json:"prompt_input,omitempty"` // How one-shot prompts reach the CLI; empty uses argv unless the prompt exceeds 64 KiB
+	MessageBufferSize        int                         `json:"message_buffer_size,omitempty"`
+	ErrorBufferSize          int                         `json:"error_buffer_size,omitempty"`
+	InlineErrors             bool                        `json:"inline_errors,omitempty"`            // Deliver errors as ErrorMessage on the message channel
+	QueryTimeout             int                         `json:"query_timeout,omitempty"`            // Timeout in seconds for the entire query
+	ReadOnly                 bool                        `json:"read_only,omitempty"`                // Plan mode, read-only tools only, mutating tools denied before they run, and queries stopped on any mutating tool use
+	TurnLimit                int                         `json:"turn_limit,omitempty"`               // SDK-side cap on turns (distinct assistant message IDs), enforced independently of MaxTurns
+	MaxCostUSD               float64                     `json:"max_cost_usd,omitempty"`             // SDK-side spending limit checked against the cost each result reports
+	StallTimeout             int                         `json:"stall_timeout,omitempty"`            // Seconds without CLI output before it is interrupted, then killed
+	StartupTimeout           int                         `json:"startup_timeout,omitempty"`          // Seconds from spawning the CLI (or a session's first prompt) to its init message before it is killed
+	DisconnectTimeout        int                         `json:"disconnect_timeout,omitempty"`       // Seconds to wait for the CLI to exit after interrupting it before killing it; 0 means 5
+	Retry                    *RetryPolicy                `json:"retry,omitempty"`                    // Retries transient CLI failures and rate-limited results with backoff
+	IncludePartialMessages   bool                        `json:"include_partial_messages,omitempty"` // Deliver StreamEvent messages while a reply is generated
+	Logger                   *slog.Logger                `json:"-"`                                  // Logs CLI lifecycle, redacted args and, at debug level, raw JSON lines
+	Clock                    Clock                       `json:"-"`                                  // Time source for timeouts, backoff and usage timestamps; nil uses the system clock
+	Locale                   string                      `json:"locale,omitempty"`                   // LANG and LC_ALL for the CLI, e.g. "en_US.UTF-8"; empty inherits
+	Timezone                 string                      `json:"timezone,omitempty"`                 // TZ for the CLI, e.g. "UTC"; empty inherits
+	HTTPProxy                string                      `json:"http_proxy,omitempty"`               // HTTP_PROXY for the CLI, e.g. "http://proxy.corp:3128"
+	HTTPSProxy               string                      `json:"https_proxy,omitempty"`              // HTTPS_PROXY for the CLI
+	NoProxy                  string                      `json:"no_proxy,omitempty"`                 // NO_PROXY for the CLI, comma-separated hosts that bypass the proxy
+	Env                      map[string]string           `json:"env,omitempty"`                      // Variables for the CLI; override filtered, inherited and option-derived values
+	InheritEnv               bool                        `json:"inherit_env,omitempty"`              // Pass the whole parent environment instead of filtering it
+
+	outputFormat string         // CLI output format override used by QueryResult
+	capture      *CaptureBundle // Diagnostics capture used by CaptureBundle.Query
+	profileErr   error          // Why Profile could not provide these options
 }
 
 // NewOptions creates a new Options instance with default values
@@ -135,6 +351,9 @@ func (o *Options) BuildCLIArgs() ([]string, error) {
 	if o == nil {
 		return []string{}, nil
 	}
+	if o.profileErr != nil {
+		return nil, o.profileErr
+	}
 
 	args := []string{}
 
@@ -171,18 +390,95 @@ func (o *Options) BuildCLIArgs() ([]string, error) {
 	return args, nil
 }
 
+// Validate runs every check BuildCLIArgs performs, plus a check that Cwd is
+// an existing directory, and reports all failures at once so invalid
+// configurations can be rejected at startup rather than on the first query.
+// It returns nil if the options are valid and an Errors value otherwise.
+func (o *Options) Validate() error {
+	if o == nil {
+		return nil
+	}
+
+	if o.profileErr != nil {
+		return o.profileErr
+	}
+
+	var errs Errors
+	for _, add := range []func(*[]string) error{
+		o.addPromptArgs,
+		o.addToolArgs,
+		o.addConfigArgs,
+		o.addPermissionArgs,
+		o.addSessionArgs,
+		o.addMCPArgs,
+	} {
+		var args []string
+		if err := add(&args); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if _, err := o.GetEnv(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if o.MaxCostUSD < 0 {
+		errs = append(errs, fmt.Errorf("max cost must not be negative, got %v", o.MaxCostUSD))
+	}
+
+	if err := o.Retry.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch o.PromptInput {
+	case "", PromptInputArgv, PromptInputStdin, PromptInputStreamJSON:
+	default:
+		errs = append(errs, fmt.Errorf("invalid prompt input mode %q", o.PromptInput))
+	}
+
+	if o.RequireCLIVersion != "" {
+		if err := validation.ValidateVersionConstraint(o.RequireCLIVersion); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if o.Cwd != "" {
+		if dir, err := validation.ValidateWorkingDirectory(o.Cwd); err != nil {
+			errs = append(errs, fmt.Errorf("invalid working directory: %w", err))
+		} else if info, err := os.Stat(dir); err != nil {
+			errs = append(errs, fmt.Errorf("invalid working directory: %w", err))
+		} else if !info.IsDir() {
+			errs = append(errs, fmt.Errorf("invalid working directory: %s is not a directory", o.Cwd))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
 // addPromptArgs adds system prompt related arguments
 func (o *Options) addPromptArgs(args *[]string) error {
-	if o.SystemPrompt != "" {
-		sanitized, err := validation.SanitizeString(o.SystemPrompt, validation.MaxStringLength)
+	systemPrompt, err := resolvePrompt(o.SystemPrompt, o.SystemPromptFile, "system prompt")
+	if err != nil {
+		return err
+	}
+	if systemPrompt != "" {
+		sanitized, err := validation.SanitizeString(systemPrompt, validation.MaxStringLength)
 		if err != nil {
 			return fmt.Errorf("invalid system prompt: %w", err)
 		}
 		*args = append(*args, "--system-prompt", sanitized)
 	}
 
-	if o.AppendSystemPrompt != "" {
-		sanitized, err := validation.SanitizeString(o.AppendSystemPrompt, validation.MaxStringLength)
+	appendSystemPrompt, err := resolvePrompt(o.AppendSystemPrompt, o.AppendSystemPromptFile, "append system prompt")
+	if err != nil {
+		return err
+	}
+	appendSystemPrompt, err = appendContextDocuments(appendSystemPrompt, o.ContextDocuments)
+	if err != nil {
+		return err
+	}
+	if appendSystemPrompt != "" {
+		sanitized, err := validation.SanitizeString(appendSystemPrompt, validation.MaxStringLength)
 		if err != nil {
 			return fmt.Errorf("invalid append system prompt: %w", err)
 		}
@@ -192,11 +488,115 @@ func (o *Options) addPromptArgs(args *[]string) error {
 	return nil
 }
 
+// resolvePrompt returns the inline prompt or, when a file is configured, its
+// current contents. Setting both is rejected as ambiguous.
+func resolvePrompt(inline, file, name string) (string, error) {
+	if file == "" {
+		return inline, nil
+	}
+	if inline != "" {
+		return "", fmt.Errorf("%s and %s file are mutually exclusive", name, name)
+	}
+
+	path, err := validation.ValidatePath(file)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s file: %w", name, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s file: %w", name, err)
+	}
+	if info.Size() > validation.MaxStringLength {
+		return "", fmt.Errorf("%s file exceeds maximum length of %d characters", name, validation.MaxStringLength)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s file: %w", name, err)
+	}
+	return string(content), nil
+}
+
+// resolveSettings checks that settings is a JSON object, inline or in a
+// file, and returns the --settings value: the inline JSON or the file path
+func resolveSettings(settings string) (string, error) {
+	data := []byte(settings)
+	if !strings.HasPrefix(strings.TrimSpace(settings), "{") {
+		path, err := validation.ValidatePath(settings)
+		if err != nil {
+			return "", fmt.Errorf("invalid settings file: %w", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("invalid settings file: %w", err)
+		}
+		if info.IsDir() {
+			return "", fmt.Errorf("invalid settings file: %s is a directory", settings)
+		}
+		if info.Size() > validation.MaxJSONSize {
+			return "", fmt.Errorf("settings file exceeds maximum size of %d bytes", validation.MaxJSONSize)
+		}
+		if data, err = os.ReadFile(path); err != nil {
+			return "", fmt.Errorf("failed to read settings file: %w", err)
+		}
+		settings = path
+	} else if len(data) > validation.MaxJSONSize {
+		return "", fmt.Errorf("settings exceed maximum size of %d bytes", validation.MaxJSONSize)
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil || object == nil {
+		return "", fmt.Errorf("settings must be a JSON object")
+	}
+	return settings, nil
+}
+
+// appendContextDocuments renders context documents after the append system
+// prompt, each wrapped in a named <context> element
+func appendContextDocuments(prompt string, docs []ContextDocument) (string, error) {
+	if len(docs) == 0 {
+		return prompt, nil
+	}
+
+	var b strings.Builder
+	b.WriteString(prompt)
+	for _, doc := range docs {
+		name, err := validation.SanitizeString(doc.Name, 200)
+		if err != nil {
+			return "", fmt.Errorf("invalid context document name: %w", err)
+		}
+		if name == "" {
+			return "", fmt.Errorf("context document name cannot be empty")
+		}
+		if strings.ContainsAny(name, "\"<>") {
+			return "", fmt.Errorf("invalid context document name %q", name)
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "<context name=%q>\n%s\n</context>", name, strings.TrimSpace(doc.Content))
+	}
+	return b.String(), nil
+}
+
 // addToolArgs adds tool-related arguments
 func (o *Options) addToolArgs(args *[]string) error {
+	allowed, disallowed := o.AllowedTools, o.DisallowedTools
+	if o.ReadOnly {
+		// Read-only mode narrows the allowlist and always denies mutating tools
+		for _, tool := range allowed {
+			if !containsTool(readOnlyModeTools, tool) {
+				return fmt.Errorf("allowed tool %q is not permitted in read-only mode", tool)
+			}
+		}
+		if len(allowed) == 0 {
+			allowed = readOnlyModeTools
+		}
+		disallowed = append(append([]string(nil), disallowed...), mutatingTools...)
+	}
+
 	// Allowed tools
-	if len(o.AllowedTools) > 0 {
-		tools, err := o.validateToolList(o.AllowedTools, "allowed")
+	if len(allowed) > 0 {
+		tools, err := o.validateToolList(allowed, "allowed")
 		if err != nil {
 			return err
 		}
@@ -204,8 +604,8 @@ func (o *Options) addToolArgs(args *[]string) error {
 	}
 
 	// Disallowed tools
-	if len(o.DisallowedTools) > 0 {
-		tools, err := o.validateToolList(o.DisallowedTools, "disallowed")
+	if len(disallowed) > 0 {
+		tools, err := o.validateToolList(disallowed, "disallowed")
 		if err != nil {
 			return err
 		}
@@ -217,8 +617,14 @@ func (o *Options) addToolArgs(args *[]string) error {
 
 // addPermissionArgs adds permission-related arguments
 func (o *Options) addPermissionArgs(args *[]string) error {
-	// Permission prompt tool
-	if o.PermissionPromptToolName != "" {
+	// Permission prompt tool. A CanUseTool callback answers the prompts
+	// itself over the control protocol.
+	if o.CanUseTool != nil {
+		if o.PermissionPromptToolName != "" {
+			return fmt.Errorf("permission prompt tool cannot be combined with a CanUseTool callback")
+		}
+		*args = append(*args, "--permission-prompt-tool", "stdio")
+	} else if o.PermissionPromptToolName != "" {
 		sanitized, err := validation.SanitizeCommandArg(o.PermissionPromptToolName)
 		if err != nil {
 			return fmt.Errorf("invalid permission prompt tool name: %w", err)
@@ -227,9 +633,17 @@ func (o *Options) addPermissionArgs(args *[]string) error {
 	}
 
 	// Permission mode
-	if o.PermissionMode != nil {
-		mode := string(*o.PermissionMode)
-		if mode != "default" && mode != "acceptEdits" && mode != "bypassPermissions" {
+	permissionMode := o.PermissionMode
+	if o.ReadOnly {
+		if permissionMode != nil && *permissionMode != PermissionModePlan {
+			return fmt.Errorf("permission mode %s conflicts with read-only mode", *permissionMode)
+		}
+		mode := PermissionModePlan
+		permissionMode = &mode
+	}
+	if permissionMode != nil {
+		mode := string(*permissionMode)
+		if mode != "default" && mode != "acceptEdits" && mode != "bypassPermissions" && mode != "plan" {
 			return fmt.Errorf("invalid permission mode: %s", mode)
 		}
 		*args = append(*args, "--permission-mode", mode)
@@ -256,6 +670,50 @@ func (o *Options) addConfigArgs(args *[]string) error {
 		*args = append(*args, "--model", o.Model)
 	}
 
+	// Setting sources (nil leaves the CLI default in place)
+	if o.SettingSources != nil {
+		sources := make([]string, 0, len(o.SettingSources))
+		for _, source := range o.SettingSources {
+			switch source {
+			case SettingSourceUser, SettingSourceProject, SettingSourceLocal:
+				sources = append(sources, string(source))
+			default:
+				return fmt.Errorf("invalid setting source: %s", source)
+			}
+		}
+		*args = append(*args, "--setting-sources", strings.Join(sources, ","))
+	}
+
+	// Additional settings file or inline JSON
+	if o.Settings != "" {
+		settings, err := resolveSettings(o.Settings)
+		if err != nil {
+			return err
+		}
+		*args = append(*args, "--settings", settings)
+	}
+
+	// Additional workspace directories
+	for _, dir := range o.AddDirs {
+		path, err := validation.ValidatePath(dir)
+		if err != nil {
+			return fmt.Errorf("invalid additional directory: %w", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("invalid additional directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("invalid additional directory: %s is not a directory", dir)
+		}
+		*args = append(*args, "--add-dir", path)
+	}
+
+	// Partial message streaming
+	if o.IncludePartialMessages {
+		*args = append(*args, "--include-partial-messages")
+	}
+
 	// Max thinking tokens
 	if o.MaxThinkingTokens != 8000 {
 		if o.MaxThinkingTokens < 0 || o.MaxThinkingTokens > 100000 {
@@ -281,6 +739,26 @@ func (o *Options) addSessionArgs(args *[]string) error {
 		*args = append(*args, "--resume", sanitized)
 	}
 
+	if o.ForkSession {
+		if o.Resume == "" && !o.ContinueConversation {
+			return fmt.Errorf("ForkSession requires Resume or ContinueConversation")
+		}
+		*args = append(*args, "--fork-session")
+	}
+
+	// A transcript is replayed over stdin rather than passed as a flag
+	if o.ResumeFrom != "" {
+		if o.Resume != "" || o.ContinueConversation {
+			return fmt.Errorf("ResumeFrom cannot be combined with Resume or ContinueConversation")
+		}
+		if _, err := validation.ValidatePath(o.ResumeFrom); err != nil {
+			return fmt.Errorf("invalid transcript path: %w", err)
+		}
+		if _, err := os.Stat(o.ResumeFrom); err != nil {
+			return fmt.Errorf("invalid transcript path: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -295,10 +773,24 @@ func (o *Options) addMCPArgs(args *[]string) error {
 		*args = append(*args, "--mcp-tools", strings.Join(tools, ","))
 	}
 
-	// MCP servers
-	if len(o.McpServers) > 0 {
+	// MCP servers. In-process servers are only named in the config; the CLI
+	// reaches them over the control protocol.
+	if len(o.McpServers) > 0 || len(o.SdkMcpServers) > 0 {
+		servers := make(map[string]interface{}, len(o.McpServers)+len(o.SdkMcpServers))
+		for name, server := range o.McpServers {
+			if err := server.validate(name); err != nil {
+				return err
+			}
+			servers[name] = server
+		}
+		for name := range o.SdkMcpServers {
+			if _, dup := servers[name]; dup {
+				return fmt.Errorf("MCP server %q is configured both in McpServers and SdkMcpServers", name)
+			}
+			servers[name] = map[string]interface{}{"type": "sdk", "name": name}
+		}
 		mcpConfig := map[string]interface{}{
-			"mcpServers": o.McpServers,
+			"mcpServers": servers,
 		}
 		configJSON, err := json.Marshal(mcpConfig)
 		if err != nil {
@@ -360,13 +852,181 @@ func (o *Options) GetQueryTimeout() time.Duration {
 	return time.Duration(o.QueryTimeout) * time.Second
 }
 
+// GetCallbackTimeout returns how long a CanUseTool or hook callback may run.
+// Returns 0 if callbacks are not timed out.
+func (o *Options) GetCallbackTimeout() time.Duration {
+	if o == nil || o.CallbackTimeout <= 0 {
+		return 0
+	}
+	return time.Duration(o.CallbackTimeout) * time.Second
+}
+
+// GetRequiredCLIVersion returns the version constraint the CLI must satisfy.
+// Returns "" if any version is accepted.
+func (o *Options) GetRequiredCLIVersion() string {
+	if o == nil {
+		return ""
+	}
+	return o.RequireCLIVersion
+}
+
+// GetLogger returns the logger for transport and client activity.
+// Returns nil if logging is disabled.
+func (o *Options) GetLogger() *slog.Logger {
+	if o == nil {
+		return nil
+	}
+	return o.Logger
+}
+
+// GetClock returns the clock measuring timeouts and backoff, SystemClock
+// unless Options.Clock is set
+func (o *Options) GetClock() Clock {
+	if o == nil {
+		return SystemClock
+	}
+	return clock.Or(o.Clock)
+}
+
+// GetPromptInput returns how one-shot prompts reach the CLI, or "" to pass
+// them as an argument unless they are too long for one
+func (o *Options) GetPromptInput() string {
+	if o == nil {
+		return ""
+	}
+	return string(o.PromptInput)
+}
+
+// GetOutputFormat returns the CLI output format to request.
+// Returns "" for the default streaming format.
+func (o *Options) GetOutputFormat() string {
+	if o == nil {
+		return ""
+	}
+	return o.outputFormat
+}
+
+// GetStallTimeout returns how long the CLI may stay silent before it is
+// interrupted, and after another such period killed.
+// Returns 0 if stall detection is disabled.
+func (o *Options) GetStallTimeout() time.Duration {
+	if o == nil || o.StallTimeout <= 0 {
+		return 0
+	}
+	return time.Duration(o.StallTimeout) * time.Second
+}
+
+// GetStartupTimeout returns how long the CLI may take to report its init
+// message. Returns 0 if the startup check is disabled.
+func (o *Options) GetStartupTimeout() time.Duration {
+	if o == nil || o.StartupTimeout <= 0 {
+		return 0
+	}
+	return time.Duration(o.StartupTimeout) * time.Second
+}
+
+// GetDisconnectTimeout returns how long Disconnect waits for the CLI to exit
+// after interrupting it before killing it. Returns 0 for the transport's
+// default.
+func (o *Options) GetDisconnectTimeout() time.Duration {
+	if o == nil || o.DisconnectTimeout <= 0 {
+		return 0
+	}
+	return time.Duration(o.DisconnectTimeout) * time.Second
+}
+
+// GetEnv returns the variables set for the CLI on top of the filtered parent
+// environment, as KEY=value pairs. They replace inherited values, and
+// variables in Env replace those derived from other options.
+func (o *Options) GetEnv() ([]string, error) {
+	if o == nil {
+		return nil, nil
+	}
+
+	var env []string
+	if o.Locale != "" {
+		if err := validation.ValidateLocale(o.Locale); err != nil {
+			return nil, err
+		}
+		env = append(env, "LANG="+o.Locale, "LC_ALL="+o.Locale)
+	}
+	if o.Timezone != "" {
+		if err := validation.ValidateTimezone(o.Timezone); err != nil {
+			return nil, err
+		}
+		env = append(env, "TZ="+o.Timezone)
+	}
+
+	// Proxy variables are set in both cases since tools disagree on which
+	// they read
+	for _, proxy := range []struct{ name, value string }{
+		{"HTTP_PROXY", o.HTTPProxy},
+		{"HTTPS_PROXY", o.HTTPSProxy},
+	} {
+		if proxy.value == "" {
+			continue
+		}
+		if err := validation.ValidateProxyURL(proxy.value); err != nil {
+			return nil, err
+		}
+		env = append(env, proxy.name+"="+proxy.value, strings.ToLower(proxy.name)+"="+proxy.value)
+	}
+	if o.NoProxy != "" {
+		if err := validation.ValidateNoProxy(o.NoProxy); err != nil {
+			return nil, err
+		}
+		env = append(env, "NO_PROXY="+o.NoProxy, "no_proxy="+o.NoProxy)
+	}
+
+	if len(o.Env) > 0 {
+		keys := make([]string, 0, len(o.Env))
+		for key, value := range o.Env {
+			if err := validation.ValidateEnvVar(key, value); err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		derived := env
+		env = make([]string, 0, len(derived)+len(keys))
+		for _, e := range derived {
+			if _, ok := o.Env[strings.SplitN(e, "=", 2)[0]]; !ok {
+				env = append(env, e)
+			}
+		}
+		for _, key := range keys {
+			env = append(env, key+"="+o.Env[key])
+		}
+	}
+	return env, nil
+}
+
+// GetInheritEnv reports whether the CLI gets the whole parent environment
+// rather than the filtered one
+func (o *Options) GetInheritEnv() bool {
+	return o != nil && o.InheritEnv
+}
+
+// GetStreamCapture returns the hooks that copy the CLI command line, with
+// credential flags masked, and raw output into a CaptureBundle. All are nil unless the query runs through
+// CaptureBundle.Query.
+func (o *Options) GetStreamCapture() (onStart func(args, env []string, dir string), stdout, stderr io.Writer) {
+	if o == nil || o.capture == nil {
+		return nil, nil, nil
+	}
+	return o.capture.captureStart, &o.capture.stdout, &o.capture.stderr
+}
+
 // Custom JSON marshaling/unmarshaling for ContentBlock to handle polymorphism
 
 type contentBlockJSON struct {
 	Type string `json:"type"`
 	*TextBlock
+	*ThinkingBlock
 	*ToolUseBlock
 	*ToolResultBlock
+	*ImageBlock
 }
 
 func (cb *contentBlockJSON) UnmarshalJSON(data []byte) error {
@@ -387,6 +1047,15 @@ func (cb *contentBlockJSON) UnmarshalJSON(data []byte) error {
 		if text, ok := raw["text"].(string); ok {
 			cb.TextBlock.Text = text
 		}
+	case "thinking":
+		cb.Type = "thinking"
+		cb.ThinkingBlock = &ThinkingBlock{}
+		if thinking, ok := raw["thinking"].(string); ok {
+			cb.ThinkingBlock.Thinking = thinking
+		}
+		if signature, ok := raw["signature"].(string); ok {
+			cb.ThinkingBlock.Signature = signature
+		}
 	case "tool_use":
 		cb.Type = "tool_use"
 		cb.ToolUseBlock = &ToolUseBlock{}
@@ -411,6 +1080,18 @@ func (cb *contentBlockJSON) UnmarshalJSON(data []byte) error {
 		if isError, ok := raw["is_error"].(bool); ok {
 			cb.ToolResultBlock.IsError = &isError
 		}
+	case "image":
+		cb.Type = "image"
+		cb.ImageBlock = &ImageBlock{}
+		if mediaType, ok := raw["media_type"].(string); ok {
+			cb.ImageBlock.MediaType = mediaType
+		}
+		if data, ok := raw["data"].(string); ok {
+			cb.ImageBlock.Data = data
+		}
+		if path, ok := raw["path"].(string); ok {
+			cb.ImageBlock.Path = path
+		}
 	}
 
 	return nil
@@ -426,6 +1107,14 @@ func (cb contentBlockJSON) MarshalJSON() ([]byte, error) {
 			Type:      "text",
 			TextBlock: cb.TextBlock,
 		})
+	case "thinking":
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			*ThinkingBlock
+		}{
+			Type:          "thinking",
+			ThinkingBlock: cb.ThinkingBlock,
+		})
 	case "tool_use":
 		return json.Marshal(struct {
 			Type string `json:"type"`
@@ -442,19 +1131,90 @@ func (cb contentBlockJSON) MarshalJSON() ([]byte, error) {
 			Type:            "tool_result",
 			ToolResultBlock: cb.ToolResultBlock,
 		})
+	case "image":
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			*ImageBlock
+		}{
+			Type:       "image",
+			ImageBlock: cb.ImageBlock,
+		})
 	}
 	return nil, nil
 }
 
 // MarshalJSON for AssistantMessage to handle ContentBlock polymorphism
 func (am AssistantMessage) MarshalJSON() ([]byte, error) {
-	temp := struct {
-		Content []json.RawMessage `json:"content"`
+	content, err := marshalContentBlocks(am.Content)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Content []json.RawMessage      `json:"content"`
+		ID      string                 `json:"id,omitempty"`
+		Usage   map[string]interface{} `json:"usage,omitempty"`
+	}{
+		Content: content,
+		ID:      am.ID,
+		Usage:   am.Usage,
+	})
+}
+
+// UnmarshalJSON for AssistantMessage to handle ContentBlock polymorphism
+func (am *AssistantMessage) UnmarshalJSON(data []byte) error {
+	var temp struct {
+		Content []contentBlockJSON     `json:"content"`
+		ID      string                 `json:"id"`
+		Usage   map[string]interface{} `json:"usage"`
+	}
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return err
+	}
+	am.ID = temp.ID
+	am.Usage = temp.Usage
+	am.Content = unmarshalContentBlocks(temp.Content)
+	return nil
+}
+
+// MarshalJSON for UserMessage to handle ContentBlock polymorphism
+func (um UserMessage) MarshalJSON() ([]byte, error) {
+	var blocks []json.RawMessage
+	if len(um.Blocks) > 0 {
+		var err error
+		if blocks, err = marshalContentBlocks(um.Blocks); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(struct {
+		Content string            `json:"content"`
+		Blocks  []json.RawMessage `json:"blocks,omitempty"`
 	}{
-		Content: make([]json.RawMessage, 0, len(am.Content)),
+		Content: um.Content,
+		Blocks:  blocks,
+	})
+}
+
+// UnmarshalJSON for UserMessage to handle ContentBlock polymorphism
+func (um *UserMessage) UnmarshalJSON(data []byte) error {
+	var temp struct {
+		Content string             `json:"content"`
+		Blocks  []contentBlockJSON `json:"blocks"`
+	}
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return err
 	}
+	um.Content = temp.Content
+	um.Blocks = nil
+	if len(temp.Blocks) > 0 {
+		um.Blocks = unmarshalContentBlocks(temp.Blocks)
+	}
+	return nil
+}
 
-	for _, block := range am.Content {
+// marshalContentBlocks encodes blocks with their type tags
+func marshalContentBlocks(blocks []ContentBlock) ([]json.RawMessage, error) {
+	encoded := make([]json.RawMessage, 0, len(blocks))
+	for _, block := range blocks {
 		var data []byte
 		var err error
 
@@ -467,6 +1227,14 @@ func (am AssistantMessage) MarshalJSON() ([]byte, error) {
 				Type:      "text",
 				TextBlock: b,
 			})
+		case ThinkingBlock:
+			data, err = json.Marshal(struct {
+				Type string `json:"type"`
+				ThinkingBlock
+			}{
+				Type:          "thinking",
+				ThinkingBlock: b,
+			})
 		case ToolUseBlock:
 			data, err = json.Marshal(struct {
 				Type string `json:"type"`
@@ -483,6 +1251,14 @@ func (am AssistantMessage) MarshalJSON() ([]byte, error) {
 				Type:            "tool_result",
 				ToolResultBlock: b,
 			})
+		case ImageBlock:
+			data, err = json.Marshal(struct {
+				Type string `json:"type"`
+				ImageBlock
+			}{
+				Type:       "image",
+				ImageBlock: b,
+			})
 		default:
 			continue
 		}
@@ -490,32 +1266,29 @@ func (am AssistantMessage) MarshalJSON() ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
-		temp.Content = append(temp.Content, data)
+		encoded = append(encoded, data)
 	}
 
-	return json.Marshal(temp)
+	return encoded, nil
 }
 
-// UnmarshalJSON for AssistantMessage to handle ContentBlock polymorphism
-func (am *AssistantMessage) UnmarshalJSON(data []byte) error {
-	var temp struct {
-		Content []contentBlockJSON `json:"content"`
-	}
-	if err := json.Unmarshal(data, &temp); err != nil {
-		return err
-	}
-
-	am.Content = make([]ContentBlock, 0, len(temp.Content))
-	for _, cb := range temp.Content {
+// unmarshalContentBlocks converts decoded blocks to ContentBlock values,
+// skipping unknown types
+func unmarshalContentBlocks(decoded []contentBlockJSON) []ContentBlock {
+	blocks := make([]ContentBlock, 0, len(decoded))
+	for _, cb := range decoded {
 		switch cb.Type {
 		case "text":
-			am.Content = append(am.Content, *cb.TextBlock)
+			blocks = append(blocks, *cb.TextBlock)
+		case "thinking":
+			blocks = append(blocks, *cb.ThinkingBlock)
 		case "tool_use":
-			am.Content = append(am.Content, *cb.ToolUseBlock)
+			blocks = append(blocks, *cb.ToolUseBlock)
 		case "tool_result":
-			am.Content = append(am.Content, *cb.ToolResultBlock)
+			blocks = append(blocks, *cb.ToolResultBlock)
+		case "image":
+			blocks = append(blocks, *cb.ImageBlock)
 		}
 	}
-
-	return nil
+	return blocks
 }
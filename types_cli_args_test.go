@@ -1,6 +1,10 @@
 package claudecode
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -45,6 +49,40 @@ func TestBuildCLIArgs_AllOptions(t *testing.T) {
 			},
 			expected: []string{"--allowedTools", "Read,Write,Edit"},
 		},
+		{
+			name: "include partial messages",
+			options: &Options{
+				IncludePartialMessages: true,
+				MaxThinkingTokens:      8000,
+			},
+			expected: []string{"--include-partial-messages"},
+		},
+		{
+			name: "read only",
+			options: &Options{
+				ReadOnly:          true,
+				MaxThinkingTokens: 8000,
+			},
+			expected: []string{
+				"--allowedTools", "ExitPlanMode,TodoWrite,Read,Glob,Grep,LS,NotebookRead,WebFetch,WebSearch,TodoRead",
+				"--disallowedTools", "Bash,KillShell,Task,Edit,MultiEdit,Write,NotebookEdit",
+				"--permission-mode", "plan",
+			},
+		},
+		{
+			name: "read only keeps narrower allowlist",
+			options: &Options{
+				ReadOnly:          true,
+				AllowedTools:      []string{"Read", "Grep"},
+				DisallowedTools:   []string{"WebFetch"},
+				MaxThinkingTokens: 8000,
+			},
+			expected: []string{
+				"--allowedTools", "Read,Grep",
+				"--disallowedTools", "WebFetch,Bash,KillShell,Task,Edit,MultiEdit,Write,NotebookEdit",
+				"--permission-mode", "plan",
+			},
+		},
 		{
 			name: "max turns",
 			options: &Options{
@@ -343,6 +381,71 @@ func TestBuildCLIArgs_ValidationErrors(t *testing.T) {
 			},
 			expectedErr: "shell metacharacters",
 		},
+		{
+			name: "permission prompt tool with callback",
+			options: &Options{
+				PermissionPromptToolName: "mcp__auth__prompt",
+				CanUseTool: func(context.Context, string, map[string]interface{}) PermissionDecision {
+					return AllowTool()
+				},
+				MaxThinkingTokens: 8000,
+			},
+			expectedErr: "cannot be combined with a CanUseTool callback",
+		},
+		{
+			name: "read only with mutating allowed tool",
+			options: &Options{
+				ReadOnly:          true,
+				AllowedTools:      []string{"Read", "Bash"},
+				MaxThinkingTokens: 8000,
+			},
+			expectedErr: "not permitted in read-only mode",
+		},
+		{
+			name: "read only with conflicting permission mode",
+			options: &Options{
+				ReadOnly:          true,
+				PermissionMode:    permissionModePtr(PermissionModeAcceptEdits),
+				MaxThinkingTokens: 8000,
+			},
+			expectedErr: "conflicts with read-only mode",
+		},
+		{
+			name: "mcp server without transport",
+			options: &Options{
+				McpServers:        map[string]McpServerConfig{"srv": {}},
+				MaxThinkingTokens: 8000,
+			},
+			expectedErr: "stdio servers need a transport command",
+		},
+		{
+			name: "sse mcp server with invalid url",
+			options: &Options{
+				McpServers:        map[string]McpServerConfig{"srv": McpSSEServer("localhost:8080", nil)},
+				MaxThinkingTokens: 8000,
+			},
+			expectedErr: "invalid URL",
+		},
+		{
+			name: "http mcp server with transport",
+			options: &Options{
+				McpServers: map[string]McpServerConfig{"srv": {
+					Type:      McpServerTypeHTTP,
+					URL:       "https://example.com/mcp",
+					Transport: []string{"stdio"},
+				}},
+				MaxThinkingTokens: 8000,
+			},
+			expectedErr: "take a URL, not a transport command",
+		},
+		{
+			name: "unknown mcp server type",
+			options: &Options{
+				McpServers:        map[string]McpServerConfig{"srv": {Type: "websocket", URL: "wss://example.com"}},
+				MaxThinkingTokens: 8000,
+			},
+			expectedErr: "unknown type",
+		},
 	}
 
 	for _, tt := range tests {
@@ -359,6 +462,235 @@ func TestBuildCLIArgs_ValidationErrors(t *testing.T) {
 	}
 }
 
+func TestBuildCLIArgs_PromptFiles(t *testing.T) {
+	dir := t.TempDir()
+	systemFile := filepath.Join(dir, "system.md")
+	appendFile := filepath.Join(dir, "append.md")
+	if err := os.WriteFile(systemFile, []byte("You are a reviewer\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(appendFile, []byte("Be concise"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("reads prompt files", func(t *testing.T) {
+		options := &Options{
+			SystemPromptFile:       systemFile,
+			AppendSystemPromptFile: appendFile,
+			MaxThinkingTokens:      8000,
+		}
+		result, err := options.BuildCLIArgs()
+		if err != nil {
+			t.Fatalf("BuildCLIArgs() returned error: %v", err)
+		}
+		expected := []string{"--system-prompt", "You are a reviewer", "--append-system-prompt", "Be concise"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("BuildCLIArgs() = %v, want %v", result, expected)
+		}
+	})
+
+	t.Run("picks up changes on the next build", func(t *testing.T) {
+		file := filepath.Join(dir, "reload.md")
+		if err := os.WriteFile(file, []byte("first"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		options := &Options{SystemPromptFile: file, MaxThinkingTokens: 8000}
+		if _, err := options.BuildCLIArgs(); err != nil {
+			t.Fatalf("BuildCLIArgs() returned error: %v", err)
+		}
+		if err := os.WriteFile(file, []byte("second"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		result, err := options.BuildCLIArgs()
+		if err != nil {
+			t.Fatalf("BuildCLIArgs() returned error: %v", err)
+		}
+		if result[1] != "second" {
+			t.Errorf("Expected reloaded prompt 'second', got %q", result[1])
+		}
+	})
+
+	t.Run("inline and file are mutually exclusive", func(t *testing.T) {
+		options := &Options{SystemPrompt: "inline", SystemPromptFile: systemFile, MaxThinkingTokens: 8000}
+		_, err := options.BuildCLIArgs()
+		if err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+			t.Errorf("Expected mutually exclusive error, got %v", err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		options := &Options{AppendSystemPromptFile: filepath.Join(dir, "missing.md"), MaxThinkingTokens: 8000}
+		_, err := options.BuildCLIArgs()
+		if err == nil || !strings.Contains(err.Error(), "failed to read append system prompt file") {
+			t.Errorf("Expected read error, got %v", err)
+		}
+	})
+
+	t.Run("file too large", func(t *testing.T) {
+		file := filepath.Join(dir, "large.md")
+		if err := os.WriteFile(file, []byte(strings.Repeat("x", 10001)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		options := &Options{SystemPromptFile: file, MaxThinkingTokens: 8000}
+		_, err := options.BuildCLIArgs()
+		if err == nil || !strings.Contains(err.Error(), "exceeds maximum length") {
+			t.Errorf("Expected size error, got %v", err)
+		}
+	})
+}
+
+func TestBuildCLIArgs_ProjectContext(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  *Options
+		expected []string
+	}{
+		{
+			name:     "nil setting sources keep CLI default",
+			options:  &Options{MaxThinkingTokens: 8000},
+			expected: []string{},
+		},
+		{
+			name:     "empty setting sources run clean room",
+			options:  &Options{SettingSources: []SettingSource{}, MaxThinkingTokens: 8000},
+			expected: []string{"--setting-sources", ""},
+		},
+		{
+			name: "project aware",
+			options: &Options{
+				SettingSources:    []SettingSource{SettingSourceUser, SettingSourceProject},
+				MaxThinkingTokens: 8000,
+			},
+			expected: []string{"--setting-sources", "user,project"},
+		},
+		{
+			name: "context documents only",
+			options: &Options{
+				ContextDocuments:  []ContextDocument{{Name: "style", Content: "Use tabs.\n"}},
+				MaxThinkingTokens: 8000,
+			},
+			expected: []string{"--append-system-prompt", "<context name=\"style\">\nUse tabs.\n</context>"},
+		},
+		{
+			name: "context documents after append system prompt",
+			options: &Options{
+				AppendSystemPrompt: "Be brief",
+				ContextDocuments: []ContextDocument{
+					{Name: "a", Content: "first"},
+					{Name: "b", Content: "second"},
+				},
+				MaxThinkingTokens: 8000,
+			},
+			expected: []string{"--append-system-prompt", "Be brief\n\n<context name=\"a\">\nfirst\n</context>\n\n<context name=\"b\">\nsecond\n</context>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.options.BuildCLIArgs()
+			if err != nil {
+				t.Fatalf("BuildCLIArgs() returned error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("BuildCLIArgs() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+
+	t.Run("invalid setting source", func(t *testing.T) {
+		options := &Options{SettingSources: []SettingSource{"global"}, MaxThinkingTokens: 8000}
+		if _, err := options.BuildCLIArgs(); err == nil || !strings.Contains(err.Error(), "invalid setting source") {
+			t.Errorf("Expected invalid setting source error, got %v", err)
+		}
+	})
+
+	t.Run("invalid context document name", func(t *testing.T) {
+		options := &Options{ContextDocuments: []ContextDocument{{Name: `a"><b`, Content: "x"}}, MaxThinkingTokens: 8000}
+		if _, err := options.BuildCLIArgs(); err == nil || !strings.Contains(err.Error(), "invalid context document name") {
+			t.Errorf("Expected invalid name error, got %v", err)
+		}
+	})
+}
+
+func TestBuildCLIArgs_Settings(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "settings.json")
+	if err := os.WriteFile(file, []byte(`{"model":"sonnet","permissions":{"allow":["Bash(go test:*)"]}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	notObject := filepath.Join(dir, "list.json")
+	if err := os.WriteFile(notObject, []byte(`["a"]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, settings := range []string{file, `{"model": "opus"}`} {
+		options := &Options{Settings: settings, MaxThinkingTokens: 8000}
+		result, err := options.BuildCLIArgs()
+		if err != nil {
+			t.Fatalf("BuildCLIArgs() returned error: %v", err)
+		}
+		if want := []string{"--settings", settings}; !reflect.DeepEqual(result, want) {
+			t.Errorf("BuildCLIArgs() = %q, want %q", result, want)
+		}
+	}
+
+	for name, settings := range map[string]string{
+		"missing file":  filepath.Join(dir, "missing.json"),
+		"directory":     dir,
+		"not an object": notObject,
+		"invalid JSON":  `{"model":`,
+		"null":          "null",
+	} {
+		options := &Options{Settings: settings, MaxThinkingTokens: 8000}
+		if _, err := options.BuildCLIArgs(); err == nil || !strings.Contains(err.Error(), "settings") {
+			t.Errorf("%s: expected settings error, got %v", name, err)
+		}
+	}
+}
+
+func TestBuildCLIArgs_AddDirs(t *testing.T) {
+	api, web := t.TempDir(), t.TempDir()
+	options := &Options{AddDirs: []string{api, web + "/."}, MaxThinkingTokens: 8000}
+	result, err := options.BuildCLIArgs()
+	if err != nil {
+		t.Fatalf("BuildCLIArgs() returned error: %v", err)
+	}
+	if want := []string{"--add-dir", api, "--add-dir", web}; !reflect.DeepEqual(result, want) {
+		t.Errorf("BuildCLIArgs() = %q, want %q", result, want)
+	}
+
+	file := filepath.Join(api, "go.mod")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for name, dir := range map[string]string{
+		"missing": filepath.Join(api, "missing"),
+		"file":    file,
+		"empty":   "",
+	} {
+		options := &Options{AddDirs: []string{dir}, MaxThinkingTokens: 8000}
+		if _, err := options.BuildCLIArgs(); err == nil || !strings.Contains(err.Error(), "invalid additional directory") {
+			t.Errorf("%s: expected invalid directory error, got %v", name, err)
+		}
+	}
+}
+
+func TestBuildCLIArgs_ForkSession(t *testing.T) {
+	options := &Options{Resume: "s1", ForkSession: true, MaxThinkingTokens: 8000}
+	result, err := options.BuildCLIArgs()
+	if err != nil {
+		t.Fatalf("BuildCLIArgs() returned error: %v", err)
+	}
+	if want := []string{"--resume", "s1", "--fork-session"}; !reflect.DeepEqual(result, want) {
+		t.Errorf("BuildCLIArgs() = %q, want %q", result, want)
+	}
+
+	options = &Options{ForkSession: true, MaxThinkingTokens: 8000}
+	if _, err := options.BuildCLIArgs(); err == nil || !strings.Contains(err.Error(), "ForkSession") {
+		t.Errorf("Expected error forking without a session, got %v", err)
+	}
+}
+
 // Helper function
 func permissionModePtr(mode PermissionMode) *PermissionMode {
 	return &mode
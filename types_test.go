@@ -2,6 +2,9 @@ package claudecode
 
 import (
 	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -130,7 +133,125 @@ func TestOptions(t *testing.T) {
 	})
 }
 
+func TestOptionsValidate(t *testing.T) {
+	t.Run("valid options", func(t *testing.T) {
+		options := NewOptions()
+		options.Cwd = t.TempDir()
+		options.AllowedTools = []string{"Read"}
+		if err := options.Validate(); err != nil {
+			t.Errorf("Validate() unexpected error: %v", err)
+		}
+	})
+
+	t.Run("nil options", func(t *testing.T) {
+		var options *Options
+		if err := options.Validate(); err != nil {
+			t.Errorf("Validate() unexpected error: %v", err)
+		}
+	})
+
+	t.Run("reports every invalid setting", func(t *testing.T) {
+		options := NewOptions()
+		options.Model = "gpt-4"
+		options.AllowedTools = []string{"Read; rm -rf /"}
+		mode := PermissionMode("yolo")
+		options.PermissionMode = &mode
+		options.Resume = "../session"
+		options.Cwd = filepath.Join(t.TempDir(), "missing")
+		options.Timezone = "Europe/../../etc/passwd"
+
+		err := options.Validate()
+		if err == nil {
+			t.Fatal("Validate() expected error")
+		}
+		for _, want := range []string{
+			"invalid model",
+			"invalid allowed tool name",
+			"invalid permission mode",
+			"invalid resume ID",
+			"invalid timezone",
+			"invalid working directory",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Validate() error missing %q: %v", want, err)
+			}
+		}
+	})
+}
+
+func TestOptionsGetEnv(t *testing.T) {
+	options := NewOptions()
+	options.Locale = "en_US.UTF-8"
+	options.Timezone = "UTC"
+	options.HTTPSProxy = "http://proxy.corp:3128"
+	options.NoProxy = "localhost,.corp"
+
+	env, err := options.GetEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"LANG=en_US.UTF-8",
+		"LC_ALL=en_US.UTF-8",
+		"TZ=UTC",
+		"HTTPS_PROXY=http://proxy.corp:3128",
+		"https_proxy=http://proxy.corp:3128",
+		"NO_PROXY=localhost,.corp",
+		"no_proxy=localhost,.corp",
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("GetEnv() = %v, want %v", env, want)
+	}
+
+	options.HTTPProxy = "proxy.corp:3128"
+	if _, err := options.GetEnv(); err == nil || !strings.Contains(err.Error(), "invalid proxy URL") {
+		t.Errorf("expected proxy URL error, got %v", err)
+	}
+}
+
+func TestOptionsGetEnvOverrides(t *testing.T) {
+	options := NewOptions()
+	options.Timezone = "UTC"
+	options.Env = map[string]string{"TZ": "Europe/Paris", "ANTHROPIC_API_KEY": "sk-test"}
+
+	env, err := options.GetEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ANTHROPIC_API_KEY=sk-test", "TZ=Europe/Paris"}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("GetEnv() = %v, want %v", env, want)
+	}
+
+	options.Env = map[string]string{"BAD=NAME": "x"}
+	if _, err := options.GetEnv(); err == nil || !strings.Contains(err.Error(), "invalid environment variable name") {
+		t.Errorf("expected environment variable name error, got %v", err)
+	}
+}
+
 func TestContentBlockJSONMarshaling(t *testing.T) {
+	t.Run("ThinkingBlock round trip", func(t *testing.T) {
+		msg := AssistantMessage{Content: []ContentBlock{
+			ThinkingBlock{Thinking: "Consider the edge cases", Signature: "abc"},
+			TextBlock{Text: "Done"},
+		}}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), `{"type":"thinking","thinking":"Consider the edge cases","signature":"abc"}`) {
+			t.Errorf("unexpected JSON: %s", data)
+		}
+
+		var decoded AssistantMessage
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, msg) {
+			t.Errorf("round trip mismatch: %+v", decoded)
+		}
+	})
+
 	t.Run("AssistantMessage JSON unmarshaling", func(t *testing.T) {
 		jsonData := `{
 			"content": [
@@ -720,6 +841,30 @@ func TestBuildCLIArgs(t *testing.T) {
 				"--mcp-config", `{"mcpServers":{"test-server":{"transport":["stdio","test-mcp-server"],"env":{"PORT":8080}}}}`,
 			},
 		},
+		{
+			name: "with SSE MCP server",
+			options: &Options{
+				McpServers: map[string]McpServerConfig{
+					"remote": McpSSEServer("https://example.com/sse", map[string]string{"Authorization": "Bearer token"}),
+				},
+				MaxThinkingTokens: 8000,
+			},
+			expected: []string{
+				"--mcp-config", `{"mcpServers":{"remote":{"type":"sse","url":"https://example.com/sse","headers":{"Authorization":"Bearer token"}}}}`,
+			},
+		},
+		{
+			name: "with HTTP MCP server",
+			options: &Options{
+				McpServers: map[string]McpServerConfig{
+					"remote": McpHTTPServer("https://example.com/mcp", nil),
+				},
+				MaxThinkingTokens: 8000,
+			},
+			expected: []string{
+				"--mcp-config", `{"mcpServers":{"remote":{"type":"http","url":"https://example.com/mcp"}}}`,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,181 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/transport"
+	"github.com/f-pisani/claude-code-sdk-go/internal/validation"
+)
+
+// defaultUpdateCheckInterval is how often an UpdateChecker checks by default
+const defaultUpdateCheckInterval = 24 * time.Hour
+
+// CLIUpdateAdvisory reports that the installed CLI is older than the
+// minimum supported version. It is delivered on the message channel of
+// queries wrapped by UpdateChecker.
+type CLIUpdateAdvisory struct {
+	CLIPath    string    `json:"cli_path"`
+	Version    string    `json:"version"`     // Version the CLI reports
+	MinVersion string    `json:"min_version"` // Minimum supported version it is older than
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+func (CLIUpdateAdvisory) isMessage() {}
+
+// UpdateChecker checks in the background whether the installed CLI is older
+// than a minimum supported version, so fleets can track hosts that have not
+// updated. Checks are rate-limited to one per Interval, and the version of
+// an unchanged binary is only read once per process.
+//
+// Example:
+//
+//	checker := &UpdateChecker{MinVersion: "1.0.60"}
+//	query := checker.Wrap(Query)
+//	msgCh, errCh := query(ctx, "Hello", opts)
+//	for msg := range msgCh {
+//	    if adv, ok := msg.(CLIUpdateAdvisory); ok {
+//	        log.Printf("claude %s on this host is older than %s", adv.Version, adv.MinVersion)
+//	    }
+//	}
+type UpdateChecker struct {
+	// MinVersion is the oldest supported CLI version, e.g. "1.0.60"
+	MinVersion string
+	// CLIPath is the CLI to check (defaults to the one Query finds)
+	CLIPath string
+	// Interval is the minimum time between checks (defaults to 24h)
+	Interval time.Duration
+
+	mu        sync.Mutex
+	lastCheck time.Time
+}
+
+// Check reports whether the CLI is older than MinVersion, returning nil if
+// it is up to date. It is not rate-limited.
+func (c *UpdateChecker) Check(ctx context.Context) (*CLIUpdateAdvisory, error) {
+	if _, err := validation.VersionSatisfies(c.MinVersion, ">=0"); err != nil {
+		return nil, fmt.Errorf("invalid minimum CLI version: %w", err)
+	}
+	cliPath := c.CLIPath
+	if cliPath == "" {
+		cliPath = transport.FindCLI()
+	}
+	if cliPath == "" {
+		return nil, NewCLINotFoundError("Claude Code not found", "")
+	}
+
+	version, err := transport.CLIVersion(ctx, cliPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CLI version: %w", err)
+	}
+	current, err := validation.VersionSatisfies(version, ">="+c.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	if current {
+		return nil, nil
+	}
+	return &CLIUpdateAdvisory{
+		CLIPath:    cliPath,
+		Version:    version,
+		MinVersion: c.MinVersion,
+		CheckedAt:  time.Now(),
+	}, nil
+}
+
+// due reports whether a check is due and, if so, records it as made
+func (c *UpdateChecker) due() bool {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = defaultUpdateCheckInterval
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if !c.lastCheck.IsZero() && now.Sub(c.lastCheck) < interval {
+		return false
+	}
+	c.lastCheck = now
+	return true
+}
+
+// Wrap returns a QueryFunc that runs next and, when a check is due, checks
+// the CLI alongside it, delivering a CLIUpdateAdvisory if it is outdated.
+// Failed checks are ignored. With Options.InlineErrors, any ErrorMessage is
+// still delivered last.
+func (c *UpdateChecker) Wrap(next QueryFunc) QueryFunc {
+	return func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+		if options == nil {
+			options = NewOptions()
+		}
+		if !c.due() {
+			return next(ctx, prompt, options)
+		}
+
+		advisoryCh := make(chan *CLIUpdateAdvisory, 1)
+		go func() {
+			advisory, _ := c.Check(ctx)
+			advisoryCh <- advisory
+		}()
+
+		inMsgCh, errCh := next(ctx, prompt, options)
+		msgCh := make(chan Message, options.GetMessageBufferSize())
+		go func() {
+			defer close(msgCh)
+
+			emit := func(msg Message) bool {
+				select {
+				case msgCh <- msg:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			}
+
+			var errMsg Message
+			for inMsgCh != nil {
+				select {
+				case msg, ok := <-inMsgCh:
+					if !ok {
+						inMsgCh = nil
+						continue
+					}
+					if _, isErr := msg.(ErrorMessage); isErr {
+						errMsg = msg
+						continue
+					}
+					if !emit(msg) {
+						return
+					}
+				case advisory := <-advisoryCh:
+					advisoryCh = nil
+					if advisory != nil && !emit(*advisory) {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			// A check still running when the query ends is waited for, so
+			// its advisory is not lost
+			if advisoryCh != nil {
+				select {
+				case advisory := <-advisoryCh:
+					if advisory != nil && !emit(*advisory) {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+			if errMsg != nil {
+				emit(errMsg)
+			}
+		}()
+		return msgCh, errCh
+	}
+}
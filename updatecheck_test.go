@@ -0,0 +1,87 @@
+package claudecode
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeVersionCLI writes an executable reporting version and returns its path
+func writeVersionCLI(t *testing.T, version string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "claude")
+	script := "#!/bin/sh\necho \"" + version + " (Claude Code)\"\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUpdateCheckerCheck(t *testing.T) {
+	cliPath := writeVersionCLI(t, "1.0.40")
+
+	checker := &UpdateChecker{MinVersion: "1.0.60", CLIPath: cliPath}
+	advisory, err := checker.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if advisory == nil || advisory.Version != "1.0.40" || advisory.MinVersion != "1.0.60" || advisory.CLIPath != cliPath {
+		t.Errorf("unexpected advisory %+v", advisory)
+	}
+
+	checker.MinVersion = "1.0.40"
+	if advisory, err := checker.Check(context.Background()); err != nil || advisory != nil {
+		t.Errorf("expected no advisory for a current CLI, got %+v, %v", advisory, err)
+	}
+
+	checker.MinVersion = "latest"
+	if _, err := checker.Check(context.Background()); err == nil {
+		t.Error("expected error for an invalid minimum version")
+	}
+}
+
+func TestUpdateCheckerWrap(t *testing.T) {
+	checker := &UpdateChecker{MinVersion: "2.0.0", CLIPath: writeVersionCLI(t, "1.0.40"), Interval: time.Hour}
+
+	agent := func(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+		msgCh := make(chan Message, 2)
+		errCh := make(chan error)
+		msgCh <- AssistantMessage{Content: []ContentBlock{TextBlock{Text: "done"}}}
+		msgCh <- ErrorMessage{Err: errors.New("boom")}
+		close(msgCh)
+		close(errCh)
+		return msgCh, errCh
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	run := func() (advisories int, last Message) {
+		opts := NewOptions()
+		opts.InlineErrors = true
+		msgCh, _ := checker.Wrap(agent)(ctx, "test", opts)
+		for msg := range msgCh {
+			if _, ok := msg.(CLIUpdateAdvisory); ok {
+				advisories++
+			}
+			last = msg
+		}
+		return advisories, last
+	}
+
+	advisories, last := run()
+	if advisories != 1 {
+		t.Errorf("expected 1 advisory, got %d", advisories)
+	}
+	if _, ok := last.(ErrorMessage); !ok {
+		t.Errorf("expected ErrorMessage last, got %T", last)
+	}
+
+	// The next query falls within the interval and is not checked
+	if advisories, _ := run(); advisories != 0 {
+		t.Errorf("expected rate-limited check, got %d advisories", advisories)
+	}
+}
@@ -0,0 +1,165 @@
+package claudecode
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/clock"
+)
+
+// UsageRecord is the usage of one completed query
+type UsageRecord struct {
+	Time                     time.Time         `json:"time"`
+	Labels                   map[string]string `json:"labels,omitempty"`
+	SessionID                string            `json:"session_id,omitempty"`
+	CostUSD                  float64           `json:"cost_usd"`
+	InputTokens              int               `json:"input_tokens"`
+	OutputTokens             int               `json:"output_tokens"`
+	CacheCreationInputTokens int               `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int               `json:"cache_read_input_tokens"`
+	NumTurns                 int               `json:"num_turns"`
+	DurationMs               int               `json:"duration_ms"`
+	IsError                  bool              `json:"is_error"`
+}
+
+// UsageTotals aggregates the usage of a group of queries
+type UsageTotals struct {
+	// Labels holds the values of the grouping labels shared by the group
+	Labels                   map[string]string `json:"labels"`
+	Queries                  int               `json:"queries"`
+	Errors                   int               `json:"errors"`
+	CostUSD                  float64           `json:"cost_usd"`
+	InputTokens              int               `json:"input_tokens"`
+	OutputTokens             int               `json:"output_tokens"`
+	CacheCreationInputTokens int               `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int               `json:"cache_read_input_tokens"`
+}
+
+// UsageTracker records the cost and token usage of queries along with their
+// cost allocation labels, for chargeback across the consumers of a shared
+// service. It is safe for concurrent use.
+//
+// Example:
+//
+//	usage := NewUsageTracker()
+//	msgCh, errCh := Do(ctx, &QueryRequest{
+//	    Prompt: prompt,
+//	    Labels: map[string]string{"team": "search"},
+//	    Usage:  usage,
+//	})
+//	...
+//	for _, totals := range usage.Report("team") {
+//	    fmt.Printf("%s: $%.2f\n", totals.Labels["team"], totals.CostUSD)
+//	}
+type UsageTracker struct {
+	// Clock timestamps the records; nil uses the system clock
+	Clock Clock
+
+	mu      sync.Mutex
+	records []UsageRecord
+}
+
+// NewUsageTracker creates an empty usage tracker
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{}
+}
+
+// Query behaves like the package level Query and records the usage reported
+// by the query's result, labelled with LabelsFromContext(ctx)
+func (u *UsageTracker) Query(ctx context.Context, prompt string, options *Options) (<-chan Message, <-chan error) {
+	msgCh, errCh := Query(ctx, prompt, options)
+	return u.track(ctx, options, msgCh, errCh)
+}
+
+// track forwards a query's channels, recording the usage of its result
+func (u *UsageTracker) track(ctx context.Context, options *Options, msgCh <-chan Message, errCh <-chan error) (<-chan Message, <-chan error) {
+	if options == nil {
+		options = NewOptions()
+	}
+	labels := LabelsFromContext(ctx)
+	return forwardQuery(ctx, options, msgCh, errCh, func(msg Message, _ error) {
+		if result, ok := msg.(ResultMessage); ok {
+			u.Record(labels, result)
+		}
+	}, nil)
+}
+
+// Record adds the usage reported by result under labels
+func (u *UsageTracker) Record(labels map[string]string, result ResultMessage) {
+	record := UsageRecord{
+		Time:                     clock.Or(u.Clock).Now(),
+		SessionID:                result.SessionID,
+		CostUSD:                  SafeFloat64Ptr(result.TotalCostUSD),
+		InputTokens:              getInt(result.Usage, "input_tokens"),
+		OutputTokens:             getInt(result.Usage, "output_tokens"),
+		CacheCreationInputTokens: getInt(result.Usage, "cache_creation_input_tokens"),
+		CacheReadInputTokens:     getInt(result.Usage, "cache_read_input_tokens"),
+		NumTurns:                 result.NumTurns,
+		DurationMs:               result.DurationMs,
+		IsError:                  result.IsError,
+	}
+	if len(labels) > 0 {
+		record.Labels = make(map[string]string, len(labels))
+		for k, v := range labels {
+			record.Labels[k] = v
+		}
+	}
+
+	u.mu.Lock()
+	u.records = append(u.records, record)
+	u.mu.Unlock()
+}
+
+// Records returns a copy of every usage record, oldest first
+func (u *UsageTracker) Records() []UsageRecord {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]UsageRecord(nil), u.records...)
+}
+
+// Report aggregates the records by the values of the given label keys,
+// ordered by those values. Records lacking a key are grouped under an empty
+// value. Without keys, a single group totals everything.
+func (u *UsageTracker) Report(groupBy ...string) []UsageTotals {
+	groups := make(map[string]*UsageTotals)
+	for _, record := range u.Records() {
+		values := make([]string, len(groupBy))
+		for i, key := range groupBy {
+			values[i] = record.Labels[key]
+		}
+		id := strings.Join(values, "\x00")
+
+		totals, ok := groups[id]
+		if !ok {
+			totals = &UsageTotals{Labels: make(map[string]string, len(groupBy))}
+			for i, key := range groupBy {
+				totals.Labels[key] = values[i]
+			}
+			groups[id] = totals
+		}
+		totals.Queries++
+		if record.IsError {
+			totals.Errors++
+		}
+		totals.CostUSD += record.CostUSD
+		totals.InputTokens += record.InputTokens
+		totals.OutputTokens += record.OutputTokens
+		totals.CacheCreationInputTokens += record.CacheCreationInputTokens
+		totals.CacheReadInputTokens += record.CacheReadInputTokens
+	}
+
+	ids := make([]string, 0, len(groups))
+	for id := range groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	report := make([]UsageTotals, 0, len(ids))
+	for _, id := range ids {
+		report = append(report, *groups[id])
+	}
+	return report
+}
@@ -0,0 +1,102 @@
+package claudecode
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUsageTrackerWithDo(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+echo '{"type":"result","subtype":"success","num_turns":2,"session_id":"s1","total_cost_usd":0.25,"usage":{"input_tokens":100,"output_tokens":40,"cache_read_input_tokens":10}}'
+`)
+
+	usage := NewUsageTracker()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = WithLabels(ctx, map[string]string{"team": "search", "feature": "default"})
+
+	for _, feature := range []string{"autocomplete", "ranking", "ranking"} {
+		msgCh, errCh := Do(ctx, &QueryRequest{
+			Prompt: "hi",
+			Labels: map[string]string{"feature": feature},
+			Usage:  usage,
+		})
+		if err := Drain(ctx, msgCh, errCh); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records := usage.Records()
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	r := records[0]
+	if r.Labels["team"] != "search" || r.Labels["feature"] != "autocomplete" {
+		t.Errorf("expected merged labels, got %v", r.Labels)
+	}
+	if r.CostUSD != 0.25 || r.InputTokens != 100 || r.OutputTokens != 40 || r.CacheReadInputTokens != 10 || r.NumTurns != 2 || r.SessionID != "s1" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+
+	report := usage.Report("feature")
+	if len(report) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", report)
+	}
+	if report[0].Labels["feature"] != "autocomplete" || report[0].Queries != 1 {
+		t.Errorf("unexpected first group: %+v", report[0])
+	}
+	if report[1].Labels["feature"] != "ranking" || report[1].Queries != 2 || report[1].CostUSD != 0.5 || report[1].InputTokens != 200 {
+		t.Errorf("unexpected second group: %+v", report[1])
+	}
+
+	if total := usage.Report(); len(total) != 1 || total[0].Queries != 3 {
+		t.Errorf("unexpected overall total: %+v", total)
+	}
+}
+
+func TestUsageTrackerReportMissingLabels(t *testing.T) {
+	usage := NewUsageTracker()
+	usage.Record(map[string]string{"team": "a"}, ResultMessage{TotalCostUSD: Float64Ptr(1)})
+	usage.Record(nil, ResultMessage{TotalCostUSD: Float64Ptr(2), IsError: true})
+
+	report := usage.Report("team")
+	if len(report) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", report)
+	}
+	if report[0].Labels["team"] != "" || report[0].CostUSD != 2 || report[0].Errors != 1 {
+		t.Errorf("expected unlabelled group first, got %+v", report[0])
+	}
+}
+
+func TestUsageTrackerClock(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	usage := NewUsageTracker()
+	usage.Clock = NewFakeClock(now)
+	usage.Record(nil, ResultMessage{})
+	if got := usage.Records()[0].Time; !got.Equal(now) {
+		t.Errorf("expected the record to be stamped %v, got %v", now, got)
+	}
+}
+
+func TestLabelsFromContext(t *testing.T) {
+	ctx := context.Background()
+	if LabelsFromContext(ctx) != nil {
+		t.Error("expected no labels")
+	}
+
+	ctx = WithLabels(ctx, map[string]string{"team": "a", "ticket": "T-1"})
+	ctx = WithLabels(ctx, map[string]string{"team": "b"})
+	labels := LabelsFromContext(ctx)
+	if labels["team"] != "b" || labels["ticket"] != "T-1" {
+		t.Errorf("unexpected labels %v", labels)
+	}
+	if MetadataFromContext(ctx) != nil {
+		t.Error("labels leaked into metadata")
+	}
+
+	labels["team"] = "mutated"
+	if LabelsFromContext(ctx)["team"] != "b" {
+		t.Error("LabelsFromContext must return a copy")
+	}
+}
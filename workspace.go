@@ -0,0 +1,151 @@
+package claudecode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/f-pisani/claude-code-sdk-go/internal/validation"
+)
+
+// Workspace is an isolated working directory provisioned for one query
+type Workspace struct {
+	// Dir is the workspace root, used as the query's working directory
+	Dir string
+	// Base is the commit the workspace was created from (git workspaces only)
+	Base string
+	// Branch is the branch checked out in the workspace (git workspaces only)
+	Branch string
+
+	release   func() error
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewWorkspace wraps dir as a Workspace whose Close runs release. It is
+// intended for custom WorkspaceProvider implementations.
+func NewWorkspace(dir string, release func() error) *Workspace {
+	return &Workspace{Dir: dir, release: release}
+}
+
+// Close disposes of the workspace. It is safe to call more than once.
+func (w *Workspace) Close() error {
+	w.closeOnce.Do(func() {
+		if w.release != nil {
+			w.closeErr = w.release()
+		}
+	})
+	return w.closeErr
+}
+
+// WorkspaceProvider materializes a fresh workspace for each query so that
+// concurrent agents cannot trample each other's edits
+type WorkspaceProvider interface {
+	Acquire(ctx context.Context) (*Workspace, error)
+}
+
+// CopyWorkspaceProvider provisions workspaces by copying Source into a new
+// temporary directory, which is removed when the workspace is closed
+type CopyWorkspaceProvider struct {
+	// Source is the directory tree copied into every workspace
+	Source string
+	// TempDir is where workspaces are created (defaults to os.TempDir())
+	TempDir string
+	// Skip lists file or directory names not copied, e.g. ".git" or "node_modules"
+	Skip []string
+}
+
+// Acquire copies Source into a new temporary directory
+func (p *CopyWorkspaceProvider) Acquire(ctx context.Context) (*Workspace, error) {
+	src, err := validation.ValidatePath(p.Source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workspace source: %w", err)
+	}
+
+	dir, err := os.MkdirTemp(p.TempDir, "claude-workspace-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	ws := NewWorkspace(dir, func() error {
+		return os.RemoveAll(dir)
+	})
+
+	if err := copyTree(ctx, src, dir, p.Skip); err != nil {
+		ws.Close()
+		return nil, fmt.Errorf("failed to populate workspace: %w", err)
+	}
+	return ws, nil
+}
+
+// copyTree copies the directory tree at src into the existing directory dst
+func copyTree(ctx context.Context, src, dst string, skip []string) error {
+	skipped := make(map[string]bool, len(skip))
+	for _, name := range skip {
+		skipped[name] = true
+	}
+
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if skipped[d.Name()] {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.Mkdir(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return nil // Skip sockets, devices and other special files
+		}
+	})
+}
+
+// copyFile copies a regular file
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
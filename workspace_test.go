@@ -0,0 +1,104 @@
+package claudecode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCopyWorkspaceProvider(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{
+		"main.go":           "package main",
+		"pkg/util.go":       "package pkg",
+		".git/HEAD":         "ref: refs/heads/main",
+		"node_modules/x.js": "x",
+	})
+
+	provider := &CopyWorkspaceProvider{Source: src, TempDir: t.TempDir(), Skip: []string{".git", "node_modules"}}
+	ws, err := provider.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire() error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(ws.Dir, "pkg", "util.go"))
+	if err != nil || string(data) != "package pkg" {
+		t.Errorf("Expected nested file to be copied, got %q, %v", data, err)
+	}
+	for _, skipped := range []string{".git", "node_modules"} {
+		if _, err := os.Stat(filepath.Join(ws.Dir, skipped)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be skipped", skipped)
+		}
+	}
+
+	if err := ws.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if err := ws.Close(); err != nil {
+		t.Errorf("Second Close() error: %v", err)
+	}
+	if _, err := os.Stat(ws.Dir); !os.IsNotExist(err) {
+		t.Error("Expected workspace to be removed on Close")
+	}
+}
+
+func TestDoWithWorkspace(t *testing.T) {
+	installFakeCLI(t, `#!/bin/sh
+echo "changed" > main.go
+echo "{\"type\":\"result\",\"subtype\":\"success\",\"result\":\"$(pwd)\"}"
+`)
+
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{"main.go": "original"})
+	tmp := t.TempDir()
+
+	opts := NewOptions()
+	opts.Cwd = src
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msgCh, errCh := Do(ctx, &QueryRequest{
+		Prompt:    "edit main.go",
+		Options:   opts,
+		Workspace: &CopyWorkspaceProvider{Source: src, TempDir: tmp},
+	})
+	var dir string
+	for msg := range msgCh {
+		if m, ok := msg.(ResultMessage); ok {
+			dir = SafeStringPtr(m.Result)
+		}
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(dir, tmp) {
+		t.Errorf("Expected query to run inside the workspace, ran in %q", dir)
+	}
+	if data, _ := os.ReadFile(filepath.Join(src, "main.go")); string(data) != "original" {
+		t.Errorf("Source tree was modified: %q", data)
+	}
+	if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+		t.Errorf("Expected workspace to be cleaned up, found %v", entries)
+	}
+	if opts.Cwd != src {
+		t.Error("Do modified the caller's options")
+	}
+}